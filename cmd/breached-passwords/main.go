@@ -0,0 +1,163 @@
+/*
+Package Name: main
+File Name: main.go
+Abstract: A command that builds the list of breached passwords the password policy
+checks from a list of hashes of Have I Been Pwned.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== ENTRY POINT ========
+func main() {
+
+	//	======== FLAGS ========
+	items := flag.Uint64("n", 1000000, "the number of passwords the list will hold")
+	rate := flag.Float64("p", 0.001, "the rate of passwords wrongly reported as breached")
+	output := flag.String("o", "breached-passwords.bloom", "the file to write the list to")
+	minCount := flag.Uint64("min-count", 0, "skip the hashes seen fewer times in breaches")
+	plain := flag.Bool("plain", false, "read one plaintext password per line instead of hashes")
+	flag.Usage = func() {
+		fmt.Println("Usage: breached-passwords [-n 1000000] [-p 0.001] [-o breached-passwords.bloom] [-min-count 0] [-plain] [file]")
+		fmt.Println("\nReads the lines SHA1:COUNT of the Pwned Passwords downloader, or the standard input if no file is given.")
+		os.Exit(1)
+	}
+	flag.Parse()
+
+	if *rate <= 0 || *rate >= 1 || flag.NArg() > 1 {
+		flag.Usage()
+	}
+
+	var input io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Println("Unable to open the list of passwords:", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	// ======== BUILDING THE FILTER ========
+	filter := common.NewBloomFilter(*items, *rate)
+	added, skipped := uint64(0), uint64(0)
+
+	scanner := bufio.NewScanner(input)
+	for line := 1; scanner.Scan(); line++ {
+		digest, count, err := parseLine(scanner.Text(), *plain)
+		if err != nil {
+			fmt.Printf("Line %d is not valid: %s\n", line, err)
+			os.Exit(1)
+		}
+		if digest == nil || count < *minCount {
+			skipped++
+			continue
+		}
+		filter.Add(digest)
+		added++
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println("Unable to read the list of passwords:", err)
+		os.Exit(1)
+	}
+
+	if added > *items {
+		fmt.Printf("Warning: %d passwords were added to a list sized for %d, so more will be wrongly reported as breached.\n", added, *items)
+	}
+
+	// ======== WRITING THE FILTER ========
+	file, err := os.Create(*output)
+	if err != nil {
+		fmt.Println("Unable to create the output file:", err)
+		os.Exit(1)
+	}
+	writer := bufio.NewWriter(file)
+	written, err := filter.WriteTo(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		fmt.Println("Unable to write the list of breached passwords:", err)
+		os.Exit(1)
+	}
+
+	// The output can be pasted into the configs/.env.{environment} file.
+	fmt.Printf("\n# %d passwords added (%d skipped), %d bytes\n", added, skipped, written)
+	fmt.Printf("PASSWORD_BREACHED_FILTER=%s\n", *output)
+}
+
+// ======== PRIVATE METHODS ========
+
+// parseLine returns the SHA-1 digest of the password of a line and how many
+// times it was seen in breaches. Empty lines return a nil digest.
+func parseLine(line string, plain bool) ([]byte, uint64, error) {
+	if plain {
+		if line == "" {
+			return nil, 0, nil
+		}
+		sum := sha1.Sum([]byte(line))
+		return sum[:], 1, nil
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, 0, nil
+	}
+
+	hash, count, found := strings.Cut(line, ":")
+	digest, err := hex.DecodeString(hash)
+	if err != nil || len(digest) != sha1.Size {
+		return nil, 0, fmt.Errorf("'%s' is not a SHA-1 hash", hash)
+	}
+	if !found {
+		return digest, 1, nil
+	}
+
+	seen, err := strconv.ParseUint(count, 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("'%s' is not a count", count)
+	}
+	return digest, seen, nil
+}
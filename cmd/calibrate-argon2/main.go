@@ -0,0 +1,81 @@
+/*
+Package Name: main
+File Name: main.go
+Abstract: A command that picks the argon2 parameters that hash a password in a
+target time on the current machine.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== ENTRY POINT ========
+func main() {
+
+	//	======== FLAGS ========
+	parallelism := runtime.NumCPU()
+	if parallelism > 4 {
+		parallelism = 4
+	}
+
+	target := flag.Duration("target", 500*time.Millisecond, "how long hashing a password should take")
+	memory := flag.Uint("memory", 64*1024, "the maximum memory to use, in KiB")
+	threads := flag.Uint("parallelism", uint(parallelism), "the number of threads to use")
+	flag.Usage = func() {
+		fmt.Println("Usage: calibrate-argon2 [-target 500ms] [-memory 65536] [-parallelism 4]")
+		os.Exit(1)
+	}
+	flag.Parse()
+
+	if *threads < 1 || *threads > 255 || *memory < 8**threads {
+		fmt.Println("The parameters are not valid!")
+		os.Exit(1)
+	}
+
+	// ======== CALIBRATION ========
+	fmt.Printf("Calibrating argon2id for %s on this machine...\n", *target)
+	params := common.Hasher.Calibrate(*target, uint32(*memory), uint8(*threads))
+
+	start := time.Now()
+	common.Hasher.HashWithParameters("calibration password", params)
+	elapsed := time.Since(start)
+
+	// The output can be pasted into the configs/.env.{environment} file.
+	fmt.Printf("\n# Hashing takes %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("ARGON2_MEMORY=%d\n", params.Memory())
+	fmt.Printf("ARGON2_ITERATIONS=%d\n", params.Iterations())
+	fmt.Printf("ARGON2_PARALLELISM=%d\n", params.Parallelism())
+}
@@ -7,7 +7,7 @@ and perform more complex tasks.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -41,6 +41,7 @@ import (
 	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
 	"github.com/alexmodrono/gin-restapi-template/pkg/auth"
 	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
 	"github.com/alexmodrono/gin-restapi-template/pkg/users"
 	"go.uber.org/fx"
 )
@@ -105,6 +106,7 @@ var Module = fx.Options(
 	// Context exports
 	users.Context,
 	auth.Context,
+	roles.Context,
 
 	// Bootstrap exports
 	fx.Provide(GetRoutes),
@@ -5,7 +5,7 @@ Abstract: The wrapper for setting up all the routes.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -33,6 +33,7 @@ package bootstrap
 
 import (
 	"github.com/alexmodrono/gin-restapi-template/pkg/auth"
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
 	"github.com/alexmodrono/gin-restapi-template/pkg/users"
 )
 
@@ -52,10 +53,12 @@ type Routes []Route
 func GetRoutes(
 	userRoutes users.UsersRoutes,
 	authRoutes auth.AuthRoutes,
+	rolesRoutes roles.RolesRoutes,
 ) Routes {
 	return Routes{
 		userRoutes,
 		authRoutes,
+		rolesRoutes,
 	}
 }
 
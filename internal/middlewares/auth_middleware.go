@@ -5,7 +5,7 @@ Abstract: The middleware for protecting routes.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -45,37 +45,172 @@ import (
 
 // AuthMiddleware middleware for authentication
 type AuthMiddleware struct {
-	service interfaces.AuthService
-	logger  lib.Logger
+	service        interfaces.AuthService
+	apiKeys        interfaces.APIKeysRepository
+	sessions       interfaces.SessionsRepository
+	impersonations interfaces.ImpersonationsRepository
+	logger         lib.Logger
 }
 
+// HandlerOption configures the checks done by the handler of the middleware.
+type HandlerOption func(options *handlerOptions)
+
+// handlerOptions are the checks done by the handler on top of verifying the token.
+type handlerOptions struct {
+	requireVerifiedEmail bool
+	rejectAPIKeys        bool
+	rejectOAuthClients   bool
+	rejectSessions       bool
+	requireSession       bool
+	rejectImpersonation  bool
+}
+
+// ======== CONSTANTS ========
+
+// principalKey is the key the principal is stored under in the gin context.
+const principalKey = "principal"
+
+// apiKeyHeader is the header API keys can be sent in instead of the
+// Authorization header.
+const apiKeyHeader = "X-API-Key"
+
+// ======== ERRORS ========
+
+var (
+	ForbiddenException                = errors.New("You do not have permission to access this resource.")
+	EmailNotVerifiedException         = errors.New("You must verify your email before accessing this resource.")
+	APIKeyNotAllowedException         = errors.New("This resource cannot be accessed with an API key.")
+	OAuthClientNotAllowedException    = errors.New("This resource cannot be accessed by third-party applications.")
+	SessionRequiredException          = errors.New("A session cookie is required for accessing this data.")
+	ImpersonationNotAllowedException  = errors.New("This action cannot be performed while impersonating a user.")
+	ImpersonationNotRecordedException = errors.New("The request could not be recorded in the audit trail of the impersonation.")
+)
+
 // ======== PUBLIC METHODS ========
 
 // GetAuthMiddleware returns the auth middleware
 func GetAuthMiddleware(
 	logger lib.Logger,
 	service interfaces.AuthService,
+	apiKeys interfaces.APIKeysRepository,
+	sessions interfaces.SessionsRepository,
+	impersonations interfaces.ImpersonationsRepository,
 ) AuthMiddleware {
 	return AuthMiddleware{
-		service: service,
-		logger:  logger,
+		service:        service,
+		apiKeys:        apiKeys,
+		sessions:       sessions,
+		impersonations: impersonations,
+		logger:         logger,
 	}
 }
 
 // Setup sets up jwt auth middleware
 func (middleware AuthMiddleware) Setup() {}
 
-// Handler handles the middleware's functionality
-func (middleware AuthMiddleware) Handler() gin.HandlerFunc {
+// RequireVerifiedEmail makes the handler reject the users that have not verified
+// their email yet.
+func RequireVerifiedEmail() HandlerOption {
+	return func(options *handlerOptions) {
+		options.requireVerifiedEmail = true
+	}
+}
+
+// RejectAPIKeys makes the handler reject the requests authenticated with an
+// API key, for routes that manage the account and its credentials.
+func RejectAPIKeys() HandlerOption {
+	return func(options *handlerOptions) {
+		options.rejectAPIKeys = true
+	}
+}
+
+// RejectOAuthClients makes the handler reject the tokens issued to OAuth
+// clients, for routes that only the API's own applications can use.
+func RejectOAuthClients() HandlerOption {
+	return func(options *handlerOptions) {
+		options.rejectOAuthClients = true
+	}
+}
+
+// RejectSessions makes the handler ignore cookie sessions, so that only bearer
+// tokens and API keys are accepted.
+func RejectSessions() HandlerOption {
+	return func(options *handlerOptions) {
+		options.rejectSessions = true
+	}
+}
+
+// RequireSession makes the handler only accept cookie sessions, for routes that
+// are only meant for browser clients.
+func RequireSession() HandlerOption {
+	return func(options *handlerOptions) {
+		options.requireSession = true
+	}
+}
+
+// RejectImpersonation makes the handler reject the tokens issued to admins for
+// impersonating a user, for routes that change the credentials of the account
+// or that only the user should be able to use.
+func RejectImpersonation() HandlerOption {
+	return func(options *handlerOptions) {
+		options.rejectImpersonation = true
+	}
+}
+
+// Handler handles the middleware's functionality. Requests can be authenticated
+// with a bearer token or an API key in the Authorization header, or with the
+// cookie of a session, in that order. Requests with unsafe methods authenticated
+// with a cookie must also send the CSRF token of the session.
+func (middleware AuthMiddleware) Handler(opts ...HandlerOption) gin.HandlerFunc {
+	options := handlerOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return func(ctx *gin.Context) {
-		// Retrieve the Authorization header from the request
-		authHeader := ctx.Request.Header.Get("Authorization")
-		authHeaderSplit := strings.Split(authHeader, " ")
+		scheme, credentials := getCredentials(ctx.Request)
+		if scheme == "" && !options.rejectSessions {
+			if cookie, err := ctx.Cookie(interfaces.SessionCookie); err == nil && cookie != "" {
+				scheme, credentials = "session", cookie
+			}
+		}
 
-		if len(authHeaderSplit) != 2 || strings.ToLower(authHeaderSplit[0]) != "bearer" {
+		if options.requireSession && scheme != "session" {
+			middleware.logger.Info("Tried to access a route that requires a session without one.")
+			ctx.AbortWithError(http.StatusUnauthorized, SessionRequiredException)
+			return
+		}
+
+		var principal *interfaces.Principal
+		var err error
+		switch scheme {
+		case "session":
+			var session *interfaces.Session
+			principal, session, err = middleware.sessions.CheckSession(credentials)
+			// Browsers send cookies along requests started by any website, so
+			// the requests that change something must prove they come from
+			// the client by sending the CSRF token, which other websites
+			// cannot read.
+			if err == nil && !isSafeMethod(ctx.Request.Method) &&
+				!session.CheckCSRFToken(ctx.GetHeader(interfaces.CSRFHeader)) {
+				middleware.logger.Info("User", principal.UserID, "sent a request without a valid CSRF token.")
+				ctx.AbortWithError(http.StatusForbidden, interfaces.InvalidCSRFTokenException)
+				return
+			}
+		case "bearer":
+			// Check the validity of the token using the authentication service
+			principal, err = middleware.service.CheckToken(credentials)
+		case "apikey":
+			if options.rejectAPIKeys {
+				middleware.logger.Info("Tried to access a route that does not accept API keys with one.")
+				ctx.AbortWithError(http.StatusForbidden, APIKeyNotAllowedException)
+				return
+			}
+			principal, err = middleware.apiKeys.CheckAPIKey(credentials)
+		default:
 			middleware.logger.Info("Tried to access protected route without credentials.")
-			// If the Authorization header is missing or does not start with "Bearer",
-			// return an HTTP 401 Unauthorized response or handle the error appropriately.
+			// If there is neither a bearer token, an API key nor a session,
+			// return an HTTP 401 Unauthorized response.
 			ctx.AbortWithError(
 				http.StatusUnauthorized,
 				errors.New("An access token is required for accessing this data."),
@@ -83,20 +218,154 @@ func (middleware AuthMiddleware) Handler() gin.HandlerFunc {
 			return
 		}
 
-		// Extract the token from the Authorization header
-		token := authHeaderSplit[1]
-		// Check the validity of the token using the authentication service
-		id, err := middleware.service.CheckToken(token)
 		if err != nil {
-			// If there is an error in token verification, return an internal server error
-			ctx.AbortWithError(http.StatusInternalServerError, err)
+			// If the token, the key or the session are invalid, expired or
+			// have been revoked, the client has to authenticate again.
+			middleware.logger.Info("Tried to access protected route with invalid credentials:", err)
+			ctx.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		// Every request made while impersonating a user is recorded before
+		// anything else is checked, and refused if it cannot be, so that the
+		// audit trail cannot miss any of them.
+		if principal.IsImpersonated() {
+			id, err := middleware.impersonations.RecordRequest(principal.SessionID, interfaces.ImpersonatedRequest{
+				Method:    ctx.Request.Method,
+				Path:      ctx.Request.URL.RequestURI(),
+				IPAddress: ctx.ClientIP(),
+				UserAgent: ctx.Request.UserAgent(),
+			})
+			if errors.Is(err, interfaces.ImpersonationNotFoundException) {
+				// The impersonation has ended, so its token has been revoked.
+				ctx.AbortWithError(http.StatusUnauthorized, interfaces.RevokedTokenException)
+				return
+			} else if err != nil {
+				middleware.logger.Error("Could not record a request made by user", principal.ActorID, "as user", principal.UserID, "-", err)
+				ctx.AbortWithError(http.StatusInternalServerError, ImpersonationNotRecordedException)
+				return
+			}
+			defer func() {
+				if err := middleware.impersonations.CompleteRequest(id, ctx.Writer.Status()); err != nil {
+					middleware.logger.Error("Could not record the status of request", id, "-", err)
+				}
+			}()
+
+			if options.rejectImpersonation {
+				middleware.logger.Info("User", principal.ActorID, "tried to access a route that does not allow impersonation as user", principal.UserID)
+				ctx.AbortWithError(http.StatusForbidden, ImpersonationNotAllowedException)
+				return
+			}
+		}
+
+		if options.rejectOAuthClients && principal.ClientID != "" {
+			middleware.logger.Info("The OAuth client", principal.ClientID, "tried to access a first-party route.")
+			ctx.AbortWithError(http.StatusForbidden, OAuthClientNotAllowedException)
 			return
 		}
 
-		// Set the authenticated user's ID in the context for downstream handlers to access
-		ctx.Set("id", id)
+		if options.requireVerifiedEmail && !principal.EmailVerified {
+			middleware.logger.Info("User", principal.UserID, "tried to access a protected route without verifying their email.")
+			ctx.AbortWithError(http.StatusForbidden, EmailNotVerifiedException)
+			return
+		}
+
+		// Set the authenticated principal in the context for downstream handlers
+		// to access through GetPrincipal.
+		ctx.Set(principalKey, principal)
 		ctx.Next()
 		return
 
 	}
 }
+
+// Require returns a handler that only lets through principals that have been
+// granted every one of the permissions provided. It must be used after Handler,
+// and the response is always a 403 with the permissions that were required.
+func (middleware AuthMiddleware) Require(permissions ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal := GetPrincipal(ctx)
+		if principal == nil {
+			ctx.AbortWithError(
+				http.StatusUnauthorized,
+				errors.New("An access token is required for accessing this data."),
+			)
+			return
+		}
+
+		missing := []string{}
+		for _, permission := range permissions {
+			if !principal.HasScope(permission) {
+				missing = append(missing, permission)
+			}
+		}
+
+		if len(missing) > 0 {
+			middleware.logger.Info(
+				"User", principal.UserID, "was denied access to", ctx.Request.Method, ctx.FullPath(),
+				"- missing permissions:", strings.Join(missing, ", "),
+			)
+			ctx.AbortWithError(http.StatusForbidden, ForbiddenException).SetMeta(gin.H{
+				"required_permissions": permissions,
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// GetPrincipal returns the principal stored in the context by the auth
+// middleware, or nil if the request was not authenticated.
+func GetPrincipal(ctx *gin.Context) *interfaces.Principal {
+	if value, ok := ctx.Get(principalKey); ok {
+		if principal, ok := value.(*interfaces.Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// MustGetPrincipal returns the principal stored in the context by the auth
+// middleware, and panics if the request was not authenticated. It should
+// only be used in routes protected by the middleware.
+func MustGetPrincipal(ctx *gin.Context) *interfaces.Principal {
+	principal := GetPrincipal(ctx)
+	if principal == nil {
+		panic("the request has not been authenticated by the auth middleware")
+	}
+	return principal
+}
+
+// ======== PRIVATE METHODS ========
+
+// getCredentials returns the lowercased authorization scheme of a request and
+// its credentials. Both "Authorization: Bearer <token>" and
+// "Authorization: ApiKey <key>" are accepted, as well as the key alone in the
+// X-API-Key header.
+func getCredentials(request *http.Request) (string, string) {
+	authHeader := request.Header.Get("Authorization")
+	if authHeader == "" {
+		if key := request.Header.Get(apiKeyHeader); key != "" {
+			return "apikey", key
+		}
+		return "", ""
+	}
+
+	authHeaderSplit := strings.Split(authHeader, " ")
+	if len(authHeaderSplit) != 2 {
+		return "", ""
+	}
+	return strings.ToLower(authHeaderSplit[0]), authHeaderSplit[1]
+}
+
+// isSafeMethod returns whether an HTTP method is safe (RFC 9110, section 9.2.1),
+// i.e. it is not meant to change anything.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,388 @@
+/*
+Package Name: middlewares
+File Name: auth_middleware_test.go
+Abstract: Tests for the auth middleware.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package middlewares_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRequireRouter(authService *mocks.MockAuthService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errorsMiddleware.Setup()
+
+	authMiddleware := middlewares.GetAuthMiddleware(mocks.NewMockLogger(), authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+	router.GET(
+		"/protected",
+		authMiddleware.Handler(),
+		authMiddleware.Require("users:read"),
+		func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+	)
+	router.GET(
+		"/unauthenticated",
+		authMiddleware.Require("users:read"),
+		func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+	)
+
+	return router
+}
+
+func TestAuthMiddleware_Require(t *testing.T) {
+	t.Run("Allowed", func(t *testing.T) {
+		router := setupRequireRouter(&mocks.MockAuthService{Scopes: []string{"users:read"}})
+
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("MissingPermission", func(t *testing.T) {
+		router := setupRequireRouter(&mocks.MockAuthService{Scopes: []string{"roles:read"}})
+
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, middlewares.ForbiddenException.Error(), response["error"])
+		assert.Equal(t, []interface{}{"users:read"}, response["required_permissions"])
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		router := setupRequireRouter(&mocks.MockAuthService{})
+
+		req, _ := http.NewRequest("GET", "/unauthenticated", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthMiddleware_RequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	setup := func(authService *mocks.MockAuthService) *gin.Engine {
+		router := gin.New()
+		errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+		errorsMiddleware.Setup()
+
+		authMiddleware := middlewares.GetAuthMiddleware(mocks.NewMockLogger(), authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+		router.GET(
+			"/verified",
+			authMiddleware.Handler(middlewares.RequireVerifiedEmail()),
+			func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+		)
+		return router
+	}
+
+	t.Run("Verified", func(t *testing.T) {
+		router := setup(&mocks.MockAuthService{EmailVerified: true})
+
+		req, _ := http.NewRequest("GET", "/verified", nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Unverified", func(t *testing.T) {
+		router := setup(&mocks.MockAuthService{})
+
+		req, _ := http.NewRequest("GET", "/verified", nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, middlewares.EmailNotVerifiedException.Error(), response["error"])
+	})
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errorsMiddleware.Setup()
+
+	apiKeys := &mocks.MockAPIKeysService{Scopes: []string{"users:read"}}
+	authMiddleware := middlewares.GetAuthMiddleware(mocks.NewMockLogger(), &mocks.MockAuthService{}, apiKeys, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+	router.GET(
+		"/protected",
+		authMiddleware.Handler(),
+		authMiddleware.Require("users:read"),
+		func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, middlewares.MustGetPrincipal(ctx).AuthMethod)
+		},
+	)
+	router.GET(
+		"/account",
+		authMiddleware.Handler(middlewares.RejectAPIKeys()),
+		func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+	)
+
+	// get performs a request with the headers given.
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("AuthorizationHeader", func(t *testing.T) {
+		w := get("/protected", map[string]string{"Authorization": "ApiKey " + mocks.MockAPIKey})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "api_key", w.Body.String())
+	})
+
+	t.Run("XAPIKeyHeader", func(t *testing.T) {
+		w := get("/protected", map[string]string{"X-API-Key": mocks.MockAPIKey})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "api_key", w.Body.String())
+	})
+
+	t.Run("BearerTakesPrecedence", func(t *testing.T) {
+		w := get("/protected", map[string]string{
+			"Authorization": "Bearer mock_jwt_token",
+			"X-API-Key":     mocks.MockAPIKey,
+		})
+
+		// The principal of the mock JWT has no scopes.
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		w := get("/protected", map[string]string{"X-API-Key": "sk_invalid"})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		w := get("/account", map[string]string{"X-API-Key": mocks.MockAPIKey})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, middlewares.APIKeyNotAllowedException.Error(), response["error"])
+	})
+}
+
+func TestAuthMiddleware_Session(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errorsMiddleware.Setup()
+
+	sessions := &mocks.MockSessionsService{}
+	sessions.CreateSession(1, "pwd", interfaces.Device{})
+	authMiddleware := middlewares.GetAuthMiddleware(mocks.NewMockLogger(), &mocks.MockAuthService{}, &mocks.MockAPIKeysService{}, sessions, &mocks.MockImpersonationsService{})
+
+	handler := func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, middlewares.MustGetPrincipal(ctx).SessionID)
+	}
+	router.GET("/protected", authMiddleware.Handler(), handler)
+	router.POST("/protected", authMiddleware.Handler(), handler)
+	router.GET("/tokens", authMiddleware.Handler(middlewares.RejectSessions()), handler)
+	router.GET("/browser", authMiddleware.Handler(middlewares.RequireSession()), handler)
+
+	// request performs a request with the session cookie and the headers given.
+	request := func(method string, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: mocks.MockSessionToken})
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("SafeMethod", func(t *testing.T) {
+		w := request("GET", "/protected", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "session:1", w.Body.String())
+	})
+
+	t.Run("CSRF", func(t *testing.T) {
+		// Test case 1: Unsafe methods require the CSRF token
+		w := request("POST", "/protected", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// Test case 2: Which must be the one of the session
+		w = request("POST", "/protected", map[string]string{"X-CSRF-Token": "other"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = request("POST", "/protected", map[string]string{"X-CSRF-Token": mocks.MockCSRFToken})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("BearerTakesPrecedence", func(t *testing.T) {
+		// Bearer tokens are not sent automatically by browsers, so they do
+		// not need a CSRF token.
+		w := request("POST", "/protected", map[string]string{"Authorization": "Bearer mock_jwt_token"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "mock_session", w.Body.String())
+	})
+
+	t.Run("RejectSessions", func(t *testing.T) {
+		w := request("GET", "/tokens", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("RequireSession", func(t *testing.T) {
+		w := request("GET", "/browser", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = request("GET", "/browser", map[string]string{"Authorization": "Bearer mock_jwt_token"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		sessions.RevokeSession(1, 1)
+
+		w := request("GET", "/protected", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	setup := func(authService *mocks.MockAuthService, impersonations *mocks.MockImpersonationsService) *gin.Engine {
+		router := gin.New()
+		errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+		errorsMiddleware.Setup()
+
+		authMiddleware := middlewares.GetAuthMiddleware(mocks.NewMockLogger(), authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, impersonations)
+		handler := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+		router.GET("/protected", authMiddleware.Handler(), handler)
+		router.POST("/account", authMiddleware.Handler(middlewares.RejectImpersonation()), handler)
+
+		return router
+	}
+
+	request := func(router *gin.Engine, method string, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		req.Header.Set("User-Agent", "support-tool")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Recorded", func(t *testing.T) {
+		impersonations := &mocks.MockImpersonationsService{}
+		router := setup(&mocks.MockAuthService{ActorID: 7}, impersonations)
+
+		w := request(router, "GET", "/protected?page=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		if assert.Len(t, impersonations.Requests, 1) {
+			recorded := impersonations.Requests[0]
+			assert.Equal(t, "GET", recorded.Method)
+			assert.Equal(t, "/protected?page=2", recorded.Path)
+			assert.Equal(t, "support-tool", recorded.UserAgent)
+			if assert.NotNil(t, recorded.Status) {
+				assert.Equal(t, int32(http.StatusOK), *recorded.Status)
+			}
+		}
+	})
+
+	t.Run("RejectImpersonation", func(t *testing.T) {
+		impersonations := &mocks.MockImpersonationsService{}
+		router := setup(&mocks.MockAuthService{ActorID: 7}, impersonations)
+
+		w := request(router, "POST", "/account")
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, middlewares.ImpersonationNotAllowedException.Error(), response["error"])
+
+		// Rejected requests are recorded too
+		if assert.Len(t, impersonations.Requests, 1) && assert.NotNil(t, impersonations.Requests[0].Status) {
+			assert.Equal(t, int32(http.StatusForbidden), *impersonations.Requests[0].Status)
+		}
+	})
+
+	t.Run("NotRecorded", func(t *testing.T) {
+		// Requests that cannot be recorded are not handled
+		impersonations := &mocks.MockImpersonationsService{Unavailable: true}
+		router := setup(&mocks.MockAuthService{ActorID: 7}, impersonations)
+
+		w := request(router, "GET", "/protected")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("NotImpersonated", func(t *testing.T) {
+		impersonations := &mocks.MockImpersonationsService{}
+		router := setup(&mocks.MockAuthService{}, impersonations)
+
+		w := request(router, "POST", "/account")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, impersonations.Requests)
+	})
+}
@@ -5,7 +5,7 @@ Abstract: The cors middleware for implementing CORS.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/12/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -33,6 +33,7 @@ package middlewares
 
 import (
 	"os"
+	"strings"
 
 	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
 	cors "github.com/rs/cors/wrapper/gin"
@@ -61,11 +62,26 @@ func (middleware CorsMiddleware) Setup() {
 	middleware.logger.Info("Setting up [CORS] middleware")
 
 	debug := os.Getenv("ENVIRONMENT") == "development"
+
+	// Browsers send the cookies of sessions along cross-origin requests, so
+	// the origins that can read the responses can be limited with a comma
+	// separated list in CORS_ALLOWED_ORIGINS. Every origin is allowed otherwise.
+	origins := strings.FieldsFunc(os.Getenv("CORS_ALLOWED_ORIGINS"), func(r rune) bool { return r == ',' || r == ' ' })
 	middleware.router.Use(cors.New(cors.Options{
 		AllowCredentials: true,
-		AllowOriginFunc:  func(origin string) bool { return true },
-		AllowedHeaders:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "HEAD", "OPTIONS"},
-		Debug:            debug,
+		AllowOriginFunc: func(origin string) bool {
+			if len(origins) == 0 {
+				return true
+			}
+			for _, allowed := range origins {
+				if origin == allowed {
+					return true
+				}
+			}
+			return false
+		},
+		AllowedHeaders: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		Debug:          debug,
 	}))
 }
@@ -0,0 +1,79 @@
+/*
+Package Name: middlewares
+File Name: login_attempts.go
+Abstract: Helpers for throttling the handlers that check passwords or codes, with
+the failed login attempts of accounts and IP addresses.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== PUBLIC METHODS ========
+
+// AbortIfThrottled aborts the request with a 429 if the account or the IP address
+// of the request have to wait before trying to log in again.
+func AbortIfThrottled(ctx *gin.Context, attempts interfaces.LoginAttemptsRepository, account string) bool {
+	wait, err := attempts.Check(account, ctx.ClientIP())
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return true
+	}
+	if wait > 0 {
+		AbortWithRetryAfter(ctx, wait, interfaces.TooManyLoginAttemptsException)
+		return true
+	}
+	return false
+}
+
+// RecordFailedLogin records a failed login of the account from the IP address of
+// the request. The request fails anyway, so errors are only logged.
+func RecordFailedLogin(ctx *gin.Context, logger lib.Logger, attempts interfaces.LoginAttemptsRepository, account string) {
+	if _, err := attempts.RecordFailure(account, ctx.ClientIP()); err != nil {
+		logger.Error("Could not record the failed login attempt:", err)
+	}
+}
+
+// AbortWithRetryAfter aborts the request with a 429 telling the client how many
+// seconds to wait before trying again.
+func AbortWithRetryAfter(ctx *gin.Context, wait time.Duration, err error) {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	ctx.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	ctx.AbortWithError(http.StatusTooManyRequests, err).SetMeta(gin.H{
+		"retry_after": seconds,
+	})
+}
@@ -5,7 +5,7 @@ Abstract: Wrapper for exposing to fx all the components of the 'users' context.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -38,6 +38,30 @@ import "go.uber.org/fx"
 // Module exports services present
 var Context = fx.Options(
 	fx.Provide(GetAuthController),
+	fx.Provide(GetPasswordController),
+	fx.Provide(GetVerificationController),
+	fx.Provide(GetMFAController),
+	fx.Provide(GetMFAService),
+	fx.Provide(GetLockoutsController),
+	fx.Provide(GetAPIKeysController),
+	fx.Provide(GetAPIKeysService),
+	fx.Provide(GetOIDCController),
+	fx.Provide(GetIdentitiesService),
+	fx.Provide(GetOAuthController),
+	fx.Provide(GetOAuthClientsController),
+	fx.Provide(GetOAuthService),
+	fx.Provide(GetMagicLinkController),
+	fx.Provide(GetMagicLinksService),
+	fx.Provide(GetPasskeysController),
+	fx.Provide(GetPasskeysService),
+	fx.Provide(GetImpersonationsController),
+	fx.Provide(GetImpersonationsService),
+	fx.Provide(GetSessionsController),
+	fx.Provide(GetSessionsService),
+	fx.Provide(GetLoginAttemptsService),
 	fx.Provide(GetAuthService),
+	fx.Provide(GetRevocationStore),
+	fx.Provide(GetKeyring),
+	fx.Provide(GetUserTokensService),
 	fx.Provide(SetAuthRoutes),
 )
@@ -0,0 +1,303 @@
+/*
+Package Name: auth
+File Name: auth_api_keys.go
+Abstract: The service that manages the API keys of the users and
+authenticates the requests made with them.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// APIKeysService service layer
+type APIKeysService struct {
+	logger lib.Logger
+	db     *lib.Database
+	roles  roles.RolesRepository
+}
+
+// ======== CONSTANTS ========
+
+const (
+	// apiKeyScheme is the first part of every API key, which makes them easy
+	// to recognize (e.g. by secret scanners).
+	apiKeyScheme = "sk"
+	// apiKeyPrefixBytes is how many random bytes the prefix of a key has.
+	apiKeyPrefixBytes = 6
+	// apiKeyLastUsedPrecision is how often the last use of a key is recorded,
+	// so that busy keys do not write to the database on every request.
+	apiKeyLastUsedPrecision = time.Minute
+)
+
+// apiKeysQuery selects the public fields of the API keys.
+const apiKeysQuery = `
+	SELECT id, name, prefix, scopes, created_at, expires_at, last_used_at
+	FROM auth.api_key`
+
+// ======== METHODS ========
+
+// GetAPIKeysService returns the API keys service.
+func GetAPIKeysService(logger lib.Logger, db *lib.Database, roles roles.RolesRepository) interfaces.APIKeysRepository {
+	return APIKeysService{
+		logger: logger,
+		db:     db,
+		roles:  roles,
+	}
+}
+
+// CreateAPIKey creates a new API key for the user. Keys have the form
+// sk_<prefix>_<secret>, and only the prefix and the hash of the whole key are
+// stored.
+func (service APIKeysService) CreateAPIKey(
+	userID int32,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (*interfaces.APIKey, string, error) {
+	bytes := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", err
+	}
+	prefix := hex.EncodeToString(bytes)
+
+	secret, err := common.Tokens.Generate()
+	if err != nil {
+		return nil, "", err
+	}
+	key := apiKeyScheme + "_" + prefix + "_" + secret
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	apiKey := interfaces.APIKey{}
+	err = service.db.QueryRow(
+		context.Background(),
+		`INSERT INTO auth.api_key (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, name, prefix, scopes, created_at, expires_at, last_used_at;`,
+		userID,
+		name,
+		prefix,
+		common.Tokens.Hash(key),
+		scopes,
+		expiresAt,
+	).Scan(
+		&apiKey.ID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.Scopes,
+		&apiKey.CreatedAt,
+		&apiKey.ExpiresAt,
+		&apiKey.LastUsedAt,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	service.logger.Info("Created API key", apiKey.ID, "for user with id", userID)
+	return &apiKey, key, nil
+}
+
+// GetAPIKeys returns the API keys of the user that have not been revoked,
+// including the ones that have expired.
+func (service APIKeysService) GetAPIKeys(userID int32) ([]interfaces.APIKey, error) {
+	rows, err := service.db.Query(
+		context.Background(),
+		apiKeysQuery+` WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at;`,
+		userID,
+	)
+	if err != nil {
+		service.logger.Error("Error while executing query. Err:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []interfaces.APIKey{}
+	for rows.Next() {
+		var apiKey interfaces.APIKey
+		if err := rows.Scan(
+			&apiKey.ID,
+			&apiKey.Name,
+			&apiKey.Prefix,
+			&apiKey.Scopes,
+			&apiKey.CreatedAt,
+			&apiKey.ExpiresAt,
+			&apiKey.LastUsedAt,
+		); err != nil {
+			service.logger.Error("Error while iterating dataset. Err:", err)
+			return nil, err
+		}
+		results = append(results, apiKey)
+	}
+
+	return results, rows.Err()
+}
+
+// RevokeAPIKey revokes an API key of the user, which stops working immediately.
+func (service APIKeysService) RevokeAPIKey(userID int32, id int32) error {
+	tag, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.api_key SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;`,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return interfaces.APIKeyNotFoundException
+	}
+
+	service.logger.Info("Revoked API key", id, "of user with id", userID)
+	return nil
+}
+
+// CheckAPIKey validates an API key and returns the identity of its owner. The
+// scopes of the principal are the permissions the owner has right now, limited
+// to the scopes of the key, so revoking a role also takes effect on the keys.
+func (service APIKeysService) CheckAPIKey(key string) (*interfaces.Principal, error) {
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, interfaces.InvalidAPIKeyException
+	}
+
+	var (
+		id            int32
+		keyHash       string
+		keyScopes     []string
+		createdAt     time.Time
+		expiresAt     *time.Time
+		lastUsedAt    *time.Time
+		principal     = interfaces.Principal{AuthMethod: interfaces.AuthMethodAPIKey}
+		emailVerified bool
+	)
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT k.id, k.user_id, k.key_hash, k.scopes, k.created_at, k.expires_at, k.last_used_at,
+			u.email_verified_at IS NOT NULL
+		FROM auth.api_key k
+		JOIN auth.user u ON u.id = k.user_id
+		WHERE k.prefix = $1 AND k.revoked_at IS NULL
+			AND (k.expires_at IS NULL OR k.expires_at > now());`,
+		prefix,
+	).Scan(&id, &principal.UserID, &keyHash, &keyScopes, &createdAt, &expiresAt, &lastUsedAt, &emailVerified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.InvalidAPIKeyException
+	} else if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(keyHash), []byte(common.Tokens.Hash(key))) != 1 {
+		return nil, interfaces.InvalidAPIKeyException
+	}
+
+	roles, permissions, err := service.roles.GetUserPermissions(principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	principal.SessionID = "api_key:" + strconv.Itoa(int(id))
+	principal.Roles = roles
+	principal.Scopes = limitScopes(permissions, keyScopes)
+	principal.IssuedAt = createdAt
+	principal.EmailVerified = emailVerified
+	if expiresAt != nil {
+		principal.ExpiresAt = *expiresAt
+	}
+
+	if lastUsedAt == nil || time.Since(*lastUsedAt) > apiKeyLastUsedPrecision {
+		service.touch(id)
+	}
+
+	return &principal, nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// touch records that an API key has just been used. Failing to do so is not a
+// reason for rejecting the request, so errors are only logged.
+func (service APIKeysService) touch(id int32) {
+	_, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.api_key SET last_used_at = now() WHERE id = $1;`,
+		id,
+	)
+	if err != nil {
+		service.logger.Error("Could not record the use of API key", id, "Err:", err)
+	}
+}
+
+// parseAPIKey returns the prefix of an API key, and whether the key has the
+// form of the keys created by the service.
+func parseAPIKey(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != 2*apiKeyPrefixBytes || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// limitScopes returns the permissions that are also in the scopes of a key. A
+// key without scopes is granted every permission.
+func limitScopes(permissions []string, scopes []string) []string {
+	if len(scopes) == 0 {
+		return permissions
+	}
+
+	allowed := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		allowed[scope] = true
+	}
+
+	limited := []string{}
+	for _, permission := range permissions {
+		if allowed[permission] {
+			limited = append(limited, permission)
+		}
+	}
+	return limited
+}
@@ -0,0 +1,156 @@
+/*
+Package Name: auth
+File Name: auth_api_keys_controller.go
+Abstract: The controller for creating, listing and revoking the API keys
+of the authenticated user.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// APIKeysController struct
+type APIKeysController struct {
+	logger  lib.Logger
+	apiKeys interfaces.APIKeysRepository
+}
+
+type CreateAPIKeyBody struct {
+	Name string `json:"name" form:"name" binding:"required,max=100"`
+	// Scopes limit the permissions of the key. When empty, the key is granted
+	// every permission of the user.
+	Scopes    []string   `json:"scopes" form:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at" form:"expires_at"`
+}
+
+// ======== METHODS ========
+
+// GetAPIKeysController retrieves a new API keys controller.
+func GetAPIKeysController(logger lib.Logger, apiKeys interfaces.APIKeysRepository) APIKeysController {
+	return APIKeysController{
+		logger:  logger,
+		apiKeys: apiKeys,
+	}
+}
+
+// Create creates a new API key for the authenticated user. The key is only
+// returned in this response.
+func (controller APIKeysController) Create(ctx *gin.Context) {
+	controller.logger.Info("[POST] Create API key route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := CreateAPIKeyBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The expiration date must be in the future."))
+		return
+	}
+
+	// A key cannot be granted permissions the user does not have.
+	principal := middlewares.MustGetPrincipal(ctx)
+	missing := []string{}
+	for _, scope := range body.Scopes {
+		if !principal.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		ctx.AbortWithError(http.StatusForbidden, middlewares.ForbiddenException).SetMeta(gin.H{
+			"required_permissions": missing,
+		})
+		return
+	}
+
+	apiKey, key, err := controller.apiKeys.CreateAPIKey(principal.UserID, body.Name, body.Scopes, body.ExpiresAt)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully. Store it now, as it will not be shown again.",
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+// GetAll returns the API keys of the authenticated user.
+func (controller APIKeysController) GetAll(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all API keys.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	apiKeys, err := controller.apiKeys.GetAPIKeys(principal.UserID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, apiKeys)
+}
+
+// Revoke revokes an API key of the authenticated user.
+func (controller APIKeysController) Revoke(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Revoking API key with id", ctx.Param("id"))
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	err = controller.apiKeys.RevokeAPIKey(principal.UserID, int32(id))
+	if errors.Is(err, interfaces.APIKeyNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked successfully.",
+	})
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeysController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	authService := &mocks.MockAuthService{Scopes: []string{"users:read"}}
+	apiKeys := &mocks.MockAPIKeysService{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, apiKeys, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+	apiKeysController := GetAPIKeysController(logger, apiKeys)
+
+	api := router.Group("/").Use(authMiddleware.Handler(middlewares.RejectAPIKeys()))
+	api.POST("/api-keys", apiKeysController.Create)
+	api.GET("/api-keys", apiKeysController.GetAll)
+	api.DELETE("/api-keys/:id", apiKeysController.Revoke)
+
+	// request performs a request authenticated with a JWT.
+	request := func(method string, path string, body interface{}) (int, []byte) {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		code, body := request("POST", "/api-keys", CreateAPIKeyBody{
+			Name:   "ci",
+			Scopes: []string{"users:read"},
+		})
+
+		var response map[string]interface{}
+		json.Unmarshal(body, &response)
+
+		assert.Equal(t, http.StatusCreated, code)
+		assert.Equal(t, mocks.MockAPIKey, response["key"])
+		assert.Equal(t, "ci", response["api_key"].(map[string]interface{})["name"])
+		assert.Len(t, apiKeys.Keys, 1)
+	})
+
+	t.Run("CreateWithMissingPermissions", func(t *testing.T) {
+		code, body := request("POST", "/api-keys", CreateAPIKeyBody{
+			Name:   "escalation",
+			Scopes: []string{"users:read", "roles:write"},
+		})
+
+		var response map[string]interface{}
+		json.Unmarshal(body, &response)
+
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Equal(t, []interface{}{"roles:write"}, response["required_permissions"])
+		assert.Len(t, apiKeys.Keys, 1)
+	})
+
+	t.Run("CreateExpired", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour)
+		code, _ := request("POST", "/api-keys", CreateAPIKeyBody{Name: "expired", ExpiresAt: &expiresAt})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("GetAll", func(t *testing.T) {
+		code, body := request("GET", "/api-keys", nil)
+
+		var response []map[string]interface{}
+		json.Unmarshal(body, &response)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, response, 1)
+		assert.NotContains(t, response[0], "key")
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		code, _ := request("DELETE", "/api-keys/1", nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, apiKeys.Keys)
+
+		// Test case 2: The key no longer exists
+		code, _ = request("DELETE", "/api-keys/1", nil)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("WithAPIKey", func(t *testing.T) {
+		// API keys cannot be used for creating more API keys.
+		req, _ := http.NewRequest("POST", "/api-keys", bytes.NewBufferString(`{"name":"other"}`))
+		req.Header.Set("X-API-Key", mocks.MockAPIKey)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAPIKey(t *testing.T) {
+	// Test case 1: The secret of a key can contain underscores
+	prefix, ok := parseAPIKey("sk_0123456789ab_secret_with_underscores")
+	assert.True(t, ok)
+	assert.Equal(t, "0123456789ab", prefix)
+
+	// Test case 2: Keys with another form are rejected
+	for _, key := range []string{"", "sk_0123456789ab", "sk_0123456789ab_", "pk_0123456789ab_secret", "sk_short_secret"} {
+		_, ok := parseAPIKey(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func TestLimitScopes(t *testing.T) {
+	permissions := []string{"roles:read", "users:read"}
+
+	// Test case 1: Keys without scopes get every permission
+	assert.Equal(t, permissions, limitScopes(permissions, nil))
+
+	// Test case 2: Otherwise they only get the permissions in their scopes
+	assert.Equal(t, []string{"users:read"}, limitScopes(permissions, []string{"users:read", "roles:write"}))
+
+	// Test case 3: Scopes the user no longer has are not granted
+	assert.Equal(t, []string{}, limitScopes(permissions, []string{"roles:write"}))
+}
@@ -0,0 +1,111 @@
+/*
+Package Name: auth
+File Name: auth_challenge.go
+Abstract: Short-lived tokens issued between the steps of a login.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ======== TYPES ========
+
+// ChallengeClaims are the claims of a challenge token, which proves that a
+// user completed a step of a login (e.g. the password) and can only be used
+// for completing the step given by its purpose.
+type ChallengeClaims struct {
+	jwt.RegisteredClaims
+	Purpose string `json:"purpose"`
+}
+
+// ======== CONSTANTS ========
+
+// challengeTokenType is the typ header of challenge tokens, which keeps them
+// from being mistaken for access tokens.
+const challengeTokenType = "challenge+jwt"
+
+// ======== METHODS ========
+
+// CreateChallenge returns a challenge token for the user.
+func (service AuthService) CreateChallenge(id int32, purpose string) (string, error) {
+	key, err := service.keyring.SigningKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := ChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(int(id)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(challengeTokenTTL())),
+			Issuer:    tokenIssuer(),
+		},
+		Purpose: purpose,
+	}
+	if audience := tokenAudience(); audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+
+	return signToken(key, claims, challengeTokenType)
+}
+
+// CheckChallenge checks a challenge token and returns the user it was issued for.
+func (service AuthService) CheckChallenge(tokenString string, purpose string) (int32, error) {
+	claims := ChallengeClaims{}
+	options := append(claimsParserOptions(), jwt.WithValidMethods(service.keyring.Methods()))
+	token, err := jwt.ParseWithClaims(tokenString, &claims, service.verificationKey, options...)
+	if err != nil || !token.Valid {
+		return 0, interfaces.InvalidChallengeException
+	}
+
+	if typ, _ := token.Header["typ"].(string); typ != challengeTokenType || claims.Purpose != purpose {
+		return 0, interfaces.InvalidChallengeException
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, interfaces.InvalidChallengeException
+	}
+
+	return int32(id), nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// challengeTokenTTL returns how long challenge tokens are valid for.
+func challengeTokenTTL() time.Duration {
+	return common.Env.Duration("CHALLENGE_TOKEN_TTL", 5*time.Minute)
+}
@@ -0,0 +1,163 @@
+/*
+Package Name: auth
+File Name: auth_claims.go
+Abstract: The claims of the access tokens issued by the API and their
+conversion to and from the principal of a request.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ======== TYPES ========
+
+// AccessClaims are the claims of an access token.
+//
+// Besides the registered claims, tokens carry the session they belong to
+// (sid), the roles of the user, the granted scopes as a space delimited
+// string (scope), the authentication methods used (amr), whether the
+// email of the user has been verified (email_verified), the OAuth client
+// they were issued to (client_id, as in RFC 9068) and the admin acting as
+// the user when impersonating them (act, as in RFC 8693).
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	SessionID     string   `json:"sid,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	Scope         string   `json:"scope,omitempty"`
+	AuthMethods   []string `json:"amr,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
+	ClientID      string   `json:"client_id,omitempty"`
+	Actor         *Actor   `json:"act,omitempty"`
+}
+
+// Actor identifies the party acting on behalf of the subject of a token.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// ======== METHODS ========
+
+// NewAccessClaims returns the claims of a token for the principal. The
+// issuer and audience are taken from the JWT_ISSUER and JWT_AUDIENCE
+// variables when they are set.
+func NewAccessClaims(principal interfaces.Principal) AccessClaims {
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(int(principal.UserID)),
+			ID:        principal.TokenID,
+			IssuedAt:  jwt.NewNumericDate(principal.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(principal.ExpiresAt),
+			Issuer:    tokenIssuer(),
+		},
+		SessionID:     principal.SessionID,
+		Roles:         principal.Roles,
+		Scope:         strings.Join(principal.Scopes, " "),
+		EmailVerified: principal.EmailVerified,
+		ClientID:      principal.ClientID,
+	}
+
+	if audience := tokenAudience(); audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+	if principal.AuthMethod != "" {
+		claims.AuthMethods = []string{principal.AuthMethod}
+	}
+	if principal.ActorID != 0 {
+		claims.Actor = &Actor{Subject: strconv.Itoa(int(principal.ActorID))}
+	}
+
+	return claims
+}
+
+// Principal returns the principal the claims were issued for.
+func (claims AccessClaims) Principal() (*interfaces.Principal, error) {
+	id, err := strconv.ParseInt(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, jwt.ErrTokenInvalidSubject
+	}
+
+	if claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, jwt.ErrTokenRequiredClaimMissing
+	}
+
+	principal := interfaces.Principal{
+		UserID:        int32(id),
+		SessionID:     claims.SessionID,
+		TokenID:       claims.ID,
+		Roles:         claims.Roles,
+		Scopes:        strings.Fields(claims.Scope),
+		IssuedAt:      claims.IssuedAt.Time,
+		ExpiresAt:     claims.ExpiresAt.Time,
+		EmailVerified: claims.EmailVerified,
+		ClientID:      claims.ClientID,
+	}
+	if len(claims.AuthMethods) > 0 {
+		principal.AuthMethod = claims.AuthMethods[0]
+	}
+	if claims.Actor != nil {
+		actorID, err := strconv.ParseInt(claims.Actor.Subject, 10, 32)
+		if err != nil || actorID == 0 {
+			return nil, jwt.ErrTokenInvalidClaims
+		}
+		principal.ActorID = int32(actorID)
+	}
+
+	return &principal, nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// claimsParserOptions returns the options that validate the issuer and the
+// audience of a token when they are configured.
+func claimsParserOptions() []jwt.ParserOption {
+	options := []jwt.ParserOption{jwt.WithIssuedAt()}
+	if issuer := tokenIssuer(); issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience := tokenAudience(); audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+	return options
+}
+
+// tokenIssuer returns the value of the iss claim of the tokens.
+func tokenIssuer() string {
+	return common.Env.String("JWT_ISSUER", "")
+}
+
+// tokenAudience returns the value of the aud claim of the tokens.
+func tokenAudience() string {
+	return common.Env.String("JWT_AUDIENCE", "")
+}
@@ -5,7 +5,7 @@ Abstract: The controller for everything related with authenticating users.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -34,7 +34,9 @@ package auth
 import (
 	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
 	"github.com/alexmodrono/gin-restapi-template/pkg/common"
 	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
 	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
@@ -49,11 +51,28 @@ type AuthController struct {
 	logger       lib.Logger
 	service      interfaces.AuthService
 	usersService users.UsersRepository
+	verification VerificationController
+	mfa          interfaces.MFARepository
+	attempts     interfaces.LoginAttemptsRepository
+	sessions     interfaces.SessionsRepository
+	hasher       common.PasswordHasher
+	policy       common.PasswordPolicy
+}
+
+// LoginOptions are the options of the routes that log a user in.
+type LoginOptions struct {
+	// Cookie makes the login start a cookie session instead of returning
+	// tokens, for browser clients.
+	Cookie bool `json:"cookie" form:"cookie"`
+	// DeviceName names the session in the list of sessions of the user. If
+	// it is not given, it is guessed from the user agent.
+	DeviceName string `json:"device_name" form:"device_name" binding:"max=100"`
 }
 
 type LoginBody struct {
 	Email    string `json:"email" form:"email" binding:"required,email"`
 	Password string `json:"password" form:"password" binding:"required"`
+	LoginOptions
 }
 
 type SignupBody struct {
@@ -61,6 +80,11 @@ type SignupBody struct {
 	Email           string `json:"email" form:"email" binding:"required,email"`
 	Password        string `json:"password" form:"password" binding:"required"`
 	ConfirmPassword string `json:"confirm_password" form:"confirm_password" binding:"required,eqfield=Password"`
+	LoginOptions
+}
+
+type RefreshBody struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token" binding:"required"`
 }
 
 // ======== METHODS ========
@@ -70,11 +94,23 @@ func GetAuthController(
 	logger lib.Logger,
 	service interfaces.AuthService,
 	usersService users.UsersRepository,
+	verification VerificationController,
+	mfa interfaces.MFARepository,
+	attempts interfaces.LoginAttemptsRepository,
+	sessions interfaces.SessionsRepository,
+	hasher common.PasswordHasher,
+	policy common.PasswordPolicy,
 ) AuthController {
 	return AuthController{
 		logger:       logger,
 		service:      service,
 		usersService: usersService,
+		verification: verification,
+		mfa:          mfa,
+		attempts:     attempts,
+		sessions:     sessions,
+		hasher:       hasher,
+		policy:       policy,
 	}
 }
 
@@ -101,38 +137,74 @@ func (controller AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
+	// ======== CHECK ATTEMPTS ========
+	// Accounts and IP addresses with too many failed logins are rejected
+	// before checking the password, which is expensive on purpose.
+	if middlewares.AbortIfThrottled(ctx, controller.attempts, body.Email) {
+		return
+	}
+
 	// ======== CHECK CREDENTIALS ========
 	// Retrieve the user from the database by the email.
 	user, err := controller.usersService.GetUserByEmail(body.Email)
 	if err != nil {
+		middlewares.RecordFailedLogin(ctx, controller.logger, controller.attempts, body.Email)
 		ctx.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
 
 	// Check whether the password is correct using the hasher's
-	// compare function.
-	matches, err := common.Hasher.Compare(body.Password, user.Password)
+	// compare function, which supports every algorithm the
+	// password could have been hashed with.
+	matches, err := controller.hasher.Compare(body.Password, user.Password)
 	if err != nil {
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
 	if matches {
-		// Create a JWT token for the user with the subject.
-		token, err := controller.service.CreateToken(user.ID)
+		// ======== REHASH PASSWORD ========
+		// Hashes created with another algorithm or weaker parameters than
+		// the current ones are upgraded while the password is available.
+		if controller.hasher.NeedsRehash(user.Password) {
+			if err := controller.usersService.RehashPassword(user.ID, body.Password); err != nil {
+				controller.logger.Error("Could not rehash the password:", err)
+			}
+		}
+
+		// ======== CHECK MFA ========
+		// Users with two-factor authentication only get a challenge token,
+		// which has to be exchanged along a code for their tokens.
+		enabled, err := controller.mfa.IsEnabled(user.ID)
 		if err != nil {
 			ctx.AbortWithError(http.StatusInternalServerError, err)
 			return
 		}
+		if enabled {
+			respondWithMFAChallenge(ctx, controller.service, user.ID)
+			return
+		}
 
-		// And, finally, return the token.
-		ctx.JSON(200, gin.H{
-			"message": "Logged in successfully.",
-			"token":   token,
-		})
+		// The counters are only reset once the login is complete, otherwise
+		// knowing the password would allow guessing codes indefinitely.
+		if err := controller.attempts.Reset(body.Email); err != nil {
+			controller.logger.Error("Could not reset the failed login attempts:", err)
+		}
+
+		// And, finally, start a session or return the tokens.
+		respondWithLogin(
+			ctx,
+			controller.service,
+			controller.sessions,
+			user.ID,
+			interfaces.AuthMethodPassword,
+			body.LoginOptions,
+			"Logged in successfully.",
+		)
 		return
 	}
 
+	middlewares.RecordFailedLogin(ctx, controller.logger, controller.attempts, body.Email)
 	ctx.AbortWithError(http.StatusUnauthorized, errors.New("The password provided is incorrect."))
 }
 
@@ -152,6 +224,12 @@ func (controller AuthController) Signup(ctx *gin.Context) {
 		return
 	}
 
+	// ======== CHECK PASSWORD POLICY ========
+	if errors := controller.policy.Validate("password", body.Password, body.Username, body.Email); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
 	// ======== CREATE USER ========
 
 	// Retrieve the user from the database by the email.
@@ -161,16 +239,221 @@ func (controller AuthController) Signup(ctx *gin.Context) {
 		return
 	}
 
-	// Create a JWT token for the user with the subject.
-	token, err := controller.service.CreateToken(*id)
+	// ======== VERIFY EMAIL ========
+	// The user can log in right away, but routes that require a verified email
+	// will be blocked until they follow the link sent to them. If the email
+	// cannot be sent, they can ask for another one.
+	if err := controller.verification.SendVerificationEmail(*id, body.Email, body.Username); err != nil {
+		controller.logger.Error("Could not send the verification email:", err)
+	}
+
+	// And, finally, start a session or return the tokens.
+	respondWithLogin(
+		ctx,
+		controller.service,
+		controller.sessions,
+		*id,
+		interfaces.AuthMethodPassword,
+		body.LoginOptions,
+		"User signed-up successfully.",
+	)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens.
+func (controller AuthController) Refresh(ctx *gin.Context) {
+	controller.logger.Info("[POST] Refresh token route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := RefreshBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== ROTATE TOKENS ========
+	tokens, err := controller.service.RefreshTokens(body.RefreshToken)
+	if err != nil {
+		// Any problem with the refresh token itself means the client
+		// has to log in again.
+		if errors.Is(err, interfaces.InvalidRefreshTokenException) ||
+			errors.Is(err, interfaces.ExpiredRefreshTokenException) ||
+			errors.Is(err, interfaces.ReusedRefreshTokenException) {
+			ctx.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"message":       "Tokens refreshed successfully.",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// Logout revokes the access token used for the request along with the
+// refresh tokens issued with it, or ends the cookie session of the request.
+func (controller AuthController) Logout(ctx *gin.Context) {
+	controller.logger.Info("[POST] Logout route.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	if id, ok := parseSessionPrincipalID(*principal); ok {
+		err := controller.sessions.RevokeSession(principal.UserID, id)
+		if err != nil && !errors.Is(err, interfaces.SessionNotFoundException) {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		clearSessionCookies(ctx)
+	} else if err := controller.service.RevokeToken(*principal); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"message": "Logged out successfully.",
+	})
+}
+
+// LogoutAll revokes every token and session of the authenticated user, logging
+// them out of every device.
+func (controller AuthController) LogoutAll(ctx *gin.Context) {
+	controller.logger.Info("[POST] Logout from all devices route.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	if err := controller.service.RevokeAllTokens(principal.UserID); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if _, ok := parseSessionPrincipalID(*principal); ok {
+		clearSessionCookies(ctx)
+	}
+
+	ctx.JSON(200, gin.H{
+		"message": "Logged out from every device successfully.",
+	})
+}
+
+// Jwks publishes the public keys used for verifying the tokens issued by
+// the API, so that other services can verify them without sharing a secret.
+func (controller AuthController) Jwks(ctx *gin.Context) {
+	// Keys rarely change and new ones are published before being used, so
+	// clients can safely cache them for a while.
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(200, controller.service.PublicKeys())
+}
+
+// ======== PRIVATE METHODS ========
+
+// respondWithMFAChallenge responds with a challenge token for a user that has
+// two-factor authentication enabled, which has to be exchanged along a code for
+// their tokens at /login/mfa.
+func respondWithMFAChallenge(ctx *gin.Context, service interfaces.AuthService, userID int32) {
+	challenge, err := service.CreateChallenge(userID, interfaces.ChallengePurposeMFA)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"message":         "Two-factor authentication is required.",
+		"mfa_required":    true,
+		"challenge_token": challenge,
+		"expires_in":      int64(challengeTokenTTL().Seconds()),
+	})
+}
+
+// respondWithLogin completes the login of a user. Clients that asked for a
+// cookie session get the cookies of a new session, and every other client gets
+// an access token and a refresh token bound to a new session.
+func respondWithLogin(
+	ctx *gin.Context,
+	service interfaces.AuthService,
+	sessions interfaces.SessionsRepository,
+	userID int32,
+	method string,
+	options LoginOptions,
+	message string,
+) {
+	device := interfaces.Device{
+		Name:      options.DeviceName,
+		UserAgent: ctx.Request.UserAgent(),
+		IPAddress: ctx.ClientIP(),
+	}
+	if device.Name == "" {
+		device.Name = deviceName(device.UserAgent)
+	}
+
+	if options.Cookie {
+		session, token, csrfToken, err := sessions.CreateSession(userID, method, device)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		setSessionCookies(ctx, token, csrfToken)
+		ctx.JSON(200, gin.H{
+			"message":    message,
+			"csrf_token": csrfToken,
+			"expires_at": session.ExpiresAt,
+		})
+		return
+	}
+
+	// Create an access token and a refresh token for the user.
+	tokens, err := service.IssueTokens(userID, method, device)
 	if err != nil {
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	// And, finally, return the token.
 	ctx.JSON(200, gin.H{
-		"message": "User signed-up successfully.",
-		"token":   token,
+		"message":       message,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
+
+// setSessionCookies sets the cookie of a session, which scripts cannot read,
+// and the cookie with its CSRF token, which the client has to read and send back
+// in the X-CSRF-Token header.
+func setSessionCookies(ctx *gin.Context, token string, csrfToken string) {
+	maxAge := int(sessionTTL().Seconds())
+	setSessionCookie(ctx, interfaces.SessionCookie, token, maxAge, true)
+	setSessionCookie(ctx, interfaces.CSRFCookie, csrfToken, maxAge, false)
+}
+
+// clearSessionCookies removes the cookies of a session from the browser.
+func clearSessionCookies(ctx *gin.Context) {
+	setSessionCookie(ctx, interfaces.SessionCookie, "", -1, true)
+	setSessionCookie(ctx, interfaces.CSRFCookie, "", -1, false)
+}
+
+// setSessionCookie sets a cookie of a session. The cookies are Secure unless
+// SESSION_COOKIE_SECURE is false, and their SameSite attribute is taken from
+// SESSION_COOKIE_SAMESITE (lax by default).
+func setSessionCookie(ctx *gin.Context, name string, value string, maxAge int, httpOnly bool) {
+	switch strings.ToLower(common.Env.String("SESSION_COOKIE_SAMESITE", "lax")) {
+	case "strict":
+		ctx.SetSameSite(http.SameSiteStrictMode)
+	case "none":
+		ctx.SetSameSite(http.SameSiteNoneMode)
+	default:
+		ctx.SetSameSite(http.SameSiteLaxMode)
+	}
+
+	ctx.SetCookie(
+		name,
+		value,
+		maxAge,
+		"/",
+		common.Env.String("SESSION_COOKIE_DOMAIN", ""),
+		common.Env.Bool("SESSION_COOKIE_SECURE", true),
+		httpOnly,
+	)
+}
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
 	"github.com/alexmodrono/gin-restapi-template/test/mocks"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -29,7 +31,8 @@ func TestAuthController_Login(t *testing.T) {
 	authService := &mocks.MockAuthService{}
 
 	// Create the auth controller for testing
-	authController := GetAuthController(logger, authService, usersService)
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(logger, authService, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
 	// Add the route to the router
 	router.POST("/login", authController.Login)
 
@@ -57,6 +60,7 @@ func TestAuthController_Login(t *testing.T) {
 
 		assert.Equal(t, "Logged in successfully.", response["message"])
 		assert.Equal(t, "mock_jwt_token", response["token"])
+		assert.Equal(t, "mock_refresh_token", response["refresh_token"])
 	})
 
 	t.Run("InvalidCredentials", func(t *testing.T) {
@@ -84,3 +88,357 @@ func TestAuthController_Login(t *testing.T) {
 		assert.Equal(t, "The password provided is incorrect.", response["error"])
 	})
 }
+
+func TestAuthController_Refresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Initialize a new gin router for testing
+	router := gin.Default()
+
+	// Sets the errors middleware so that failed routes have a body.
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	// Create the auth controller for testing
+	usersService := &mocks.MockUsersService{}
+	verificationController := GetVerificationController(&mocks.MockLogger{}, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(&mocks.MockLogger{}, &mocks.MockAuthService{}, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
+	router.POST("/token/refresh", authController.Refresh)
+
+	// refresh performs a request to the refresh route with the given token.
+	refresh := func(refreshToken string) (int, map[string]interface{}) {
+		jsonBody, _ := json.Marshal(RefreshBody{RefreshToken: refreshToken})
+		req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	t.Run("ValidToken", func(t *testing.T) {
+		code, response := refresh("mock_refresh_token")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "mock_jwt_token", response["token"])
+		assert.Equal(t, "mock_rotated_refresh_token", response["refresh_token"])
+	})
+
+	t.Run("ReusedToken", func(t *testing.T) {
+		code, response := refresh("mock_used_refresh_token")
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, interfaces.ReusedRefreshTokenException.Error(), response["error"])
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		code, response := refresh("not_a_refresh_token")
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, interfaces.InvalidRefreshTokenException.Error(), response["error"])
+	})
+}
+
+func TestAuthController_Logout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Initialize a new gin router for testing
+	router := gin.Default()
+
+	// Sets the errors middleware so that failed routes have a body.
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	// Protect the routes with the auth middleware, which uses the mocked
+	// service to check the tokens.
+	logger := &mocks.MockLogger{}
+	authService := &mocks.MockAuthService{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+
+	usersService := &mocks.MockUsersService{}
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(logger, authService, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
+	api := router.Group("/").Use(authMiddleware.Handler())
+	api.POST("/logout", authController.Logout)
+	api.POST("/logout-all", authController.LogoutAll)
+
+	t.Run("Logout", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/logout", nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"mock_jti"}, authService.RevokedTokens)
+	})
+
+	t.Run("LogoutAll", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/logout-all", nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int32{1}, authService.RevokedUsers)
+	})
+
+	t.Run("MissingToken", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/logout", nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthController_CookieSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	authService := &mocks.MockAuthService{}
+	sessions := &mocks.MockSessionsService{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, sessions, &mocks.MockImpersonationsService{})
+
+	usersService := &mocks.MockUsersService{}
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(logger, authService, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, sessions, common.Hasher, common.DefaultPasswordPolicy())
+	router.POST("/login", authController.Login)
+	router.Group("/").Use(authMiddleware.Handler()).POST("/logout", authController.Logout)
+
+	// cookies returns the cookies set by a response, indexed by their name.
+	cookies := func(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+		result := map[string]*http.Cookie{}
+		for _, cookie := range w.Result().Cookies() {
+			result[cookie.Name] = cookie
+		}
+		return result
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		jsonBody, _ := json.Marshal(LoginBody{
+			Email:        "user@example.com",
+			Password:     "password123",
+			LoginOptions: LoginOptions{Cookie: true},
+		})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		// No tokens are returned, only the CSRF token
+		assert.Nil(t, response["token"])
+		assert.Equal(t, mocks.MockCSRFToken, response["csrf_token"])
+
+		set := cookies(w)
+		assert.Equal(t, mocks.MockSessionToken, set["session"].Value)
+		assert.True(t, set["session"].HttpOnly)
+		assert.True(t, set["session"].Secure)
+		assert.Equal(t, http.SameSiteLaxMode, set["session"].SameSite)
+		assert.Equal(t, mocks.MockCSRFToken, set["csrf_token"].Value)
+		assert.False(t, set["csrf_token"].HttpOnly)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/logout", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: mocks.MockSessionToken})
+		req.Header.Set("X-CSRF-Token", mocks.MockCSRFToken)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, sessions.Sessions)
+		assert.Empty(t, authService.RevokedTokens)
+		assert.Equal(t, -1, cookies(w)["session"].MaxAge)
+	})
+}
+
+func TestAuthController_Login_Throttled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	// Sets the errors middleware so that failed routes have a body.
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	usersService := &mocks.MockUsersService{}
+	attempts := &mocks.MockLoginAttemptsService{}
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(logger, &mocks.MockAuthService{}, usersService, verificationController, &mocks.MockMFAService{}, attempts, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
+	router.POST("/login", authController.Login)
+
+	// login performs a login request with the given password.
+	login := func(password string) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(LoginBody{Email: "user@example.com", Password: password})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("SuccessResetsFailures", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, login("incorrect_password").Code)
+		assert.Equal(t, 1, attempts.Failures["user@example.com"])
+
+		assert.Equal(t, http.StatusOK, login("password123").Code)
+		assert.Zero(t, attempts.Failures["user@example.com"])
+
+		// The failures of the IP address are not forgotten, otherwise logging
+		// into an account of their own would let clients keep guessing.
+		for ip, failures := range attempts.IPFailures {
+			assert.Equal(t, 1, failures, ip)
+		}
+		assert.Len(t, attempts.IPFailures, 1)
+	})
+
+	t.Run("LockedOut", func(t *testing.T) {
+		for i := 0; i < mocks.MockLoginAttemptsThreshold; i++ {
+			assert.Equal(t, http.StatusUnauthorized, login("incorrect_password").Code)
+		}
+
+		// Even the right password is rejected while the account is locked.
+		w := login("password123")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, interfaces.TooManyLoginAttemptsException.Error(), response["error"])
+	})
+}
+
+func TestAuthController_Login_Rehash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	t.Setenv("ARGON2_MEMORY", "16384")
+	t.Setenv("ARGON2_ITERATIONS", "2")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+
+	// The stored hash uses fewer iterations than the current parameters.
+	weakHash, _ := common.Hasher.HashWithParameters("password123", common.NewParameters(16384, 1, 1))
+
+	logger := &mocks.MockLogger{}
+	usersService := &mocks.MockUsersService{PasswordHash: weakHash}
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(logger, &mocks.MockAuthService{}, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
+	router.POST("/login", authController.Login)
+
+	jsonBody, _ := json.Marshal(LoginBody{Email: "user@example.com", Password: "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "password123", usersService.RehashedPasswords[1])
+	// Rehashing does not count as choosing a new password.
+	assert.Empty(t, usersService.UpdatedPasswords)
+}
+
+func TestAuthController_Login_UpgradeLegacyHash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	t.Setenv("BCRYPT_COST", "4")
+
+	// The stored hash was imported from a system that used bcrypt.
+	legacy, _ := common.NewPasswordHasher(common.AlgorithmBcrypt)
+	legacyHash, _ := legacy.Hash("password123")
+	hasher, _ := common.NewPasswordHasher(common.AlgorithmArgon2id)
+
+	logger := &mocks.MockLogger{}
+	usersService := &mocks.MockUsersService{PasswordHash: legacyHash}
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(logger, &mocks.MockAuthService{}, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, &mocks.MockSessionsService{}, hasher, common.DefaultPasswordPolicy())
+	router.POST("/login", authController.Login)
+
+	jsonBody, _ := json.Marshal(LoginBody{Email: "user@example.com", Password: "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "password123", usersService.RehashedPasswords[1])
+}
+
+func TestAuthController_Signup_PasswordPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	usersService := &mocks.MockUsersService{}
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	authController := GetAuthController(logger, &mocks.MockAuthService{}, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
+	router.POST("/signup", authController.Signup)
+
+	// signup performs a request to the signup route with the given password
+	// and returns the messages of the errors about it.
+	signup := func(password string) (int, []string) {
+		jsonBody, _ := json.Marshal(SignupBody{
+			Username:        "alejandro",
+			Email:           "alex@example.com",
+			Password:        password,
+			ConfirmPassword: password,
+		})
+		req, _ := http.NewRequest("POST", "/signup", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response struct {
+			Errors []common.ValidationErrorMessage `json:"errors"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		messages := []string{}
+		for _, err := range response.Errors {
+			assert.Equal(t, "password", err.Field)
+			messages = append(messages, err.Message)
+		}
+		return w.Code, messages
+	}
+
+	t.Run("WeakPassword", func(t *testing.T) {
+		code, messages := signup("password123")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, []string{"The password is too easy to guess. Try a longer one, or a few unrelated words."}, messages)
+	})
+
+	t.Run("ContainsUsername", func(t *testing.T) {
+		code, messages := signup("Alejandro!x7#Kp")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, messages, "The password must not contain your username or email.")
+	})
+
+	t.Run("StrongPassword", func(t *testing.T) {
+		code, messages := signup("correct horse battery staple")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, messages)
+		assert.Equal(t, []string{"alejandro"}, usersService.CreatedUsernames)
+	})
+}
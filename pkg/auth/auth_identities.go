@@ -0,0 +1,162 @@
+/*
+Package Name: auth
+File Name: auth_identities.go
+Abstract: The service that stores the pending logins with external identity
+providers and links their accounts to users.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// IdentitiesService service layer
+type IdentitiesService struct {
+	logger lib.Logger
+	db     *lib.Database
+}
+
+// ======== METHODS ========
+
+// GetIdentitiesService returns the identities service, and schedules the
+// removal of the pending logins that have expired.
+func GetIdentitiesService(
+	logger lib.Logger,
+	db *lib.Database,
+	scheduler *lib.Scheduler,
+) interfaces.IdentitiesRepository {
+	service := IdentitiesService{
+		logger: logger,
+		db:     db,
+	}
+
+	scheduler.Every(
+		"purge OIDC authorizations",
+		common.Env.Duration("OIDC_AUTHORIZATIONS_PURGE_INTERVAL", time.Hour),
+		service.purge,
+	)
+
+	return service
+}
+
+// SaveAuthorization stores a pending login, identified by the hash of its state.
+func (service IdentitiesService) SaveAuthorization(
+	state string,
+	authorization interfaces.OIDCAuthorization,
+	ttl time.Duration,
+) error {
+	_, err := service.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.oidc_authorization (state_hash, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4);`,
+		common.Tokens.Hash(state),
+		authorization.Nonce,
+		authorization.Verifier,
+		time.Now().Add(ttl),
+	)
+	return err
+}
+
+// ConsumeAuthorization removes a pending login and returns it. It is checked and
+// removed in a single statement, so it cannot be used twice even by concurrent
+// requests.
+func (service IdentitiesService) ConsumeAuthorization(state string) (*interfaces.OIDCAuthorization, error) {
+	authorization := interfaces.OIDCAuthorization{}
+	err := service.db.QueryRow(
+		context.Background(),
+		`DELETE FROM auth.oidc_authorization
+		WHERE state_hash = $1 AND expires_at > now()
+		RETURNING nonce, code_verifier;`,
+		common.Tokens.Hash(state),
+	).Scan(&authorization.Nonce, &authorization.Verifier)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.InvalidOIDCStateException
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &authorization, nil
+}
+
+// GetIdentityUser returns the user the subject of an issuer is linked to.
+func (service IdentitiesService) GetIdentityUser(issuer string, subject string) (int32, error) {
+	var userID int32
+	err := service.db.QueryRow(
+		context.Background(),
+		`UPDATE auth.user_identity SET last_login_at = now()
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id;`,
+		issuer,
+		subject,
+	).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, interfaces.IdentityNotFoundException
+	}
+	return userID, err
+}
+
+// LinkIdentity links the subject of an issuer to a user. Linking a subject that
+// is already linked to the same user does nothing.
+func (service IdentitiesService) LinkIdentity(userID int32, issuer string, subject string, email string) error {
+	service.logger.Info("Linking the identity", subject, "of", issuer, "to user with id", userID)
+
+	_, err := service.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.user_identity (user_id, issuer, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (issuer, subject) DO UPDATE
+			SET email = excluded.email, last_login_at = now()
+			WHERE auth.user_identity.user_id = excluded.user_id;`,
+		userID,
+		issuer,
+		subject,
+		email,
+	)
+	return err
+}
+
+// ======== PRIVATE METHODS ========
+
+// purge removes the pending logins that have expired.
+func (service IdentitiesService) purge(ctx context.Context) error {
+	_, err := service.db.Exec(
+		ctx,
+		`DELETE FROM auth.oidc_authorization WHERE expires_at < now();`,
+	)
+	return err
+}
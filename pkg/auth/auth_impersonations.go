@@ -0,0 +1,267 @@
+/*
+Package Name: auth
+File Name: auth_impersonations.go
+Abstract: The service that records the impersonations started by admins and the
+requests made during them.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// ImpersonationsService service layer
+type ImpersonationsService struct {
+	logger      lib.Logger
+	db          *lib.Database
+	revocations *RevocationStore
+}
+
+// ======== CONSTANTS ========
+
+// impersonationFields are the fields of the impersonations, in the order
+// scanImpersonation expects them.
+const impersonationFields = `id, actor_id, user_id, reason, ip_address, created_at, expires_at, ended_at, session_id`
+
+// ======== METHODS ========
+
+// GetImpersonationsService returns the impersonations service.
+func GetImpersonationsService(
+	logger lib.Logger,
+	db *lib.Database,
+	revocations *RevocationStore,
+) interfaces.ImpersonationsRepository {
+	return ImpersonationsService{
+		logger:      logger,
+		db:          db,
+		revocations: revocations,
+	}
+}
+
+// StartImpersonation records an impersonation, and returns it with the session
+// id its token has to carry.
+func (service ImpersonationsService) StartImpersonation(
+	actorID int32,
+	userID int32,
+	reason string,
+	ipAddress string,
+	ttl time.Duration,
+) (*interfaces.Impersonation, error) {
+	sessionID, err := common.Tokens.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	impersonation, err := scanImpersonation(service.db.QueryRow(
+		context.Background(),
+		`INSERT INTO auth.impersonation (session_id, actor_id, user_id, reason, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+impersonationFields+`;`,
+		sessionID,
+		actorID,
+		userID,
+		reason,
+		ipAddress,
+		time.Now().Add(ttl),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	service.logger.Info("User", actorID, "started impersonating user", userID, "-", reason)
+	return impersonation, nil
+}
+
+// GetImpersonations returns every impersonation, newest first.
+func (service ImpersonationsService) GetImpersonations() ([]interfaces.Impersonation, error) {
+	rows, err := service.db.Query(
+		context.Background(),
+		`SELECT `+impersonationFields+` FROM auth.impersonation ORDER BY created_at DESC, id DESC;`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	impersonations := []interfaces.Impersonation{}
+	for rows.Next() {
+		impersonation, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, err
+		}
+		impersonations = append(impersonations, *impersonation)
+	}
+
+	return impersonations, rows.Err()
+}
+
+// GetImpersonatedRequests returns the requests made during an impersonation, in
+// the order they were made.
+func (service ImpersonationsService) GetImpersonatedRequests(id int32) ([]interfaces.ImpersonatedRequest, error) {
+	var exists bool
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM auth.impersonation WHERE id = $1);`,
+		id,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, interfaces.ImpersonationNotFoundException
+	}
+
+	rows, err := service.db.Query(
+		context.Background(),
+		`SELECT id, method, path, status, ip_address, user_agent, created_at
+		FROM auth.impersonation_request
+		WHERE impersonation_id = $1
+		ORDER BY id;`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []interfaces.ImpersonatedRequest{}
+	for rows.Next() {
+		var request interfaces.ImpersonatedRequest
+		err := rows.Scan(
+			&request.ID,
+			&request.Method,
+			&request.Path,
+			&request.Status,
+			&request.IPAddress,
+			&request.UserAgent,
+			&request.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
+
+// EndImpersonation ends an impersonation that has not ended yet, and revokes the
+// token issued for it.
+func (service ImpersonationsService) EndImpersonation(id int32) error {
+	var (
+		sessionID string
+		expiresAt time.Time
+	)
+	err := service.db.QueryRow(
+		context.Background(),
+		`UPDATE auth.impersonation SET ended_at = now()
+		WHERE id = $1 AND ended_at IS NULL AND expires_at > now()
+		RETURNING session_id, expires_at;`,
+		id,
+	).Scan(&sessionID, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return interfaces.ImpersonationNotFoundException
+	} else if err != nil {
+		return err
+	}
+
+	// The token carries the impersonation as its session, and it does not
+	// outlive the impersonation.
+	if err := service.revocations.RevokeSession(sessionID, expiresAt); err != nil {
+		return err
+	}
+
+	service.logger.Info("Ended impersonation", id)
+	return nil
+}
+
+// RecordRequest records a request made during the impersonation with the given
+// session id, which must not have ended.
+func (service ImpersonationsService) RecordRequest(sessionID string, request interfaces.ImpersonatedRequest) (int64, error) {
+	var id int64
+	err := service.db.QueryRow(
+		context.Background(),
+		`INSERT INTO auth.impersonation_request (impersonation_id, method, path, ip_address, user_agent)
+		SELECT id, $2, $3, $4, $5
+		FROM auth.impersonation
+		WHERE session_id = $1 AND ended_at IS NULL
+		RETURNING id;`,
+		sessionID,
+		request.Method,
+		request.Path,
+		request.IPAddress,
+		request.UserAgent,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, interfaces.ImpersonationNotFoundException
+	}
+	return id, err
+}
+
+// CompleteRequest records the status a request was answered with.
+func (service ImpersonationsService) CompleteRequest(id int64, status int) error {
+	_, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.impersonation_request SET status = $2 WHERE id = $1;`,
+		id,
+		status,
+	)
+	return err
+}
+
+// ======== PRIVATE METHODS ========
+
+// scanImpersonation scans a row with the impersonationFields.
+func scanImpersonation(row pgx.Row) (*interfaces.Impersonation, error) {
+	var impersonation interfaces.Impersonation
+	err := row.Scan(
+		&impersonation.ID,
+		&impersonation.ActorID,
+		&impersonation.UserID,
+		&impersonation.Reason,
+		&impersonation.IPAddress,
+		&impersonation.CreatedAt,
+		&impersonation.ExpiresAt,
+		&impersonation.EndedAt,
+		&impersonation.SessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &impersonation, nil
+}
@@ -0,0 +1,232 @@
+/*
+Package Name: auth
+File Name: auth_impersonations_controller.go
+Abstract: The controller that lets admins impersonate users and review the audit
+trail of the impersonations.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// ImpersonationsController struct
+type ImpersonationsController struct {
+	logger         lib.Logger
+	service        interfaces.AuthService
+	usersService   users.UsersRepository
+	roles          roles.RolesRepository
+	impersonations interfaces.ImpersonationsRepository
+}
+
+type ImpersonateBody struct {
+	// Reason is kept in the audit trail, e.g. the support ticket being
+	// worked on.
+	Reason string `json:"reason" form:"reason" binding:"required,max=500"`
+}
+
+// ======== ERRORS ========
+var (
+	SelfImpersonationException       = errors.New("You cannot impersonate yourself.")
+	PrivilegedImpersonationException = errors.New("You cannot impersonate a user with permissions you do not have.")
+)
+
+// ======== METHODS ========
+
+// GetImpersonationsController retrieves a new impersonations controller.
+func GetImpersonationsController(
+	logger lib.Logger,
+	service interfaces.AuthService,
+	usersService users.UsersRepository,
+	roles roles.RolesRepository,
+	impersonations interfaces.ImpersonationsRepository,
+) ImpersonationsController {
+	return ImpersonationsController{
+		logger:         logger,
+		service:        service,
+		usersService:   usersService,
+		roles:          roles,
+		impersonations: impersonations,
+	}
+}
+
+// Impersonate returns a token for acting as a user, which names the admin in its
+// act claim and expires after IMPERSONATION_TTL (10m by default). Every request
+// made with it is recorded, and it cannot be refreshed nor used for changing the
+// credentials of the user.
+func (controller ImpersonationsController) Impersonate(ctx *gin.Context) {
+	controller.logger.Info("[POST] Impersonating user with id", ctx.Param("id"))
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := ImpersonateBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	if principal.UserID == int32(id) {
+		ctx.AbortWithError(http.StatusBadRequest, SelfImpersonationException)
+		return
+	}
+
+	// ======== CHECK USER ========
+	user, err := controller.usersService.GetUserById(int(id))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// Impersonating a user must not grant the admin any permission they do
+	// not already have.
+	_, permissions, err := controller.roles.GetUserPermissions(user.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	for _, permission := range permissions {
+		if !principal.HasScope(permission) {
+			controller.logger.Info("User", principal.UserID, "tried to impersonate the more privileged user", user.ID)
+			ctx.AbortWithError(http.StatusForbidden, PrivilegedImpersonationException)
+			return
+		}
+	}
+
+	// ======== ISSUE TOKEN ========
+	ttl := impersonationTTL()
+	impersonation, err := controller.impersonations.StartImpersonation(
+		principal.UserID,
+		user.ID,
+		body.Reason,
+		ctx.ClientIP(),
+		ttl,
+	)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	token, err := controller.service.CreateImpersonationToken(principal.UserID, user.ID, impersonation.SessionID, ttl)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message":       "Impersonation started successfully.",
+		"token":         *token,
+		"expires_in":    int64(ttl.Seconds()),
+		"impersonation": impersonation,
+	})
+}
+
+// GetAll returns every impersonation, newest first.
+func (controller ImpersonationsController) GetAll(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all impersonations.")
+
+	impersonations, err := controller.impersonations.GetImpersonations()
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, impersonations)
+}
+
+// GetRequests returns the requests made during an impersonation.
+func (controller ImpersonationsController) GetRequests(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting the requests of impersonation", ctx.Param("id"))
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return
+	}
+
+	requests, err := controller.impersonations.GetImpersonatedRequests(int32(id))
+	if errors.Is(err, interfaces.ImpersonationNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+}
+
+// End ends an impersonation before it expires, revoking its token.
+func (controller ImpersonationsController) End(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Ending impersonation", ctx.Param("id"))
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return
+	}
+
+	err = controller.impersonations.EndImpersonation(int32(id))
+	if errors.Is(err, interfaces.ImpersonationNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Impersonation ended successfully.",
+	})
+}
+
+// ======== PRIVATE METHODS ========
+
+// impersonationTTL returns how long impersonation tokens are valid for.
+func impersonationTTL() time.Duration {
+	return common.Env.Duration("IMPERSONATION_TTL", 10*time.Minute)
+}
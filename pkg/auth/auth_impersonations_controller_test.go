@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationsController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	// The admin is the user 1, and every user has the permissions of the
+	// roles service.
+	logger := &mocks.MockLogger{}
+	authService := &mocks.MockAuthService{Scopes: []string{"users:impersonate", "impersonations:read", "users:read"}}
+	rolesService := &mocks.MockRolesService{Permissions: []string{"users:read"}}
+	impersonations := &mocks.MockImpersonationsService{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, impersonations)
+	impersonationsController := GetImpersonationsController(logger, authService, &mocks.MockUsersService{}, rolesService, impersonations)
+
+	api := router.Group("/").Use(authMiddleware.Handler(middlewares.RejectImpersonation()))
+	api.POST("/admin/users/:id/impersonate", authMiddleware.Require("users:impersonate"), impersonationsController.Impersonate)
+	api.GET("/admin/impersonations", authMiddleware.Require("impersonations:read"), impersonationsController.GetAll)
+	api.GET("/admin/impersonations/:id/requests", authMiddleware.Require("impersonations:read"), impersonationsController.GetRequests)
+	api.DELETE("/admin/impersonations/:id", authMiddleware.Require("users:impersonate"), impersonationsController.End)
+
+	request := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Impersonate", func(t *testing.T) {
+		w := request("POST", "/admin/users/2/impersonate", ImpersonateBody{Reason: "Ticket #1234"})
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "mock_impersonation_token", response["token"])
+		assert.Equal(t, float64(600), response["expires_in"])
+		if assert.Len(t, impersonations.Impersonations, 1) {
+			assert.Equal(t, int32(1), impersonations.Impersonations[0].ActorID)
+			assert.Equal(t, int32(2), impersonations.Impersonations[0].UserID)
+			assert.Equal(t, "Ticket #1234", impersonations.Impersonations[0].Reason)
+		}
+	})
+
+	t.Run("ImpersonateWithoutReason", func(t *testing.T) {
+		w := request("POST", "/admin/users/2/impersonate", ImpersonateBody{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("ImpersonateYourself", func(t *testing.T) {
+		w := request("POST", "/admin/users/1/impersonate", ImpersonateBody{Reason: "Testing"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("ImpersonateUnknownUser", func(t *testing.T) {
+		w := request("POST", "/admin/users/3/impersonate", ImpersonateBody{Reason: "Testing"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("ImpersonateMorePrivilegedUser", func(t *testing.T) {
+		rolesService.Permissions = []string{"users:read", "roles:write"}
+		defer func() { rolesService.Permissions = []string{"users:read"} }()
+
+		w := request("POST", "/admin/users/2/impersonate", ImpersonateBody{Reason: "Testing"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("ImpersonateWhileImpersonating", func(t *testing.T) {
+		authService.ActorID = 3
+		defer func() { authService.ActorID = 0 }()
+
+		w := request("POST", "/admin/users/2/impersonate", ImpersonateBody{Reason: "Testing"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Len(t, impersonations.Impersonations, 1)
+	})
+
+	t.Run("GetAllAndRequests", func(t *testing.T) {
+		w := request("GET", "/admin/impersonations", nil)
+
+		var response []interfaces.Impersonation
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, response, 1)
+		assert.Equal(t, "Ticket #1234", response[0].Reason)
+
+		// The request rejected while impersonating is in the audit trail
+		w = request("GET", "/admin/impersonations/1/requests", nil)
+
+		var requests []interfaces.ImpersonatedRequest
+		json.Unmarshal(w.Body.Bytes(), &requests)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		if assert.Len(t, requests, 1) {
+			assert.Equal(t, "/admin/users/2/impersonate", requests[0].Path)
+			assert.Equal(t, int32(http.StatusForbidden), *requests[0].Status)
+		}
+
+		w = request("GET", "/admin/impersonations/2/requests", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("End", func(t *testing.T) {
+		w := request("DELETE", "/admin/impersonations/1", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotNil(t, impersonations.Impersonations[0].EndedAt)
+
+		// Test case 2: It has already ended
+		w = request("DELETE", "/admin/impersonations/1", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,361 @@
+/*
+Package Name: auth
+File Name: auth_keyring.go
+Abstract: The keyring holding the keys used for signing and verifying
+JWTs. Keys are loaded from PEM files and identified by the `kid` header
+of the tokens, which allows rotating them without invalidating the
+tokens signed with the previous key.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ======== TYPES ========
+
+// SigningKey is a key of the keyring.
+//
+// Each PEM file of the keys directory holds a single key, and the following
+// optional PEM headers control how it is used:
+//   - Kid: The id of the key. Defaults to the name of the file.
+//   - Alg: The signing algorithm. Defaults to RS256 for RSA keys, ES256,
+//     ES384 or ES512 for ECDSA keys depending on the curve, and EdDSA for
+//     Ed25519 keys.
+//   - Not-Before: RFC 3339 date from which the key can sign tokens. Keys
+//     are published before that, so that other services can cache them
+//     before they see the first token signed with them.
+//   - Not-After: RFC 3339 date from which the key stops signing tokens. It
+//     is kept for verifying tokens until the last one it signed expires.
+//
+// Files holding a public key can only be used for verification, which allows
+// removing the private key of a retired key while its tokens are still valid.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+	NotBefore  time.Time
+	NotAfter   time.Time
+}
+
+// Keyring holds every key that can be used for signing or verifying tokens.
+//
+// If no keys directory is configured, the keyring falls back to a single
+// HS256 key derived from the SECRET_KEY variable, which cannot be published.
+type Keyring struct {
+	mutex sync.RWMutex
+	keys  []SigningKey
+}
+
+// ======== ERRORS ========
+var (
+	NoSigningKeyException      = errors.New("There is no key available for signing tokens.")
+	UnknownSigningKeyException = errors.New("The token was signed with an unknown key.")
+)
+
+// ======== METHODS ========
+
+// GetKeyring loads the keyring and schedules a task that reloads it, so that
+// new keys can be added and old ones removed without restarting the API.
+func GetKeyring(logger lib.Logger, scheduler *lib.Scheduler) *Keyring {
+	dir := os.Getenv("JWT_KEYS_DIR")
+
+	keyring, err := LoadKeyring(dir)
+	if err != nil {
+		logger.Fatal("Unable to load the JWT keys: ", err)
+		os.Exit(1)
+	}
+
+	if dir != "" {
+		scheduler.Every(
+			"reload JWT keys",
+			common.Env.Duration("JWT_KEYS_RELOAD_INTERVAL", time.Minute),
+			func(ctx context.Context) error {
+				return keyring.Reload(dir)
+			},
+		)
+	}
+
+	return keyring
+}
+
+// LoadKeyring loads the keys from the PEM files in a directory, or returns an
+// HS256 keyring if the directory is empty.
+func LoadKeyring(dir string) (*Keyring, error) {
+	keyring := &Keyring{}
+	if err := keyring.Reload(dir); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// Reload replaces the keys of the keyring with the ones in the directory. If
+// any file cannot be loaded the current keys are kept.
+func (keyring *Keyring) Reload(dir string) error {
+	var keys []SigningKey
+
+	if dir == "" {
+		keys = []SigningKey{{
+			Method:     jwt.SigningMethodHS256,
+			PrivateKey: []byte(os.Getenv("SECRET_KEY")),
+			PublicKey:  []byte(os.Getenv("SECRET_KEY")),
+		}}
+	} else {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			key, err := loadSigningKey(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			keys = append(keys, *key)
+		}
+
+		if len(keys) == 0 {
+			return fmt.Errorf("no keys found in %s", dir)
+		}
+	}
+
+	keyring.mutex.Lock()
+	defer keyring.mutex.Unlock()
+	keyring.keys = keys
+	return nil
+}
+
+// SigningKey returns the key that should be used for signing new tokens,
+// which is the most recent key that has a private key and is within its
+// signing window.
+func (keyring *Keyring) SigningKey(now time.Time) (*SigningKey, error) {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	var active *SigningKey
+	for i, key := range keyring.keys {
+		if key.PrivateKey == nil || now.Before(key.NotBefore) {
+			continue
+		}
+		if !key.NotAfter.IsZero() && !now.Before(key.NotAfter) {
+			continue
+		}
+		if active == nil || key.NotBefore.After(active.NotBefore) ||
+			(key.NotBefore.Equal(active.NotBefore) && key.ID > active.ID) {
+			active = &keyring.keys[i]
+		}
+	}
+
+	if active == nil {
+		return nil, NoSigningKeyException
+	}
+	return active, nil
+}
+
+// VerificationKey returns the key with the given id, as long as it has not
+// been retired for longer than the lifetime of the tokens it signed.
+func (keyring *Keyring) VerificationKey(kid string, now time.Time, retention time.Duration) (*SigningKey, error) {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	for i, key := range keyring.keys {
+		if key.ID == kid && isRetained(key, now, retention) {
+			return &keyring.keys[i], nil
+		}
+	}
+
+	return nil, UnknownSigningKeyException
+}
+
+// Methods returns the name of the algorithms used by the keys of the keyring.
+func (keyring *Keyring) Methods() []string {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	var methods []string
+	seen := make(map[string]bool)
+	for _, key := range keyring.keys {
+		if !seen[key.Method.Alg()] {
+			seen[key.Method.Alg()] = true
+			methods = append(methods, key.Method.Alg())
+		}
+	}
+	return methods
+}
+
+// JWKS returns the public keys that can be used for verifying tokens. Keys
+// that are not active yet are included too, while symmetric keys never are.
+func (keyring *Keyring) JWKS(now time.Time, retention time.Duration) common.JWKS {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	jwks := common.JWKS{Keys: []common.JWK{}}
+	for _, key := range keyring.keys {
+		if !isRetained(key, now, retention) {
+			continue
+		}
+		jwk, err := common.NewJWK(key.ID, key.Method.Alg(), key.PublicKey)
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, *jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// ======== PRIVATE METHODS ========
+
+// isRetained returns whether a key can still verify tokens.
+func isRetained(key SigningKey, now time.Time, retention time.Duration) bool {
+	return key.NotAfter.IsZero() || now.Before(key.NotAfter.Add(retention))
+}
+
+// loadSigningKey loads a key from a PEM file.
+func loadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("the file does not contain a PEM block")
+	}
+
+	key := SigningKey{
+		ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+	}
+	if kid, ok := block.Headers["Kid"]; ok {
+		key.ID = kid
+	}
+
+	// ======== PARSE KEY ========
+	switch block.Type {
+	case "PRIVATE KEY":
+		key.PrivateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.PrivateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key.PrivateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key.PublicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if signer, ok := key.PrivateKey.(crypto.Signer); ok {
+		key.PublicKey = signer.Public()
+	}
+
+	// ======== SIGNING METHOD ========
+	alg, err := defaultAlgorithm(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if header, ok := block.Headers["Alg"]; ok {
+		alg = header
+	}
+
+	key.Method = jwt.GetSigningMethod(alg)
+	if key.Method == nil || !isCompatible(alg, key.PublicKey) {
+		return nil, fmt.Errorf("the algorithm %q cannot be used with this key", alg)
+	}
+
+	// ======== SIGNING WINDOW ========
+	if header, ok := block.Headers["Not-Before"]; ok {
+		if key.NotBefore, err = time.Parse(time.RFC3339, header); err != nil {
+			return nil, err
+		}
+	}
+	if header, ok := block.Headers["Not-After"]; ok {
+		if key.NotAfter, err = time.Parse(time.RFC3339, header); err != nil {
+			return nil, err
+		}
+	}
+
+	return &key, nil
+}
+
+// defaultAlgorithm returns the algorithm used by default for a public key.
+func defaultAlgorithm(key crypto.PublicKey) (string, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().Name {
+		case "P-256":
+			return "ES256", nil
+		case "P-384":
+			return "ES384", nil
+		case "P-521":
+			return "ES512", nil
+		}
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	}
+	return "", common.UnsupportedKeyException
+}
+
+// isCompatible returns whether an algorithm can be used with a public key.
+func isCompatible(alg string, key crypto.PublicKey) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		defaultAlg, _ := defaultAlgorithm(key)
+		return alg == defaultAlg
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey writes a private key to a PEM file with the given headers.
+func writeKey(t *testing.T, dir string, name string, key interface{}, headers map[string]string) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Headers: headers, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0600))
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2023, 7, 24, 12, 0, 0, 0, time.UTC)
+	retention := 15 * time.Minute
+
+	// The old key was retired an hour ago, the current one is active and the
+	// next one will start signing tomorrow.
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writeKey(t, dir, "old.pem", oldKey, map[string]string{
+		"Not-After": now.Add(-5 * time.Minute).Format(time.RFC3339),
+	})
+	_, currentKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "current.pem", currentKey, map[string]string{
+		"Kid":        "2023-07",
+		"Not-Before": now.Add(-24 * time.Hour).Format(time.RFC3339),
+	})
+	nextKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	writeKey(t, dir, "next.pem", nextKey, map[string]string{
+		"Not-Before": now.Add(24 * time.Hour).Format(time.RFC3339),
+	})
+
+	keyring, err := LoadKeyring(dir)
+	require.NoError(t, err)
+
+	// Test case 1: The active key signs new tokens
+	signingKey, err := keyring.SigningKey(now)
+	require.NoError(t, err)
+	assert.Equal(t, "2023-07", signingKey.ID)
+	assert.Equal(t, "EdDSA", signingKey.Method.Alg())
+
+	// Test case 2: Tokens signed with the retired key are still valid until they expire
+	_, err = keyring.VerificationKey("old", now, retention)
+	assert.NoError(t, err)
+	_, err = keyring.VerificationKey("old", now.Add(retention), retention)
+	assert.ErrorIs(t, err, UnknownSigningKeyException)
+
+	// Test case 3: Every key that can verify tokens is published, including the next one
+	jwks := keyring.JWKS(now, retention)
+	require.Len(t, jwks.Keys, 3)
+	assert.Equal(t, []string{"2023-07", "next", "old"}, []string{jwks.Keys[0].Kid, jwks.Keys[1].Kid, jwks.Keys[2].Kid})
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "ES384", jwks.Keys[1].Alg)
+
+	// Test case 4: Once the next key becomes active it takes over
+	signingKey, err = keyring.SigningKey(now.Add(25 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "next", signingKey.ID)
+}
+
+func TestKeyring_SignAndVerifyWithJWKS(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writeKey(t, dir, "key.pem", key, nil)
+
+	keyring, err := LoadKeyring(dir)
+	require.NoError(t, err)
+
+	signingKey, err := keyring.SigningKey(time.Now())
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(signingKey.Method, jwt.MapClaims{"sub": "1"})
+	token.Header["kid"] = signingKey.ID
+	tokenString, err := token.SignedString(signingKey.PrivateKey)
+	require.NoError(t, err)
+
+	// Another service verifies the token using only the published key set.
+	jwks := keyring.JWKS(time.Now(), time.Minute)
+	parsed, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		jwk, ok := jwks.Find(token.Header["kid"].(string))
+		require.True(t, ok)
+		return jwk.PublicKey()
+	})
+	require.NoError(t, err)
+	assert.True(t, parsed.Valid)
+}
+
+func TestKeyring_SecretFallback(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+
+	keyring, err := LoadKeyring("")
+	require.NoError(t, err)
+
+	// Without a keys directory tokens are signed with HS256, and the secret is never published.
+	signingKey, err := keyring.SigningKey(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "HS256", signingKey.Method.Alg())
+	assert.Empty(t, keyring.JWKS(time.Now(), time.Minute).Keys)
+}
+
+func TestKeyring_IncompatibleAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writeKey(t, dir, "key.pem", key, map[string]string{"Alg": "RS256"})
+
+	_, err := LoadKeyring(dir)
+	assert.Error(t, err)
+}
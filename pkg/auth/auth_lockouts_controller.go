@@ -0,0 +1,96 @@
+/*
+Package Name: auth
+File Name: auth_lockouts_controller.go
+Abstract: The controller that lets admins see and lift the lockouts caused by failed logins.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// LockoutsController struct
+type LockoutsController struct {
+	logger   lib.Logger
+	attempts interfaces.LoginAttemptsRepository
+}
+
+// ======== METHODS ========
+
+// GetLockoutsController retrieves a new lockouts controller.
+func GetLockoutsController(logger lib.Logger, attempts interfaces.LoginAttemptsRepository) LockoutsController {
+	return LockoutsController{
+		logger:   logger,
+		attempts: attempts,
+	}
+}
+
+// GetAll returns the accounts and IP addresses with failed logins.
+func (controller LockoutsController) GetAll(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all lockouts.")
+
+	lockouts, err := controller.attempts.GetLockouts()
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lockouts)
+}
+
+// Unlock forgets the failed logins of an account or IP address.
+func (controller LockoutsController) Unlock(ctx *gin.Context) {
+	kind, identifier := ctx.Param("kind"), ctx.Param("identifier")
+	controller.logger.Info("[DELETE] Lifting the lockout of", kind, identifier)
+
+	if kind != interfaces.LockoutKindAccount && kind != interfaces.LockoutKindIP {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The kind must be either account or ip."))
+		return
+	}
+
+	err := controller.attempts.Unlock(kind, identifier)
+	if errors.Is(err, interfaces.LockoutNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Lockout lifted successfully.",
+	})
+}
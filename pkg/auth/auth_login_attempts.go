@@ -0,0 +1,315 @@
+/*
+Package Name: auth
+File Name: auth_login_attempts.go
+Abstract: The service that tracks failed logins and slows down brute force attacks.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+)
+
+// ======== TYPES ========
+
+// LoginAttemptsService service layer
+type LoginAttemptsService struct {
+	logger   lib.Logger
+	db       *lib.Database
+	policies map[string]lockoutPolicy
+}
+
+// lockoutPolicy controls how failed logins are penalized.
+//
+// After backoffAfter consecutive failures, every failure makes the next attempt
+// wait twice as long as the previous one, starting at backoffBase and up to
+// backoffMax. After lockoutThreshold failures, no attempt is allowed for the
+// whole lockoutDuration. Failures older than window are forgotten.
+type lockoutPolicy struct {
+	backoffAfter     int32
+	backoffBase      time.Duration
+	backoffMax       time.Duration
+	lockoutThreshold int32
+	lockoutDuration  time.Duration
+	window           time.Duration
+}
+
+// ======== METHODS ========
+
+// GetLoginAttemptsService returns the login attempts service, and schedules the
+// removal of the failures that have been forgotten.
+//
+// Accounts are locked after LOGIN_MAX_ACCOUNT_FAILURES failures (10 by default)
+// and IP addresses after LOGIN_MAX_IP_FAILURES (100 by default), for
+// LOGIN_LOCKOUT_DURATION (15m by default).
+func GetLoginAttemptsService(
+	logger lib.Logger,
+	db *lib.Database,
+	scheduler *lib.Scheduler,
+) interfaces.LoginAttemptsRepository {
+	base := lockoutPolicy{
+		backoffAfter:    int32(common.Env.Int("LOGIN_BACKOFF_AFTER", 3)),
+		backoffBase:     common.Env.Duration("LOGIN_BACKOFF_BASE", time.Second),
+		backoffMax:      common.Env.Duration("LOGIN_BACKOFF_MAX", time.Minute),
+		lockoutDuration: common.Env.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		window:          common.Env.Duration("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+
+	account, ip := base, base
+	account.lockoutThreshold = int32(common.Env.Int("LOGIN_MAX_ACCOUNT_FAILURES", 10))
+	ip.lockoutThreshold = int32(common.Env.Int("LOGIN_MAX_IP_FAILURES", 100))
+	// Many users can share an IP address, so it only starts slowing down once
+	// it has failed more times than any single account would.
+	ip.backoffAfter = ip.lockoutThreshold / 2
+
+	service := LoginAttemptsService{
+		logger: logger,
+		db:     db,
+		policies: map[string]lockoutPolicy{
+			interfaces.LockoutKindAccount: account,
+			interfaces.LockoutKindIP:      ip,
+		},
+	}
+
+	scheduler.Every(
+		"purge login attempts",
+		common.Env.Duration("LOGIN_ATTEMPTS_PURGE_INTERVAL", time.Hour),
+		service.purge,
+	)
+
+	return service
+}
+
+// Check returns how long the account and IP address have to wait before trying
+// to log in again.
+func (service LoginAttemptsService) Check(account string, ip string) (time.Duration, error) {
+	var lockedUntil *time.Time
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT max(locked_until) FROM auth.login_attempt
+		WHERE (kind = $1 AND identifier = $2) OR (kind = $3 AND identifier = $4);`,
+		interfaces.LockoutKindAccount,
+		normalizeAccount(account),
+		interfaces.LockoutKindIP,
+		ip,
+	).Scan(&lockedUntil)
+	if err != nil {
+		return 0, err
+	}
+
+	return retryAfter(lockedUntil, time.Now()), nil
+}
+
+// RecordFailure records a failed login of the account from the IP address.
+func (service LoginAttemptsService) RecordFailure(account string, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for kind, identifier := range map[string]string{
+		interfaces.LockoutKindAccount: normalizeAccount(account),
+		interfaces.LockoutKindIP:      ip,
+	} {
+		delay, err := service.recordFailure(kind, identifier)
+		if err != nil {
+			return 0, err
+		}
+		if delay > wait {
+			wait = delay
+		}
+	}
+
+	return wait, nil
+}
+
+// Reset forgets the failed logins of the account. The failures of the IP
+// address are left to expire, otherwise logging into an account of their own
+// would let clients keep guessing the passwords of others from the same IP.
+func (service LoginAttemptsService) Reset(account string) error {
+	_, err := service.db.Exec(
+		context.Background(),
+		`DELETE FROM auth.login_attempt WHERE kind = $1 AND identifier = $2;`,
+		interfaces.LockoutKindAccount,
+		normalizeAccount(account),
+	)
+	return err
+}
+
+// GetLockouts returns the accounts and IP addresses with failed logins, the ones
+// that are locked out first.
+func (service LoginAttemptsService) GetLockouts() ([]interfaces.Lockout, error) {
+	rows, err := service.db.Query(
+		context.Background(),
+		`SELECT kind, identifier, failures, last_failure_at, locked_until
+		FROM auth.login_attempt
+		ORDER BY locked_until DESC NULLS LAST, failures DESC;`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lockouts := []interfaces.Lockout{}
+	for rows.Next() {
+		lockout := interfaces.Lockout{}
+		err := rows.Scan(&lockout.Kind, &lockout.Identifier, &lockout.Failures, &lockout.LastFailureAt, &lockout.LockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		lockouts = append(lockouts, lockout)
+	}
+
+	return lockouts, rows.Err()
+}
+
+// Unlock forgets the failed logins of an account or IP address.
+func (service LoginAttemptsService) Unlock(kind string, identifier string) error {
+	if kind == interfaces.LockoutKindAccount {
+		identifier = normalizeAccount(identifier)
+	}
+
+	tag, err := service.db.Exec(
+		context.Background(),
+		`DELETE FROM auth.login_attempt WHERE kind = $1 AND identifier = $2;`,
+		kind,
+		identifier,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return interfaces.LockoutNotFoundException
+	}
+
+	return nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// recordFailure counts a failure of an account or IP address and locks it for
+// as long as its policy says.
+func (service LoginAttemptsService) recordFailure(kind string, identifier string) (time.Duration, error) {
+	policy := service.policies[kind]
+	ctx := context.Background()
+	now := time.Now()
+
+	// The counter is incremented atomically, and starts again if the last
+	// failure is older than the window.
+	var failures int32
+	err := service.db.QueryRow(
+		ctx,
+		`INSERT INTO auth.login_attempt (kind, identifier, failures, last_failure_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (kind, identifier) DO UPDATE SET
+			failures = CASE
+				WHEN auth.login_attempt.last_failure_at < $4 THEN 1
+				ELSE auth.login_attempt.failures + 1
+			END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING failures;`,
+		kind,
+		identifier,
+		now,
+		now.Add(-policy.window),
+	).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	delay := policy.delay(failures)
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	if failures == policy.lockoutThreshold {
+		service.logger.Info("Locked out", kind, identifier, "after", failures, "failed login attempts.")
+	}
+
+	_, err = service.db.Exec(
+		ctx,
+		`UPDATE auth.login_attempt SET locked_until = greatest(locked_until, $3)
+		WHERE kind = $1 AND identifier = $2;`,
+		kind,
+		identifier,
+		now.Add(delay),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return delay, nil
+}
+
+// purge removes the failures that have already been forgotten.
+func (service LoginAttemptsService) purge(ctx context.Context) error {
+	_, err := service.db.Exec(
+		ctx,
+		`DELETE FROM auth.login_attempt
+		WHERE (locked_until IS NULL OR locked_until < now()) AND last_failure_at < $1;`,
+		time.Now().Add(-service.policies[interfaces.LockoutKindAccount].window),
+	)
+	return err
+}
+
+// delay returns how long has to be waited after the number of consecutive
+// failures given.
+func (policy lockoutPolicy) delay(failures int32) time.Duration {
+	if policy.lockoutThreshold > 0 && failures >= policy.lockoutThreshold {
+		return policy.lockoutDuration
+	}
+	if failures < policy.backoffAfter {
+		return 0
+	}
+
+	delay := policy.backoffBase
+	for i := policy.backoffAfter; i < failures && delay < policy.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > policy.backoffMax {
+		delay = policy.backoffMax
+	}
+	return delay
+}
+
+// retryAfter returns how long is left until the date given, or zero if it is
+// in the past.
+func retryAfter(lockedUntil *time.Time, now time.Time) time.Duration {
+	if lockedUntil == nil || !lockedUntil.After(now) {
+		return 0
+	}
+	return lockedUntil.Sub(now)
+}
+
+// normalizeAccount makes the same email count as the same account regardless of
+// its case.
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutPolicy_Delay(t *testing.T) {
+	policy := lockoutPolicy{
+		backoffAfter:     3,
+		backoffBase:      time.Second,
+		backoffMax:       10 * time.Second,
+		lockoutThreshold: 10,
+		lockoutDuration:  15 * time.Minute,
+	}
+
+	// The first failures are free, then the delay doubles up to the maximum,
+	// and the lockout kicks in at the threshold.
+	expected := map[int32]time.Duration{
+		1:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		5:  4 * time.Second,
+		6:  8 * time.Second,
+		7:  10 * time.Second,
+		9:  10 * time.Second,
+		10: 15 * time.Minute,
+		20: 15 * time.Minute,
+	}
+	for failures, delay := range expected {
+		assert.Equal(t, delay, policy.delay(failures), "failures %d", failures)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	assert.Equal(t, time.Duration(0), retryAfter(nil, now))
+	assert.Equal(t, time.Duration(0), retryAfter(&past, now))
+	assert.Equal(t, time.Minute, retryAfter(&future, now))
+}
@@ -0,0 +1,245 @@
+/*
+Package Name: auth
+File Name: auth_magic_link_controller.go
+Abstract: The controller that lets users log in with a link sent to their email
+instead of a password.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// MagicLinkController struct
+type MagicLinkController struct {
+	logger       lib.Logger
+	service      interfaces.AuthService
+	usersService users.UsersRepository
+	mfa          interfaces.MFARepository
+	magicLinks   interfaces.MagicLinksRepository
+	sessions     interfaces.SessionsRepository
+	mailer       lib.Mailer
+}
+
+type MagicLinkBody struct {
+	Email string `json:"email" form:"email" binding:"required,email"`
+}
+
+type MagicLinkVerifyBody struct {
+	Token string `json:"token" form:"token" binding:"required"`
+	// Nonce is the nonce returned when the link was requested. Browsers
+	// can omit it, since it is also set in a cookie.
+	Nonce string `json:"nonce" form:"nonce"`
+	LoginOptions
+}
+
+// ======== METHODS ========
+
+// GetMagicLinkController retrieves a new magic link controller.
+func GetMagicLinkController(
+	logger lib.Logger,
+	service interfaces.AuthService,
+	usersService users.UsersRepository,
+	mfa interfaces.MFARepository,
+	magicLinks interfaces.MagicLinksRepository,
+	sessions interfaces.SessionsRepository,
+	mailer lib.Mailer,
+) MagicLinkController {
+	return MagicLinkController{
+		logger:       logger,
+		service:      service,
+		usersService: usersService,
+		mfa:          mfa,
+		magicLinks:   magicLinks,
+		sessions:     sessions,
+		mailer:       mailer,
+	}
+}
+
+// Request sends a link for logging in to the email of the user, and binds it
+// to the client that asked for it with a nonce, which is returned and set in a
+// cookie. The link only works along with the nonce, so it cannot be used by
+// whoever asked for it without access to the email, nor by whoever gets hold of
+// the email without the nonce.
+//
+// The response is the same whether or not there is an account with the email
+// provided, so that this route cannot be used for finding out who is registered.
+func (controller MagicLinkController) Request(ctx *gin.Context) {
+	controller.logger.Info("[POST] Magic link route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := MagicLinkBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== CHECK RATE LIMIT ========
+	wait, err := controller.magicLinks.Check(body.Email)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if wait > 0 {
+		middlewares.AbortWithRetryAfter(ctx, wait, interfaces.TooManyMagicLinksException)
+		return
+	}
+
+	// ======== CREATE LINK ========
+	// A link is created even if there is no account with the email, so that
+	// the rate limit applies the same way.
+	var userID *int32
+	user, err := controller.usersService.GetUserByEmail(body.Email)
+	if err == nil {
+		userID = &user.ID
+	}
+
+	ttl := common.Env.Duration("MAGIC_LINK_TTL", 15*time.Minute)
+	token, nonce, err := controller.magicLinks.CreateMagicLink(body.Email, userID, ttl)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if user != nil {
+		if err := controller.sendMagicLink(user, token, ttl); err != nil {
+			controller.logger.Error("Could not send the magic link:", err)
+		}
+	}
+
+	setSessionCookie(ctx, interfaces.MagicLinkNonceCookie, nonce, int(ttl.Seconds()), true)
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message":    "If there is an account with that email, a login link has been sent to it.",
+		"nonce":      nonce,
+		"expires_in": int64(ttl.Seconds()),
+	})
+}
+
+// Verify exchanges the token of a link and its nonce for the same tokens, or
+// cookie session, a login with a password returns. Users with two-factor
+// authentication get a challenge instead.
+func (controller MagicLinkController) Verify(ctx *gin.Context) {
+	controller.logger.Info("[POST] Verify magic link route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := MagicLinkVerifyBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	if body.Nonce == "" {
+		body.Nonce, _ = ctx.Cookie(interfaces.MagicLinkNonceCookie)
+	}
+	if body.Nonce == "" {
+		ctx.AbortWithError(http.StatusBadRequest, interfaces.InvalidMagicLinkException)
+		return
+	}
+
+	// ======== CONSUME LINK ========
+	userID, err := controller.magicLinks.ConsumeMagicLink(body.Token, body.Nonce)
+	if errors.Is(err, interfaces.InvalidMagicLinkException) {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	setSessionCookie(ctx, interfaces.MagicLinkNonceCookie, "", -1, true)
+
+	// ======== VERIFY EMAIL ========
+	// Following the link proves that the user owns the email.
+	user, err := controller.usersService.GetUserById(int(userID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if user.EmailVerifiedAt == nil {
+		if err := controller.usersService.MarkEmailVerified(userID); err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	// ======== CHECK MFA ========
+	// The link replaces the password, not the second factor.
+	enabled, err := controller.mfa.IsEnabled(userID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if enabled {
+		respondWithMFAChallenge(ctx, controller.service, userID)
+		return
+	}
+
+	respondWithLogin(
+		ctx,
+		controller.service,
+		controller.sessions,
+		userID,
+		interfaces.AuthMethodMagicLink,
+		body.LoginOptions,
+		"Logged in successfully.",
+	)
+}
+
+// ======== PRIVATE METHODS ========
+
+// sendMagicLink sends the link for logging in to the user.
+func (controller MagicLinkController) sendMagicLink(user *users.InternalUser, token string, ttl time.Duration) error {
+	return controller.mailer.Send(lib.Mail{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the following link within the next %s to log in, from the same browser you asked for it:\n\n%s\n\nIf you did not ask for it, you can safely ignore this email.\n",
+			user.Username,
+			ttl,
+			tokenLink(common.Env.String("MAGIC_LINK_URL", ""), token),
+		),
+	})
+}
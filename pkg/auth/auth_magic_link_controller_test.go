@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicLinkController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	usersService := &mocks.MockUsersService{}
+	authService := &mocks.MockAuthService{}
+	mfa := &mocks.MockMFAService{}
+	magicLinks := &mocks.MockMagicLinksService{MaxRequests: 3}
+	mailer := &mocks.MockMailer{}
+
+	magicLinkController := GetMagicLinkController(
+		mocks.NewMockLogger(),
+		authService,
+		usersService,
+		mfa,
+		magicLinks,
+		&mocks.MockSessionsService{},
+		mailer,
+	)
+	router.POST("/login/magic-link", magicLinkController.Request)
+	router.POST("/login/magic-link/verify", magicLinkController.Verify)
+
+	post := func(path string, body interface{}, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// request asks for a link and returns its token, nonce and cookie.
+	request := func(t *testing.T) (string, string, *http.Cookie) {
+		mailer.Sent = nil
+		w := post("/login/magic-link", MagicLinkBody{Email: "user@example.com"})
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		nonce := response["nonce"].(string)
+
+		var cookie *http.Cookie
+		for _, value := range w.Result().Cookies() {
+			if value.Name == interfaces.MagicLinkNonceCookie {
+				cookie = value
+			}
+		}
+		require.NotNil(t, cookie)
+		assert.Equal(t, nonce, cookie.Value)
+		assert.True(t, cookie.HttpOnly)
+
+		require.Len(t, mailer.Sent, 1)
+		for token, link := range magicLinks.Links {
+			if link.Nonce == nonce {
+				assert.True(t, strings.Contains(mailer.Sent[0].Body, token))
+				return token, nonce, cookie
+			}
+		}
+		t.Fatal("The link was not created.")
+		return "", "", nil
+	}
+
+	t.Run("UnknownEmail", func(t *testing.T) {
+		w := post("/login/magic-link", MagicLinkBody{Email: "nobody@example.com"})
+
+		// The response must not reveal whether the account exists.
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Empty(t, mailer.Sent)
+	})
+
+	t.Run("VerifyWithNonce", func(t *testing.T) {
+		token, nonce, _ := request(t)
+
+		w := post("/login/magic-link/verify", MagicLinkVerifyBody{Token: token, Nonce: nonce})
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "mock_jwt_token", response["token"])
+		assert.Equal(t, []int32{1}, usersService.VerifiedUsers)
+
+		// Test case 2: The link can only be used once
+		w = post("/login/magic-link/verify", MagicLinkVerifyBody{Token: token, Nonce: nonce})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("VerifyWithCookie", func(t *testing.T) {
+		token, _, cookie := request(t)
+
+		w := post("/login/magic-link/verify", MagicLinkVerifyBody{Token: token}, cookie)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("VerifyFromAnotherBrowser", func(t *testing.T) {
+		token, nonce, _ := request(t)
+
+		// Test case 1: Without the nonce
+		w := post("/login/magic-link/verify", MagicLinkVerifyBody{Token: token})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Test case 2: With the nonce of another link
+		w = post("/login/magic-link/verify", MagicLinkVerifyBody{Token: token, Nonce: "mock_magic_link_nonce_1"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Test case 3: The link still works in the browser that asked for it
+		w = post("/login/magic-link/verify", MagicLinkVerifyBody{Token: token, Nonce: nonce})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		w := post("/login/magic-link", MagicLinkBody{Email: "user@example.com"})
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("VerifyWithMFA", func(t *testing.T) {
+		magicLinks.MaxRequests = 0
+		mfa.Enabled = map[int32]bool{1: true}
+		defer func() { mfa.Enabled = nil }()
+
+		token, nonce, _ := request(t)
+		w := post("/login/magic-link/verify", MagicLinkVerifyBody{Token: token, Nonce: nonce})
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, true, response["mfa_required"])
+		assert.Nil(t, response["token"])
+	})
+}
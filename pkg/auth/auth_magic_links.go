@@ -0,0 +1,180 @@
+/*
+Package Name: auth
+File Name: auth_magic_links.go
+Abstract: The service that issues the single-use links users can log in with instead
+of a password, and rate limits them by email.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// MagicLinksService service layer
+type MagicLinksService struct {
+	logger lib.Logger
+	db     *lib.Database
+}
+
+// ======== METHODS ========
+
+// GetMagicLinksService returns the magic links service, and schedules the
+// removal of the links that can no longer be used nor count towards the rate
+// limit.
+func GetMagicLinksService(
+	logger lib.Logger,
+	db *lib.Database,
+	scheduler *lib.Scheduler,
+) interfaces.MagicLinksRepository {
+	service := MagicLinksService{
+		logger: logger,
+		db:     db,
+	}
+
+	scheduler.Every(
+		"purge magic links",
+		common.Env.Duration("MAGIC_LINKS_PURGE_INTERVAL", time.Hour),
+		service.purge,
+	)
+
+	return service
+}
+
+// Check returns how long has to pass before another link can be sent to the
+// email. At most MAGIC_LINK_MAX_REQUESTS links can be sent to an email within
+// MAGIC_LINK_WINDOW.
+func (service MagicLinksService) Check(email string) (time.Duration, error) {
+	window := magicLinkWindow()
+
+	var count int
+	var oldest *time.Time
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT count(*), min(created_at) FROM auth.magic_link
+		WHERE email = $1 AND created_at > $2;`,
+		normalizeAccount(email),
+		time.Now().Add(-window),
+	).Scan(&count, &oldest)
+	if err != nil {
+		return 0, err
+	}
+
+	if count < common.Env.Int("MAGIC_LINK_MAX_REQUESTS", 3) || oldest == nil {
+		return 0, nil
+	}
+
+	// Another link can be sent once the oldest one leaves the window.
+	return time.Until(oldest.Add(window)), nil
+}
+
+// CreateMagicLink returns the token of a new link for the email and its nonce.
+// Only their hashes are stored, so neither can be recovered afterwards.
+func (service MagicLinksService) CreateMagicLink(
+	email string,
+	userID *int32,
+	ttl time.Duration,
+) (string, string, error) {
+	token, err := common.Tokens.Generate()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := common.Tokens.Generate()
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = service.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.magic_link (email, user_id, token_hash, nonce_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5);`,
+		normalizeAccount(email),
+		userID,
+		common.Tokens.Hash(token),
+		common.Tokens.Hash(nonce),
+		time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, nonce, nil
+}
+
+// ConsumeMagicLink marks the link as used and returns the user it was sent to.
+// The link is checked and consumed in a single statement, so it cannot be used
+// twice even by concurrent requests. Presenting the wrong nonce does not use
+// the link, so that opening it in another browser does not stop it from
+// working in the one that asked for it.
+func (service MagicLinksService) ConsumeMagicLink(token string, nonce string) (int32, error) {
+	var userID int32
+	err := service.db.QueryRow(
+		context.Background(),
+		`UPDATE auth.magic_link SET used_at = now()
+		WHERE token_hash = $1 AND nonce_hash = $2 AND user_id IS NOT NULL
+			AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id;`,
+		common.Tokens.Hash(token),
+		common.Tokens.Hash(nonce),
+	).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, interfaces.InvalidMagicLinkException
+	} else if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// purge removes the links that can no longer be used and have left the window
+// of the rate limit.
+func (service MagicLinksService) purge(ctx context.Context) error {
+	_, err := service.db.Exec(
+		ctx,
+		`DELETE FROM auth.magic_link
+		WHERE created_at < $1 AND (expires_at < now() OR used_at IS NOT NULL);`,
+		time.Now().Add(-magicLinkWindow()),
+	)
+	return err
+}
+
+// magicLinkWindow returns the window the links sent to an email are counted in.
+func magicLinkWindow() time.Duration {
+	return common.Env.Duration("MAGIC_LINK_WINDOW", 15*time.Minute)
+}
@@ -0,0 +1,296 @@
+/*
+Package Name: auth
+File Name: auth_mfa.go
+Abstract: The service for two-factor authentication with TOTP codes and recovery codes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// MFAService service layer
+type MFAService struct {
+	logger lib.Logger
+	db     *lib.Database
+}
+
+// ======== CONSTANTS ========
+
+const (
+	// recoveryCodesCount is how many recovery codes are generated for a user.
+	recoveryCodesCount = 10
+	// totpSkew is how many time steps before and after the current one are
+	// accepted, to allow for clock drift.
+	totpSkew = 1
+)
+
+// ======== METHODS ========
+
+// GetMFAService returns the MFA service.
+func GetMFAService(logger lib.Logger, db *lib.Database) interfaces.MFARepository {
+	return MFAService{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// IsEnabled returns whether the user has two-factor authentication enabled.
+func (service MFAService) IsEnabled(userID int32) (bool, error) {
+	var enabled bool
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM auth.user_mfa WHERE user_id = $1 AND enabled_at IS NOT NULL);`,
+		userID,
+	).Scan(&enabled)
+	return enabled, err
+}
+
+// BeginEnrollment generates a new secret for the user, replacing any enrollment
+// that was not confirmed.
+func (service MFAService) BeginEnrollment(userID int32, account string) (*interfaces.MFAEnrollment, error) {
+	secret, err := common.TOTP.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	// The secret is only replaced if two-factor authentication is not enabled
+	// yet, otherwise no rows are returned.
+	err = service.db.QueryRow(
+		context.Background(),
+		`INSERT INTO auth.user_mfa (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret = excluded.secret, created_at = now(), last_used_step = 0
+			WHERE auth.user_mfa.enabled_at IS NULL
+		RETURNING user_id;`,
+		userID,
+		secret,
+	).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.MFAAlreadyEnabledException
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &interfaces.MFAEnrollment{
+		Secret: secret,
+		URI:    common.TOTP.URI(common.Env.String("MFA_ISSUER", "API"), account, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication if the code is valid, and
+// replaces the recovery codes of the user with new ones.
+func (service MFAService) ConfirmEnrollment(userID int32, code string) ([]string, error) {
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	// ======== CHECK CODE ========
+	var (
+		secret    string
+		enabledAt *time.Time
+	)
+	err = tx.QueryRow(
+		ctx,
+		`SELECT secret, enabled_at FROM auth.user_mfa WHERE user_id = $1 FOR UPDATE;`,
+		userID,
+	).Scan(&secret, &enabledAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.MFANotEnrolledException
+	} else if err != nil {
+		return nil, err
+	}
+	if enabledAt != nil {
+		return nil, interfaces.MFAAlreadyEnabledException
+	}
+
+	step, ok := common.TOTP.Validate(secret, code, time.Now(), totpSkew, 0)
+	if !ok {
+		return nil, interfaces.InvalidMFACodeException
+	}
+
+	// ======== ENABLE ========
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE auth.user_mfa SET enabled_at = now(), last_used_step = $2 WHERE user_id = $1;`,
+		userID,
+		step,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// ======== RECOVERY CODES ========
+	_, err = tx.Exec(ctx, `DELETE FROM auth.mfa_recovery_code WHERE user_id = $1;`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO auth.mfa_recovery_code (user_id, code_hash) VALUES ($1, $2);`,
+			userID,
+			common.Tokens.Hash(normalizeRecoveryCode(codes[i])),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks a TOTP code or an unused recovery code of the user, and makes
+// sure it cannot be used again.
+func (service MFAService) Verify(userID int32, code string) error {
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	// The row is locked so that the same code cannot be used by two
+	// concurrent requests.
+	var (
+		secret       string
+		lastUsedStep int64
+	)
+	err = tx.QueryRow(
+		ctx,
+		`SELECT secret, last_used_step FROM auth.user_mfa
+		WHERE user_id = $1 AND enabled_at IS NOT NULL
+		FOR UPDATE;`,
+		userID,
+	).Scan(&secret, &lastUsedStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return interfaces.MFANotEnabledException
+	} else if err != nil {
+		return err
+	}
+
+	// ======== TOTP CODE ========
+	if step, ok := common.TOTP.Validate(secret, code, time.Now(), totpSkew, lastUsedStep); ok {
+		_, err = tx.Exec(ctx, `UPDATE auth.user_mfa SET last_used_step = $2 WHERE user_id = $1;`, userID, step)
+		if err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+
+	// ======== RECOVERY CODE ========
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE auth.mfa_recovery_code SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;`,
+		userID,
+		common.Tokens.Hash(normalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return interfaces.InvalidMFACodeException
+	}
+
+	service.logger.Info("User", userID, "used a recovery code.")
+	return tx.Commit(ctx)
+}
+
+// Disable disables two-factor authentication and removes the recovery codes of
+// the user if the code provided is valid.
+func (service MFAService) Disable(userID int32, code string) error {
+	if err := service.Verify(userID, code); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM auth.user_mfa WHERE user_id = $1;`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM auth.mfa_recovery_code WHERE user_id = $1;`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ======== PRIVATE METHODS ========
+
+// generateRecoveryCode returns a random recovery code with 50 bits of entropy,
+// formatted as two groups of five characters so that it is easy to type.
+func generateRecoveryCode() (string, error) {
+	bytes := make([]byte, 10)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(bytes))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+// normalizeRecoveryCode removes the formatting of a recovery code, so that it
+// matches regardless of how the user typed it.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
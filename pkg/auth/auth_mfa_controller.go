@@ -0,0 +1,262 @@
+/*
+Package Name: auth
+File Name: auth_mfa_controller.go
+Abstract: The controller for setting up two-factor authentication and completing logins with it.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// MFAController struct
+type MFAController struct {
+	logger       lib.Logger
+	service      interfaces.AuthService
+	usersService users.UsersRepository
+	mfa          interfaces.MFARepository
+	attempts     interfaces.LoginAttemptsRepository
+	sessions     interfaces.SessionsRepository
+}
+
+type MFACodeBody struct {
+	Code string `json:"code" form:"code" binding:"required"`
+}
+
+type MFALoginBody struct {
+	ChallengeToken string `json:"challenge_token" form:"challenge_token" binding:"required"`
+	Code           string `json:"code" form:"code" binding:"required"`
+	LoginOptions
+}
+
+// ======== METHODS ========
+
+// GetMFAController retrieves a new MFA controller.
+func GetMFAController(
+	logger lib.Logger,
+	service interfaces.AuthService,
+	usersService users.UsersRepository,
+	mfa interfaces.MFARepository,
+	attempts interfaces.LoginAttemptsRepository,
+	sessions interfaces.SessionsRepository,
+) MFAController {
+	return MFAController{
+		logger:       logger,
+		service:      service,
+		usersService: usersService,
+		mfa:          mfa,
+		attempts:     attempts,
+		sessions:     sessions,
+	}
+}
+
+// Enroll generates a new TOTP secret for the authenticated user, which has to be
+// confirmed with a code before two-factor authentication is enabled.
+func (controller MFAController) Enroll(ctx *gin.Context) {
+	controller.logger.Info("[POST] Enroll MFA route.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	user, err := controller.usersService.GetUserById(int(principal.UserID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	enrollment, err := controller.mfa.BeginEnrollment(user.ID, user.Email)
+	if errors.Is(err, interfaces.MFAAlreadyEnabledException) {
+		ctx.AbortWithError(http.StatusConflict, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Add the secret to your authenticator app and confirm it with a code.",
+		"secret":  enrollment.Secret,
+		"uri":     enrollment.URI,
+	})
+}
+
+// Confirm enables two-factor authentication for the authenticated user and
+// returns their recovery codes, which are only shown this once.
+func (controller MFAController) Confirm(ctx *gin.Context) {
+	controller.logger.Info("[POST] Confirm MFA route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := MFACodeBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	var codes []string
+	if !controller.checkCode(ctx, func(userID int32) (err error) {
+		codes, err = controller.mfa.ConfirmEnrollment(userID, body.Code)
+		return err
+	}) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled successfully.",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable disables two-factor authentication for the authenticated user, who
+// has to provide a current code.
+func (controller MFAController) Disable(ctx *gin.Context) {
+	controller.logger.Info("[POST] Disable MFA route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := MFACodeBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	if !controller.checkCode(ctx, func(userID int32) error {
+		return controller.mfa.Disable(userID, body.Code)
+	}) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled successfully.",
+	})
+}
+
+// Login exchanges the challenge token returned by the login route and a TOTP or
+// recovery code for the tokens of the user.
+func (controller MFAController) Login(ctx *gin.Context) {
+	controller.logger.Info("[POST] MFA login route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := MFALoginBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== CHECK CHALLENGE ========
+	userID, err := controller.service.CheckChallenge(body.ChallengeToken, interfaces.ChallengePurposeMFA)
+	if err != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, err)
+		return
+	}
+
+	// ======== CHECK ATTEMPTS ========
+	// Failed codes count as failed logins of the account, so that codes
+	// cannot be guessed either.
+	user, err := controller.usersService.GetUserById(int(userID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, interfaces.InvalidChallengeException)
+		return
+	}
+	if middlewares.AbortIfThrottled(ctx, controller.attempts, user.Email) {
+		return
+	}
+
+	// ======== CHECK CODE ========
+	if err := controller.mfa.Verify(userID, body.Code); err != nil {
+		if errors.Is(err, interfaces.InvalidMFACodeException) || errors.Is(err, interfaces.MFANotEnabledException) {
+			middlewares.RecordFailedLogin(ctx, controller.logger, controller.attempts, user.Email)
+			ctx.AbortWithError(http.StatusUnauthorized, interfaces.InvalidMFACodeException)
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := controller.attempts.Reset(user.Email); err != nil {
+		controller.logger.Error("Could not reset the failed login attempts:", err)
+	}
+
+	respondWithLogin(
+		ctx,
+		controller.service,
+		controller.sessions,
+		userID,
+		interfaces.AuthMethodMFA,
+		body.LoginOptions,
+		"Logged in successfully.",
+	)
+}
+
+// ======== PRIVATE METHODS ========
+
+// checkCode runs an action of the authenticated user that checks a code, like
+// confirming or disabling two-factor authentication. Wrong codes count as failed
+// logins of the account, as in Login, so that codes cannot be guessed with a
+// stolen token either. It returns false if the request was aborted.
+func (controller MFAController) checkCode(ctx *gin.Context, action func(userID int32) error) bool {
+	principal := middlewares.MustGetPrincipal(ctx)
+	user, err := controller.usersService.GetUserById(int(principal.UserID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return false
+	}
+	if middlewares.AbortIfThrottled(ctx, controller.attempts, user.Email) {
+		return false
+	}
+
+	if err := action(principal.UserID); err != nil {
+		if errors.Is(err, interfaces.InvalidMFACodeException) {
+			middlewares.RecordFailedLogin(ctx, controller.logger, controller.attempts, user.Email)
+		}
+		controller.abortWithMFAError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// abortWithMFAError aborts the request with the status that matches the error.
+func (controller MFAController) abortWithMFAError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, interfaces.InvalidMFACodeException),
+		errors.Is(err, interfaces.MFANotEnrolledException),
+		errors.Is(err, interfaces.MFANotEnabledException):
+		ctx.AbortWithError(http.StatusBadRequest, err)
+	case errors.Is(err, interfaces.MFAAlreadyEnabledException):
+		ctx.AbortWithError(http.StatusConflict, err)
+	default:
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMFAController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	authService := &mocks.MockAuthService{}
+	usersService := &mocks.MockUsersService{}
+	mfa := &mocks.MockMFAService{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+
+	verificationController := GetVerificationController(logger, usersService, &mocks.MockUserTokensService{}, &mocks.MockMailer{})
+	attempts := &mocks.MockLoginAttemptsService{}
+	authController := GetAuthController(logger, authService, usersService, verificationController, mfa, attempts, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
+	mfaController := GetMFAController(logger, authService, usersService, mfa, attempts, &mocks.MockSessionsService{})
+
+	router.POST("/login", authController.Login)
+	router.POST("/login/mfa", mfaController.Login)
+	api := router.Group("/").Use(authMiddleware.Handler())
+	api.POST("/mfa/enroll", mfaController.Enroll)
+	api.POST("/mfa/confirm", mfaController.Confirm)
+	api.POST("/mfa/disable", mfaController.Disable)
+
+	// post performs an authenticated request with the body given.
+	post := func(path string, body interface{}) (int, map[string]interface{}) {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	t.Run("Enroll", func(t *testing.T) {
+		code, response := post("/mfa/enroll", nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", response["secret"])
+		assert.Contains(t, response["uri"], "otpauth://totp/")
+	})
+
+	t.Run("ConfirmWithInvalidCode", func(t *testing.T) {
+		code, _ := post("/mfa/confirm", MFACodeBody{Code: "000000"})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.False(t, mfa.Enabled[1])
+		assert.Equal(t, 1, attempts.Failures["user@example.com"])
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		code, response := post("/mfa/confirm", MFACodeBody{Code: mocks.MockTOTPCode})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []interface{}{mocks.MockRecoveryCode}, response["recovery_codes"])
+		assert.True(t, mfa.Enabled[1])
+	})
+
+	t.Run("LoginRequiresCode", func(t *testing.T) {
+		code, response := post("/login", LoginBody{Email: "user@example.com", Password: "password123"})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, true, response["mfa_required"])
+		assert.Equal(t, "mock_mfa_challenge_token", response["challenge_token"])
+		assert.Nil(t, response["token"])
+	})
+
+	t.Run("LoginWithInvalidCode", func(t *testing.T) {
+		code, _ := post("/login/mfa", MFALoginBody{ChallengeToken: "mock_mfa_challenge_token", Code: "000000"})
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("LoginWithInvalidChallenge", func(t *testing.T) {
+		code, _ := post("/login/mfa", MFALoginBody{ChallengeToken: "mock_jwt_token", Code: mocks.MockTOTPCode})
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("LoginWithCode", func(t *testing.T) {
+		code, response := post("/login/mfa", MFALoginBody{ChallengeToken: "mock_mfa_challenge_token", Code: mocks.MockTOTPCode})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "mock_jwt_token", response["token"])
+	})
+
+	t.Run("LoginWithRecoveryCode", func(t *testing.T) {
+		code, _ := post("/login/mfa", MFALoginBody{ChallengeToken: "mock_mfa_challenge_token", Code: mocks.MockRecoveryCode})
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("DisableRequiresCode", func(t *testing.T) {
+		code, _ := post("/mfa/disable", MFACodeBody{Code: "000000"})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.True(t, mfa.Enabled[1])
+	})
+
+	// Wrong codes count as failed logins, so that a stolen token cannot be
+	// used for guessing codes until two-factor authentication is disabled.
+	t.Run("DisableIsThrottled", func(t *testing.T) {
+		for attempts.Failures["user@example.com"] < mocks.MockLoginAttemptsThreshold {
+			code, _ := post("/mfa/disable", MFACodeBody{Code: "000000"})
+			assert.Equal(t, http.StatusBadRequest, code)
+		}
+
+		code, _ := post("/mfa/disable", MFACodeBody{Code: mocks.MockTOTPCode})
+		assert.Equal(t, http.StatusTooManyRequests, code)
+		assert.True(t, mfa.Enabled[1])
+
+		delete(attempts.Failures, "user@example.com")
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		code, _ := post("/mfa/disable", MFACodeBody{Code: mocks.MockTOTPCode})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.False(t, mfa.Enabled[1])
+	})
+}
@@ -0,0 +1,487 @@
+/*
+Package Name: auth
+File Name: auth_oauth.go
+Abstract: The service that stores the clients, consents, authorization codes
+and refresh tokens of the OAuth 2.0 authorization server.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// OAuthService service layer
+type OAuthService struct {
+	logger lib.Logger
+	db     *lib.Database
+}
+
+// ======== CONSTANTS ========
+
+// oauthClientsQuery selects the public fields of the clients.
+const oauthClientsQuery = `
+	SELECT id, name, redirect_uris, scopes, grant_types, secret_hash IS NOT NULL, user_id, created_at
+	FROM auth.oauth_client`
+
+// ======== METHODS ========
+
+// GetOAuthService returns the OAuth service, and schedules the removal of the
+// codes and refresh tokens that can no longer be used.
+func GetOAuthService(
+	logger lib.Logger,
+	db *lib.Database,
+	scheduler *lib.Scheduler,
+) interfaces.OAuthRepository {
+	service := OAuthService{
+		logger: logger,
+		db:     db,
+	}
+
+	scheduler.Every(
+		"purge OAuth tokens",
+		common.Env.Duration("OAUTH_PURGE_INTERVAL", time.Hour),
+		service.purge,
+	)
+
+	return service
+}
+
+// CreateClient registers a client with a random id. Confidential clients get a
+// random secret, of which only the hash is stored.
+func (service OAuthService) CreateClient(client interfaces.OAuthClient) (*interfaces.OAuthClient, string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", err
+	}
+	client.ID = hex.EncodeToString(bytes)
+
+	var secret string
+	var secretHash *string
+	if client.Confidential {
+		var err error
+		if secret, err = common.Tokens.Generate(); err != nil {
+			return nil, "", err
+		}
+		hash := common.Tokens.Hash(secret)
+		secretHash = &hash
+	}
+
+	err := service.db.QueryRow(
+		context.Background(),
+		`INSERT INTO auth.oauth_client (id, name, secret_hash, redirect_uris, scopes, grant_types, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at;`,
+		client.ID,
+		client.Name,
+		secretHash,
+		nonNil(client.RedirectURIs),
+		nonNil(client.Scopes),
+		nonNil(client.GrantTypes),
+		client.UserID,
+	).Scan(&client.CreatedAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	service.logger.Info("Registered OAuth client", client.ID, "-", client.Name)
+	return &client, secret, nil
+}
+
+// GetClients returns every client.
+func (service OAuthService) GetClients() ([]interfaces.OAuthClient, error) {
+	rows, err := service.db.Query(context.Background(), oauthClientsQuery+` ORDER BY created_at;`)
+	if err != nil {
+		service.logger.Error("Error while executing query. Err:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []interfaces.OAuthClient{}
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			service.logger.Error("Error while iterating dataset. Err:", err)
+			return nil, err
+		}
+		results = append(results, *client)
+	}
+
+	return results, rows.Err()
+}
+
+// GetClient returns a client.
+func (service OAuthService) GetClient(id string) (*interfaces.OAuthClient, error) {
+	client, err := scanOAuthClient(service.db.QueryRow(
+		context.Background(),
+		oauthClientsQuery+` WHERE id = $1;`,
+		id,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.OAuthClientNotFoundException
+	}
+	return client, err
+}
+
+// DeleteClient removes a client. Its consents, codes and refresh tokens are
+// removed along with it, while the access tokens it holds expire on their own.
+func (service OAuthService) DeleteClient(id string) error {
+	tag, err := service.db.Exec(context.Background(), `DELETE FROM auth.oauth_client WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return interfaces.OAuthClientNotFoundException
+	}
+
+	service.logger.Info("Deleted OAuth client", id)
+	return nil
+}
+
+// AuthenticateClient returns the client if the secret is correct.
+func (service OAuthService) AuthenticateClient(id string, secret string) (*interfaces.OAuthClient, error) {
+	var secretHash *string
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT secret_hash FROM auth.oauth_client WHERE id = $1;`,
+		id,
+	).Scan(&secretHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.InvalidOAuthClientException
+	} else if err != nil {
+		return nil, err
+	}
+
+	if secretHash == nil {
+		// Public clients have no secret to present.
+		if secret != "" {
+			return nil, interfaces.InvalidOAuthClientException
+		}
+	} else if subtle.ConstantTimeCompare([]byte(*secretHash), []byte(common.Tokens.Hash(secret))) != 1 {
+		return nil, interfaces.InvalidOAuthClientException
+	}
+
+	return service.GetClient(id)
+}
+
+// GetConsent returns the scopes a user has consented to give a client.
+func (service OAuthService) GetConsent(userID int32, clientID string) ([]string, error) {
+	scopes := []string{}
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT scopes FROM auth.oauth_consent WHERE user_id = $1 AND client_id = $2;`,
+		userID,
+		clientID,
+	).Scan(&scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []string{}, nil
+	}
+	return scopes, err
+}
+
+// SaveConsent adds scopes to the consent of a user for a client.
+func (service OAuthService) SaveConsent(userID int32, clientID string, scopes []string) error {
+	_, err := service.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.oauth_consent (user_id, client_id, scopes)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE
+			SET scopes = ARRAY(
+				SELECT DISTINCT unnest(auth.oauth_consent.scopes || excluded.scopes) ORDER BY 1
+			),
+			granted_at = now();`,
+		userID,
+		clientID,
+		nonNil(scopes),
+	)
+	return err
+}
+
+// GetConsents returns the consents a user has given.
+func (service OAuthService) GetConsents(userID int32) ([]interfaces.OAuthConsent, error) {
+	rows, err := service.db.Query(
+		context.Background(),
+		`SELECT c.client_id, cl.name, c.scopes, c.granted_at
+		FROM auth.oauth_consent c
+		JOIN auth.oauth_client cl ON cl.id = c.client_id
+		WHERE c.user_id = $1
+		ORDER BY c.granted_at;`,
+		userID,
+	)
+	if err != nil {
+		service.logger.Error("Error while executing query. Err:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []interfaces.OAuthConsent{}
+	for rows.Next() {
+		var consent interfaces.OAuthConsent
+		if err := rows.Scan(&consent.ClientID, &consent.ClientName, &consent.Scopes, &consent.GrantedAt); err != nil {
+			service.logger.Error("Error while iterating dataset. Err:", err)
+			return nil, err
+		}
+		results = append(results, consent)
+	}
+
+	return results, rows.Err()
+}
+
+// RevokeConsent removes the consent of a user for a client and revokes the
+// refresh tokens the client holds for the user, in a single transaction.
+func (service OAuthService) RevokeConsent(userID int32, clientID string) error {
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		`DELETE FROM auth.oauth_consent WHERE user_id = $1 AND client_id = $2;`,
+		userID,
+		clientID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return interfaces.OAuthConsentNotFoundException
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE auth.oauth_refresh_token SET revoked_at = now()
+		WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL;`,
+		userID,
+		clientID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// IssueAuthorizationCode returns a new authorization code. Only its hash is stored.
+func (service OAuthService) IssueAuthorizationCode(grant interfaces.OAuthGrant, ttl time.Duration) (string, error) {
+	code, err := common.Tokens.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = service.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.oauth_authorization_code
+			(code_hash, client_id, user_id, scopes, redirect_uri, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		common.Tokens.Hash(code),
+		grant.ClientID,
+		grant.UserID,
+		nonNil(grant.Scopes),
+		grant.RedirectURI,
+		grant.CodeChallenge,
+		time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// ConsumeAuthorizationCode marks a code as used and returns its grant. The code
+// is checked and consumed in a single statement, so it cannot be used twice even
+// by concurrent requests.
+func (service OAuthService) ConsumeAuthorizationCode(code string) (*interfaces.OAuthGrant, error) {
+	grant := interfaces.OAuthGrant{}
+	err := service.db.QueryRow(
+		context.Background(),
+		`UPDATE auth.oauth_authorization_code SET used_at = now()
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING client_id, user_id, scopes, redirect_uri, code_challenge;`,
+		common.Tokens.Hash(code),
+	).Scan(&grant.ClientID, &grant.UserID, &grant.Scopes, &grant.RedirectURI, &grant.CodeChallenge)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.InvalidOAuthGrantException
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &grant, nil
+}
+
+// IssueRefreshToken returns a new refresh token for the grant.
+func (service OAuthService) IssueRefreshToken(grant interfaces.OAuthGrant, ttl time.Duration) (string, error) {
+	return service.issueRefreshToken(context.Background(), service.db, grant, ttl)
+}
+
+// RotateRefreshToken revokes a refresh token and issues a new one for the same
+// grant, in a single transaction.
+func (service OAuthService) RotateRefreshToken(
+	token string,
+	clientID string,
+	ttl time.Duration,
+) (*interfaces.OAuthGrant, string, error) {
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	grant := interfaces.OAuthGrant{ClientID: clientID}
+	err = tx.QueryRow(
+		ctx,
+		`UPDATE auth.oauth_refresh_token SET revoked_at = now()
+		WHERE token_hash = $1 AND client_id = $2 AND revoked_at IS NULL AND expires_at > now()
+		RETURNING user_id, scopes;`,
+		common.Tokens.Hash(token),
+		clientID,
+	).Scan(&grant.UserID, &grant.Scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", interfaces.InvalidOAuthGrantException
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	newToken, err := service.issueRefreshToken(ctx, tx, grant, ttl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", err
+	}
+
+	return &grant, newToken, nil
+}
+
+// RevokeRefreshToken revokes a refresh token of the client. Revoking a token
+// that does not exist or has already been revoked does nothing.
+func (service OAuthService) RevokeRefreshToken(token string, clientID string) error {
+	_, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.oauth_refresh_token SET revoked_at = now()
+		WHERE token_hash = $1 AND client_id = $2 AND revoked_at IS NULL;`,
+		common.Tokens.Hash(token),
+		clientID,
+	)
+	return err
+}
+
+// ======== PRIVATE METHODS ========
+
+// issueRefreshToken stores a new refresh token for the grant.
+func (service OAuthService) issueRefreshToken(
+	ctx context.Context,
+	db executor,
+	grant interfaces.OAuthGrant,
+	ttl time.Duration,
+) (string, error) {
+	token, err := common.Tokens.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(
+		ctx,
+		`INSERT INTO auth.oauth_refresh_token (token_hash, client_id, user_id, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5);`,
+		common.Tokens.Hash(token),
+		grant.ClientID,
+		grant.UserID,
+		nonNil(grant.Scopes),
+		time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// purge removes the codes and refresh tokens that can no longer be used.
+func (service OAuthService) purge(ctx context.Context) error {
+	_, err := service.db.Exec(
+		ctx,
+		`DELETE FROM auth.oauth_authorization_code WHERE expires_at < now() OR used_at IS NOT NULL;`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.db.Exec(
+		ctx,
+		`DELETE FROM auth.oauth_refresh_token WHERE expires_at < now() OR revoked_at IS NOT NULL;`,
+	)
+	return err
+}
+
+// scanOAuthClient scans a row selected with oauthClientsQuery.
+func scanOAuthClient(row pgx.Row) (*interfaces.OAuthClient, error) {
+	client := interfaces.OAuthClient{}
+	err := row.Scan(
+		&client.ID,
+		&client.Name,
+		&client.RedirectURIs,
+		&client.Scopes,
+		&client.GrantTypes,
+		&client.Confidential,
+		&client.UserID,
+		&client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// nonNil returns an empty slice instead of nil, so that arrays are never stored
+// as null.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
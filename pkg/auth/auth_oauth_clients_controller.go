@@ -0,0 +1,181 @@
+/*
+Package Name: auth
+File Name: auth_oauth_clients_controller.go
+Abstract: The controller for registering and removing the clients of the OAuth
+2.0 authorization server.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// OAuthClientsController struct
+type OAuthClientsController struct {
+	logger lib.Logger
+	oauth  interfaces.OAuthRepository
+}
+
+type CreateOAuthClientBody struct {
+	Name         string   `json:"name" form:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" form:"redirect_uris"`
+	Scopes       []string `json:"scopes" form:"scopes"`
+	GrantTypes   []string `json:"grant_types" form:"grant_types" binding:"required,min=1"`
+	Confidential bool     `json:"confidential" form:"confidential"`
+	// UserID is the user the client acts as in the client credentials grant.
+	UserID *int32 `json:"user_id" form:"user_id"`
+}
+
+// ======== METHODS ========
+
+// GetOAuthClientsController retrieves a new OAuth clients controller.
+func GetOAuthClientsController(logger lib.Logger, oauth interfaces.OAuthRepository) OAuthClientsController {
+	return OAuthClientsController{
+		logger: logger,
+		oauth:  oauth,
+	}
+}
+
+// GetAll returns every registered client.
+func (controller OAuthClientsController) GetAll(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all OAuth clients.")
+
+	clients, err := controller.oauth.GetClients()
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, clients)
+}
+
+// Create registers a new client. The secret of confidential clients is only
+// returned in this response.
+func (controller OAuthClientsController) Create(ctx *gin.Context) {
+	controller.logger.Info("[POST] Create OAuth client route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := CreateOAuthClientBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	if err := validateOAuthClient(body); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	client, secret, err := controller.oauth.CreateClient(interfaces.OAuthClient{
+		Name:         body.Name,
+		RedirectURIs: body.RedirectURIs,
+		Scopes:       body.Scopes,
+		GrantTypes:   body.GrantTypes,
+		Confidential: body.Confidential,
+		UserID:       body.UserID,
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	response := gin.H{
+		"message": "Client registered successfully.",
+		"client":  client,
+	}
+	if secret != "" {
+		response["message"] = "Client registered successfully. Store the secret now, as it will not be shown again."
+		response["client_secret"] = secret
+	}
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// Delete removes a client, revoking every consent and refresh token given to it.
+func (controller OAuthClientsController) Delete(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Deleting OAuth client with id", ctx.Param("id"))
+
+	err := controller.oauth.DeleteClient(ctx.Param("id"))
+	if errors.Is(err, interfaces.OAuthClientNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Client deleted successfully.",
+	})
+}
+
+// ======== PRIVATE METHODS ========
+
+// validateOAuthClient checks that a client can use the grant types it is
+// registered with.
+func validateOAuthClient(body CreateOAuthClientBody) error {
+	for _, grantType := range body.GrantTypes {
+		switch grantType {
+		case interfaces.GrantTypeAuthorizationCode, interfaces.GrantTypeClientCredentials, interfaces.GrantTypeRefreshToken:
+		default:
+			return fmt.Errorf("The grant type '%s' is not supported.", grantType)
+		}
+	}
+
+	if contains(body.GrantTypes, interfaces.GrantTypeAuthorizationCode) && len(body.RedirectURIs) == 0 {
+		return errors.New("Clients using the authorization code grant need at least one redirect URI.")
+	}
+
+	// Redirect URIs are compared exactly, so they must be absolute and cannot
+	// have a fragment (RFC 6749, section 3.1.2).
+	for _, redirectURI := range body.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			return fmt.Errorf("The redirect URI '%s' must be an absolute URI without a fragment.", redirectURI)
+		}
+	}
+
+	if contains(body.GrantTypes, interfaces.GrantTypeClientCredentials) && (!body.Confidential || body.UserID == nil) {
+		return errors.New("Clients using the client credentials grant must be confidential and act as a user.")
+	}
+	if !contains(body.GrantTypes, interfaces.GrantTypeClientCredentials) && body.UserID != nil {
+		return errors.New("Only clients using the client credentials grant can act as a user.")
+	}
+
+	return nil
+}
@@ -0,0 +1,625 @@
+/*
+Package Name: auth
+File Name: auth_oauth_controller.go
+Abstract: The controller of the OAuth 2.0 authorization server, which
+implements the authorization code grant with PKCE, the client credentials
+grant and the refresh token grant, along with token revocation.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// OAuthController struct
+type OAuthController struct {
+	logger       lib.Logger
+	service      interfaces.AuthService
+	usersService users.UsersRepository
+	roles        roles.RolesRepository
+	oauth        interfaces.OAuthRepository
+}
+
+// OAuthAuthorizeBody are the parameters of an authorization request (RFC 6749,
+// section 4.1.1, and RFC 7636), along with the decision of the user.
+type OAuthAuthorizeBody struct {
+	ResponseType        string `json:"response_type" form:"response_type" binding:"required"`
+	ClientID            string `json:"client_id" form:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+	// Approve is whether the user consents to the request. It is only used
+	// when the user decides.
+	Approve bool `json:"approve" form:"approve"`
+}
+
+// oauthAuthorization is an authorization request that has been validated.
+type oauthAuthorization struct {
+	client      *interfaces.OAuthClient
+	redirectURI string
+	scopes      []string
+	state       string
+	challenge   string
+}
+
+// ======== ERRORS ========
+var (
+	InvalidRedirectURIException      = errors.New("The redirect URI is not registered for the client.")
+	UnsupportedResponseTypeException = errors.New("Only the 'code' response type is supported.")
+	UnsupportedGrantTypeException    = errors.New("The grant type is not supported.")
+	UnauthorizedClientException      = errors.New("The client is not allowed to use this grant type.")
+	InvalidScopeException            = errors.New("The client cannot ask for some of the scopes requested.")
+	CodeChallengeRequiredException   = errors.New("A code challenge using the S256 method is required.")
+	InvalidOAuthRequestException     = errors.New("The request is missing a required parameter or is malformed.")
+	AccessDeniedException            = errors.New("The user denied the request.")
+)
+
+// ======== METHODS ========
+
+// GetOAuthController retrieves a new OAuth controller.
+func GetOAuthController(
+	logger lib.Logger,
+	service interfaces.AuthService,
+	usersService users.UsersRepository,
+	roles roles.RolesRepository,
+	oauth interfaces.OAuthRepository,
+) OAuthController {
+	return OAuthController{
+		logger:       logger,
+		service:      service,
+		usersService: usersService,
+		roles:        roles,
+		oauth:        oauth,
+	}
+}
+
+// Authorize validates an authorization request of the authenticated user. If the
+// user already consented to every scope requested, the request is approved right
+// away and the URL to send the user back to the client is returned. Otherwise,
+// the client and scopes are returned so that the user can be asked for consent.
+func (controller OAuthController) Authorize(ctx *gin.Context) {
+	controller.logger.Info("[GET] OAuth authorize route.")
+
+	body := OAuthAuthorizeBody{}
+	authorization, ok := controller.validateAuthorization(ctx, &body)
+	if !ok {
+		return
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	consented, err := controller.oauth.GetConsent(principal.UserID, authorization.client.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if containsAll(consented, authorization.scopes) {
+		controller.approve(ctx, principal.UserID, authorization)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"consent_required": true,
+		"client_id":        authorization.client.ID,
+		"client_name":      authorization.client.Name,
+		"scopes":           authorization.scopes,
+		"redirect_uri":     authorization.redirectURI,
+	})
+}
+
+// Decide records the decision of the authenticated user on an authorization
+// request, and returns the URL to send the user back to the client.
+func (controller OAuthController) Decide(ctx *gin.Context) {
+	controller.logger.Info("[POST] OAuth authorize route.")
+
+	body := OAuthAuthorizeBody{}
+	authorization, ok := controller.validateAuthorization(ctx, &body)
+	if !ok {
+		return
+	}
+
+	if !body.Approve {
+		ctx.JSON(http.StatusOK, gin.H{
+			"redirect_to": authorizationErrorURL(authorization, AccessDeniedException),
+		})
+		return
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	if err := controller.oauth.SaveConsent(principal.UserID, authorization.client.ID, authorization.scopes); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	controller.approve(ctx, principal.UserID, authorization)
+}
+
+// Token issues tokens to an authenticated client (RFC 6749, section 3.2). The
+// responses and errors follow the format of the RFC instead of the format used
+// by the rest of the API.
+func (controller OAuthController) Token(ctx *gin.Context) {
+	controller.logger.Info("[POST] OAuth token route.")
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	client, ok := controller.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+
+	grantType := ctx.PostForm("grant_type")
+	switch grantType {
+	case interfaces.GrantTypeAuthorizationCode, interfaces.GrantTypeClientCredentials, interfaces.GrantTypeRefreshToken:
+		if !contains(client.GrantTypes, grantType) {
+			abortWithOAuthError(ctx, http.StatusBadRequest, UnauthorizedClientException)
+			return
+		}
+	default:
+		abortWithOAuthError(ctx, http.StatusBadRequest, UnsupportedGrantTypeException)
+		return
+	}
+
+	switch grantType {
+	case interfaces.GrantTypeAuthorizationCode:
+		controller.exchangeAuthorizationCode(ctx, client)
+	case interfaces.GrantTypeClientCredentials:
+		controller.issueClientCredentials(ctx, client)
+	case interfaces.GrantTypeRefreshToken:
+		controller.refresh(ctx, client)
+	}
+}
+
+// Revoke revokes an access token or a refresh token of an authenticated client
+// (RFC 7009). Tokens that are not valid or belong to other clients are ignored,
+// so the response is always the same.
+func (controller OAuthController) Revoke(ctx *gin.Context) {
+	controller.logger.Info("[POST] OAuth revoke route.")
+
+	client, ok := controller.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+
+	token := ctx.PostForm("token")
+	if token == "" {
+		abortWithOAuthError(ctx, http.StatusBadRequest, InvalidOAuthRequestException)
+		return
+	}
+
+	if principal, err := controller.service.CheckToken(token); err == nil {
+		if principal.ClientID == client.ID {
+			if err := controller.service.RevokeToken(*principal); err != nil {
+				ctx.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+		}
+	} else if err := controller.oauth.RevokeRefreshToken(token, client.ID); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// GetConsents returns the applications the authenticated user has granted access to.
+func (controller OAuthController) GetConsents(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all OAuth consents.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	consents, err := controller.oauth.GetConsents(principal.UserID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, consents)
+}
+
+// RevokeConsent revokes the access of an application to the account of the
+// authenticated user.
+func (controller OAuthController) RevokeConsent(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Revoking OAuth consent of client", ctx.Param("client_id"))
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	err := controller.oauth.RevokeConsent(principal.UserID, ctx.Param("client_id"))
+	if errors.Is(err, interfaces.OAuthConsentNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Access revoked successfully.",
+	})
+}
+
+// ======== PRIVATE METHODS ========
+
+// validateAuthorization validates the parameters of an authorization request.
+// Errors are returned to the client through the redirect URI once it is known to
+// be registered for the client, and to the user otherwise.
+func (controller OAuthController) validateAuthorization(ctx *gin.Context, body *OAuthAuthorizeBody) (*oauthAuthorization, bool) {
+	if errors := common.Validation.ValidateBody(ctx, body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return nil, false
+	}
+
+	// ======== CHECK CLIENT ========
+	client, err := controller.oauth.GetClient(body.ClientID)
+	if errors.Is(err, interfaces.OAuthClientNotFoundException) {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return nil, false
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	// The redirect URI can only be omitted if the client has a single one.
+	redirectURI := body.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !contains(client.RedirectURIs, redirectURI) {
+		ctx.AbortWithError(http.StatusBadRequest, InvalidRedirectURIException)
+		return nil, false
+	}
+
+	authorization := &oauthAuthorization{
+		client:      client,
+		redirectURI: redirectURI,
+		scopes:      strings.Fields(body.Scope),
+		state:       body.State,
+		challenge:   body.CodeChallenge,
+	}
+	if len(authorization.scopes) == 0 {
+		authorization.scopes = client.Scopes
+	}
+
+	// ======== CHECK REQUEST ========
+	var invalid error
+	switch {
+	case body.ResponseType != "code":
+		invalid = UnsupportedResponseTypeException
+	case !contains(client.GrantTypes, interfaces.GrantTypeAuthorizationCode):
+		invalid = UnauthorizedClientException
+	case !containsAll(client.Scopes, authorization.scopes):
+		invalid = InvalidScopeException
+	case body.CodeChallenge == "" || body.CodeChallengeMethod != "S256":
+		// PKCE is required for every client, confidential or not.
+		invalid = CodeChallengeRequiredException
+	}
+	if invalid != nil {
+		ctx.AbortWithError(http.StatusBadRequest, invalid).SetMeta(gin.H{
+			"redirect_to": authorizationErrorURL(authorization, invalid),
+		})
+		return nil, false
+	}
+
+	return authorization, true
+}
+
+// approve issues an authorization code for the request, and responds with the
+// URL to send the user back to the client with it.
+func (controller OAuthController) approve(ctx *gin.Context, userID int32, authorization *oauthAuthorization) {
+	code, err := controller.oauth.IssueAuthorizationCode(interfaces.OAuthGrant{
+		ClientID:      authorization.client.ID,
+		UserID:        userID,
+		Scopes:        authorization.scopes,
+		RedirectURI:   authorization.redirectURI,
+		CodeChallenge: authorization.challenge,
+	}, common.Env.Duration("OAUTH_CODE_TTL", 5*time.Minute))
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"redirect_to": authorizationURL(authorization.redirectURI, url.Values{
+			"code":  {code},
+			"state": {authorization.state},
+		}),
+	})
+}
+
+// authenticateClient authenticates the client of a request to the token or the
+// revocation endpoints, with either HTTP basic authentication or the client_id
+// and client_secret parameters.
+func (controller OAuthController) authenticateClient(ctx *gin.Context) (*interfaces.OAuthClient, bool) {
+	id, secret, basic := ctx.Request.BasicAuth()
+	if basic {
+		// The credentials are form-encoded before being used for basic
+		// authentication (RFC 6749, section 2.3.1).
+		var errID, errSecret error
+		id, errID = url.QueryUnescape(id)
+		secret, errSecret = url.QueryUnescape(secret)
+		if errID != nil || errSecret != nil || ctx.PostForm("client_secret") != "" {
+			abortWithOAuthError(ctx, http.StatusBadRequest, InvalidOAuthRequestException)
+			return nil, false
+		}
+	} else {
+		id, secret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+
+	client, err := controller.oauth.AuthenticateClient(id, secret)
+	if errors.Is(err, interfaces.InvalidOAuthClientException) {
+		if basic {
+			ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		abortWithOAuthError(ctx, http.StatusUnauthorized, err)
+		return nil, false
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	return client, true
+}
+
+// exchangeAuthorizationCode exchanges an authorization code for an access token
+// and, if the client can refresh it, a refresh token.
+func (controller OAuthController) exchangeAuthorizationCode(ctx *gin.Context, client *interfaces.OAuthClient) {
+	grant, err := controller.oauth.ConsumeAuthorizationCode(ctx.PostForm("code"))
+	if errors.Is(err, interfaces.InvalidOAuthGrantException) {
+		abortWithOAuthError(ctx, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// The code must be redeemed by the client it was issued to, with the same
+	// redirect URI and the verifier of the challenge.
+	if grant.ClientID != client.ID ||
+		grant.RedirectURI != ctx.PostForm("redirect_uri") ||
+		common.PKCEChallenge(ctx.PostForm("code_verifier")) != grant.CodeChallenge {
+		abortWithOAuthError(ctx, http.StatusBadRequest, interfaces.InvalidOAuthGrantException)
+		return
+	}
+
+	controller.respondWithTokens(ctx, client, *grant, contains(client.GrantTypes, interfaces.GrantTypeRefreshToken))
+}
+
+// issueClientCredentials issues an access token to a client acting as the user
+// it has been registered with.
+func (controller OAuthController) issueClientCredentials(ctx *gin.Context, client *interfaces.OAuthClient) {
+	if client.UserID == nil || !client.Confidential {
+		abortWithOAuthError(ctx, http.StatusBadRequest, UnauthorizedClientException)
+		return
+	}
+
+	scopes := strings.Fields(ctx.PostForm("scope"))
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	if !containsAll(client.Scopes, scopes) {
+		abortWithOAuthError(ctx, http.StatusBadRequest, InvalidScopeException)
+		return
+	}
+
+	controller.respondWithTokens(ctx, client, interfaces.OAuthGrant{
+		ClientID: client.ID,
+		UserID:   *client.UserID,
+		Scopes:   scopes,
+	}, false)
+}
+
+// refresh exchanges a refresh token for a new access token and refresh token. The
+// scopes can be narrowed, but never widened.
+func (controller OAuthController) refresh(ctx *gin.Context, client *interfaces.OAuthClient) {
+	ttl := common.Env.Duration("OAUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	grant, refreshToken, err := controller.oauth.RotateRefreshToken(ctx.PostForm("refresh_token"), client.ID, ttl)
+	if errors.Is(err, interfaces.InvalidOAuthGrantException) {
+		abortWithOAuthError(ctx, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	scopes := grant.Scopes
+	if requested := strings.Fields(ctx.PostForm("scope")); len(requested) > 0 {
+		if !containsAll(grant.Scopes, requested) {
+			abortWithOAuthError(ctx, http.StatusBadRequest, InvalidScopeException)
+			return
+		}
+		scopes = requested
+	}
+
+	response, err := controller.accessToken(client, grant.UserID, scopes)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	response["refresh_token"] = refreshToken
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// respondWithTokens responds with an access token for the grant and, if asked
+// to, a refresh token.
+func (controller OAuthController) respondWithTokens(
+	ctx *gin.Context,
+	client *interfaces.OAuthClient,
+	grant interfaces.OAuthGrant,
+	withRefreshToken bool,
+) {
+	response, err := controller.accessToken(client, grant.UserID, grant.Scopes)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if withRefreshToken {
+		ttl := common.Env.Duration("OAUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+		refreshToken, err := controller.oauth.IssueRefreshToken(grant, ttl)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		response["refresh_token"] = refreshToken
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// accessToken creates an access token for a client acting on behalf of a user.
+// The token is granted the scopes that the user still has permission for, so
+// that clients never get more access than their users.
+func (controller OAuthController) accessToken(client *interfaces.OAuthClient, userID int32, scopes []string) (gin.H, error) {
+	user, err := controller.usersService.GetUserById(int(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	_, permissions, err := controller.roles.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := []string{}
+	if len(scopes) > 0 {
+		granted = limitScopes(permissions, scopes)
+	}
+
+	token, err := controller.service.CreateToken(interfaces.Principal{
+		UserID:        userID,
+		Scopes:        granted,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		ClientID:      client.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"access_token": *token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(accessTokenTTL().Seconds()),
+		"scope":        strings.Join(granted, " "),
+	}, nil
+}
+
+// abortWithOAuthError aborts a request to the token or revocation endpoints with
+// an error response as defined in RFC 6749, section 5.2.
+func abortWithOAuthError(ctx *gin.Context, status int, err error) {
+	ctx.AbortWithStatusJSON(status, gin.H{
+		"error":             oauthErrorCode(err),
+		"error_description": err.Error(),
+	})
+}
+
+// oauthErrorCode returns the error code defined in RFC 6749 for an error.
+func oauthErrorCode(err error) string {
+	switch {
+	case errors.Is(err, interfaces.InvalidOAuthClientException):
+		return "invalid_client"
+	case errors.Is(err, interfaces.InvalidOAuthGrantException):
+		return "invalid_grant"
+	case errors.Is(err, UnauthorizedClientException):
+		return "unauthorized_client"
+	case errors.Is(err, UnsupportedGrantTypeException):
+		return "unsupported_grant_type"
+	case errors.Is(err, UnsupportedResponseTypeException):
+		return "unsupported_response_type"
+	case errors.Is(err, InvalidScopeException):
+		return "invalid_scope"
+	case errors.Is(err, AccessDeniedException):
+		return "access_denied"
+	default:
+		return "invalid_request"
+	}
+}
+
+// authorizationErrorURL returns the URL to send the user back to the client with
+// the error of an authorization request (RFC 6749, section 4.1.2.1).
+func authorizationErrorURL(authorization *oauthAuthorization, err error) string {
+	return authorizationURL(authorization.redirectURI, url.Values{
+		"error":             {oauthErrorCode(err)},
+		"error_description": {err.Error()},
+		"state":             {authorization.state},
+	})
+}
+
+// authorizationURL adds parameters to the query of a redirect URI. Empty values
+// are left out.
+func authorizationURL(redirectURI string, values url.Values) string {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := parsed.Query()
+	for key, value := range values {
+		if len(value) > 0 && value[0] != "" {
+			query.Set(key, value[0])
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// contains returns whether a value is in a slice.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAll returns whether every one of the subset is in the values.
+func containsAll(values []string, subset []string) bool {
+	for _, value := range subset {
+		if !contains(values, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errorsMiddleware.Setup()
+
+	// The real service is used so that the tokens issued to clients are checked
+	// by the auth middleware like any other token.
+	logger := &mocks.MockLogger{}
+	authService := newTestAuthService(t)
+	oauth := &mocks.MockOAuthService{}
+	roles := &mocks.MockRolesService{Permissions: []string{"users:read"}}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+	oauthController := GetOAuthController(logger, authService, &mocks.MockUsersService{}, roles, oauth)
+
+	router.POST("/oauth/token", oauthController.Token)
+	router.POST("/oauth/revoke", oauthController.Revoke)
+	account := router.Group("/").Use(authMiddleware.Handler(middlewares.RejectOAuthClients()))
+	account.GET("/oauth/authorize", oauthController.Authorize)
+	account.POST("/oauth/authorize", oauthController.Decide)
+	account.DELETE("/oauth/consents/:client_id", oauthController.RevokeConsent)
+
+	app, _, _ := oauth.CreateClient(interfaces.OAuthClient{
+		Name:         "App",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"users:read", "users:write"},
+		GrantTypes:   []string{interfaces.GrantTypeAuthorizationCode, interfaces.GrantTypeRefreshToken},
+	})
+	userID := int32(1)
+	worker, secret, _ := oauth.CreateClient(interfaces.OAuthClient{
+		Name:         "Worker",
+		Scopes:       []string{"users:read"},
+		GrantTypes:   []string{interfaces.GrantTypeClientCredentials},
+		Confidential: true,
+		UserID:       &userID,
+	})
+
+	userToken, err := authService.CreateToken(interfaces.Principal{UserID: 1, AuthMethod: interfaces.AuthMethodPassword})
+	require.NoError(t, err)
+
+	verifier := "verifier-of-at-least-forty-three-characters-long"
+	authorizeQuery := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {app.ID},
+		"scope":                 {"users:read users:write"},
+		"state":                 {"xyz"},
+		"code_challenge":        {common.PKCEChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	// authorize performs an authorization request as the user. The decision
+	// of the user is sent along the parameters of the request.
+	authorize := func(method string, query url.Values, approve bool) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, "/oauth/authorize?"+query.Encode(), nil)
+		if method == "POST" {
+			body := OAuthAuthorizeBody{
+				ResponseType:        query.Get("response_type"),
+				ClientID:            query.Get("client_id"),
+				RedirectURI:         query.Get("redirect_uri"),
+				Scope:               query.Get("scope"),
+				State:               query.Get("state"),
+				CodeChallenge:       query.Get("code_challenge"),
+				CodeChallengeMethod: query.Get("code_challenge_method"),
+				Approve:             approve,
+			}
+			jsonBody, _ := json.Marshal(body)
+			req, _ = http.NewRequest(method, "/oauth/authorize", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Authorization", "Bearer "+*userToken)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// token performs a request to the token endpoint.
+	token := func(form url.Values, username string, password string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// redirectParams returns the query of the URL the user is sent back to.
+	redirectParams := func(t *testing.T, response map[string]interface{}) url.Values {
+		redirect, ok := response["redirect_to"].(string)
+		require.True(t, ok, response)
+		parsed, err := url.Parse(redirect)
+		require.NoError(t, err)
+		assert.Equal(t, "app.example.com", parsed.Host)
+		return parsed.Query()
+	}
+
+	var refreshToken string
+
+	t.Run("AuthorizationCode", func(t *testing.T) {
+		// Test case 1: The user is asked for consent
+		code, response := authorize("GET", authorizeQuery, false)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, true, response["consent_required"])
+		assert.Equal(t, "App", response["client_name"])
+
+		// Test case 2: Once approved, the user is sent back with a code
+		code, response = authorize("POST", authorizeQuery, true)
+		require.Equal(t, http.StatusOK, code)
+		params := redirectParams(t, response)
+		assert.Equal(t, "xyz", params.Get("state"))
+
+		// Test case 3: The code is exchanged for tokens scoped to the
+		// permissions the user actually has
+		code, response = token(url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {app.ID},
+			"code":          {params.Get("code")},
+			"redirect_uri":  {"https://app.example.com/callback"},
+			"code_verifier": {verifier},
+		}, "", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "Bearer", response["token_type"])
+		assert.Equal(t, "users:read", response["scope"])
+		refreshToken = response["refresh_token"].(string)
+
+		principal, err := authService.CheckToken(response["access_token"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, app.ID, principal.ClientID)
+		assert.Equal(t, []string{"users:read"}, principal.Scopes)
+
+		// Test case 4: The token cannot be used for authorizing other clients
+		req, _ := http.NewRequest("GET", "/oauth/authorize?"+authorizeQuery.Encode(), nil)
+		req.Header.Set("Authorization", "Bearer "+response["access_token"].(string))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// Test case 5: The code cannot be used twice
+		code, response = token(url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {app.ID},
+			"code":          {params.Get("code")},
+			"redirect_uri":  {"https://app.example.com/callback"},
+			"code_verifier": {verifier},
+		}, "", "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_grant", response["error"])
+
+		// Test case 6: Once consented, the user is not asked again
+		code, response = authorize("GET", authorizeQuery, false)
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEmpty(t, redirectParams(t, response).Get("code"))
+	})
+
+	t.Run("WrongVerifier", func(t *testing.T) {
+		_, response := authorize("GET", authorizeQuery, false)
+		code, response := token(url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {app.ID},
+			"code":          {redirectParams(t, response).Get("code")},
+			"redirect_uri":  {"https://app.example.com/callback"},
+			"code_verifier": {"another-verifier"},
+		}, "", "")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_grant", response["error"])
+	})
+
+	t.Run("InvalidRequests", func(t *testing.T) {
+		// Test case 1: Errors are sent back to registered redirect URIs
+		query := url.Values{}
+		for key, value := range authorizeQuery {
+			query[key] = value
+		}
+		query.Del("code_challenge")
+		code, response := authorize("GET", query, false)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_request", redirectParams(t, response).Get("error"))
+
+		query.Set("code_challenge", common.PKCEChallenge(verifier))
+		query.Set("scope", "roles:write")
+		code, response = authorize("GET", query, false)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_scope", redirectParams(t, response).Get("error"))
+
+		// Test case 2: But never to unregistered ones
+		query.Set("redirect_uri", "https://evil.example.com/callback")
+		code, response = authorize("GET", query, false)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Nil(t, response["redirect_to"])
+	})
+
+	t.Run("AccessDenied", func(t *testing.T) {
+		code, response := authorize("POST", authorizeQuery, false)
+
+		assert.Equal(t, http.StatusOK, code)
+		params := redirectParams(t, response)
+		assert.Equal(t, "access_denied", params.Get("error"))
+		assert.Empty(t, params.Get("code"))
+	})
+
+	t.Run("RefreshToken", func(t *testing.T) {
+		require.NotEmpty(t, refreshToken)
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {app.ID},
+			"refresh_token": {refreshToken},
+		}
+
+		// Test case 1: The refresh token is rotated
+		code, response := token(form, "", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEmpty(t, response["access_token"])
+		assert.NotEqual(t, refreshToken, response["refresh_token"])
+
+		// Test case 2: And cannot be used again
+		code, response = token(form, "", "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_grant", response["error"])
+	})
+
+	t.Run("ClientCredentials", func(t *testing.T) {
+		form := url.Values{"grant_type": {"client_credentials"}}
+
+		// Test case 1: The client must authenticate
+		code, response := token(form, worker.ID, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Equal(t, "invalid_client", response["error"])
+
+		// Test case 2: The client gets a token acting as its user
+		code, response = token(form, worker.ID, secret)
+		require.Equal(t, http.StatusOK, code)
+		assert.Nil(t, response["refresh_token"])
+
+		principal, err := authService.CheckToken(response["access_token"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), principal.UserID)
+		assert.Equal(t, worker.ID, principal.ClientID)
+
+		// Test case 3: Clients cannot use grant types they are not registered for
+		code, response = token(url.Values{"grant_type": {"client_credentials"}, "client_id": {app.ID}}, "", "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "unauthorized_client", response["error"])
+	})
+
+	t.Run("RevokeConsent", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/oauth/consents/"+app.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+*userToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The user is asked for consent again
+		code, response := authorize("GET", authorizeQuery, false)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, true, response["consent_required"])
+	})
+}
@@ -0,0 +1,345 @@
+/*
+Package Name: auth
+File Name: auth_oidc_controller.go
+Abstract: The controller for logging in with an external OpenID Connect
+provider, which creates the accounts of new users just in time.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// OIDCController struct
+type OIDCController struct {
+	logger       lib.Logger
+	service      interfaces.AuthService
+	usersService users.UsersRepository
+	identities   interfaces.IdentitiesRepository
+	mfa          interfaces.MFARepository
+	sessions     interfaces.SessionsRepository
+	client       *common.OIDCClient
+}
+
+type OIDCCallbackBody struct {
+	Code  string `json:"code" form:"code" binding:"required"`
+	State string `json:"state" form:"state" binding:"required"`
+	LoginOptions
+}
+
+// ======== ERRORS ========
+var (
+	OIDCNotConfiguredException  = errors.New("Logging in with an identity provider is not enabled.")
+	OIDCEmailRequiredException  = errors.New("The identity provider did not share the email of the user.")
+	OIDCAccountExistsException  = errors.New("An account with the same email already exists. Log in with your password and verify your email first.")
+	OIDCSignupDisabledException = errors.New("There is no account linked to this identity.")
+)
+
+// ======== CONSTANTS ========
+
+// usernameInvalidCharacters matches the characters that are removed from the
+// usernames suggested by the identity provider. Usernames can only have
+// letters, as when signing up or changing them.
+var usernameInvalidCharacters = regexp.MustCompile(`[^a-zA-Z]+`)
+
+// ======== METHODS ========
+
+// GetOIDCController retrieves a new OIDC controller. The client is nil if no
+// identity provider has been configured.
+func GetOIDCController(
+	logger lib.Logger,
+	service interfaces.AuthService,
+	usersService users.UsersRepository,
+	identities interfaces.IdentitiesRepository,
+	mfa interfaces.MFARepository,
+	sessions interfaces.SessionsRepository,
+	client *common.OIDCClient,
+) OIDCController {
+	return OIDCController{
+		logger:       logger,
+		service:      service,
+		usersService: usersService,
+		identities:   identities,
+		mfa:          mfa,
+		sessions:     sessions,
+		client:       client,
+	}
+}
+
+// Authorize starts a login with the identity provider, and returns the URL the
+// user has to be sent to along with the state the provider will send back. The
+// client must check that the state it receives is the one returned here before
+// sending the code to the callback.
+func (controller OIDCController) Authorize(ctx *gin.Context) {
+	controller.logger.Info("[GET] OIDC authorize route.")
+
+	if controller.client == nil {
+		ctx.AbortWithError(http.StatusNotFound, OIDCNotConfiguredException)
+		return
+	}
+
+	// The state identifies the login, the nonce binds the ID token to it and
+	// the verifier proves that whoever redeems the code started the login.
+	values := make([]string, 3)
+	for i := range values {
+		value, err := common.Tokens.Generate()
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	ttl := common.Env.Duration("OIDC_AUTHORIZATION_TTL", 10*time.Minute)
+	err := controller.identities.SaveAuthorization(state, interfaces.OIDCAuthorization{
+		Nonce:    nonce,
+		Verifier: verifier,
+	}, ttl)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	url, err := controller.client.AuthorizationURL(ctx.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		controller.logger.Error("Could not reach the identity provider:", err)
+		ctx.AbortWithError(http.StatusBadGateway, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"authorization_url": url,
+		"state":             state,
+		"expires_in":        int64(ttl.Seconds()),
+	})
+}
+
+// Callback finishes a login with the identity provider. The code is exchanged
+// for an ID token, and the user it identifies is logged in, linking their
+// identity to an existing account or creating a new one if needed.
+func (controller OIDCController) Callback(ctx *gin.Context) {
+	controller.logger.Info("[POST] OIDC callback route.")
+
+	if controller.client == nil {
+		ctx.AbortWithError(http.StatusNotFound, OIDCNotConfiguredException)
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := OIDCCallbackBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	authorization, err := controller.identities.ConsumeAuthorization(body.State)
+	if errors.Is(err, interfaces.InvalidOIDCStateException) {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// ======== VERIFY IDENTITY ========
+	idToken, err := controller.client.Exchange(ctx.Request.Context(), body.Code, authorization.Verifier)
+	if err != nil {
+		controller.logger.Info("Could not exchange the authorization code:", err)
+		ctx.AbortWithError(http.StatusUnauthorized, common.OIDCExchangeException)
+		return
+	}
+
+	claims, err := controller.client.VerifyIDToken(ctx.Request.Context(), idToken, authorization.Nonce)
+	if err != nil {
+		controller.logger.Info("Rejected an ID token:", err)
+		ctx.AbortWithError(http.StatusUnauthorized, common.InvalidIDTokenException)
+		return
+	}
+
+	// ======== FIND USER ========
+	userID, err := controller.identities.GetIdentityUser(controller.client.Issuer(), claims.Subject)
+	if errors.Is(err, interfaces.IdentityNotFoundException) {
+		userID, err = controller.linkUser(ctx, claims)
+		if err != nil {
+			return
+		}
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// ======== CHECK MFA ========
+	enabled, err := controller.mfa.IsEnabled(userID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if enabled {
+		respondWithMFAChallenge(ctx, controller.service, userID)
+		return
+	}
+
+	respondWithLogin(
+		ctx,
+		controller.service,
+		controller.sessions,
+		userID,
+		interfaces.AuthMethodOIDC,
+		body.LoginOptions,
+		"Logged in successfully.",
+	)
+}
+
+// ======== PRIVATE METHODS ========
+
+// linkUser links an identity that is not linked yet to the account with the
+// same email, or to a new account if there is none and OIDC_ALLOW_SIGNUP is
+// not false. The request is aborted if it returns an error.
+func (controller OIDCController) linkUser(ctx *gin.Context, claims *common.OIDCClaims) (int32, error) {
+	if claims.Email == "" {
+		ctx.AbortWithError(http.StatusBadRequest, OIDCEmailRequiredException)
+		return 0, OIDCEmailRequiredException
+	}
+
+	// Only an account that does not exist can be signed up, as a lookup that
+	// failed says nothing about whether the email is taken.
+	var userID int32
+	user, err := controller.usersService.GetUserByEmail(claims.Email)
+	if err != nil && !errors.Is(err, users.UserNotFoundException) {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return 0, err
+	}
+
+	if err == nil {
+		// Both the provider and the user must have verified the email,
+		// otherwise whoever registered it first could take over the account.
+		if !claims.EmailVerified || user.EmailVerifiedAt == nil {
+			ctx.AbortWithError(http.StatusConflict, OIDCAccountExistsException)
+			return 0, OIDCAccountExistsException
+		}
+		userID = user.ID
+	} else {
+		if !common.Env.Bool("OIDC_ALLOW_SIGNUP", true) {
+			ctx.AbortWithError(http.StatusForbidden, OIDCSignupDisabledException)
+			return 0, OIDCSignupDisabledException
+		}
+
+		id, err := controller.createUser(claims)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err)
+			return 0, err
+		}
+		userID = id
+	}
+
+	if err := controller.identities.LinkIdentity(userID, controller.client.Issuer(), claims.Subject, claims.Email); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// createUser creates the account of a user that logs in with the identity
+// provider for the first time. Their password is random, so it can only be
+// used after resetting it.
+func (controller OIDCController) createUser(claims *common.OIDCClaims) (int32, error) {
+	password, err := common.Tokens.Generate()
+	if err != nil {
+		return 0, err
+	}
+
+	username := suggestUsername(claims)
+	id, err := controller.usersService.CreateUser(claims.Email, username, password)
+	if errors.Is(err, users.UsernameTakenException) {
+		// The username is taken, so try once more with a random suffix.
+		suffix, suffixErr := randomLetters(6)
+		if suffixErr != nil {
+			return 0, suffixErr
+		}
+		id, err = controller.usersService.CreateUser(claims.Email, username+suffix, password)
+	}
+	if err != nil {
+		return 0, err
+	}
+	controller.logger.Info("Created user with id", *id, "for the identity", claims.Subject)
+
+	if claims.EmailVerified {
+		if err := controller.usersService.MarkEmailVerified(*id); err != nil {
+			return 0, err
+		}
+	}
+
+	return *id, nil
+}
+
+// suggestUsername returns a username for a new user from the claims of their
+// ID token.
+func suggestUsername(claims *common.OIDCClaims) string {
+	username := claims.PreferredUsername
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	username = usernameInvalidCharacters.ReplaceAllString(username, "")
+	if len(username) > 90 {
+		username = username[:90]
+	}
+	if username == "" {
+		username = "user"
+	}
+	return username
+}
+
+// randomLetters returns a string of random lowercase letters, for making the
+// usernames suggested by the identity provider unique.
+func randomLetters(length int) (string, error) {
+	letters := make([]byte, length)
+	if _, err := rand.Read(letters); err != nil {
+		return "", err
+	}
+	for i := range letters {
+		letters[i] = 'a' + letters[i]%26
+	}
+	return string(letters), nil
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	provider := mocks.NewMockOIDCProvider()
+	defer provider.Close()
+
+	// setup returns a router with the OIDC routes, using the mocks given.
+	setup := func(usersService *mocks.MockUsersService, identities *mocks.MockIdentitiesService, mfa *mocks.MockMFAService) *gin.Engine {
+		router := gin.New()
+		errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+		errorsMiddleware.Setup()
+
+		client := common.NewOIDCClient(provider.Config(), nil)
+		oidcController := GetOIDCController(&mocks.MockLogger{}, &mocks.MockAuthService{}, usersService, identities, mfa, &mocks.MockSessionsService{}, client)
+		router.GET("/oidc/authorize", oidcController.Authorize)
+		router.POST("/oidc/callback", oidcController.Callback)
+		return router
+	}
+
+	// login starts a login, logs the user in with the provider and sends the
+	// code to the callback.
+	login := func(router *gin.Engine) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/oidc/authorize", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var authorization map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &authorization)
+
+		code, state, err := provider.Authorize(authorization["authorization_url"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, authorization["state"], state)
+
+		jsonBody, _ := json.Marshal(OIDCCallbackBody{Code: code, State: state})
+		req, _ = http.NewRequest("POST", "/oidc/callback", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	t.Run("Signup", func(t *testing.T) {
+		usersService := &mocks.MockUsersService{}
+		identities := &mocks.MockIdentitiesService{}
+		router := setup(usersService, identities, &mocks.MockMFAService{})
+
+		code, response := login(router)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "mock_jwt_token", response["token"])
+		assert.Equal(t, []string{"oidc"}, usersService.CreatedUsernames)
+		assert.Equal(t, []int32{1}, usersService.VerifiedUsers)
+		assert.Equal(t, int32(1), identities.Identities["mock_subject"])
+	})
+
+	t.Run("SignupWithTakenUsername", func(t *testing.T) {
+		// Test case 1: A random suffix is added to a username that is taken
+		usersService := &mocks.MockUsersService{TakenUsernames: []string{"oidc"}}
+		code, _ := login(setup(usersService, &mocks.MockIdentitiesService{}, &mocks.MockMFAService{}))
+
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, usersService.CreatedUsernames, 2)
+		assert.Regexp(t, `^oidc[a-z]{6}$`, usersService.CreatedUsernames[1])
+
+		// Test case 2: But other errors are not retried
+		usersService = &mocks.MockUsersService{CreateUserError: errors.New("User with email oidc@example.com already exists.")}
+		identities := &mocks.MockIdentitiesService{}
+		code, _ = login(setup(usersService, identities, &mocks.MockMFAService{}))
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, []string{"oidc"}, usersService.CreatedUsernames)
+		assert.Empty(t, identities.Identities)
+	})
+
+	t.Run("LookupFailed", func(t *testing.T) {
+		// An account is only created if there is none with the email, not
+		// whenever looking it up fails.
+		usersService := &mocks.MockUsersService{LookupError: errors.New("connection refused")}
+		identities := &mocks.MockIdentitiesService{}
+		code, _ := login(setup(usersService, identities, &mocks.MockMFAService{}))
+
+		assert.Equal(t, http.StatusInternalServerError, code)
+		assert.Empty(t, usersService.CreatedUsernames)
+		assert.Empty(t, identities.Identities)
+	})
+
+	t.Run("LinkedIdentity", func(t *testing.T) {
+		usersService := &mocks.MockUsersService{}
+		identities := &mocks.MockIdentitiesService{Identities: map[string]int32{"mock_subject": 1}}
+		router := setup(usersService, identities, &mocks.MockMFAService{})
+
+		code, _ := login(router)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, usersService.CreatedUsernames)
+	})
+
+	t.Run("ExistingAccount", func(t *testing.T) {
+		provider.User.Email = "user@example.com"
+		defer func() { provider.User.Email = "oidc@example.com" }()
+
+		// Test case 1: The identity is linked to the account with the same email
+		usersService := &mocks.MockUsersService{EmailVerified: true}
+		identities := &mocks.MockIdentitiesService{}
+		code, _ := login(setup(usersService, identities, &mocks.MockMFAService{}))
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, usersService.CreatedUsernames)
+		assert.Equal(t, int32(1), identities.Identities["mock_subject"])
+
+		// Test case 2: Unless the account has not verified its email
+		identities = &mocks.MockIdentitiesService{}
+		code, response := login(setup(&mocks.MockUsersService{}, identities, &mocks.MockMFAService{}))
+
+		assert.Equal(t, http.StatusConflict, code)
+		assert.Equal(t, OIDCAccountExistsException.Error(), response["error"])
+		assert.Empty(t, identities.Identities)
+	})
+
+	t.Run("MFARequired", func(t *testing.T) {
+		identities := &mocks.MockIdentitiesService{Identities: map[string]int32{"mock_subject": 1}}
+		mfa := &mocks.MockMFAService{Enabled: map[int32]bool{1: true}}
+
+		code, response := login(setup(&mocks.MockUsersService{}, identities, mfa))
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, true, response["mfa_required"])
+		assert.Equal(t, "mock_mfa_challenge_token", response["challenge_token"])
+		assert.NotContains(t, response, "token")
+	})
+
+	t.Run("InvalidState", func(t *testing.T) {
+		router := setup(&mocks.MockUsersService{}, &mocks.MockIdentitiesService{}, &mocks.MockMFAService{})
+
+		jsonBody, _ := json.Marshal(OIDCCallbackBody{Code: "code", State: "unknown"})
+		req, _ := http.NewRequest("POST", "/oidc/callback", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		router := gin.New()
+		errorsMiddleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+		errorsMiddleware.Setup()
+
+		oidcController := GetOIDCController(&mocks.MockLogger{}, &mocks.MockAuthService{}, &mocks.MockUsersService{}, &mocks.MockIdentitiesService{}, &mocks.MockMFAService{}, &mocks.MockSessionsService{}, nil)
+		router.GET("/oidc/authorize", oidcController.Authorize)
+
+		req, _ := http.NewRequest("GET", "/oidc/authorize", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestSuggestUsername(t *testing.T) {
+	assert.Equal(t, "jdoe", suggestUsername(&common.OIDCClaims{PreferredUsername: "jdoe", Email: "john@example.com"}))
+	assert.Equal(t, "johndoe", suggestUsername(&common.OIDCClaims{Email: "john.doe@example.com"}))
+	assert.Equal(t, "JohnDoe", suggestUsername(&common.OIDCClaims{PreferredUsername: "John Doe!"}))
+	assert.Equal(t, "user", suggestUsername(&common.OIDCClaims{PreferredUsername: "@@@"}))
+
+	// Usernames only have letters, so that users can send them back when
+	// changing their account.
+	assert.Equal(t, "jdoe", suggestUsername(&common.OIDCClaims{PreferredUsername: "j-doe_42"}))
+
+	suffix, err := randomLetters(6)
+	require.NoError(t, err)
+	assert.Regexp(t, `^[a-z]{6}$`, suffix)
+}
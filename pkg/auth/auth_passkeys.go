@@ -0,0 +1,259 @@
+/*
+Package Name: auth
+File Name: auth_passkeys.go
+Abstract: The service that stores the passkeys of the users and the challenges of the
+WebAuthn ceremonies in progress.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ======== TYPES ========
+
+// PasskeysService service layer
+type PasskeysService struct {
+	logger lib.Logger
+	db     *lib.Database
+}
+
+// ======== CONSTANTS ========
+
+// passkeysQuery selects the fields of the passkeys.
+const passkeysQuery = `
+	SELECT id, user_id, name, credential_id, public_key, sign_count, backed_up, created_at, last_used_at
+	FROM auth.passkey`
+
+// ======== METHODS ========
+
+// GetPasskeysService returns the passkeys service, and schedules the removal of
+// the ceremonies that were never completed.
+func GetPasskeysService(
+	logger lib.Logger,
+	db *lib.Database,
+	scheduler *lib.Scheduler,
+) interfaces.PasskeysRepository {
+	service := PasskeysService{
+		logger: logger,
+		db:     db,
+	}
+
+	scheduler.Every(
+		"purge passkey ceremonies",
+		common.Env.Duration("PASSKEY_CEREMONIES_PURGE_INTERVAL", time.Hour),
+		service.purge,
+	)
+
+	return service
+}
+
+// BeginCeremony returns the challenge of a new ceremony. Only its hash is
+// stored, which is how the ceremony is found when the response arrives.
+func (service PasskeysService) BeginCeremony(userID *int32, purpose string, ttl time.Duration) ([]byte, error) {
+	challenge, err := common.WebAuthn.GenerateChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = service.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.passkey_ceremony (challenge_hash, user_id, purpose, expires_at)
+		VALUES ($1, $2, $3, $4);`,
+		common.Tokens.Hash(string(challenge)),
+		userID,
+		purpose,
+		time.Now().Add(ttl),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// ConsumeCeremony ends the ceremony of a challenge and returns the user it was
+// started for. The ceremony is found and removed in a single statement, so its
+// challenge cannot be used twice even by concurrent requests.
+func (service PasskeysService) ConsumeCeremony(challenge []byte, purpose string) (*int32, error) {
+	var userID *int32
+	err := service.db.QueryRow(
+		context.Background(),
+		`DELETE FROM auth.passkey_ceremony
+		WHERE challenge_hash = $1 AND purpose = $2 AND expires_at > now()
+		RETURNING user_id;`,
+		common.Tokens.Hash(string(challenge)),
+		purpose,
+	).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.InvalidPasskeyCeremonyException
+	} else if err != nil {
+		return nil, err
+	}
+
+	return userID, nil
+}
+
+// CreatePasskey stores a credential registered by the user.
+func (service PasskeysService) CreatePasskey(
+	userID int32,
+	name string,
+	credential common.WebAuthnCredential,
+) (*interfaces.Passkey, error) {
+	row := service.db.QueryRow(
+		context.Background(),
+		`INSERT INTO auth.passkey (user_id, name, credential_id, public_key, sign_count, backup_eligible, backed_up)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, user_id, name, credential_id, public_key, sign_count, backed_up, created_at, last_used_at;`,
+		userID,
+		name,
+		credential.ID,
+		credential.PublicKey,
+		int64(credential.SignCount),
+		credential.BackupEligible,
+		credential.BackedUp,
+	)
+	passkey, err := scanPasskey(row)
+	if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "23505" {
+		// A unique violation means the credential is already registered.
+		return nil, interfaces.PasskeyAlreadyRegisteredException
+	} else if err != nil {
+		return nil, err
+	}
+
+	service.logger.Info("Registered passkey", passkey.ID, "for user with id", userID)
+	return passkey, nil
+}
+
+// GetPasskeys returns the passkeys of the user, the most recent first.
+func (service PasskeysService) GetPasskeys(userID int32) ([]interfaces.Passkey, error) {
+	rows, err := service.db.Query(
+		context.Background(),
+		passkeysQuery+` WHERE user_id = $1 ORDER BY created_at DESC;`,
+		userID,
+	)
+	if err != nil {
+		service.logger.Error("Error while executing query. Err:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []interfaces.Passkey{}
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			service.logger.Error("Error while iterating dataset. Err:", err)
+			return nil, err
+		}
+		results = append(results, *passkey)
+	}
+
+	return results, rows.Err()
+}
+
+// GetPasskeyByCredentialID returns the passkey of a credential.
+func (service PasskeysService) GetPasskeyByCredentialID(credentialID []byte) (*interfaces.Passkey, error) {
+	passkey, err := scanPasskey(service.db.QueryRow(
+		context.Background(),
+		passkeysQuery+` WHERE credential_id = $1;`,
+		credentialID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.PasskeyNotFoundException
+	}
+	return passkey, err
+}
+
+// RecordUse stores the signature counter of a passkey and when it was used.
+func (service PasskeysService) RecordUse(id int32, signCount uint32) error {
+	_, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.passkey SET sign_count = $2, last_used_at = now() WHERE id = $1;`,
+		id,
+		int64(signCount),
+	)
+	return err
+}
+
+// DeletePasskey deletes a passkey of the user.
+func (service PasskeysService) DeletePasskey(userID int32, id int32) error {
+	tag, err := service.db.Exec(
+		context.Background(),
+		`DELETE FROM auth.passkey WHERE id = $1 AND user_id = $2;`,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return interfaces.PasskeyNotFoundException
+	}
+
+	service.logger.Info("Deleted passkey", id, "of user with id", userID)
+	return nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// purge removes the ceremonies that have expired.
+func (service PasskeysService) purge(ctx context.Context) error {
+	_, err := service.db.Exec(ctx, `DELETE FROM auth.passkey_ceremony WHERE expires_at < now();`)
+	return err
+}
+
+// scanPasskey scans a row selected with the fields of passkeysQuery.
+func scanPasskey(row pgx.Row) (*interfaces.Passkey, error) {
+	passkey := interfaces.Passkey{}
+	var signCount int64
+	err := row.Scan(
+		&passkey.ID,
+		&passkey.UserID,
+		&passkey.Name,
+		&passkey.CredentialID,
+		&passkey.PublicKey,
+		&signCount,
+		&passkey.BackedUp,
+		&passkey.CreatedAt,
+		&passkey.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	passkey.SignCount = uint32(signCount)
+	return &passkey, nil
+}
@@ -0,0 +1,630 @@
+/*
+Package Name: auth
+File Name: auth_passkeys_controller.go
+Abstract: The controller that lets users register passkeys and use them for logging
+in, either instead of a password or as a second factor.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// PasskeysController struct
+type PasskeysController struct {
+	logger       lib.Logger
+	service      interfaces.AuthService
+	usersService users.UsersRepository
+	passkeys     interfaces.PasskeysRepository
+	sessions     interfaces.SessionsRepository
+}
+
+// PasskeyCredential is the JSON serialization of the response of an
+// authenticator, as returned by PublicKeyCredential.toJSON() in browsers.
+// Binary data is encoded with base64url.
+type PasskeyCredential struct {
+	ID       string                    `json:"id" binding:"required"`
+	Type     string                    `json:"type" binding:"required,eq=public-key"`
+	Response PasskeyCredentialResponse `json:"response"`
+}
+
+type PasskeyCredentialResponse struct {
+	ClientDataJSON string `json:"clientDataJSON" binding:"required"`
+	// Registration
+	AttestationObject string `json:"attestationObject"`
+	// Authentication
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle"`
+}
+
+type RegisterPasskeyBody struct {
+	// Name names the passkey in the list of passkeys of the user. If it is
+	// not given, it is guessed from the user agent.
+	Name       string            `json:"name" binding:"max=100"`
+	Credential PasskeyCredential `json:"credential"`
+}
+
+type PasskeyLoginBody struct {
+	Credential PasskeyCredential `json:"credential"`
+	LoginOptions
+}
+
+type PasskeyMFAOptionsBody struct {
+	ChallengeToken string `json:"challenge_token" form:"challenge_token" binding:"required"`
+}
+
+type PasskeyMFALoginBody struct {
+	ChallengeToken string            `json:"challenge_token" binding:"required"`
+	Credential     PasskeyCredential `json:"credential"`
+	LoginOptions
+}
+
+// ======== ERRORS ========
+var (
+	PasskeysNotConfiguredException = errors.New("Logging in with passkeys is not enabled.")
+	NoPasskeysException            = errors.New("You have not registered any passkey.")
+)
+
+// ======== METHODS ========
+
+// GetPasskeysController retrieves a new passkeys controller.
+func GetPasskeysController(
+	logger lib.Logger,
+	service interfaces.AuthService,
+	usersService users.UsersRepository,
+	passkeys interfaces.PasskeysRepository,
+	sessions interfaces.SessionsRepository,
+) PasskeysController {
+	return PasskeysController{
+		logger:       logger,
+		service:      service,
+		usersService: usersService,
+		passkeys:     passkeys,
+		sessions:     sessions,
+	}
+}
+
+// BeginRegistration starts the registration of a passkey for the authenticated
+// user, and returns the options to pass to navigator.credentials.create().
+func (controller PasskeysController) BeginRegistration(ctx *gin.Context) {
+	controller.logger.Info("[POST] Passkey registration options route.")
+
+	rp, ok := abortIfPasskeysNotConfigured(ctx)
+	if !ok {
+		return
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	user, err := controller.usersService.GetUserById(int(principal.UserID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// The passkeys the user already has are excluded, so that the same
+	// authenticator is not registered twice.
+	passkeys, err := controller.passkeys.GetPasskeys(user.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	challenge, err := controller.passkeys.BeginCeremony(&user.ID, interfaces.PasskeyCeremonyRegistration, passkeyCeremonyTTL())
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	parameters := []gin.H{}
+	for _, algorithm := range common.WebAuthnAlgorithms {
+		parameters = append(parameters, gin.H{"type": "public-key", "alg": algorithm})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"publicKey": gin.H{
+			"challenge": encodeBase64URL(challenge),
+			"rp":        gin.H{"id": rp.ID, "name": rp.Name},
+			"user": gin.H{
+				"id":          encodeBase64URL(passkeyUserHandle(user.ID)),
+				"name":        user.Email,
+				"displayName": user.Username,
+			},
+			"pubKeyCredParams":   parameters,
+			"timeout":            passkeyCeremonyTTL().Milliseconds(),
+			"excludeCredentials": passkeyDescriptors(passkeys),
+			// Passkeys are discoverable credentials, so that users can log
+			// in without typing their email.
+			"authenticatorSelection": gin.H{
+				"residentKey":        "required",
+				"requireResidentKey": true,
+				"userVerification":   "required",
+			},
+			"attestation": "none",
+		},
+	})
+}
+
+// FinishRegistration verifies the response of the authenticator to the
+// registration started with BeginRegistration, and stores the passkey.
+func (controller PasskeysController) FinishRegistration(ctx *gin.Context) {
+	controller.logger.Info("[POST] Register passkey route.")
+
+	rp, ok := abortIfPasskeysNotConfigured(ctx)
+	if !ok {
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := RegisterPasskeyBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	clientDataJSON, attestationObject, err := decodeCredential(body.Credential.Response.ClientDataJSON, body.Credential.Response.AttestationObject)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// ======== CHECK CEREMONY ========
+	principal := middlewares.MustGetPrincipal(ctx)
+	challenge, ok := controller.consumeCeremony(ctx, clientDataJSON, interfaces.PasskeyCeremonyRegistration, &principal.UserID)
+	if !ok {
+		return
+	}
+
+	// ======== VERIFY CREDENTIAL ========
+	credential, err := common.WebAuthn.VerifyRegistration(rp, challenge, clientDataJSON, attestationObject, true)
+	if err != nil {
+		controller.logger.Info("Rejected a passkey:", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	name := body.Name
+	if name == "" {
+		name = deviceName(ctx.Request.UserAgent())
+	}
+
+	passkey, err := controller.passkeys.CreatePasskey(principal.UserID, name, *credential)
+	if errors.Is(err, interfaces.PasskeyAlreadyRegisteredException) {
+		ctx.AbortWithError(http.StatusConflict, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Passkey registered successfully.",
+		"passkey": passkey,
+	})
+}
+
+// GetAll returns the passkeys of the authenticated user.
+func (controller PasskeysController) GetAll(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all passkeys.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	passkeys, err := controller.passkeys.GetPasskeys(principal.UserID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, passkeys)
+}
+
+// Delete deletes a passkey of the authenticated user. The passkey stays in the
+// authenticator, but cannot be used anymore.
+func (controller PasskeysController) Delete(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Deleting passkey with id", ctx.Param("id"))
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	err = controller.passkeys.DeletePasskey(principal.UserID, int32(id))
+	if errors.Is(err, interfaces.PasskeyNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Passkey deleted successfully.",
+	})
+}
+
+// BeginLogin starts a login with a passkey, and returns the options to pass to
+// navigator.credentials.get(). The user picks one of their passkeys, so they do
+// not have to type their email.
+func (controller PasskeysController) BeginLogin(ctx *gin.Context) {
+	controller.logger.Info("[POST] Passkey login options route.")
+
+	rp, ok := abortIfPasskeysNotConfigured(ctx)
+	if !ok {
+		return
+	}
+
+	challenge, err := controller.passkeys.BeginCeremony(nil, interfaces.PasskeyCeremonyLogin, passkeyCeremonyTTL())
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"publicKey": gin.H{
+			"challenge":        encodeBase64URL(challenge),
+			"rpId":             rp.ID,
+			"timeout":          passkeyCeremonyTTL().Milliseconds(),
+			"allowCredentials": []gin.H{},
+			"userVerification": "required",
+		},
+	})
+}
+
+// FinishLogin verifies the response of the authenticator to the login started
+// with BeginLogin, and returns the same tokens, or cookie session, a login with
+// a password returns. Passkeys verify the user on their own (e.g. with a PIN or
+// a fingerprint), so no second factor is asked for.
+func (controller PasskeysController) FinishLogin(ctx *gin.Context) {
+	controller.logger.Info("[POST] Passkey login route.")
+
+	rp, ok := abortIfPasskeysNotConfigured(ctx)
+	if !ok {
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := PasskeyLoginBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	passkey, ok := controller.verifyAssertion(ctx, rp, body.Credential, interfaces.PasskeyCeremonyLogin, nil, true)
+	if !ok {
+		return
+	}
+
+	respondWithLogin(
+		ctx,
+		controller.service,
+		controller.sessions,
+		passkey.UserID,
+		interfaces.AuthMethodPasskey,
+		body.LoginOptions,
+		"Logged in successfully.",
+	)
+}
+
+// BeginMFA starts the use of a passkey as the second factor of a login, for the
+// user a challenge token was issued to, and returns the options to pass to
+// navigator.credentials.get().
+func (controller PasskeysController) BeginMFA(ctx *gin.Context) {
+	controller.logger.Info("[POST] Passkey MFA options route.")
+
+	rp, ok := abortIfPasskeysNotConfigured(ctx)
+	if !ok {
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := PasskeyMFAOptionsBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	userID, err := controller.service.CheckChallenge(body.ChallengeToken, interfaces.ChallengePurposeMFA)
+	if err != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, err)
+		return
+	}
+
+	passkeys, err := controller.passkeys.GetPasskeys(userID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if len(passkeys) == 0 {
+		ctx.AbortWithError(http.StatusBadRequest, NoPasskeysException)
+		return
+	}
+
+	challenge, err := controller.passkeys.BeginCeremony(&userID, interfaces.PasskeyCeremonyMFA, passkeyCeremonyTTL())
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"publicKey": gin.H{
+			"challenge":        encodeBase64URL(challenge),
+			"rpId":             rp.ID,
+			"timeout":          passkeyCeremonyTTL().Milliseconds(),
+			"allowCredentials": passkeyDescriptors(passkeys),
+			"userVerification": "preferred",
+		},
+	})
+}
+
+// FinishMFA exchanges the challenge token returned by the login route and the
+// response of the authenticator to the ceremony started with BeginMFA for the
+// tokens of the user, like a TOTP code does at /login/mfa.
+func (controller PasskeysController) FinishMFA(ctx *gin.Context) {
+	controller.logger.Info("[POST] Passkey MFA login route.")
+
+	rp, ok := abortIfPasskeysNotConfigured(ctx)
+	if !ok {
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := PasskeyMFALoginBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	userID, err := controller.service.CheckChallenge(body.ChallengeToken, interfaces.ChallengePurposeMFA)
+	if err != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, err)
+		return
+	}
+
+	// The password was already checked, so the passkey only has to prove
+	// that the user has it.
+	if _, ok := controller.verifyAssertion(ctx, rp, body.Credential, interfaces.PasskeyCeremonyMFA, &userID, false); !ok {
+		return
+	}
+
+	respondWithLogin(
+		ctx,
+		controller.service,
+		controller.sessions,
+		userID,
+		interfaces.AuthMethodMFA,
+		body.LoginOptions,
+		"Logged in successfully.",
+	)
+}
+
+// ======== PRIVATE METHODS ========
+
+// verifyAssertion verifies the response of an authenticator to an authentication
+// ceremony, and returns the passkey it was made with. If the user is given, the
+// ceremony must have been started for them and the passkey must be theirs.
+func (controller PasskeysController) verifyAssertion(
+	ctx *gin.Context,
+	rp common.WebAuthnRelyingParty,
+	credential PasskeyCredential,
+	purpose string,
+	userID *int32,
+	requireUserVerification bool,
+) (*interfaces.Passkey, bool) {
+	response := credential.Response
+	clientDataJSON, authenticatorData, err := decodeCredential(response.ClientDataJSON, response.AuthenticatorData)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return nil, false
+	}
+	signature, err := common.DecodeBase64URL(response.Signature)
+	if err != nil || len(signature) == 0 {
+		ctx.AbortWithError(http.StatusBadRequest, common.InvalidWebAuthnResponseException)
+		return nil, false
+	}
+	credentialID, err := common.DecodeBase64URL(credential.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, common.InvalidWebAuthnResponseException)
+		return nil, false
+	}
+
+	// ======== CHECK CEREMONY ========
+	challenge, ok := controller.consumeCeremony(ctx, clientDataJSON, purpose, userID)
+	if !ok {
+		return nil, false
+	}
+
+	// ======== FIND PASSKEY ========
+	// Passkeys of other users are reported as unknown, so that they cannot
+	// be told apart from deleted ones.
+	passkey, err := controller.passkeys.GetPasskeyByCredentialID(credentialID)
+	if err == nil && userID != nil && passkey.UserID != *userID {
+		err = interfaces.PasskeyNotFoundException
+	}
+	if errors.Is(err, interfaces.PasskeyNotFoundException) {
+		ctx.AbortWithError(http.StatusUnauthorized, err)
+		return nil, false
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	// The user handle, when present, names the user the passkey was
+	// created for.
+	if response.UserHandle != "" {
+		handle, err := common.DecodeBase64URL(response.UserHandle)
+		if err != nil || string(handle) != string(passkeyUserHandle(passkey.UserID)) {
+			ctx.AbortWithError(http.StatusUnauthorized, common.InvalidWebAuthnResponseException)
+			return nil, false
+		}
+	}
+
+	// ======== VERIFY SIGNATURE ========
+	signCount, err := common.WebAuthn.VerifyAssertion(
+		rp,
+		challenge,
+		passkey.Credential(),
+		clientDataJSON,
+		authenticatorData,
+		signature,
+		requireUserVerification,
+	)
+	if err != nil {
+		controller.logger.Info("Rejected a passkey assertion:", err)
+		ctx.AbortWithError(http.StatusUnauthorized, err)
+		return nil, false
+	}
+
+	if err := controller.passkeys.RecordUse(passkey.ID, signCount); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	return passkey, true
+}
+
+// consumeCeremony ends the ceremony the client data belongs to and returns its
+// challenge. If the user is given, the ceremony must have been started for them.
+func (controller PasskeysController) consumeCeremony(
+	ctx *gin.Context,
+	clientDataJSON []byte,
+	purpose string,
+	userID *int32,
+) ([]byte, bool) {
+	challenge, err := common.WebAuthn.Challenge(clientDataJSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	owner, err := controller.passkeys.ConsumeCeremony(challenge, purpose)
+	if err == nil && userID != nil && (owner == nil || *owner != *userID) {
+		err = interfaces.InvalidPasskeyCeremonyException
+	}
+	if errors.Is(err, interfaces.InvalidPasskeyCeremonyException) {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return nil, false
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	return challenge, true
+}
+
+// decodeCredential decodes the client data of the response of an authenticator
+// along with its attestation object or authenticator data.
+func decodeCredential(clientData string, data string) ([]byte, []byte, error) {
+	clientDataJSON, err := common.DecodeBase64URL(clientData)
+	if err != nil {
+		return nil, nil, common.InvalidWebAuthnResponseException
+	}
+	decoded, err := common.DecodeBase64URL(data)
+	if err != nil || len(decoded) == 0 {
+		return nil, nil, common.InvalidWebAuthnResponseException
+	}
+	return clientDataJSON, decoded, nil
+}
+
+// abortIfPasskeysNotConfigured aborts the request with a 404 if there is no
+// relying party configured, and returns it otherwise.
+func abortIfPasskeysNotConfigured(ctx *gin.Context) (common.WebAuthnRelyingParty, bool) {
+	rp, ok := webauthnRelyingParty()
+	if !ok {
+		ctx.AbortWithError(http.StatusNotFound, PasskeysNotConfiguredException)
+	}
+	return rp, ok
+}
+
+// webauthnRelyingParty returns the relying party passkeys are scoped to, which
+// is configured with WEBAUTHN_RP_ID (the domain of the API or the website using
+// it), WEBAUTHN_RP_NAME and WEBAUTHN_ORIGINS (comma-separated, https:// and the
+// ID by default). Passkeys are disabled if WEBAUTHN_RP_ID is not set.
+func webauthnRelyingParty() (common.WebAuthnRelyingParty, bool) {
+	id := common.Env.String("WEBAUTHN_RP_ID", "")
+	if id == "" {
+		return common.WebAuthnRelyingParty{}, false
+	}
+
+	origins := []string{}
+	for _, origin := range strings.Split(common.Env.String("WEBAUTHN_ORIGINS", "https://"+id), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return common.WebAuthnRelyingParty{
+		ID:      id,
+		Name:    common.Env.String("WEBAUTHN_RP_NAME", id),
+		Origins: origins,
+	}, true
+}
+
+// passkeyDescriptors returns the descriptors of the credentials of passkeys, for
+// the excludeCredentials and allowCredentials options.
+func passkeyDescriptors(passkeys []interfaces.Passkey) []gin.H {
+	descriptors := []gin.H{}
+	for _, passkey := range passkeys {
+		descriptors = append(descriptors, gin.H{
+			"type": "public-key",
+			"id":   encodeBase64URL(passkey.CredentialID),
+		})
+	}
+	return descriptors
+}
+
+// passkeyUserHandle returns the user handle of the passkeys of a user, which
+// authenticators store along with them and return when logging in.
+func passkeyUserHandle(userID int32) []byte {
+	return []byte(strconv.Itoa(int(userID)))
+}
+
+// passkeyCeremonyTTL returns how long ceremonies can take.
+func passkeyCeremonyTTL() time.Duration {
+	return common.Env.Duration("PASSKEY_CEREMONY_TTL", 5*time.Minute)
+}
+
+// encodeBase64URL encodes binary data with base64url, as WebAuthn does in JSON.
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasskeysController(t *testing.T) {
+	t.Setenv("WEBAUTHN_RP_ID", "example.com")
+	t.Setenv("WEBAUTHN_ORIGINS", "https://example.com")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	authService := &mocks.MockAuthService{}
+	sessions := &mocks.MockSessionsService{}
+	passkeys := &mocks.MockPasskeysService{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, sessions, &mocks.MockImpersonationsService{})
+	passkeysController := GetPasskeysController(logger, authService, &mocks.MockUsersService{}, passkeys, sessions)
+
+	router.POST("/login/passkey/options", passkeysController.BeginLogin)
+	router.POST("/login/passkey", passkeysController.FinishLogin)
+	router.POST("/login/mfa/passkey/options", passkeysController.BeginMFA)
+	router.POST("/login/mfa/passkey", passkeysController.FinishMFA)
+	account := router.Group("/").Use(authMiddleware.Handler(middlewares.RejectAPIKeys()))
+	account.POST("/passkeys/options", passkeysController.BeginRegistration)
+	account.POST("/passkeys", passkeysController.FinishRegistration)
+	account.GET("/passkeys", passkeysController.GetAll)
+	account.DELETE("/passkeys/:id", passkeysController.Delete)
+
+	// The account routes are called with the cookie session of the user 1.
+	sessions.CreateSession(1, interfaces.AuthMethodPassword, interfaces.Device{})
+
+	request := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: interfaces.SessionCookie, Value: mocks.MockSessionToken})
+		req.Header.Set(interfaces.CSRFHeader, mocks.MockCSRFToken)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// options requests the options of a ceremony and returns its challenge.
+	options := func(t *testing.T, path string, body interface{}) ([]byte, map[string]interface{}) {
+		w := request("POST", path, body)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		challenge, err := base64.RawURLEncoding.DecodeString(response["publicKey"]["challenge"].(string))
+		require.NoError(t, err)
+		return challenge, response["publicKey"]
+	}
+
+	authenticator := mocks.NewMockAuthenticator("https://example.com", "example.com")
+	authenticator.CounterStep = 1
+	var credentialID []byte
+
+	t.Run("Register", func(t *testing.T) {
+		challenge, publicKey := options(t, "/passkeys/options", nil)
+		assert.Equal(t, "MQ", publicKey["user"].(map[string]interface{})["id"])
+		assert.Empty(t, publicKey["excludeCredentials"])
+
+		credential := authenticator.Create(challenge, []byte("1"))
+		w := request("POST", "/passkeys", gin.H{"name": "Laptop", "credential": credential})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		if assert.Len(t, passkeys.Passkeys, 1) {
+			assert.Equal(t, int32(1), passkeys.Passkeys[0].UserID)
+			assert.Equal(t, "Laptop", passkeys.Passkeys[0].Name)
+			credentialID = passkeys.Passkeys[0].CredentialID
+		}
+
+		// Test case 2: The challenge can only be used once
+		w = request("POST", "/passkeys", gin.H{"credential": authenticator.Create(challenge, []byte("1"))})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Test case 3: The registered passkey is excluded from new registrations
+		_, publicKey = options(t, "/passkeys/options", nil)
+		assert.Len(t, publicKey["excludeCredentials"], 1)
+	})
+
+	t.Run("RegisterWithoutUserVerification", func(t *testing.T) {
+		challenge, _ := options(t, "/passkeys/options", nil)
+
+		unverified := mocks.NewMockAuthenticator("https://example.com", "example.com")
+		unverified.UserVerified = false
+		w := request("POST", "/passkeys", gin.H{"credential": unverified.Create(challenge, []byte("1"))})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Len(t, passkeys.Passkeys, 1)
+	})
+
+	t.Run("RegisterForAnotherOrigin", func(t *testing.T) {
+		challenge, _ := options(t, "/passkeys/options", nil)
+
+		phishing := mocks.NewMockAuthenticator("https://example.evil.com", "example.com")
+		w := request("POST", "/passkeys", gin.H{"credential": phishing.Create(challenge, []byte("1"))})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Len(t, passkeys.Passkeys, 1)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		challenge, publicKey := options(t, "/login/passkey/options", nil)
+		assert.Equal(t, "example.com", publicKey["rpId"])
+
+		w := request("POST", "/login/passkey", gin.H{"credential": authenticator.Get(challenge, credentialID)})
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "mock_jwt_token", response["token"])
+		assert.Equal(t, uint32(2), passkeys.Passkeys[0].SignCount)
+		assert.NotNil(t, passkeys.Passkeys[0].LastUsedAt)
+	})
+
+	t.Run("LoginWithReplayedAssertion", func(t *testing.T) {
+		challenge, _ := options(t, "/login/passkey/options", nil)
+		credential := authenticator.Get(challenge, credentialID)
+
+		w := request("POST", "/login/passkey", gin.H{"credential": credential})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The ceremony has ended, so the same assertion cannot be used twice
+		w = request("POST", "/login/passkey", gin.H{"credential": credential})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("LoginWithClonedAuthenticator", func(t *testing.T) {
+		// A clone would sign with a counter lower than the stored one.
+		passkeys.Passkeys[0].SignCount = 100
+		defer func() { passkeys.Passkeys[0].SignCount = 3 }()
+
+		challenge, _ := options(t, "/login/passkey/options", nil)
+		w := request("POST", "/login/passkey", gin.H{"credential": authenticator.Get(challenge, credentialID)})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("LoginWithUnknownPasskey", func(t *testing.T) {
+		other := mocks.NewMockAuthenticator("https://example.com", "example.com")
+		other.Create([]byte("challenge"), []byte("1"))
+
+		challenge, _ := options(t, "/login/passkey/options", nil)
+		w := request("POST", "/login/passkey", gin.H{"credential": other.Get(challenge, nil)})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("MFA", func(t *testing.T) {
+		// Test case 1: An invalid challenge token is rejected
+		w := request("POST", "/login/mfa/passkey/options", PasskeyMFAOptionsBody{ChallengeToken: "invalid"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// Test case 2: The options allow the passkeys of the user
+		body := PasskeyMFAOptionsBody{ChallengeToken: "mock_mfa_challenge_token"}
+		challenge, publicKey := options(t, "/login/mfa/passkey/options", body)
+		assert.Len(t, publicKey["allowCredentials"], 1)
+
+		// Test case 3: A passkey without user verification is enough as a
+		// second factor
+		authenticator.UserVerified = false
+		defer func() { authenticator.UserVerified = true }()
+
+		w = request("POST", "/login/mfa/passkey", gin.H{
+			"challenge_token": "mock_mfa_challenge_token",
+			"credential":      authenticator.Get(challenge, credentialID),
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("LoginWithoutUserVerification", func(t *testing.T) {
+		authenticator.UserVerified = false
+		defer func() { authenticator.UserVerified = true }()
+
+		// But it is not enough on its own
+		challenge, _ := options(t, "/login/passkey/options", nil)
+		w := request("POST", "/login/passkey", gin.H{"credential": authenticator.Get(challenge, credentialID)})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("MFAWithLoginCeremony", func(t *testing.T) {
+		// A login ceremony is not bound to the user, so it cannot be used
+		// as the second factor.
+		challenge, _ := options(t, "/login/passkey/options", nil)
+		w := request("POST", "/login/mfa/passkey", gin.H{
+			"challenge_token": "mock_mfa_challenge_token",
+			"credential":      authenticator.Get(challenge, credentialID),
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("GetAllAndDelete", func(t *testing.T) {
+		w := request("GET", "/passkeys", nil)
+
+		var response []map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		if assert.Len(t, response, 1) {
+			assert.Equal(t, "Laptop", response[0]["name"])
+			assert.NotContains(t, response[0], "public_key")
+		}
+
+		w = request("DELETE", "/passkeys/1", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, passkeys.Passkeys)
+
+		// Test case 2: The passkey no longer exists
+		w = request("DELETE", "/passkeys/1", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// Test case 3: And cannot be used for logging in
+		challenge, _ := options(t, "/login/passkey/options", nil)
+		w = request("POST", "/login/passkey", gin.H{"credential": authenticator.Get(challenge, credentialID)})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestPasskeysController_NotConfigured(t *testing.T) {
+	t.Setenv("WEBAUTHN_RP_ID", "")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	passkeysController := GetPasskeysController(
+		mocks.NewMockLogger(),
+		&mocks.MockAuthService{},
+		&mocks.MockUsersService{},
+		&mocks.MockPasskeysService{},
+		&mocks.MockSessionsService{},
+	)
+	router.POST("/login/passkey/options", passkeysController.BeginLogin)
+
+	req, _ := http.NewRequest("POST", "/login/passkey/options", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,240 @@
+/*
+Package Name: auth
+File Name: auth_password_controller.go
+Abstract: The controller for recovering accounts whose password has been forgotten.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// PasswordController struct
+type PasswordController struct {
+	logger       lib.Logger
+	service      interfaces.AuthService
+	usersService users.UsersRepository
+	userTokens   interfaces.UserTokensRepository
+	mailer       lib.Mailer
+	policy       common.PasswordPolicy
+}
+
+type ForgotPasswordBody struct {
+	Email string `json:"email" form:"email" binding:"required,email"`
+}
+
+type ResetPasswordBody struct {
+	Token           string `json:"token" form:"token" binding:"required"`
+	Password        string `json:"password" form:"password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" form:"confirm_password" binding:"required,eqfield=Password"`
+}
+
+// ======== METHODS ========
+
+// GetPasswordController retrieves a new password controller.
+func GetPasswordController(
+	logger lib.Logger,
+	service interfaces.AuthService,
+	usersService users.UsersRepository,
+	userTokens interfaces.UserTokensRepository,
+	mailer lib.Mailer,
+	policy common.PasswordPolicy,
+) PasswordController {
+	return PasswordController{
+		logger:       logger,
+		service:      service,
+		usersService: usersService,
+		userTokens:   userTokens,
+		mailer:       mailer,
+		policy:       policy,
+	}
+}
+
+// Forgot sends an email with a password reset token to the user.
+//
+// The response is the same whether or not there is an account with the email
+// provided, so that this route cannot be used for finding out who is registered.
+func (controller PasswordController) Forgot(ctx *gin.Context) {
+	controller.logger.Info("[POST] Forgot password route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := ForgotPasswordBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	if err := controller.sendResetToken(body.Email); err != nil {
+		controller.logger.Error("Could not send a password reset token:", err)
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "If there is an account with that email, a password reset link has been sent to it.",
+	})
+}
+
+// Reset sets a new password for the user a reset token was sent to, and logs
+// them out of every device.
+func (controller PasswordController) Reset(ctx *gin.Context) {
+	controller.logger.Info("[POST] Reset password route.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := ResetPasswordBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== CHECK TOKEN ========
+	// The token is checked before the new password, so that it can be used
+	// again if the password is rejected.
+	userID, err := controller.userTokens.CheckUserToken(body.Token, interfaces.TokenPurposePasswordReset)
+	if errors.Is(err, interfaces.InvalidUserTokenException) {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	user, err := controller.usersService.GetUserById(int(userID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// ======== CHECK PASSWORD POLICY ========
+	if errors := controller.policy.Validate("password", body.Password, user.Username, user.Email); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+	if err := controller.usersService.CheckPasswordHistory(userID, body.Password); errors.Is(err, users.PasswordReusedException) {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, common.Validation.FieldErrors("password", err.Error()))
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// ======== CONSUME TOKEN ========
+	// The token is consumed atomically before the password is written, so
+	// that only one of the requests that use it at once can change it.
+	userID, err = controller.userTokens.ConsumeUserToken(body.Token, interfaces.TokenPurposePasswordReset)
+	if errors.Is(err, interfaces.InvalidUserTokenException) {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// ======== UPDATE PASSWORD ========
+	// The history is checked again, since the password may have changed since.
+	if err := controller.usersService.UpdatePassword(userID, body.Password); errors.Is(err, users.PasswordReusedException) {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, common.Validation.FieldErrors("password", err.Error()))
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Whoever knew the old password could still be logged in, so every
+	// session of the user is revoked.
+	if err := controller.service.RevokeAllTokens(userID); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password reset successfully.",
+	})
+}
+
+// ======== PRIVATE METHODS ========
+
+// sendResetToken issues a password reset token for the user with the email
+// provided and sends it to them.
+func (controller PasswordController) sendResetToken(email string) error {
+	user, err := controller.usersService.GetUserByEmail(email)
+	if err != nil {
+		return err
+	}
+
+	ttl := common.Env.Duration("PASSWORD_RESET_TOKEN_TTL", time.Hour)
+	token, err := controller.userTokens.IssueUserToken(user.ID, interfaces.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+
+	return controller.mailer.Send(lib.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, use the following link within the next %s:\n\n%s\n\nOtherwise, you can safely ignore this email.\n",
+			user.Username,
+			ttl,
+			tokenLink(common.Env.String("PASSWORD_RESET_URL", ""), token),
+		),
+	})
+}
+
+// tokenLink returns the link the user has to follow for using a token, or just
+// the token if there is no link.
+func tokenLink(link string, token string) string {
+	if link == "" {
+		return token
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return token
+	}
+	query := parsed.Query()
+	query.Set("token", token)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordController_ForgotAndReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	usersService := &mocks.MockUsersService{PreviousPasswords: []string{"correct horse battery staple"}}
+	authService := &mocks.MockAuthService{}
+	userTokens := &mocks.MockUserTokensService{}
+	mailer := &mocks.MockMailer{}
+
+	passwordController := GetPasswordController(mocks.NewMockLogger(), authService, usersService, userTokens, mailer, common.DefaultPasswordPolicy())
+	router.POST("/password/forgot", passwordController.Forgot)
+	router.POST("/password/reset", passwordController.Reset)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("UnknownEmail", func(t *testing.T) {
+		w := post("/password/forgot", ForgotPasswordBody{Email: "nobody@example.com"})
+
+		// The response must not reveal whether the account exists.
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Empty(t, mailer.Sent)
+	})
+
+	var token string
+	t.Run("Forgot", func(t *testing.T) {
+		w := post("/password/forgot", ForgotPasswordBody{Email: "user@example.com"})
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		require.Len(t, mailer.Sent, 1)
+		assert.Equal(t, "user@example.com", mailer.Sent[0].To)
+
+		for issued := range userTokens.Tokens {
+			token = issued
+		}
+		require.NotEmpty(t, token)
+		assert.True(t, strings.Contains(mailer.Sent[0].Body, token))
+	})
+
+	t.Run("PasswordsDoNotMatch", func(t *testing.T) {
+		w := post("/password/reset", ResetPasswordBody{
+			Token:           token,
+			Password:        "newPassword",
+			ConfirmPassword: "otherPassword",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("WeakPassword", func(t *testing.T) {
+		w := post("/password/reset", ResetPasswordBody{
+			Token:           token,
+			Password:        "newPassword",
+			ConfirmPassword: "newPassword",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response struct {
+			Errors []common.ValidationErrorMessage `json:"errors"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, "password", response.Errors[0].Field)
+		assert.Empty(t, usersService.UpdatedPasswords)
+	})
+
+	t.Run("ReusedPassword", func(t *testing.T) {
+		w := post("/password/reset", ResetPasswordBody{
+			Token:           token,
+			Password:        "correct horse battery staple",
+			ConfirmPassword: "correct horse battery staple",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), users.PasswordReusedException.Error())
+	})
+
+	// A request that loses the race for the token does not change the password
+	t.Run("ConcurrentReset", func(t *testing.T) {
+		userTokens.BeforeConsume = func(token string) {
+			issued := userTokens.Tokens[token]
+			issued.Used = true
+			userTokens.Tokens[token] = issued
+		}
+		defer func() {
+			userTokens.BeforeConsume = nil
+			issued := userTokens.Tokens[token]
+			issued.Used = false
+			userTokens.Tokens[token] = issued
+		}()
+
+		w := post("/password/reset", ResetPasswordBody{
+			Token:           token,
+			Password:        "tangerine marble helicopter",
+			ConfirmPassword: "tangerine marble helicopter",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, usersService.UpdatedPasswords)
+		assert.Empty(t, authService.RevokedUsers)
+	})
+
+	// The token is still valid after the rejected passwords
+	t.Run("Reset", func(t *testing.T) {
+		w := post("/password/reset", ResetPasswordBody{
+			Token:           token,
+			Password:        "purple monkey dishwasher",
+			ConfirmPassword: "purple monkey dishwasher",
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "purple monkey dishwasher", usersService.UpdatedPasswords[1])
+		assert.Equal(t, []int32{1}, authService.RevokedUsers)
+	})
+
+	t.Run("TokenAlreadyUsed", func(t *testing.T) {
+		w := post("/password/reset", ResetPasswordBody{
+			Token:           token,
+			Password:        "tangerine marble helicopter",
+			ConfirmPassword: "tangerine marble helicopter",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,346 @@
+/*
+Package Name: auth
+File Name: auth_revocation.go
+Abstract: The store that keeps track of the access tokens that have been
+revoked before their expiration. Revocations are persisted in Postgres and
+cached in memory so that checking a token does not hit the database.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+)
+
+// ======== TYPES ========
+
+// RevocationStore stores revoked access tokens.
+//
+// The in-memory cache is what CheckToken consults. It is written through on
+// every revocation and periodically reloaded from the database, so that
+// revocations made by other instances of the API are eventually picked up.
+type RevocationStore struct {
+	logger lib.Logger
+	db     *lib.Database
+	cache  *revocationCache
+}
+
+// revocationCache is the in-memory copy of the revocations.
+type revocationCache struct {
+	mutex sync.RWMutex
+	// tokens maps the jti of every revoked token to its expiration.
+	tokens map[string]time.Time
+	// sessions maps the id of every ended session to the moment its tokens
+	// expire.
+	sessions map[string]time.Time
+	// users maps a user id to the moment before which all of its tokens
+	// were revoked.
+	users map[int32]userRevocation
+}
+
+// userRevocation revokes every token of a user issued before a moment.
+type userRevocation struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// ======== METHODS ========
+
+// GetRevocationStore returns the revocation store and schedules the tasks that
+// keep it in sync with the database.
+func GetRevocationStore(logger lib.Logger, db *lib.Database, scheduler *lib.Scheduler) *RevocationStore {
+	store := &RevocationStore{
+		logger: logger,
+		db:     db,
+		cache:  newRevocationCache(),
+	}
+
+	scheduler.Every(
+		"sync revoked tokens",
+		common.Env.Duration("REVOCATION_SYNC_INTERVAL", 30*time.Second),
+		store.sync,
+	)
+	scheduler.Every(
+		"purge revoked tokens",
+		common.Env.Duration("REVOCATION_PURGE_INTERVAL", time.Hour),
+		store.purge,
+	)
+
+	return store
+}
+
+// RevokeToken revokes a single token until it expires.
+func (store *RevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	_, err := store.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.revoked_token (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING;`,
+		jti,
+		expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	store.cache.revokeToken(jti, expiresAt)
+	return nil
+}
+
+// RevokeSession revokes every token bound to a session until they expire.
+func (store *RevocationStore) RevokeSession(sessionID string, expiresAt time.Time) error {
+	return store.revokeSession(context.Background(), store.db, sessionID, expiresAt)
+}
+
+// revokeSession revokes every token of a session through db, which may be a
+// transaction.
+func (store *RevocationStore) revokeSession(ctx context.Context, db executor, sessionID string, expiresAt time.Time) error {
+	_, err := db.Exec(
+		ctx,
+		`INSERT INTO auth.revoked_session (session_id, expires_at) VALUES ($1, $2)
+		ON CONFLICT (session_id) DO UPDATE SET expires_at = $2;`,
+		sessionID,
+		expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	store.cache.revokeSession(sessionID, expiresAt)
+	return nil
+}
+
+// RevokeUser revokes every token of a user issued before the given moment.
+// Since no token can outlive the access token lifetime, the revocation
+// expires after it.
+//
+// Tokens only carry the second they were issued at, so the moment is rounded
+// down to the second and the tokens issued in that same second are revoked too.
+// Otherwise a token issued right before the revocation would outlive it.
+func (store *RevocationStore) RevokeUser(id int32, issuedBefore time.Time) error {
+	issuedBefore = issuedBefore.Truncate(time.Second)
+	expiresAt := issuedBefore.Add(accessTokenTTL())
+
+	_, err := store.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.revoked_user_tokens (user_id, issued_before, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET issued_before = $2, expires_at = $3;`,
+		id,
+		issuedBefore,
+		expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	store.cache.revokeUser(id, userRevocation{issuedBefore: issuedBefore, expiresAt: expiresAt})
+	return nil
+}
+
+// IsRevoked returns whether a token, or the session it is bound to, has been revoked.
+func (store *RevocationStore) IsRevoked(jti string, sessionID string, userID int32, issuedAt time.Time) bool {
+	return store.cache.isRevoked(jti, userID, issuedAt) || store.cache.isSessionRevoked(sessionID)
+}
+
+// ======== PRIVATE METHODS ========
+
+// sync reloads the cache from the database.
+func (store *RevocationStore) sync(ctx context.Context) error {
+	tokens := make(map[string]time.Time)
+	rows, err := store.db.Query(ctx, `SELECT jti, expires_at FROM auth.revoked_token WHERE expires_at > now();`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return err
+		}
+		tokens[jti] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	sessions := make(map[string]time.Time)
+	rows, err = store.db.Query(ctx, `SELECT session_id, expires_at FROM auth.revoked_session WHERE expires_at > now();`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sessionID string
+		var expiresAt time.Time
+		if err := rows.Scan(&sessionID, &expiresAt); err != nil {
+			return err
+		}
+		sessions[sessionID] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	users := make(map[int32]userRevocation)
+	rows, err = store.db.Query(ctx, `SELECT user_id, issued_before, expires_at FROM auth.revoked_user_tokens WHERE expires_at > now();`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int32
+		var revocation userRevocation
+		if err := rows.Scan(&id, &revocation.issuedBefore, &revocation.expiresAt); err != nil {
+			return err
+		}
+		users[id] = revocation
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	store.cache.replace(tokens, sessions, users)
+	return nil
+}
+
+// purge deletes the revocations of tokens that have already expired.
+func (store *RevocationStore) purge(ctx context.Context) error {
+	if _, err := store.db.Exec(ctx, `DELETE FROM auth.revoked_token WHERE expires_at <= now();`); err != nil {
+		return err
+	}
+	if _, err := store.db.Exec(ctx, `DELETE FROM auth.revoked_session WHERE expires_at <= now();`); err != nil {
+		return err
+	}
+	if _, err := store.db.Exec(ctx, `DELETE FROM auth.revoked_user_tokens WHERE expires_at <= now();`); err != nil {
+		return err
+	}
+
+	store.cache.purge(time.Now())
+	return nil
+}
+
+// newRevocationCache returns an empty cache.
+func newRevocationCache() *revocationCache {
+	return &revocationCache{
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[string]time.Time),
+		users:    make(map[int32]userRevocation),
+	}
+}
+
+// revokeToken adds a token to the cache.
+func (cache *revocationCache) revokeToken(jti string, expiresAt time.Time) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.tokens[jti] = expiresAt
+}
+
+// revokeSession adds a session to the cache.
+func (cache *revocationCache) revokeSession(sessionID string, expiresAt time.Time) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.sessions[sessionID] = expiresAt
+}
+
+// revokeUser adds a user revocation to the cache.
+func (cache *revocationCache) revokeUser(id int32, revocation userRevocation) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.users[id] = revocation
+}
+
+// isRevoked checks a token against the cache.
+func (cache *revocationCache) isRevoked(jti string, userID int32, issuedAt time.Time) bool {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	if _, ok := cache.tokens[jti]; ok {
+		return true
+	}
+
+	// The tokens issued in the second of the revocation are revoked too, as
+	// the issue time of tokens has no fractions of a second.
+	if revocation, ok := cache.users[userID]; ok &&
+		!issuedAt.Truncate(time.Second).After(revocation.issuedBefore) {
+		return true
+	}
+
+	return false
+}
+
+// isSessionRevoked checks the session of a token against the cache.
+func (cache *revocationCache) isSessionRevoked(sessionID string) bool {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	_, ok := cache.sessions[sessionID]
+	return ok
+}
+
+// replace swaps the contents of the cache.
+func (cache *revocationCache) replace(
+	tokens map[string]time.Time,
+	sessions map[string]time.Time,
+	users map[int32]userRevocation,
+) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.tokens = tokens
+	cache.sessions = sessions
+	cache.users = users
+}
+
+// purge removes the revocations that have expired by now.
+func (cache *revocationCache) purge(now time.Time) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for jti, expiresAt := range cache.tokens {
+		if !expiresAt.After(now) {
+			delete(cache.tokens, jti)
+		}
+	}
+	for sessionID, expiresAt := range cache.sessions {
+		if !expiresAt.After(now) {
+			delete(cache.sessions, sessionID)
+		}
+	}
+	for id, revocation := range cache.users {
+		if !revocation.expiresAt.After(now) {
+			delete(cache.users, id)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationCache_IsRevoked(t *testing.T) {
+	cache := newRevocationCache()
+	now := time.Now()
+
+	// Test case 1: Nothing has been revoked yet
+	assert.False(t, cache.isRevoked("jti", 1, now))
+
+	// Test case 2: A single token is revoked by its jti
+	cache.revokeToken("jti", now.Add(time.Minute))
+	assert.True(t, cache.isRevoked("jti", 1, now))
+	assert.False(t, cache.isRevoked("other", 1, now))
+
+	// Test case 3: Every token of a user issued before the cutoff is revoked
+	cache.revokeUser(2, userRevocation{issuedBefore: now, expiresAt: now.Add(time.Minute)})
+	assert.True(t, cache.isRevoked("old", 2, now.Add(-time.Second)))
+	assert.False(t, cache.isRevoked("new", 2, now.Add(time.Second)))
+	assert.False(t, cache.isRevoked("old", 3, now.Add(-time.Second)))
+
+	// Test case 4: Every token of a session is revoked by the session id
+	cache.revokeSession("session", now.Add(time.Minute))
+	assert.True(t, cache.isSessionRevoked("session"))
+	assert.False(t, cache.isSessionRevoked("other"))
+}
+
+func TestRevocationCache_Purge(t *testing.T) {
+	cache := newRevocationCache()
+	now := time.Now()
+
+	cache.revokeToken("expired", now.Add(-time.Minute))
+	cache.revokeToken("active", now.Add(time.Minute))
+	cache.revokeSession("expired", now.Add(-time.Minute))
+	cache.revokeUser(1, userRevocation{issuedBefore: now, expiresAt: now.Add(-time.Minute)})
+
+	cache.purge(now)
+
+	// Expired revocations are gone, the rest are kept
+	assert.False(t, cache.isRevoked("expired", 0, now))
+	assert.True(t, cache.isRevoked("active", 0, now))
+	assert.False(t, cache.isSessionRevoked("expired"))
+	assert.False(t, cache.isRevoked("", 1, now.Add(-time.Hour)))
+}
@@ -5,7 +5,7 @@ Abstract: The routes for logging in and signing up.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -31,15 +31,31 @@ SOFTWARE.
 */
 package auth
 
-import "github.com/alexmodrono/gin-restapi-template/pkg/lib"
+import (
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+)
 
 // ======== TYPES ========
 
 // UserRoutes struct
 type AuthRoutes struct {
-	logger         lib.Logger
-	router         *lib.Router
-	authController AuthController
+	logger                   lib.Logger
+	router                   *lib.Router
+	authController           AuthController
+	passwordController       PasswordController
+	verificationController   VerificationController
+	mfaController            MFAController
+	lockoutsController       LockoutsController
+	apiKeysController        APIKeysController
+	oidcController           OIDCController
+	oauthController          OAuthController
+	oauthClientsController   OAuthClientsController
+	sessionsController       SessionsController
+	magicLinkController      MagicLinkController
+	passkeysController       PasskeysController
+	impersonationsController ImpersonationsController
+	authMiddleware           middlewares.AuthMiddleware
 }
 
 // ======== PUBLIC METHODS ========
@@ -49,11 +65,37 @@ func SetAuthRoutes(
 	logger lib.Logger,
 	router *lib.Router,
 	authController AuthController,
+	passwordController PasswordController,
+	verificationController VerificationController,
+	mfaController MFAController,
+	lockoutsController LockoutsController,
+	apiKeysController APIKeysController,
+	oidcController OIDCController,
+	oauthController OAuthController,
+	oauthClientsController OAuthClientsController,
+	sessionsController SessionsController,
+	magicLinkController MagicLinkController,
+	passkeysController PasskeysController,
+	impersonationsController ImpersonationsController,
+	authMiddleware middlewares.AuthMiddleware,
 ) AuthRoutes {
 	return AuthRoutes{
-		router:         router,
-		logger:         logger,
-		authController: authController,
+		router:                   router,
+		logger:                   logger,
+		authController:           authController,
+		passwordController:       passwordController,
+		verificationController:   verificationController,
+		mfaController:            mfaController,
+		lockoutsController:       lockoutsController,
+		apiKeysController:        apiKeysController,
+		oidcController:           oidcController,
+		oauthController:          oauthController,
+		oauthClientsController:   oauthClientsController,
+		sessionsController:       sessionsController,
+		magicLinkController:      magicLinkController,
+		passkeysController:       passkeysController,
+		impersonationsController: impersonationsController,
+		authMiddleware:           authMiddleware,
 	}
 }
 
@@ -61,5 +103,67 @@ func SetAuthRoutes(
 func (route AuthRoutes) Setup() {
 	route.logger.Info("Setting up [AUTH] routes.")
 	route.router.POST("/login", route.authController.Login)
+	route.router.POST("/login/mfa", route.mfaController.Login)
+	route.router.POST("/login/magic-link", route.magicLinkController.Request)
+	route.router.POST("/login/magic-link/verify", route.magicLinkController.Verify)
+	route.router.POST("/login/passkey/options", route.passkeysController.BeginLogin)
+	route.router.POST("/login/passkey", route.passkeysController.FinishLogin)
+	route.router.POST("/login/mfa/passkey/options", route.passkeysController.BeginMFA)
+	route.router.POST("/login/mfa/passkey", route.passkeysController.FinishMFA)
 	route.router.POST("/signup", route.authController.Signup)
+	route.router.POST("/token/refresh", route.authController.Refresh)
+	route.router.GET("/.well-known/jwks.json", route.authController.Jwks)
+	route.router.POST("/password/forgot", route.passwordController.Forgot)
+	route.router.POST("/password/reset", route.passwordController.Reset)
+	route.router.GET("/verify-email", route.verificationController.Verify)
+	route.router.GET("/oidc/authorize", route.oidcController.Authorize)
+	route.router.POST("/oidc/callback", route.oidcController.Callback)
+	route.router.POST("/oauth/token", route.oauthController.Token)
+	route.router.POST("/oauth/revoke", route.oauthController.Revoke)
+
+	// The routes that manage the account and its credentials cannot be
+	// accessed with an API key, so that a leaked key cannot be used for
+	// taking over the account. The same goes for tokens issued to OAuth
+	// clients, which must not be able to grant themselves more access, and
+	// for admins impersonating the user, who must not be able to change
+	// the credentials of the user or impersonate anyone else.
+	account := route.router.Group("/").Use(route.authMiddleware.Handler(
+		middlewares.RejectAPIKeys(),
+		middlewares.RejectOAuthClients(),
+		middlewares.RejectImpersonation(),
+	))
+	{
+		account.POST("/logout", route.authController.Logout)
+		account.POST("/logout-all", route.authController.LogoutAll)
+		account.POST("/verify-email/resend", route.verificationController.Resend)
+		account.POST("/mfa/enroll", route.mfaController.Enroll)
+		account.POST("/mfa/confirm", route.mfaController.Confirm)
+		account.POST("/mfa/disable", route.mfaController.Disable)
+		account.POST("/api-keys", route.apiKeysController.Create)
+		account.GET("/api-keys", route.apiKeysController.GetAll)
+		account.DELETE("/api-keys/:id", route.apiKeysController.Revoke)
+		account.POST("/passkeys/options", route.passkeysController.BeginRegistration)
+		account.POST("/passkeys", route.passkeysController.FinishRegistration)
+		account.GET("/passkeys", route.passkeysController.GetAll)
+		account.DELETE("/passkeys/:id", route.passkeysController.Delete)
+		account.GET("/sessions", route.sessionsController.GetAll)
+		account.DELETE("/sessions/:id", route.sessionsController.Revoke)
+		account.GET("/oauth/authorize", route.oauthController.Authorize)
+		account.POST("/oauth/authorize", route.oauthController.Decide)
+		account.GET("/oauth/consents", route.oauthController.GetConsents)
+		account.DELETE("/oauth/consents/:client_id", route.oauthController.RevokeConsent)
+		account.POST("/admin/users/:id/impersonate", route.authMiddleware.Require("users:impersonate"), route.impersonationsController.Impersonate)
+		account.DELETE("/admin/impersonations/:id", route.authMiddleware.Require("users:impersonate"), route.impersonationsController.End)
+	}
+
+	api := route.router.Group("/").Use(route.authMiddleware.Handler())
+	{
+		api.GET("/admin/lockouts", route.authMiddleware.Require("lockouts:read"), route.lockoutsController.GetAll)
+		api.DELETE("/admin/lockouts/:kind/:identifier", route.authMiddleware.Require("lockouts:write"), route.lockoutsController.Unlock)
+		api.GET("/admin/oauth/clients", route.authMiddleware.Require("oauth:read"), route.oauthClientsController.GetAll)
+		api.POST("/admin/oauth/clients", route.authMiddleware.Require("oauth:write"), route.oauthClientsController.Create)
+		api.DELETE("/admin/oauth/clients/:id", route.authMiddleware.Require("oauth:write"), route.oauthClientsController.Delete)
+		api.GET("/admin/impersonations", route.authMiddleware.Require("impersonations:read"), route.impersonationsController.GetAll)
+		api.GET("/admin/impersonations/:id/requests", route.authMiddleware.Require("impersonations:read"), route.impersonationsController.GetRequests)
+	}
 }
@@ -5,7 +5,7 @@ Abstract: The service for verifying and creating JWTs.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -32,65 +32,488 @@ SOFTWARE.
 package auth
 
 import (
-	"os"
+	"context"
+	"errors"
 	"time"
 
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
 	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
 	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // ======== TYPES ========
 
 // AuthService service layer
 type AuthService struct {
-	db *lib.Database
+	logger      lib.Logger
+	db          *lib.Database
+	revocations *RevocationStore
+	keyring     *Keyring
+	roles       roles.RolesRepository
+}
+
+// executor is satisfied by both the database pool and transactions, so
+// that the same helpers can be used inside and outside a transaction.
+type executor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 }
 
 // ======== METHODS ========
 
 // GetUserService returns the user service.
-func GetAuthService(db *lib.Database) interfaces.AuthService {
+func GetAuthService(
+	logger lib.Logger,
+	db *lib.Database,
+	revocations *RevocationStore,
+	keyring *Keyring,
+	roles roles.RolesRepository,
+) interfaces.AuthService {
 	return AuthService{
-		db: db,
+		logger:      logger,
+		db:          db,
+		revocations: revocations,
+		keyring:     keyring,
+		roles:       roles,
 	}
 }
 
-// CheckToken checks whether the token is correct and returns the subject, which
-// in the case of our API is supposed to be the id of the user.
-func (service AuthService) CheckToken(tokenString string) (*int32, error) {
-	// Parse takes the token string and a function for looking up the key. The latter is especially
-	// useful if you use multiple keys for your application.  The standard is to use 'kid' in the
-	// head of the token to identify which key to use, but the parsed token (head and claims) is provided
-	// to the callback, providing flexibility.
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("SECRET_KEY")), nil
-	})
+// CheckToken checks whether the token is correct and returns the principal it
+// was issued for.
+func (service AuthService) CheckToken(tokenString string) (*interfaces.Principal, error) {
+	principal, err := service.parseToken(tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		subFloat := claims["sub"].(float64)
-		sub := int32(subFloat)
-		return &sub, nil
+	// ======== CHECK REVOCATION ========
+	// Tokens can be revoked before they expire, either one by one (logout),
+	// along with the session they are bound to, or all the tokens of a user
+	// at once (logout from every device).
+	if service.revocations.IsRevoked(principal.TokenID, principal.SessionID, principal.UserID, principal.IssuedAt) {
+		return nil, interfaces.RevokedTokenException
 	}
 
-	return nil, err
+	return principal, nil
 }
 
-// CreateToken creates jwt auth token
-func (service AuthService) CreateToken(id int32) (*string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": id,
-		"iat": time.Now().Unix(),
-		"exp": time.Now().AddDate(0, 0, 15).Unix(),
-	})
+// CreateToken creates an access token for the principal. The id, issue date and
+// expiration of the token are always set by this method.
+func (service AuthService) CreateToken(principal interfaces.Principal) (*string, error) {
+	return service.createToken(principal, accessTokenTTL())
+}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET_KEY")))
+// CreateImpersonationToken creates an access token for a user that names the
+// admin acting as them (the actor). It carries the roles and permissions of the
+// user, but it cannot be refreshed, so it expires once the ttl elapses.
+func (service AuthService) CreateImpersonationToken(
+	actorID int32,
+	userID int32,
+	sessionID string,
+	ttl time.Duration,
+) (*string, error) {
+	principal, err := service.userPrincipal(userID)
+	if err != nil {
+		return nil, err
+	}
+	principal.SessionID = sessionID
+	principal.AuthMethod = interfaces.AuthMethodImpersonation
+	principal.ActorID = actorID
+
+	return service.createToken(*principal, ttl)
+}
+
+// RevokeToken revokes the token of a principal, as well as the session and the
+// refresh token family it was issued with, so that none of the tokens of the
+// session can be used anymore.
+func (service AuthService) RevokeToken(principal interfaces.Principal) error {
+	if err := service.revocations.RevokeToken(principal.TokenID, principal.ExpiresAt); err != nil {
+		return err
+	}
+
+	// Tokens created along a refresh token carry its family as their session.
+	if principal.SessionID != "" {
+		if err := service.revocations.RevokeSession(principal.SessionID, time.Now().Add(accessTokenTTL())); err != nil {
+			return err
+		}
+		return revokeRefreshTokenFamily(context.Background(), service.db, principal.SessionID)
+	}
+
+	return nil
+}
+
+// PublicKeys returns the keys that can be used for verifying the tokens issued
+// by the API.
+func (service AuthService) PublicKeys() common.JWKS {
+	return service.keyring.JWKS(time.Now(), accessTokenTTL())
+}
+
+// RevokeAllTokens revokes every access and refresh token issued to a user so far,
+// including the ones of the OAuth clients they authorized, as well as their
+// cookie sessions.
+func (service AuthService) RevokeAllTokens(id int32) error {
+	if err := service.revocations.RevokeUser(id, time.Now()); err != nil {
+		return err
+	}
+
+	return revokeUserTokens(context.Background(), service.db, id)
+}
+
+// IssueTokens starts a session for the user on a device, and creates an access
+// token and a refresh token bound to it. The refresh token starts a new family,
+// which is what gets revoked if any of its tokens is ever reused or the session
+// is ended.
+func (service AuthService) IssueTokens(id int32, method string, device interfaces.Device) (*interfaces.TokenPair, error) {
+	familyID, err := common.Tokens.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO auth.session (user_id, family_id, auth_method, device_name, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		id,
+		familyID,
+		method,
+		device.Name,
+		device.UserAgent,
+		device.IPAddress,
+		time.Now().Add(refreshTokenTTL()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := createRefreshToken(ctx, tx, id, familyID, method)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return service.createTokenPair(id, familyID, method, refreshToken)
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair.
+//
+// Refresh tokens are single use: every time one is exchanged it is marked as used
+// and a new one from the same family is returned (rotation). If a token that was
+// already used is presented again, it has most likely been stolen, so the whole
+// family is revoked and both the attacker and the legitimate user have to log in
+// again.
+func (service AuthService) RefreshTokens(refreshToken string) (*interfaces.TokenPair, error) {
+	ctx := context.Background()
+
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	// ======== RETRIEVE TOKEN ========
+	// The row is locked so that two concurrent refreshes with the same token
+	// cannot both succeed.
+	var (
+		tokenID    int32
+		userID     int32
+		familyID   string
+		authMethod string
+		expiresAt  time.Time
+		usedAt     *time.Time
+		revokedAt  *time.Time
+	)
+	err = tx.QueryRow(
+		ctx,
+		`SELECT id, user_id, family_id, auth_method, expires_at, used_at, revoked_at
+		FROM auth.refresh_token
+		WHERE token_hash = $1
+		FOR UPDATE;`,
+		common.Tokens.Hash(refreshToken),
+	).Scan(&tokenID, &userID, &familyID, &authMethod, &expiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.InvalidRefreshTokenException
+	} else if err != nil {
+		return nil, err
+	}
+
+	// ======== CHECK TOKEN ========
+	if revokedAt != nil {
+		return nil, interfaces.InvalidRefreshTokenException
+	}
+
+	if usedAt != nil {
+		// Reuse detected: revoke every token in the family.
+		if err := service.revokeReusedFamily(ctx, tx, familyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+
+		service.logger.Info("Refresh token reuse detected for user", userID, "- revoked its token family.")
+		return nil, interfaces.ReusedRefreshTokenException
+	}
+
+	if time.Now().After(expiresAt) {
+		return nil, interfaces.ExpiredRefreshTokenException
+	}
+
+	// ======== ROTATE TOKEN ========
+	_, err = tx.Exec(ctx, `UPDATE auth.refresh_token SET used_at = now() WHERE id = $1;`, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	newRefreshToken, err := createRefreshToken(ctx, tx, userID, familyID, authMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	// The session lives as long as its newest refresh token.
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE auth.session SET last_seen_at = now(), expires_at = $2 WHERE family_id = $1;`,
+		familyID,
+		time.Now().Add(refreshTokenTTL()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return service.createTokenPair(userID, familyID, authMethod, newRefreshToken)
+}
+
+// ======== PRIVATE METHODS ========
+
+// createTokenPair creates an access token for the user and bundles it with
+// the refresh token provided.
+func (service AuthService) createTokenPair(
+	id int32,
+	familyID string,
+	method string,
+	refreshToken string,
+) (*interfaces.TokenPair, error) {
+	principal, err := service.userPrincipal(id)
+	if err != nil {
+		return nil, err
+	}
+	principal.SessionID = familyID
+	principal.AuthMethod = method
+
+	accessToken, err := service.CreateToken(*principal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &interfaces.TokenPair{
+		AccessToken:  *accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL().Seconds()),
+	}, nil
+}
+
+// createToken signs an access token for the principal that expires once the
+// ttl elapses.
+func (service AuthService) createToken(principal interfaces.Principal, ttl time.Duration) (*string, error) {
+	key, err := service.keyring.SigningKey(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Every token gets a unique id (jti) so that it can be revoked.
+	principal.TokenID, err = common.Tokens.Generate()
+	if err != nil {
+		return nil, err
+	}
+	principal.IssuedAt = time.Now()
+	principal.ExpiresAt = principal.IssuedAt.Add(ttl)
+
+	tokenString, err := signToken(key, NewAccessClaims(principal), "")
 	if err != nil {
 		return nil, err
 	}
 
 	return &tokenString, nil
 }
+
+// userPrincipal returns the principal of a user with their roles and permissions,
+// and whether their email is verified. They are looked up every time a token is
+// created, so changes to them take effect on the next refresh.
+func (service AuthService) userPrincipal(id int32) (*interfaces.Principal, error) {
+	roles, permissions, err := service.roles.GetUserPermissions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var emailVerified bool
+	err = service.db.QueryRow(
+		context.Background(),
+		`SELECT email_verified_at IS NOT NULL FROM auth.user WHERE id = $1;`,
+		id,
+	).Scan(&emailVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	return &interfaces.Principal{
+		UserID:        id,
+		Roles:         roles,
+		Scopes:        permissions,
+		EmailVerified: emailVerified,
+	}, nil
+}
+
+// parseToken verifies the signature, expiration, issuer and audience of a token
+// and returns the principal it was issued for.
+func (service AuthService) parseToken(tokenString string) (*interfaces.Principal, error) {
+	// ParseWithClaims takes the token string and a function for looking up the key.
+	claims := AccessClaims{}
+	options := append(claimsParserOptions(), jwt.WithValidMethods(service.keyring.Methods()))
+	token, err := jwt.ParseWithClaims(tokenString, &claims, service.verificationKey, options...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	// Other tokens signed with the same keys (e.g. challenges) must never
+	// be accepted as access tokens.
+	if typ, _ := token.Header["typ"].(string); typ != "" && typ != "JWT" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	return claims.Principal()
+}
+
+// verificationKey returns the function that looks up the key a token was signed
+// with. The 'kid' in the head of the token identifies which key of the keyring
+// was used, and only the algorithm of that key is accepted to prevent algorithm
+// confusion.
+func (service AuthService) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := service.keyring.VerificationKey(kid, time.Now(), accessTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.PublicKey, nil
+}
+
+// signToken signs the claims with the key, setting the type of the token if
+// given.
+func signToken(key *SigningKey, claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(key.Method, claims)
+	// The kid header tells whoever verifies the token which key to use.
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+
+	return token.SignedString(key.PrivateKey)
+}
+
+// createRefreshToken generates a new refresh token for the family and stores its hash
+// along with the method the user authenticated with when the family was created.
+func createRefreshToken(ctx context.Context, db executor, userID int32, familyID string, method string) (string, error) {
+	token, err := common.Tokens.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(
+		ctx,
+		`INSERT INTO auth.refresh_token (user_id, family_id, auth_method, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5);`,
+		userID,
+		familyID,
+		method,
+		common.Tokens.Hash(token),
+		time.Now().Add(refreshTokenTTL()),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// revokeUserTokens revokes every refresh token of a user, both the ones of their
+// sessions and the ones issued to OAuth clients, and ends their sessions.
+func revokeUserTokens(ctx context.Context, db executor, id int32) error {
+	for _, query := range []string{
+		`UPDATE auth.refresh_token SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`,
+		`UPDATE auth.oauth_refresh_token SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`,
+		`UPDATE auth.session SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`,
+	} {
+		if _, err := db.Exec(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeReusedFamily revokes the tokens of a family whose refresh token was
+// reused: its refresh tokens, and the access tokens already issued to it, which
+// carry it as their session and do not outlive the access token lifetime.
+//
+// The access tokens are revoked in the cache before the transaction commits. If
+// it does not, they are only rejected sooner than they would have been.
+func (service AuthService) revokeReusedFamily(ctx context.Context, tx executor, familyID string) error {
+	if err := revokeRefreshTokenFamily(ctx, tx, familyID); err != nil {
+		return err
+	}
+	return service.revocations.revokeSession(ctx, tx, familyID, time.Now().Add(accessTokenTTL()))
+}
+
+// revokeRefreshTokenFamily revokes every token of a family that has not been
+// revoked yet, and ends the session the family belongs to.
+func revokeRefreshTokenFamily(ctx context.Context, db executor, familyID string) error {
+	_, err := db.Exec(
+		ctx,
+		`UPDATE auth.refresh_token SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL;`,
+		familyID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		ctx,
+		`UPDATE auth.session SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL;`,
+		familyID,
+	)
+	return err
+}
+
+// accessTokenTTL returns how long access tokens are valid for.
+func accessTokenTTL() time.Duration {
+	return common.Env.Duration("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// refreshTokenTTL returns how long refresh tokens are valid for.
+func refreshTokenTTL() time.Duration {
+	return common.Env.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
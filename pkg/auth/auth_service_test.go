@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAuthService returns an auth service that signs tokens with a secret
+// and keeps revocations in memory.
+func newTestAuthService(t *testing.T) AuthService {
+	t.Setenv("SECRET_KEY", "secret")
+
+	keyring, err := LoadKeyring("")
+	require.NoError(t, err)
+
+	return AuthService{
+		keyring:     keyring,
+		revocations: &RevocationStore{cache: newRevocationCache()},
+	}
+}
+
+// signClaims signs arbitrary claims with the secret of the test service.
+func signClaims(t *testing.T, claims jwt.Claims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthService_CreateAndCheckToken(t *testing.T) {
+	service := newTestAuthService(t)
+
+	token, err := service.CreateToken(interfaces.Principal{
+		UserID:     42,
+		SessionID:  "session",
+		Roles:      []string{"admin"},
+		Scopes:     []string{"users:read", "users:write"},
+		AuthMethod: interfaces.AuthMethodPassword,
+	})
+	require.NoError(t, err)
+
+	principal, err := service.CheckToken(*token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(42), principal.UserID)
+	assert.Equal(t, "session", principal.SessionID)
+	assert.Equal(t, []string{"admin"}, principal.Roles)
+	assert.Equal(t, []string{"users:read", "users:write"}, principal.Scopes)
+	assert.Equal(t, interfaces.AuthMethodPassword, principal.AuthMethod)
+	assert.NotEmpty(t, principal.TokenID)
+	assert.WithinDuration(t, time.Now(), principal.IssuedAt, time.Minute)
+}
+
+func TestAuthService_CheckToken_InvalidSubject(t *testing.T) {
+	service := newTestAuthService(t)
+	now := time.Now()
+
+	// Test case 1: A subject that is not a user id is rejected instead of panicking
+	token := signClaims(t, jwt.RegisteredClaims{
+		Subject:   "not-a-number",
+		ID:        "jti",
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	})
+	_, err := service.CheckToken(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidSubject)
+
+	// Test case 2: A token without subject is rejected too
+	token = signClaims(t, jwt.RegisteredClaims{
+		ID:        "jti",
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	})
+	_, err = service.CheckToken(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidSubject)
+}
+
+func TestAuthService_CheckToken_IssuerAndAudience(t *testing.T) {
+	service := newTestAuthService(t)
+	t.Setenv("JWT_ISSUER", "https://api.example.com")
+	t.Setenv("JWT_AUDIENCE", "example")
+
+	token, err := service.CreateToken(interfaces.Principal{UserID: 1})
+	require.NoError(t, err)
+
+	// Test case 1: The issuer and audience match
+	_, err = service.CheckToken(*token)
+	assert.NoError(t, err)
+
+	// Test case 2: Tokens meant for another audience are rejected
+	t.Setenv("JWT_AUDIENCE", "other")
+	_, err = service.CheckToken(*token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	// Test case 3: Tokens from another issuer are rejected
+	t.Setenv("JWT_AUDIENCE", "example")
+	t.Setenv("JWT_ISSUER", "https://evil.example.com")
+	_, err = service.CheckToken(*token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+}
+
+func TestAuthService_CheckToken_Revoked(t *testing.T) {
+	service := newTestAuthService(t)
+
+	token, err := service.CreateToken(interfaces.Principal{UserID: 1})
+	require.NoError(t, err)
+
+	principal, err := service.CheckToken(*token)
+	require.NoError(t, err)
+
+	service.revocations.cache.revokeToken(principal.TokenID, principal.ExpiresAt)
+	_, err = service.CheckToken(*token)
+	assert.ErrorIs(t, err, interfaces.RevokedTokenException)
+}
+
+func TestAuthService_CheckToken_RevokedSession(t *testing.T) {
+	service := newTestAuthService(t)
+
+	token, err := service.CreateToken(interfaces.Principal{UserID: 1, SessionID: "family"})
+	require.NoError(t, err)
+	other, err := service.CreateToken(interfaces.Principal{UserID: 1, SessionID: "other"})
+	require.NoError(t, err)
+
+	service.revocations.cache.revokeSession("family", time.Now().Add(time.Minute))
+
+	// Only the tokens of the revoked session are rejected
+	_, err = service.CheckToken(*token)
+	assert.ErrorIs(t, err, interfaces.RevokedTokenException)
+	_, err = service.CheckToken(*other)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_Challenge(t *testing.T) {
+	service := newTestAuthService(t)
+
+	challenge, err := service.CreateChallenge(42, interfaces.ChallengePurposeMFA)
+	require.NoError(t, err)
+
+	// Test case 1: The challenge is valid for its purpose
+	id, err := service.CheckChallenge(challenge, interfaces.ChallengePurposeMFA)
+	require.NoError(t, err)
+	assert.Equal(t, int32(42), id)
+
+	// Test case 2: But not for any other
+	_, err = service.CheckChallenge(challenge, "other")
+	assert.ErrorIs(t, err, interfaces.InvalidChallengeException)
+
+	// Test case 3: Challenges are not access tokens
+	_, err = service.CheckToken(challenge)
+	assert.Error(t, err)
+
+	// Test case 4: And access tokens are not challenges
+	token, err := service.CreateToken(interfaces.Principal{UserID: 42})
+	require.NoError(t, err)
+	_, err = service.CheckChallenge(*token, interfaces.ChallengePurposeMFA)
+	assert.ErrorIs(t, err, interfaces.InvalidChallengeException)
+}
+
+func TestAuthService_CheckToken_Actor(t *testing.T) {
+	service := newTestAuthService(t)
+
+	// Test case 1: The admin impersonating the user is carried in the act claim
+	token, err := service.CreateToken(interfaces.Principal{UserID: 42, ActorID: 7})
+	require.NoError(t, err)
+
+	claims := AccessClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(*token, &claims)
+	require.NoError(t, err)
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, "7", claims.Actor.Subject)
+
+	principal, err := service.CheckToken(*token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(42), principal.UserID)
+	assert.Equal(t, int32(7), principal.ActorID)
+	assert.True(t, principal.IsImpersonated())
+
+	// Test case 2: Tokens of the user themselves have no actor
+	token, err = service.CreateToken(interfaces.Principal{UserID: 42})
+	require.NoError(t, err)
+	principal, err = service.CheckToken(*token)
+	require.NoError(t, err)
+	assert.False(t, principal.IsImpersonated())
+
+	// Test case 3: An actor that is not a user id is rejected
+	now := time.Now()
+	forged := signClaims(t, AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			ID:        "jti",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Actor: &Actor{Subject: "admin"},
+	})
+	_, err = service.CheckToken(forged)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidClaims)
+}
+
+func TestAuthService_CheckToken_RevokedUser(t *testing.T) {
+	service := newTestAuthService(t)
+	// The user is revoked a second ago, as tokens issued in the future are
+	// not valid anyway.
+	revokedAt := time.Now().Add(-time.Second)
+
+	// Tokens only carry the second they were issued at, so the revocation is
+	// stored rounded down to the second, as RevokeUser does.
+	service.revocations.cache.revokeUser(1, userRevocation{
+		issuedBefore: revokedAt.Truncate(time.Second),
+		expiresAt:    revokedAt.Add(time.Minute),
+	})
+
+	claims := func(id string, issuedAt time.Time) string {
+		return signClaims(t, jwt.RegisteredClaims{
+			Subject:   "1",
+			ID:        id,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(revokedAt.Add(time.Minute)),
+		})
+	}
+
+	// Test case 1: A token issued in the same second as the revocation is revoked
+	_, err := service.CheckToken(claims("same", revokedAt))
+	assert.ErrorIs(t, err, interfaces.RevokedTokenException)
+
+	// Test case 2: And so is one issued in a second before it
+	_, err = service.CheckToken(claims("old", revokedAt.Add(-time.Second)))
+	assert.ErrorIs(t, err, interfaces.RevokedTokenException)
+
+	// Test case 3: But one issued in a second after it is valid
+	_, err = service.CheckToken(claims("new", revokedAt.Add(time.Second)))
+	assert.NoError(t, err)
+
+	// Test case 4: The tokens of other users are not affected
+	other, err := service.CreateToken(interfaces.Principal{UserID: 2})
+	require.NoError(t, err)
+	_, err = service.CheckToken(*other)
+	assert.NoError(t, err)
+}
+
+// recordingExecutor records the statements executed through it.
+type recordingExecutor struct {
+	statements []string
+	arguments  [][]any
+}
+
+func (executor *recordingExecutor) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	executor.statements = append(executor.statements, sql)
+	executor.arguments = append(executor.arguments, arguments)
+	return pgconn.CommandTag{}, nil
+}
+
+func TestAuthService_RevokeUserTokens(t *testing.T) {
+	executor := &recordingExecutor{}
+	require.NoError(t, revokeUserTokens(context.Background(), executor, 42))
+
+	// Every kind of refresh token of the user is revoked, including the ones
+	// of the OAuth clients they authorized, and their sessions are ended.
+	tables := []string{}
+	for i, statement := range executor.statements {
+		assert.Equal(t, []any{int32(42)}, executor.arguments[i])
+		tables = append(tables, strings.Fields(statement)[1])
+	}
+	assert.Equal(t, []string{"auth.refresh_token", "auth.oauth_refresh_token", "auth.session"}, tables)
+}
+
+func TestAuthService_RevokeReusedFamily(t *testing.T) {
+	service := newTestAuthService(t)
+
+	// The access tokens issued from a family carry it as their session.
+	token, err := service.CreateToken(interfaces.Principal{UserID: 1, SessionID: "family"})
+	require.NoError(t, err)
+	other, err := service.CreateToken(interfaces.Principal{UserID: 1, SessionID: "other"})
+	require.NoError(t, err)
+	_, err = service.CheckToken(*token)
+	require.NoError(t, err)
+
+	// Replaying a rotated refresh token of the family revokes it.
+	executor := &recordingExecutor{}
+	require.NoError(t, service.revokeReusedFamily(context.Background(), executor, "family"))
+
+	require.Len(t, executor.statements, 3)
+	assert.Contains(t, executor.statements[0], "UPDATE auth.refresh_token")
+	assert.Contains(t, executor.statements[1], "UPDATE auth.session")
+	assert.Contains(t, executor.statements[2], "INSERT INTO auth.revoked_session")
+	assert.Equal(t, "family", executor.arguments[2][0])
+
+	// Test case 1: The access tokens of the family are rejected right away
+	_, err = service.CheckToken(*token)
+	assert.ErrorIs(t, err, interfaces.RevokedTokenException)
+
+	// Test case 2: But the ones of other sessions are not affected
+	_, err = service.CheckToken(*other)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,429 @@
+/*
+Package Name: auth
+File Name: auth_sessions.go
+Abstract: The service that stores the sessions started by every login on the
+server, so that users can see where they are logged in and end any session.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// SessionsService service layer
+type SessionsService struct {
+	logger      lib.Logger
+	db          *lib.Database
+	roles       roles.RolesRepository
+	revocations *RevocationStore
+}
+
+// ======== CONSTANTS ========
+
+// sessionPrincipalPrefix is the prefix of the session id of the principals
+// authenticated with a cookie session, which is followed by the id of the session.
+const sessionPrincipalPrefix = "session:"
+
+// sessionLastSeenPrecision is how often the last use of a session is recorded,
+// so that busy sessions do not write to the database on every request.
+const sessionLastSeenPrecision = time.Minute
+
+// sessionFields are the fields of the sessions, in the order scanSession expects them.
+const sessionFields = `id, user_id, CASE WHEN family_id IS NULL THEN 'cookie' ELSE 'token' END, device_name,
+	auth_method, user_agent, ip_address, created_at, last_seen_at, expires_at,
+	COALESCE(family_id, ''), COALESCE(csrf_token_hash, '')`
+
+// sessionsQuery selects the fields of the sessions.
+const sessionsQuery = `SELECT ` + sessionFields + ` FROM auth.session`
+
+// ======== METHODS ========
+
+// GetSessionsService returns the sessions service, and schedules the removal of
+// the sessions that can no longer be used.
+func GetSessionsService(
+	logger lib.Logger,
+	db *lib.Database,
+	roles roles.RolesRepository,
+	revocations *RevocationStore,
+	scheduler *lib.Scheduler,
+) interfaces.SessionsRepository {
+	service := SessionsService{
+		logger:      logger,
+		db:          db,
+		roles:       roles,
+		revocations: revocations,
+	}
+
+	scheduler.Every(
+		"purge sessions",
+		common.Env.Duration("SESSIONS_PURGE_INTERVAL", time.Hour),
+		service.purge,
+	)
+
+	return service
+}
+
+// CreateSession starts a cookie session for the user. Only the hashes of the
+// session token and the CSRF token are stored.
+func (service SessionsService) CreateSession(
+	userID int32,
+	method string,
+	device interfaces.Device,
+) (*interfaces.Session, string, string, error) {
+	token, err := common.Tokens.Generate()
+	if err != nil {
+		return nil, "", "", err
+	}
+	csrfToken, err := common.Tokens.Generate()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	session, err := scanSession(service.db.QueryRow(
+		context.Background(),
+		`INSERT INTO auth.session
+			(user_id, token_hash, csrf_token_hash, auth_method, device_name, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+sessionFields+`;`,
+		userID,
+		common.Tokens.Hash(token),
+		common.Tokens.Hash(csrfToken),
+		method,
+		device.Name,
+		device.UserAgent,
+		device.IPAddress,
+		time.Now().Add(sessionTTL()),
+	))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	service.logger.Info("Started session", session.ID, "for user with id", userID)
+	return session, token, csrfToken, nil
+}
+
+// CheckSession validates the token of a cookie session and returns the identity
+// of its user. Cookie sessions expire after SESSION_TTL, or earlier if they are not used for
+// SESSION_IDLE_TIMEOUT. The roles and permissions of the user are looked up on
+// every request, so changes to them take effect immediately.
+func (service SessionsService) CheckSession(token string) (*interfaces.Principal, *interfaces.Session, error) {
+	session, err := scanSession(service.db.QueryRow(
+		context.Background(),
+		sessionsQuery+` WHERE token_hash = $1 AND revoked_at IS NULL
+			AND expires_at > now() AND last_seen_at > $2;`,
+		common.Tokens.Hash(token),
+		time.Now().Add(-sessionIdleTimeout()),
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, interfaces.InvalidSessionException
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	var emailVerified bool
+	err = service.db.QueryRow(
+		context.Background(),
+		`SELECT email_verified_at IS NOT NULL FROM auth.user WHERE id = $1;`,
+		session.UserID,
+	).Scan(&emailVerified)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roles, permissions, err := service.roles.GetUserPermissions(session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if time.Since(session.LastSeenAt) > sessionLastSeenPrecision {
+		service.touch(session.ID)
+	}
+
+	return &interfaces.Principal{
+		UserID:        session.UserID,
+		SessionID:     sessionPrincipalID(session.ID),
+		Roles:         roles,
+		Scopes:        permissions,
+		IssuedAt:      session.CreatedAt,
+		ExpiresAt:     session.ExpiresAt,
+		AuthMethod:    session.AuthMethod,
+		EmailVerified: emailVerified,
+	}, session, nil
+}
+
+// GetSessions returns the sessions of the user that can still be used, the most
+// recently used first. The last use of token sessions is when their tokens were
+// last refreshed.
+func (service SessionsService) GetSessions(userID int32) ([]interfaces.Session, error) {
+	rows, err := service.db.Query(
+		context.Background(),
+		sessionsQuery+` WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+			AND (family_id IS NOT NULL OR last_seen_at > $2)
+		ORDER BY last_seen_at DESC;`,
+		userID,
+		time.Now().Add(-sessionIdleTimeout()),
+	)
+	if err != nil {
+		service.logger.Error("Error while executing query. Err:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []interfaces.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			service.logger.Error("Error while iterating dataset. Err:", err)
+			return nil, err
+		}
+		results = append(results, *session)
+	}
+
+	return results, rows.Err()
+}
+
+// RevokeSession ends a session of the user. Cookie sessions stop working
+// immediately, and so do the tokens of token sessions, whose refresh tokens are
+// revoked along with the session.
+func (service SessionsService) RevokeSession(userID int32, id int32) error {
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	var familyID *string
+	err = tx.QueryRow(
+		ctx,
+		`UPDATE auth.session SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING family_id;`,
+		id,
+		userID,
+	).Scan(&familyID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return interfaces.SessionNotFoundException
+	} else if err != nil {
+		return err
+	}
+
+	if familyID != nil {
+		if err := revokeRefreshTokenFamily(ctx, tx, *familyID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// The access tokens already issued carry the family as their session, and
+	// none of them outlives the access token lifetime.
+	if familyID != nil {
+		if err := service.revocations.RevokeSession(*familyID, time.Now().Add(accessTokenTTL())); err != nil {
+			return err
+		}
+	}
+
+	service.logger.Info("Revoked session", id, "of user with id", userID)
+	return nil
+}
+
+// RevokeOtherSessions ends every session of a user except the current one, for
+// logging them out of every other device.
+func (service SessionsService) RevokeOtherSessions(userID int32, current interfaces.Principal) (int, error) {
+	sessions, err := service.GetSessions(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if isCurrentSession(session, current) {
+			continue
+		}
+		err := service.RevokeSession(userID, session.ID)
+		// Sessions can end while the others are being revoked.
+		if errors.Is(err, interfaces.SessionNotFoundException) {
+			continue
+		} else if err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// touch records that a session has just been used, which keeps it from timing
+// out. Failing to do so is not a reason for rejecting the request, so errors are
+// only logged.
+func (service SessionsService) touch(id int32) {
+	_, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.session SET last_seen_at = now() WHERE id = $1;`,
+		id,
+	)
+	if err != nil {
+		service.logger.Error("Could not record the use of session", id, "Err:", err)
+	}
+}
+
+// purge removes the sessions that have expired, timed out or been revoked. Only
+// cookie sessions time out, since token sessions are not seen between refreshes.
+func (service SessionsService) purge(ctx context.Context) error {
+	_, err := service.db.Exec(
+		ctx,
+		`DELETE FROM auth.session
+		WHERE expires_at < now() OR revoked_at IS NOT NULL OR (family_id IS NULL AND last_seen_at < $1);`,
+		time.Now().Add(-sessionIdleTimeout()),
+	)
+	return err
+}
+
+// scanSession scans a row selected with sessionFields.
+func scanSession(row pgx.Row) (*interfaces.Session, error) {
+	session := interfaces.Session{}
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.Kind,
+		&session.DeviceName,
+		&session.AuthMethod,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&session.FamilyID,
+		&session.CSRFTokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// sessionPrincipalID returns the session id of the principals authenticated
+// with a cookie session.
+func sessionPrincipalID(id int32) string {
+	return sessionPrincipalPrefix + strconv.Itoa(int(id))
+}
+
+// parseSessionPrincipalID returns the id of the cookie session a principal was
+// authenticated with, and whether it was authenticated with one at all.
+func parseSessionPrincipalID(principal interfaces.Principal) (int32, bool) {
+	if !strings.HasPrefix(principal.SessionID, sessionPrincipalPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(principal.SessionID, sessionPrincipalPrefix), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// browserNames and platformNames map the tokens found in user agents to the
+// names used for the devices of the sessions. The order matters, since most
+// browsers also claim to be the ones they are based on.
+var (
+	browserNames = [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	platformNames = [][2]string{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// deviceName guesses a name for the device of a session from its user agent,
+// such as "Chrome on macOS".
+func deviceName(userAgent string) string {
+	browser := ""
+	for _, name := range browserNames {
+		if strings.Contains(userAgent, name[0]) {
+			browser = name[1]
+			break
+		}
+	}
+	platform := ""
+	for _, name := range platformNames {
+		if strings.Contains(userAgent, name[0]) {
+			platform = name[1]
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	default:
+		return "Unknown device"
+	}
+}
+
+// sessionTTL returns how long cookie sessions last at most.
+func sessionTTL() time.Duration {
+	return common.Env.Duration("SESSION_TTL", 7*24*time.Hour)
+}
+
+// sessionIdleTimeout returns how long cookie sessions last without being used.
+func sessionIdleTimeout() time.Duration {
+	return common.Env.Duration("SESSION_IDLE_TIMEOUT", 24*time.Hour)
+}
@@ -0,0 +1,123 @@
+/*
+Package Name: auth
+File Name: auth_sessions_controller.go
+Abstract: The controller that lets users see the sessions they have started and end
+any of them.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// SessionsController struct
+type SessionsController struct {
+	logger   lib.Logger
+	sessions interfaces.SessionsRepository
+}
+
+// ======== METHODS ========
+
+// GetSessionsController retrieves a new sessions controller.
+func GetSessionsController(logger lib.Logger, sessions interfaces.SessionsRepository) SessionsController {
+	return SessionsController{
+		logger:   logger,
+		sessions: sessions,
+	}
+}
+
+// GetAll returns the active sessions of the authenticated user, marking the one
+// the request was made with.
+func (controller SessionsController) GetAll(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all sessions.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	sessions, err := controller.sessions.GetSessions(principal.UserID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = isCurrentSession(sessions[i], *principal)
+	}
+
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+// Revoke ends a session of the authenticated user. The tokens issued for the
+// session stop working immediately.
+func (controller SessionsController) Revoke(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Revoking session with id", ctx.Param("id"))
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	err = controller.sessions.RevokeSession(principal.UserID, int32(id))
+	if errors.Is(err, interfaces.SessionNotFoundException) {
+		ctx.AbortWithError(http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Ending the cookie session of the request logs the browser out.
+	if current, ok := parseSessionPrincipalID(*principal); ok && current == int32(id) {
+		clearSessionCookies(ctx)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully.",
+	})
+}
+
+// ======== PRIVATE METHODS ========
+
+// isCurrentSession reports whether a principal was authenticated with a session,
+// either with its cookie or with a token issued for it.
+func isCurrentSession(session interfaces.Session, principal interfaces.Principal) bool {
+	if session.Kind == interfaces.SessionKindToken {
+		return session.FamilyID != "" && session.FamilyID == principal.SessionID
+	}
+	return sessionPrincipalID(session.ID) == principal.SessionID
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionsController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	sessions := &mocks.MockSessionsService{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, &mocks.MockAuthService{}, &mocks.MockAPIKeysService{}, sessions, &mocks.MockImpersonationsService{})
+	sessionsController := GetSessionsController(logger, sessions)
+
+	api := router.Group("/").Use(authMiddleware.Handler(middlewares.RejectAPIKeys()))
+	api.GET("/sessions", sessionsController.GetAll)
+	api.DELETE("/sessions/:id", sessionsController.Revoke)
+
+	// The first session is the cookie session of the requests, the second one
+	// was started on another device.
+	sessions.CreateSession(1, interfaces.AuthMethodPassword, interfaces.Device{Name: "Firefox on Linux"})
+	sessions.CreateSession(1, interfaces.AuthMethodPassword, interfaces.Device{Name: "Safari on iOS"})
+	sessions.CreateSession(2, interfaces.AuthMethodPassword, interfaces.Device{Name: "Someone else"})
+
+	// request performs a request authenticated with the cookie session.
+	request := func(method string, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: interfaces.SessionCookie, Value: mocks.MockSessionToken})
+		req.Header.Set(interfaces.CSRFHeader, mocks.MockCSRFToken)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("GetAll", func(t *testing.T) {
+		w := request("GET", "/sessions")
+
+		var response []interfaces.Session
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		if assert.Len(t, response, 2) {
+			assert.Equal(t, "Firefox on Linux", response[0].DeviceName)
+			assert.True(t, response[0].Current)
+			assert.Equal(t, "Safari on iOS", response[1].DeviceName)
+			assert.False(t, response[1].Current)
+		}
+	})
+
+	t.Run("RevokeOther", func(t *testing.T) {
+		w := request("DELETE", "/sessions/2")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, sessions.Sessions, int32(2))
+		assert.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("RevokeNotFound", func(t *testing.T) {
+		// The sessions of other users cannot be revoked either.
+		w := request("DELETE", "/sessions/3")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, sessions.Sessions, int32(3))
+	})
+
+	t.Run("RevokeInvalidID", func(t *testing.T) {
+		w := request("DELETE", "/sessions/abc")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("RevokeCurrent", func(t *testing.T) {
+		w := request("DELETE", "/sessions/1")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, sessions.Sessions, int32(1))
+		for _, cookie := range w.Result().Cookies() {
+			assert.Equal(t, -1, cookie.MaxAge, cookie.Name)
+		}
+		assert.Len(t, w.Result().Cookies(), 2)
+
+		// The session cannot be used anymore.
+		w = request("GET", "/sessions")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
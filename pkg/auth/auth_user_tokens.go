@@ -0,0 +1,175 @@
+/*
+Package Name: auth
+File Name: auth_user_tokens.go
+Abstract: The service for issuing and consuming the single-use tokens sent to users by email.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+)
+
+// ======== TYPES ========
+
+// UserTokensService service layer
+type UserTokensService struct {
+	logger lib.Logger
+	db     *lib.Database
+}
+
+// ======== METHODS ========
+
+// GetUserTokensService returns the user tokens service, and schedules the
+// removal of the tokens that can no longer be used.
+func GetUserTokensService(
+	logger lib.Logger,
+	db *lib.Database,
+	scheduler *lib.Scheduler,
+) interfaces.UserTokensRepository {
+	service := UserTokensService{
+		logger: logger,
+		db:     db,
+	}
+
+	scheduler.Every(
+		"purge user tokens",
+		common.Env.Duration("USER_TOKENS_PURGE_INTERVAL", time.Hour),
+		service.purge,
+	)
+
+	return service
+}
+
+// IssueUserToken returns a new token for the user. Only its hash is stored, so
+// the token itself can only be sent to the user once.
+func (service UserTokensService) IssueUserToken(userID int32, purpose string, ttl time.Duration) (string, error) {
+	token, err := common.Tokens.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	// Only the last token sent to the user can be used.
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE auth.user_token SET used_at = now()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;`,
+		userID,
+		purpose,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO auth.user_token (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4);`,
+		userID,
+		purpose,
+		common.Tokens.Hash(token),
+		time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// CheckUserToken returns the user the token was issued to if it can still be
+// consumed, but does not mark it as used.
+func (service UserTokensService) CheckUserToken(token string, purpose string) (int32, error) {
+	var userID int32
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT user_id FROM auth.user_token
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now();`,
+		common.Tokens.Hash(token),
+		purpose,
+	).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, interfaces.InvalidUserTokenException
+	} else if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// ConsumeUserToken marks the token as used and returns the user it was issued to.
+// The token is checked and consumed in a single statement, so it cannot be used
+// twice even by concurrent requests.
+func (service UserTokensService) ConsumeUserToken(token string, purpose string) (int32, error) {
+	var userID int32
+	err := service.db.QueryRow(
+		context.Background(),
+		`UPDATE auth.user_token SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id;`,
+		common.Tokens.Hash(token),
+		purpose,
+	).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, interfaces.InvalidUserTokenException
+	} else if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// purge removes the tokens that have expired or have already been used.
+func (service UserTokensService) purge(ctx context.Context) error {
+	_, err := service.db.Exec(
+		ctx,
+		`DELETE FROM auth.user_token WHERE expires_at < now() OR used_at IS NOT NULL;`,
+	)
+	return err
+}
@@ -0,0 +1,157 @@
+/*
+Package Name: auth
+File Name: auth_verification_controller.go
+Abstract: The controller for verifying the email of the users.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// VerificationController struct
+type VerificationController struct {
+	logger       lib.Logger
+	usersService users.UsersRepository
+	userTokens   interfaces.UserTokensRepository
+	mailer       lib.Mailer
+}
+
+// ======== ERRORS ========
+var (
+	EmailAlreadyVerifiedException = errors.New("Your email has already been verified.")
+)
+
+// ======== METHODS ========
+
+// GetVerificationController retrieves a new verification controller.
+func GetVerificationController(
+	logger lib.Logger,
+	usersService users.UsersRepository,
+	userTokens interfaces.UserTokensRepository,
+	mailer lib.Mailer,
+) VerificationController {
+	return VerificationController{
+		logger:       logger,
+		usersService: usersService,
+		userTokens:   userTokens,
+		mailer:       mailer,
+	}
+}
+
+// Verify marks the email of the user a verification token was sent to as verified.
+// The tokens issued from then on will say so, so the client should refresh its
+// tokens after verifying the email.
+func (controller VerificationController) Verify(ctx *gin.Context) {
+	controller.logger.Info("[GET] Verify email route.")
+
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.AbortWithError(http.StatusBadRequest, interfaces.InvalidUserTokenException)
+		return
+	}
+
+	// ======== CONSUME TOKEN ========
+	userID, err := controller.userTokens.ConsumeUserToken(token, interfaces.TokenPurposeEmailVerification)
+	if errors.Is(err, interfaces.InvalidUserTokenException) {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := controller.usersService.MarkEmailVerified(userID); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully.",
+	})
+}
+
+// Resend sends a new verification email to the authenticated user.
+func (controller VerificationController) Resend(ctx *gin.Context) {
+	controller.logger.Info("[POST] Resend verification email route.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	user, err := controller.usersService.GetUserById(int(principal.UserID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		ctx.AbortWithError(http.StatusBadRequest, EmailAlreadyVerifiedException)
+		return
+	}
+
+	if err := controller.SendVerificationEmail(user.ID, user.Email, user.Username); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "A verification email has been sent.",
+	})
+}
+
+// SendVerificationEmail issues a verification token for the user and sends it
+// to their email. Any token sent before stops being valid.
+func (controller VerificationController) SendVerificationEmail(id int32, email string, username string) error {
+	ttl := common.Env.Duration("EMAIL_VERIFICATION_TOKEN_TTL", 24*time.Hour)
+	token, err := controller.userTokens.IssueUserToken(id, interfaces.TokenPurposeEmailVerification, ttl)
+	if err != nil {
+		return err
+	}
+
+	return controller.mailer.Send(lib.Mail{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease verify your email by following this link within the next %s:\n\n%s\n",
+			username,
+			ttl,
+			tokenLink(common.Env.String("EMAIL_VERIFICATION_URL", ""), token),
+		),
+	})
+}
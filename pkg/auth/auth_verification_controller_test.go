@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerificationController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	authService := &mocks.MockAuthService{}
+	usersService := &mocks.MockUsersService{}
+	userTokens := &mocks.MockUserTokensService{}
+	mailer := &mocks.MockMailer{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, &mocks.MockSessionsService{}, &mocks.MockImpersonationsService{})
+
+	verificationController := GetVerificationController(logger, usersService, userTokens, mailer)
+	authController := GetAuthController(logger, authService, usersService, verificationController, &mocks.MockMFAService{}, &mocks.MockLoginAttemptsService{}, &mocks.MockSessionsService{}, common.Hasher, common.DefaultPasswordPolicy())
+	router.POST("/signup", authController.Signup)
+	router.GET("/verify-email", verificationController.Verify)
+	router.Group("/").Use(authMiddleware.Handler()).POST("/verify-email/resend", verificationController.Resend)
+
+	var token string
+	t.Run("Signup", func(t *testing.T) {
+		jsonBody, _ := json.Marshal(SignupBody{
+			Username:        "user",
+			Email:           "user@example.com",
+			Password:        "correct horse battery staple",
+			ConfirmPassword: "correct horse battery staple",
+		})
+		req, _ := http.NewRequest("POST", "/signup", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, mailer.Sent, 1)
+		assert.Equal(t, "user@example.com", mailer.Sent[0].To)
+
+		for issued := range userTokens.Tokens {
+			token = issued
+		}
+		assert.Contains(t, mailer.Sent[0].Body, token)
+	})
+
+	t.Run("Resend", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/verify-email/resend", nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		require.Len(t, mailer.Sent, 2)
+		// The token sent last is the one used from now on.
+		token = ""
+		for issued, value := range userTokens.Tokens {
+			if token == "" || issued > token {
+				token = issued
+			}
+			assert.False(t, value.Used)
+		}
+		assert.Contains(t, mailer.Sent[1].Body, token)
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/verify-email?token="+token, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int32{1}, usersService.VerifiedUsers)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/verify-email?token="+token, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
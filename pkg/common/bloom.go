@@ -0,0 +1,166 @@
+/*
+Package Name: common
+File Name: bloom.go
+Abstract: A bloom filter for testing membership in large sets with a fixed amount of
+memory, and the format it is stored in files.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ======== TYPES ========
+
+// BloomFilter is a probabilistic set: Test never returns false for an item that
+// was added, but it returns true for an item that was not with a probability
+// chosen when creating the filter.
+type BloomFilter struct {
+	// bits are the m bits of the filter.
+	bits []byte
+	m    uint64
+	// k is how many bits are set for every item.
+	k uint32
+}
+
+// ======== CONSTANTS ========
+
+// bloomFilterMagic starts the files bloom filters are stored in. It is followed
+// by k as a big endian uint32, m as a big endian uint64 and the bits.
+const bloomFilterMagic = "BLOOM\x00\x00\x01"
+
+// bloomFilterMaxBits is the largest filter that can be read, 16 GiB.
+const bloomFilterMaxBits = 1 << 37
+
+// ======== ERRORS ========
+var (
+	InvalidBloomFilterException = errors.New("The file is not a valid bloom filter.")
+)
+
+// ======== PUBLIC METHODS ========
+
+// NewBloomFilter returns an empty filter sized for holding n items with the
+// false positive rate p (e.g. 0.001).
+func NewBloomFilter(n uint64, p float64) *BloomFilter {
+	if n == 0 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.001
+	}
+
+	// The optimal number of bits and hashes for n items and a rate of p.
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = (m + 7) / 8 * 8
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &BloomFilter{bits: make([]byte, m/8), m: m, k: k}
+}
+
+// ReadBloomFilter reads a filter written by WriteTo.
+func ReadBloomFilter(reader io.Reader) (*BloomFilter, error) {
+	reader = bufio.NewReader(reader)
+
+	header := make([]byte, len(bloomFilterMagic)+12)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, InvalidBloomFilterException
+	}
+	if string(header[:len(bloomFilterMagic)]) != bloomFilterMagic {
+		return nil, InvalidBloomFilterException
+	}
+
+	k := binary.BigEndian.Uint32(header[len(bloomFilterMagic):])
+	m := binary.BigEndian.Uint64(header[len(bloomFilterMagic)+4:])
+	if k == 0 || k > 64 || m == 0 || m%8 != 0 || m > bloomFilterMaxBits {
+		return nil, InvalidBloomFilterException
+	}
+
+	filter := &BloomFilter{bits: make([]byte, m/8), m: m, k: k}
+	if _, err := io.ReadFull(reader, filter.bits); err != nil {
+		return nil, fmt.Errorf("%w (%v)", InvalidBloomFilterException, err)
+	}
+
+	return filter, nil
+}
+
+// Add adds an item to the filter.
+func (filter *BloomFilter) Add(item []byte) {
+	h1, h2 := bloomHashes(item)
+	for i := uint64(0); i < uint64(filter.k); i++ {
+		bit := (h1 + i*h2) % filter.m
+		filter.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Test returns whether an item may have been added to the filter.
+func (filter *BloomFilter) Test(item []byte) bool {
+	h1, h2 := bloomHashes(item)
+	for i := uint64(0); i < uint64(filter.k); i++ {
+		bit := (h1 + i*h2) % filter.m
+		if filter.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo writes the filter in the format ReadBloomFilter reads.
+func (filter *BloomFilter) WriteTo(writer io.Writer) (int64, error) {
+	header := make([]byte, len(bloomFilterMagic)+12)
+	copy(header, bloomFilterMagic)
+	binary.BigEndian.PutUint32(header[len(bloomFilterMagic):], filter.k)
+	binary.BigEndian.PutUint64(header[len(bloomFilterMagic)+4:], filter.m)
+
+	n, err := writer.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	written, err := writer.Write(filter.bits)
+	return int64(n + written), err
+}
+
+// ======== PRIVATE METHODS ========
+
+// bloomHashes returns the two hashes the k bits of an item are derived from,
+// as described in "Less Hashing, Same Performance" by Kirsch and Mitzenmacher.
+func bloomHashes(item []byte) (uint64, uint64) {
+	sum := sha256.Sum256(item)
+	// The second hash is odd, so that it is never zero and the bits of an
+	// item are spread over the filter.
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]) | 1
+}
@@ -0,0 +1,87 @@
+/*
+Package Name: common
+File Name: bloom_test.go
+Abstract: Tests for the bloom filter.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomFilter_AddAndTest(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+
+	for i := 0; i < 1000; i++ {
+		filter.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+
+	// Test case 1: There are no false negatives
+	for i := 0; i < 1000; i++ {
+		assert.True(t, filter.Test([]byte(fmt.Sprintf("item-%d", i))))
+	}
+
+	// Test case 2: False positives are close to the rate the filter was sized for
+	positives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.Test([]byte(fmt.Sprintf("other-%d", i))) {
+			positives++
+		}
+	}
+	assert.Less(t, positives, 300)
+}
+
+func TestBloomFilter_WriteAndRead(t *testing.T) {
+	filter := NewBloomFilter(100, 0.001)
+	filter.Add([]byte("password"))
+
+	var buffer bytes.Buffer
+	written, err := filter.WriteTo(&buffer)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buffer.Len()), written)
+
+	// Test case 1: The filter read contains the same items
+	read, err := ReadBloomFilter(bytes.NewReader(buffer.Bytes()))
+	require.NoError(t, err)
+	assert.True(t, read.Test([]byte("password")))
+	assert.False(t, read.Test([]byte("correct horse battery staple")))
+
+	// Test case 2: Files that are not filters are rejected
+	_, err = ReadBloomFilter(bytes.NewReader([]byte("not a bloom filter")))
+	assert.ErrorIs(t, err, InvalidBloomFilterException)
+
+	// Test case 3: And so are truncated filters
+	_, err = ReadBloomFilter(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1]))
+	assert.ErrorIs(t, err, InvalidBloomFilterException)
+}
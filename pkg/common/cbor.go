@@ -0,0 +1,227 @@
+/*
+Package Name: common
+File Name: cbor.go
+Abstract: A minimal decoder of the CBOR data items (RFC 8949) used by WebAuthn
+authenticators, which only emit definite-length items.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ======== CONSTANTS ========
+
+// The major types of CBOR data items.
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+)
+
+// cborMaxDepth is how deeply arrays and maps can be nested, which keeps
+// malicious inputs from exhausting the stack.
+const cborMaxDepth = 16
+
+// ======== ERRORS ========
+var (
+	InvalidCBORException = errors.New("The CBOR data is not valid.")
+)
+
+// ======== PRIVATE METHODS ========
+
+// decodeCBOR decodes the first data item of the data, and returns it along
+// with the bytes that follow it. Integers are decoded as int64, byte strings
+// as []byte, text strings as string, arrays as []interface{}, maps as
+// map[interface{}]interface{}, floats as float64 and the simple values as
+// bool or nil. Tags are ignored and their content is returned instead.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+// decodeCBORItem decodes a data item nested depth levels deep.
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth || len(data) == 0 {
+		return nil, nil, InvalidCBORException
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	// Floats and simple values use the additional information differently.
+	if major == cborSimple {
+		return decodeCBORSimple(info, data)
+	}
+
+	argument, data, err := decodeCBORArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if argument > math.MaxInt64 {
+			return nil, nil, InvalidCBORException
+		}
+		return int64(argument), data, nil
+
+	case cborNegative:
+		if argument > math.MaxInt64 {
+			return nil, nil, InvalidCBORException
+		}
+		return -1 - int64(argument), data, nil
+
+	case cborBytes, cborText:
+		if argument > uint64(len(data)) {
+			return nil, nil, InvalidCBORException
+		}
+		value := make([]byte, argument)
+		copy(value, data[:argument])
+		if major == cborText {
+			return string(value), data[argument:], nil
+		}
+		return value, data[argument:], nil
+
+	case cborArray:
+		// Every item takes at least a byte, so longer arrays are not valid.
+		if argument > uint64(len(data)) {
+			return nil, nil, InvalidCBORException
+		}
+		array := make([]interface{}, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			var item interface{}
+			item, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			array = append(array, item)
+		}
+		return array, data, nil
+
+	case cborMap:
+		if argument > uint64(len(data)) {
+			return nil, nil, InvalidCBORException
+		}
+		result := make(map[interface{}]interface{}, argument)
+		for i := uint64(0); i < argument; i++ {
+			var key, value interface{}
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			// Only keys that can be compared are supported, which are
+			// the only ones WebAuthn uses.
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, InvalidCBORException
+			}
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			result[key] = value
+		}
+		return result, data, nil
+
+	default: // cborTag
+		return decodeCBORItem(data, depth+1)
+	}
+}
+
+// decodeCBORArgument decodes the argument of a data item, which is its value,
+// length or size depending on its type. Indefinite lengths are not supported.
+func decodeCBORArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+	return 0, nil, InvalidCBORException
+}
+
+// decodeCBORSimple decodes a float or a simple value.
+func decodeCBORSimple(info byte, data []byte) (interface{}, []byte, error) {
+	switch {
+	case info == 20:
+		return false, data, nil
+	case info == 21:
+		return true, data, nil
+	case info == 22 || info == 23:
+		// Both null and undefined.
+		return nil, data, nil
+	case info == 25 && len(data) >= 2:
+		return float16ToFloat64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), data[8:], nil
+	}
+	return nil, nil, InvalidCBORException
+}
+
+// float16ToFloat64 converts a half-precision float.
+func float16ToFloat64(bits uint16) float64 {
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+
+	if bits&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
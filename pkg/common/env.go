@@ -0,0 +1,105 @@
+/*
+Package Name: common
+File Name: env.go
+Abstract: Env provides helper functions for reading typed configuration
+values from the environment, falling back to sensible defaults when a
+variable is missing or malformed.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// ======== NAMESPACES ========
+
+// envT is used for creating a namespace
+type envT struct{}
+
+// the Env namespace
+var Env envT
+
+// ======== PUBLIC METHODS ========
+
+// Env.String returns the value of the environment variable or the
+// fallback if it is not set.
+func (envT) String(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Env.Duration parses the environment variable as a time.Duration
+// (e.g. "15m", "720h") and returns the fallback if it is missing or
+// cannot be parsed.
+func (envT) Duration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return duration
+}
+
+// Env.Int parses the environment variable as an int and returns the
+// fallback if it is missing or cannot be parsed.
+func (envT) Int(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return number
+}
+
+// Env.Bool parses the environment variable as a bool and returns the
+// fallback if it is missing or cannot be parsed.
+func (envT) Bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	boolean, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return boolean
+}
@@ -0,0 +1,683 @@
+/*
+Package Name: common
+File Name: filter.go
+Abstract: A small filter language for the routes that list items, in the style of
+RSQL/FIQL, which is compiled into parameterized SQL.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ======== NAMESPACES ========
+
+// filtersT is used for creating a namespace
+type filtersT struct{}
+
+// the Filters namespace
+var Filters filtersT
+
+// ======== TYPES ========
+
+// FilterType is the type of the values of a field that can be filtered by.
+type FilterType int
+
+// The types of fields.
+const (
+	FilterString FilterType = iota
+	FilterInteger
+	FilterDate
+	FilterTimestamp
+	FilterBoolean
+)
+
+// FilterField is a field a list can be filtered by.
+type FilterField struct {
+	// Name is how clients refer to the field in filters.
+	Name string
+	// Column is the field in SQL. It is never taken from the filter, so it
+	// can be any expression, like "u.email_verified_at IS NOT NULL".
+	Column string
+	Type   FilterType
+	// Operators are the operators allowed for the field. If empty, every
+	// operator that makes sense for its type is allowed.
+	Operators []string
+}
+
+// FilterNode is a node of the syntax tree of a filter: a FilterLogical or a
+// FilterComparison.
+type FilterNode interface {
+	// sql appends the condition of the node to a query.
+	sql(query *filterQuery) string
+}
+
+// FilterLogical joins the conditions of its children with AND or OR.
+type FilterLogical struct {
+	Operator string
+	Children []FilterNode
+}
+
+// FilterComparison compares a field with one or more values.
+type FilterComparison struct {
+	Field     string
+	Operator  string
+	Arguments []string
+	// Position is where the comparison starts in the filter.
+	Position int
+
+	// column and values are set when the comparison is validated.
+	column    string
+	values    []interface{}
+	wildcards bool
+}
+
+// Filter is a filter that has been parsed and checked against the fields of a
+// list, which can be turned into SQL.
+type Filter struct {
+	// Root is the root of the syntax tree, or nil if the filter is empty.
+	Root FilterNode
+}
+
+// filterParser is a recursive descent parser of filters.
+type filterParser struct {
+	input       []rune
+	position    int
+	depth       int
+	comparisons int
+}
+
+// filterSyntaxError is an error found while parsing a filter.
+type filterSyntaxError struct {
+	position int
+	message  string
+}
+
+// filterQuery holds the arguments of the SQL of a filter.
+type filterQuery struct {
+	first int
+	args  []interface{}
+}
+
+// ======== CONSTANTS ========
+
+// The operators of filters. "<", "<=", ">" and ">=" are aliases of the
+// comparison operators.
+const (
+	FilterEqual          = "=="
+	FilterNotEqual       = "!="
+	FilterLess           = "=lt="
+	FilterLessOrEqual    = "=le="
+	FilterGreater        = "=gt="
+	FilterGreaterOrEqual = "=ge="
+	FilterIn             = "=in="
+	FilterNotIn          = "=out="
+)
+
+// filterAliases are the alternative spellings of some operators.
+var filterAliases = map[string]string{
+	"<":  FilterLess,
+	"<=": FilterLessOrEqual,
+	">":  FilterGreater,
+	">=": FilterGreaterOrEqual,
+}
+
+// filterOperatorsSQL are the SQL operators of the comparison operators.
+var filterOperatorsSQL = map[string]string{
+	FilterEqual:          "=",
+	FilterNotEqual:       "<>",
+	FilterLess:           "<",
+	FilterLessOrEqual:    "<=",
+	FilterGreater:        ">",
+	FilterGreaterOrEqual: ">=",
+}
+
+// The limits of filters, which keep them from being too expensive to parse
+// or to run.
+const (
+	filterMaxLength      = 2000
+	filterMaxDepth       = 10
+	filterMaxComparisons = 25
+	filterMaxArguments   = 100
+)
+
+// filterReserved are the characters that cannot appear in unquoted values.
+const filterReserved = `"'();,=!~<>`
+
+// ======== PUBLIC METHODS ========
+
+// Filters.ParseQuery reads the filter parameter of a request, with the syntax
+// of ParseFilter. If the filter is not valid, it returns the errors in the same
+// format as Validation.ValidateBody.
+func (filtersT) ParseQuery(ctx *gin.Context, fields []FilterField) (*Filter, *gin.H) {
+	filter, messages := ParseFilter(ctx.Query("filter"), fields)
+	if len(messages) > 0 {
+		return nil, &gin.H{"errors": messages}
+	}
+	return filter, nil
+}
+
+// ParseFilter parses a filter and checks it against the fields of a list. The
+// filter is made of comparisons like "username==ale*", joined with ";" (and)
+// or "," (or), which can be grouped with parentheses. And takes precedence
+// over or.
+//
+// The operators are == and != (which match patterns with * on strings), =lt=
+// (or <), =le= (or <=), =gt= (or >), =ge= (or >=), and =in= and =out=, which
+// take a list of values like "id=in=(1,2,3)". Values with reserved characters
+// or spaces can be quoted with ' or ".
+//
+// Syntax errors are reported on the "filter" field, and errors about the
+// comparison of a field on "filter.<field>".
+func ParseFilter(expression string, fields []FilterField) (*Filter, []ValidationErrorMessage) {
+	if strings.TrimSpace(expression) == "" {
+		return &Filter{}, nil
+	}
+	if len(expression) > filterMaxLength {
+		return nil, []ValidationErrorMessage{{
+			Field:   "filter",
+			Message: fmt.Sprintf("The filter cannot be longer than %d characters.", filterMaxLength),
+		}}
+	}
+
+	parser := filterParser{input: []rune(expression)}
+	root, err := parser.parse()
+	if err != nil {
+		return nil, []ValidationErrorMessage{{
+			Field:   "filter",
+			Message: fmt.Sprintf("The filter is not valid at position %d: %s.", err.position+1, err.message),
+		}}
+	}
+
+	messages := []ValidationErrorMessage{}
+	validateFilterNode(root, fields, &messages)
+	if len(messages) > 0 {
+		return nil, messages
+	}
+
+	return &Filter{Root: root}, nil
+}
+
+// SQL returns the condition of a WHERE clause that selects the items that
+// match the filter, along with its arguments, whose placeholders start at
+// $first. The values of the filter are never part of the condition.
+func (filter *Filter) SQL(first int) (string, []interface{}) {
+	if filter == nil || filter.Root == nil {
+		return "TRUE", nil
+	}
+
+	query := filterQuery{first: first}
+	return filter.Root.sql(&query), query.args
+}
+
+// ======== PRIVATE METHODS ========
+
+// ======== PARSER ========
+
+// parse parses the whole filter.
+func (parser *filterParser) parse() (FilterNode, *filterSyntaxError) {
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	if parser.position < len(parser.input) {
+		return nil, parser.errorf("unexpected '%c'", parser.input[parser.position])
+	}
+	return node, nil
+}
+
+// parseOr parses comparisons and groups joined with ",".
+func (parser *filterParser) parseOr() (FilterNode, *filterSyntaxError) {
+	return parser.parseLogical("OR", ',', parser.parseAnd)
+}
+
+// parseAnd parses comparisons and groups joined with ";".
+func (parser *filterParser) parseAnd() (FilterNode, *filterSyntaxError) {
+	return parser.parseLogical("AND", ';', parser.parseConstraint)
+}
+
+// parseLogical parses the operands of a logical operator.
+func (parser *filterParser) parseLogical(
+	operator string,
+	separator rune,
+	operand func() (FilterNode, *filterSyntaxError),
+) (FilterNode, *filterSyntaxError) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []FilterNode{first}
+	for parser.skipSpaces(); parser.peek() == separator; parser.skipSpaces() {
+		parser.position++
+		child, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &FilterLogical{Operator: operator, Children: children}, nil
+}
+
+// parseConstraint parses a group or a comparison.
+func (parser *filterParser) parseConstraint() (FilterNode, *filterSyntaxError) {
+	parser.skipSpaces()
+	if parser.peek() != '(' {
+		return parser.parseComparison()
+	}
+
+	parser.depth++
+	if parser.depth > filterMaxDepth {
+		return nil, parser.errorf("groups cannot be nested more than %d levels deep", filterMaxDepth)
+	}
+	parser.position++
+
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	if parser.peek() != ')' {
+		return nil, parser.errorf("expected ')'")
+	}
+	parser.position++
+	parser.depth--
+
+	return node, nil
+}
+
+// parseComparison parses a field, an operator and its arguments.
+func (parser *filterParser) parseComparison() (FilterNode, *filterSyntaxError) {
+	parser.comparisons++
+	if parser.comparisons > filterMaxComparisons {
+		return nil, parser.errorf("there cannot be more than %d comparisons", filterMaxComparisons)
+	}
+
+	// ======== FIELD ========
+	start := parser.position
+	for parser.position < len(parser.input) && isFilterFieldRune(parser.input[parser.position], parser.position == start) {
+		parser.position++
+	}
+	if parser.position == start {
+		return nil, parser.errorf("expected the name of a field")
+	}
+	comparison := &FilterComparison{Field: string(parser.input[start:parser.position]), Position: start}
+
+	// ======== OPERATOR ========
+	operator, err := parser.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+	comparison.Operator = operator
+
+	// ======== ARGUMENTS ========
+	if parser.peek() != '(' {
+		value, err := parser.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Arguments = []string{value}
+		return comparison, nil
+	}
+
+	parser.position++
+	for {
+		if len(comparison.Arguments) == filterMaxArguments {
+			return nil, parser.errorf("there cannot be more than %d values in a list", filterMaxArguments)
+		}
+		value, err := parser.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Arguments = append(comparison.Arguments, value)
+
+		if parser.peek() == ')' {
+			parser.position++
+			return comparison, nil
+		}
+		if parser.peek() != ',' {
+			return nil, parser.errorf("expected ',' or ')'")
+		}
+		parser.position++
+	}
+}
+
+// parseOperator parses an operator like "==" or "=in=", returning the canonical
+// spelling of its aliases.
+func (parser *filterParser) parseOperator() (string, *filterSyntaxError) {
+	rest := string(parser.input[parser.position:])
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(rest, operator) {
+			parser.position += len(operator)
+			if alias, ok := filterAliases[operator]; ok {
+				return alias, nil
+			}
+			return operator, nil
+		}
+	}
+
+	// Operators like =gt= are made of letters between equal signs.
+	if parser.peek() == '=' {
+		end := parser.position + 1
+		for end < len(parser.input) && unicode.IsLetter(parser.input[end]) {
+			end++
+		}
+		if end > parser.position+1 && end < len(parser.input) && parser.input[end] == '=' {
+			operator := string(parser.input[parser.position : end+1])
+			parser.position = end + 1
+			return operator, nil
+		}
+	}
+
+	return "", parser.errorf("expected an operator")
+}
+
+// parseValue parses a value, quoted or not.
+func (parser *filterParser) parseValue() (string, *filterSyntaxError) {
+	quote := parser.peek()
+	if quote != '"' && quote != '\'' {
+		start := parser.position
+		for parser.position < len(parser.input) && !isFilterReserved(parser.input[parser.position]) {
+			parser.position++
+		}
+		if parser.position == start {
+			return "", parser.errorf("expected a value")
+		}
+		return string(parser.input[start:parser.position]), nil
+	}
+
+	// Quoted values end at the next quote that is not escaped with \.
+	start := parser.position
+	parser.position++
+	var value strings.Builder
+	for parser.position < len(parser.input) {
+		char := parser.input[parser.position]
+		parser.position++
+		if char == quote {
+			return value.String(), nil
+		}
+		if char == '\\' && parser.position < len(parser.input) {
+			char = parser.input[parser.position]
+			parser.position++
+		}
+		value.WriteRune(char)
+	}
+
+	parser.position = start
+	return "", parser.errorf("the quoted value is not closed")
+}
+
+// peek returns the character at the current position, or 0 at the end.
+func (parser *filterParser) peek() rune {
+	if parser.position < len(parser.input) {
+		return parser.input[parser.position]
+	}
+	return 0
+}
+
+// skipSpaces skips the spaces between the comparisons and groups.
+func (parser *filterParser) skipSpaces() {
+	for parser.position < len(parser.input) && unicode.IsSpace(parser.input[parser.position]) {
+		parser.position++
+	}
+}
+
+// errorf returns a syntax error at the current position.
+func (parser *filterParser) errorf(format string, args ...interface{}) *filterSyntaxError {
+	return &filterSyntaxError{position: parser.position, message: fmt.Sprintf(format, args...)}
+}
+
+// isFilterFieldRune returns whether a character can be part of the name of a
+// field. Names start with a letter or an underscore.
+func isFilterFieldRune(char rune, first bool) bool {
+	if char == '_' || (char < unicode.MaxASCII && unicode.IsLetter(char)) {
+		return true
+	}
+	return !first && (char == '.' || (char >= '0' && char <= '9'))
+}
+
+// isFilterReserved returns whether a character cannot be part of an unquoted
+// value.
+func isFilterReserved(char rune) bool {
+	return unicode.IsSpace(char) || strings.ContainsRune(filterReserved, char)
+}
+
+// ======== VALIDATION ========
+
+// validateFilterNode checks the comparisons of a node against the fields of a
+// list, converting their arguments into values of the type of the field.
+func validateFilterNode(node FilterNode, fields []FilterField, messages *[]ValidationErrorMessage) {
+	if logical, ok := node.(*FilterLogical); ok {
+		for _, child := range logical.Children {
+			validateFilterNode(child, fields, messages)
+		}
+		return
+	}
+
+	comparison := node.(*FilterComparison)
+	invalid := func(format string, args ...interface{}) {
+		*messages = append(*messages, ValidationErrorMessage{
+			Field:   "filter." + comparison.Field,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	// ======== FIELD ========
+	var field *FilterField
+	names := make([]string, len(fields))
+	for i := range fields {
+		names[i] = fields[i].Name
+		if fields[i].Name == comparison.Field {
+			field = &fields[i]
+		}
+	}
+	if field == nil {
+		invalid("The list cannot be filtered by '%s'. Try one of: %s.", comparison.Field, strings.Join(names, ", "))
+		return
+	}
+
+	// ======== OPERATOR ========
+	allowed := field.Operators
+	if len(allowed) == 0 {
+		allowed = filterOperatorsOf(field.Type)
+	}
+	if !containsString(allowed, comparison.Operator) {
+		invalid("The operator %s cannot be used with '%s'. Try one of: %s.", comparison.Operator, field.Name, strings.Join(allowed, ", "))
+		return
+	}
+
+	list := comparison.Operator == FilterIn || comparison.Operator == FilterNotIn
+	if !list && len(comparison.Arguments) > 1 {
+		invalid("The operator %s takes a single value.", comparison.Operator)
+		return
+	}
+
+	// ======== VALUES ========
+	comparison.column = field.Column
+	comparison.values = make([]interface{}, len(comparison.Arguments))
+	for i, argument := range comparison.Arguments {
+		// Patterns are matched with LIKE, with * as the wildcard.
+		if field.Type == FilterString && !list && strings.Contains(argument, "*") &&
+			(comparison.Operator == FilterEqual || comparison.Operator == FilterNotEqual) {
+			comparison.wildcards = true
+			comparison.values[i] = likePattern(argument)
+			continue
+		}
+
+		value, err := parseFilterValue(field.Type, argument)
+		if err != nil {
+			invalid("'%s' is not a valid value for '%s': %s.", argument, field.Name, err.Error())
+			return
+		}
+		comparison.values[i] = value
+	}
+}
+
+// filterOperatorsOf returns the operators that make sense for a type.
+func filterOperatorsOf(kind FilterType) []string {
+	if kind == FilterBoolean {
+		return []string{FilterEqual, FilterNotEqual}
+	}
+	return []string{
+		FilterEqual, FilterNotEqual,
+		FilterLess, FilterLessOrEqual, FilterGreater, FilterGreaterOrEqual,
+		FilterIn, FilterNotIn,
+	}
+}
+
+// parseFilterValue converts the argument of a comparison into a value of the
+// type of its field.
+func parseFilterValue(kind FilterType, argument string) (interface{}, error) {
+	switch kind {
+	case FilterInteger:
+		value, err := strconv.ParseInt(argument, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("it must be an integer")
+		}
+		return value, nil
+	case FilterDate:
+		value, err := time.Parse("2006-01-02", argument)
+		if err != nil {
+			return nil, fmt.Errorf("it must be a date like 2006-01-02")
+		}
+		return value, nil
+	case FilterTimestamp:
+		if value, err := time.Parse(time.RFC3339, argument); err == nil {
+			return value, nil
+		}
+		value, err := time.Parse("2006-01-02", argument)
+		if err != nil {
+			return nil, fmt.Errorf("it must be a date like 2006-01-02 or a time like 2006-01-02T15:04:05Z")
+		}
+		return value, nil
+	case FilterBoolean:
+		value, err := strconv.ParseBool(argument)
+		if err != nil {
+			return nil, fmt.Errorf("it must be true or false")
+		}
+		return value, nil
+	default:
+		return argument, nil
+	}
+}
+
+// likePattern turns a value with * wildcards into a LIKE pattern, escaping the
+// characters LIKE would take as wildcards.
+func likePattern(value string) string {
+	return strings.ReplaceAll(escapeLike(value), "*", "%")
+}
+
+// containsString returns whether a list contains a string.
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ======== SQL ========
+
+// sql returns the condition of the children joined with the operator.
+func (logical *FilterLogical) sql(query *filterQuery) string {
+	conditions := make([]string, len(logical.Children))
+	for i, child := range logical.Children {
+		conditions[i] = child.sql(query)
+	}
+	return "(" + strings.Join(conditions, " "+logical.Operator+" ") + ")"
+}
+
+// sql returns the condition of the comparison, with placeholders for its values.
+func (comparison *FilterComparison) sql(query *filterQuery) string {
+	switch {
+	case comparison.Operator == FilterIn || comparison.Operator == FilterNotIn:
+		condition := fmt.Sprintf("%s = ANY(%s)", comparison.column, query.add(filterArray(comparison.values)))
+		if comparison.Operator == FilterNotIn {
+			return "NOT (" + condition + ")"
+		}
+		return condition
+	case comparison.wildcards:
+		operator := "LIKE"
+		if comparison.Operator == FilterNotEqual {
+			operator = "NOT LIKE"
+		}
+		return fmt.Sprintf(`%s %s %s ESCAPE '\'`, comparison.column, operator, query.add(comparison.values[0]))
+	default:
+		return fmt.Sprintf("%s %s %s", comparison.column, filterOperatorsSQL[comparison.Operator], query.add(comparison.values[0]))
+	}
+}
+
+// add adds an argument to the query and returns its placeholder.
+func (query *filterQuery) add(value interface{}) string {
+	query.args = append(query.args, value)
+	return fmt.Sprintf("$%d", query.first+len(query.args)-1)
+}
+
+// filterArray returns the values of a list as a slice of their type, which pgx
+// sends as an array.
+func filterArray(values []interface{}) interface{} {
+	switch values[0].(type) {
+	case int64:
+		array := make([]int64, len(values))
+		for i, value := range values {
+			array[i] = value.(int64)
+		}
+		return array
+	case time.Time:
+		array := make([]time.Time, len(values))
+		for i, value := range values {
+			array[i] = value.(time.Time)
+		}
+		return array
+	case bool:
+		array := make([]bool, len(values))
+		for i, value := range values {
+			array[i] = value.(bool)
+		}
+		return array
+	default:
+		array := make([]string, len(values))
+		for i, value := range values {
+			array[i] = value.(string)
+		}
+		return array
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Package Name: common
+File Name: filter_test.go
+Abstract: Tests for the filter language.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFilterFields are the fields of the lists of the tests.
+var testFilterFields = []FilterField{
+	{Name: "id", Column: "t.id", Type: FilterInteger},
+	{Name: "name", Column: "t.name", Type: FilterString},
+	{Name: "created_at", Column: "t.created_at", Type: FilterDate},
+	{Name: "active", Column: "t.active", Type: FilterBoolean},
+	{Name: "email", Column: "t.email", Type: FilterString, Operators: []string{FilterEqual}},
+}
+
+// compileFilter parses a filter against the test fields and returns its SQL.
+func compileFilter(t *testing.T, expression string, first int) (string, []interface{}) {
+	filter, messages := ParseFilter(expression, testFilterFields)
+	require.Empty(t, messages)
+	return filter.SQL(first)
+}
+
+func TestFilter_SQL(t *testing.T) {
+	date := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Test case 1: An empty filter selects everything
+	condition, args := compileFilter(t, "", 1)
+	assert.Equal(t, "TRUE", condition)
+	assert.Empty(t, args)
+
+	// Test case 2: And takes precedence over or, and the values are arguments
+	condition, args = compileFilter(t, "created_at>2023-01-01;name==ale*,id=le=5", 3)
+	assert.Equal(t, `((t.created_at > $3 AND t.name LIKE $4 ESCAPE '\') OR t.id <= $5)`, condition)
+	assert.Equal(t, []interface{}{date, "ale%", int64(5)}, args)
+
+	// Test case 3: Groups change the precedence
+	condition, _ = compileFilter(t, "id==1;(name!=bob,active==true)", 1)
+	assert.Equal(t, "(t.id = $1 AND (t.name <> $2 OR t.active = $3))", condition)
+
+	// Test case 4: Lists are sent as arrays
+	condition, args = compileFilter(t, "id=in=(1,2,3);name=out=('a b',\"c\")", 1)
+	assert.Equal(t, "(t.id = ANY($1) AND NOT (t.name = ANY($2)))", condition)
+	assert.Equal(t, []interface{}{[]int64{1, 2, 3}, []string{"a b", "c"}}, args)
+
+	// Test case 5: The wildcards of LIKE are escaped
+	condition, args = compileFilter(t, `name!="100%_\\*"`, 1)
+	assert.Equal(t, `t.name NOT LIKE $1 ESCAPE '\'`, condition)
+	assert.Equal(t, []interface{}{`100\%\_\\%`}, args)
+
+	// Test case 6: Values that would be SQL never make it into the condition
+	condition, args = compileFilter(t, `name=="x' OR 1=1 --"`, 1)
+	assert.Equal(t, "t.name = $1", condition)
+	assert.Equal(t, []interface{}{"x' OR 1=1 --"}, args)
+}
+
+func TestFilter_SyntaxErrors(t *testing.T) {
+	for expression, message := range map[string]string{
+		"name":             "The filter is not valid at position 5: expected an operator.",
+		"name==":           "The filter is not valid at position 7: expected a value.",
+		"name==a;":         "The filter is not valid at position 9: expected the name of a field.",
+		"(name==a":         "The filter is not valid at position 9: expected ')'.",
+		"name==a)":         "The filter is not valid at position 8: unexpected ')'.",
+		"name=='a":         "The filter is not valid at position 7: the quoted value is not closed.",
+		"id=in=(1,2":       "The filter is not valid at position 11: expected ',' or ')'.",
+		"1d==1":            "The filter is not valid at position 1: expected the name of a field.",
+		"name~=a":          "The filter is not valid at position 5: expected an operator.",
+		"((((((((((((a==b": "The filter is not valid at position 11: groups cannot be nested more than 10 levels deep.",
+	} {
+		_, messages := ParseFilter(expression, testFilterFields)
+		assert.Equal(t, []ValidationErrorMessage{{Field: "filter", Message: message}}, messages, expression)
+	}
+}
+
+func TestFilter_ValidationErrors(t *testing.T) {
+	// Test case 1: Every comparison is checked against the fields
+	_, messages := ParseFilter("password==secret;id==one,active=gt=true;email!=a", testFilterFields)
+	assert.Equal(t, []ValidationErrorMessage{
+		{
+			Field:   "filter.password",
+			Message: "The list cannot be filtered by 'password'. Try one of: id, name, created_at, active, email.",
+		},
+		{
+			Field:   "filter.id",
+			Message: "'one' is not a valid value for 'id': it must be an integer.",
+		},
+		{
+			Field:   "filter.active",
+			Message: "The operator =gt= cannot be used with 'active'. Try one of: ==, !=.",
+		},
+		{
+			Field:   "filter.email",
+			Message: "The operator != cannot be used with 'email'. Try one of: ==.",
+		},
+	}, messages)
+
+	// Test case 2: Dates must be dates, and only lists take several values
+	_, messages = ParseFilter("created_at<yesterday;id==(1,2)", testFilterFields)
+	assert.Equal(t, []ValidationErrorMessage{
+		{
+			Field:   "filter.created_at",
+			Message: "'yesterday' is not a valid value for 'created_at': it must be a date like 2006-01-02.",
+		},
+		{
+			Field:   "filter.id",
+			Message: "The operator == takes a single value.",
+		},
+	}, messages)
+}
@@ -6,7 +6,7 @@ strings with the argon2 algorithm.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/12/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -39,6 +39,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -99,17 +100,37 @@ var (
 
 // ======== PUBLIC METHODS ========
 
-// Hasher.hash returns a hash from a string.
-func (hasherT) Hash(from_string string) (encodedHash string, err error) {
-	// Set the parameters to be used by the argon2 algorithm.
-	params := &Parameters{
-		memory:      64 * 1024,
-		iterations:  3,
-		parallelism: 2,
+// NewParameters returns the parameters with the given cost, and the
+// recommended salt and key lengths.
+func NewParameters(memory uint32, iterations uint32, parallelism uint8) Parameters {
+	return Parameters{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
 		saltLength:  16,
 		keyLength:   32,
 	}
+}
+
+// Hasher.Parameters returns the parameters new hashes are created with, which
+// can be set for every environment with the ARGON2_MEMORY (in KiB),
+// ARGON2_ITERATIONS and ARGON2_PARALLELISM variables.
+func (hasherT) Parameters() Parameters {
+	return NewParameters(
+		uint32(Env.Int("ARGON2_MEMORY", 64*1024)),
+		uint32(Env.Int("ARGON2_ITERATIONS", 3)),
+		uint8(Env.Int("ARGON2_PARALLELISM", 2)),
+	)
+}
 
+// Hasher.hash returns a hash from a string.
+func (hasherT) Hash(from_string string) (encodedHash string, err error) {
+	return Hasher.HashWithParameters(from_string, Hasher.Parameters())
+}
+
+// Hasher.HashWithParameters returns a hash from a string using the
+// parameters given.
+func (hasherT) HashWithParameters(from_string string, params Parameters) (encodedHash string, err error) {
 	// Generate a random salt to be appended to the hash.
 	salt, err := generateRandomBytes(params.saltLength)
 	if err != nil {
@@ -173,8 +194,73 @@ func (hasherT) Compare(plaintext, encodedHash string) (matches bool, err error)
 	return false, nil
 }
 
+// Hasher.NeedsRehash returns whether a hash was created with weaker parameters
+// than the current ones, in which case the password should be hashed again the
+// next time it is available (i.e. when the user logs in).
+func (hasherT) NeedsRehash(encodedHash string) bool {
+	params, _, _, err := decode(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	current := Hasher.Parameters()
+	return params.memory < current.memory ||
+		params.iterations < current.iterations ||
+		params.parallelism != current.parallelism ||
+		params.saltLength < current.saltLength ||
+		params.keyLength < current.keyLength
+}
+
+// Hasher.Calibrate returns the parameters that take as close as possible to
+// the target to hash a password on the current machine, without exceeding it.
+//
+// As recommended by RFC 9106, the memory is set first (up to maxMemory) and
+// then as many iterations as fit in the target are added. If a single
+// iteration with maxMemory already takes longer, the memory is halved until it
+// does not.
+func (hasherT) Calibrate(target time.Duration, maxMemory uint32, parallelism uint8) Parameters {
+	params := NewParameters(maxMemory, 1, parallelism)
+
+	// ======== MEMORY ========
+	for params.memory > 8*uint32(parallelism) && measureHash(params) > target {
+		params.memory /= 2
+	}
+
+	// ======== ITERATIONS ========
+	for {
+		next := params
+		next.iterations++
+		if measureHash(next) > target {
+			return params
+		}
+		params = next
+	}
+}
+
+// Memory returns the memory used by the algorithm, in kibibytes.
+func (params Parameters) Memory() uint32 {
+	return params.memory
+}
+
+// Iterations returns the number of passes over the memory.
+func (params Parameters) Iterations() uint32 {
+	return params.iterations
+}
+
+// Parallelism returns the number of threads used by the algorithm.
+func (params Parameters) Parallelism() uint8 {
+	return params.parallelism
+}
+
 // ======== PRIVATE METHODS ========
 
+// measureHash returns how long it takes to hash a password with the parameters.
+func measureHash(params Parameters) time.Duration {
+	start := time.Now()
+	Hasher.HashWithParameters("calibration password", params)
+	return time.Since(start)
+}
+
 // GenerateRandomBytes generates a random salt that will be appended
 // to the hash.
 func generateRandomBytes(n uint32) ([]byte, error) {
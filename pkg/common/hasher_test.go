@@ -4,7 +4,7 @@ File Name: hasher_test.go
 Abstract: Tests for the hasher functions
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/26/2023
-Last Updated: 07/26/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -33,6 +33,7 @@ package common
 import (
 	"encoding/base64"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -99,3 +100,45 @@ func TestHasher_Decode_InvalidHash(t *testing.T) {
 	_, _, _, err = decode(encodedHash)
 	assert.EqualError(t, err, IncompatibleVersionException.Error())
 }
+
+func TestHasher_Parameters(t *testing.T) {
+	t.Setenv("ARGON2_MEMORY", "32768")
+	t.Setenv("ARGON2_ITERATIONS", "4")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+
+	hashedPassword, err := Hasher.Hash("mySecretPassword")
+	require.NoError(t, err)
+
+	params, _, _, err := decode(hashedPassword)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(32768), params.memory)
+	assert.Equal(t, uint32(4), params.iterations)
+	assert.Equal(t, uint8(1), params.parallelism)
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	t.Setenv("ARGON2_MEMORY", "16384")
+	t.Setenv("ARGON2_ITERATIONS", "2")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+
+	hashedPassword, err := Hasher.Hash("mySecretPassword")
+	require.NoError(t, err)
+
+	// Test case 1: Hashes with the current parameters are fine
+	assert.False(t, Hasher.NeedsRehash(hashedPassword))
+
+	// Test case 2: But not once the parameters are stronger
+	t.Setenv("ARGON2_ITERATIONS", "3")
+	assert.True(t, Hasher.NeedsRehash(hashedPassword))
+
+	// Test case 3: Hashes that cannot be decoded have to be replaced
+	assert.True(t, Hasher.NeedsRehash("invalidHash"))
+}
+
+func TestHasher_Calibrate(t *testing.T) {
+	params := Hasher.Calibrate(20*time.Millisecond, 4*1024, 1)
+
+	assert.LessOrEqual(t, params.Memory(), uint32(4*1024))
+	assert.GreaterOrEqual(t, params.Iterations(), uint32(1))
+	assert.Equal(t, uint8(1), params.Parallelism())
+}
@@ -0,0 +1,374 @@
+/*
+Package Name: common
+File Name: oidc.go
+Abstract: A generic OpenID Connect client that implements the authorization
+code flow with PKCE and verifies the ID tokens issued by the provider.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ======== TYPES ========
+
+// OIDCConfig is the configuration of a client registered with an OpenID
+// Connect provider.
+type OIDCConfig struct {
+	// Issuer is the URL of the provider, which is used for discovering its
+	// endpoints and must match the issuer of its ID tokens.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the user back with a code.
+	RedirectURL string
+	// Scopes are the scopes requested, "openid" is always included.
+	Scopes []string
+}
+
+// OIDCDiscovery is the part of the discovery document of a provider that is
+// used by the client.
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// OIDCClaims are the claims of an ID token used for identifying the user.
+type OIDCClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     bool   `json:"email_verified,omitempty"`
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+}
+
+// OIDCClient is a client of an OpenID Connect provider. The discovery
+// document and the keys of the provider are fetched when first needed and
+// cached, and the keys are fetched again when a token is signed with a key
+// that is not known yet.
+type OIDCClient struct {
+	config     OIDCConfig
+	httpClient *http.Client
+
+	mutex         sync.Mutex
+	discovery     *OIDCDiscovery
+	jwks          JWKS
+	jwksFetchedAt time.Time
+}
+
+// ======== CONSTANTS ========
+
+// oidcJwksRefreshInterval is how long the client waits before fetching the
+// keys of the provider again when it finds an unknown key, so that tokens
+// with made-up key ids cannot be used for flooding the provider.
+const oidcJwksRefreshInterval = time.Minute
+
+// oidcSigningMethods are the algorithms accepted for ID tokens. Symmetric
+// algorithms are not accepted, since they would be keyed with the client
+// secret.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// ======== ERRORS ========
+var (
+	OIDCDiscoveryException  = errors.New("The discovery document of the identity provider is not valid.")
+	OIDCExchangeException   = errors.New("The identity provider did not accept the authorization code.")
+	InvalidIDTokenException = errors.New("The ID token issued by the identity provider is not valid.")
+)
+
+// ======== PUBLIC METHODS ========
+
+// NewOIDCClient returns a client of the provider configured. Requests to the
+// provider are made with the HTTP client given, or the default one if nil.
+func NewOIDCClient(config OIDCConfig, httpClient *http.Client) *OIDCClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	return &OIDCClient{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+// Issuer returns the issuer of the provider.
+func (client *OIDCClient) Issuer() string {
+	return client.config.Issuer
+}
+
+// Discover returns the discovery document of the provider.
+func (client *OIDCClient) Discover(ctx context.Context) (*OIDCDiscovery, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.discover(ctx)
+}
+
+// AuthorizationURL returns the URL the user has to be sent to for logging in
+// with the provider. The state is returned unchanged along the code, the
+// nonce is included in the ID token, and the verifier is the PKCE code
+// verifier that has to be presented when exchanging the code.
+func (client *OIDCClient) AuthorizationURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	discovery, err := client.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", OIDCDiscoveryException
+	}
+
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", client.config.ClientID)
+	query.Set("redirect_uri", client.config.RedirectURL)
+	query.Set("scope", strings.Join(client.scopes(), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", PKCEChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	endpoint.RawQuery = query.Encode()
+
+	return endpoint.String(), nil
+}
+
+// Exchange exchanges an authorization code for the tokens of the user and
+// returns the ID token, which still has to be verified with VerifyIDToken.
+func (client *OIDCClient) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	discovery, err := client.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", client.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", client.config.ClientID)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if client.config.ClientSecret != "" {
+		// The credentials are form-encoded before being used for basic
+		// authentication (RFC 6749, section 2.3.1).
+		request.SetBasicAuth(url.QueryEscape(client.config.ClientID), url.QueryEscape(client.config.ClientSecret))
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w (%s)", OIDCExchangeException, response.Status)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w (%s: %s)", OIDCExchangeException, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("%w (no ID token was issued)", OIDCExchangeException)
+	}
+
+	return body.IDToken, nil
+}
+
+// VerifyIDToken checks the signature of an ID token against the keys of the
+// provider, along with its issuer, audience, expiration and nonce, and returns
+// its claims.
+func (client *OIDCClient) VerifyIDToken(ctx context.Context, idToken string, nonce string) (*OIDCClaims, error) {
+	claims := OIDCClaims{}
+	_, err := jwt.ParseWithClaims(
+		idToken,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return client.publicKey(ctx, kid)
+		},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(client.config.Issuer),
+		jwt.WithAudience(client.config.ClientID),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", InvalidIDTokenException, err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w (the expiration is missing)", InvalidIDTokenException)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w (the subject is missing)", InvalidIDTokenException)
+	}
+	// Tokens issued to several audiences must have been requested by the client.
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != client.config.ClientID {
+		return nil, fmt.Errorf("%w (the authorized party is not the client)", InvalidIDTokenException)
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w (the nonce does not match)", InvalidIDTokenException)
+	}
+
+	return &claims, nil
+}
+
+// PKCEChallenge returns the S256 code challenge of a PKCE code verifier.
+func PKCEChallenge(verifier string) string {
+	digest := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// ======== PRIVATE METHODS ========
+
+// scopes returns the scopes requested, making sure "openid" is one of them.
+func (client *OIDCClient) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range client.config.Scopes {
+		if scope != "openid" && scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// discover fetches the discovery document of the provider unless it has
+// already been fetched. The mutex must be held.
+func (client *OIDCClient) discover(ctx context.Context) (*OIDCDiscovery, error) {
+	if client.discovery != nil {
+		return client.discovery, nil
+	}
+
+	discovery := OIDCDiscovery{}
+	if err := client.getJSON(ctx, client.config.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+
+	// The issuer of the document must be the one configured, otherwise the
+	// tokens would be verified against the keys of another provider.
+	if discovery.Issuer != client.config.Issuer ||
+		discovery.AuthorizationEndpoint == "" ||
+		discovery.TokenEndpoint == "" ||
+		discovery.JwksURI == "" {
+		return nil, OIDCDiscoveryException
+	}
+
+	client.discovery = &discovery
+	return client.discovery, nil
+}
+
+// publicKey returns the key of the provider with the given id, fetching the
+// keys of the provider if it is not known yet.
+func (client *OIDCClient) publicKey(ctx context.Context, kid string) (interface{}, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	jwk, ok := client.findKey(kid)
+	if !ok && time.Since(client.jwksFetchedAt) > oidcJwksRefreshInterval {
+		discovery, err := client.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwks := JWKS{}
+		if err := client.getJSON(ctx, discovery.JwksURI, &jwks); err != nil {
+			return nil, err
+		}
+		client.jwks = jwks
+		client.jwksFetchedAt = time.Now()
+
+		jwk, ok = client.findKey(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("the key '%s' is not one of the keys of the provider", kid)
+	}
+
+	return jwk.PublicKey()
+}
+
+// findKey returns the key with the given id or, if the token does not say
+// which key signed it, the only key of the provider. The mutex must be held.
+func (client *OIDCClient) findKey(kid string) (*JWK, bool) {
+	if kid == "" {
+		if len(client.jwks.Keys) == 1 {
+			return &client.jwks.Keys[0], true
+		}
+		return nil, false
+	}
+	return client.jwks.Find(kid)
+}
+
+// getJSON fetches a JSON document from the provider.
+func (client *OIDCClient) getJSON(ctx context.Context, url string, value interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w (%s responded with %s)", OIDCDiscoveryException, url, response.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(value); err != nil {
+		return fmt.Errorf("%w (%v)", OIDCDiscoveryException, err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Package Name: common
+File Name: oidc_test.go
+Abstract: Tests for the OpenID Connect client, run against a mock provider.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// login goes through the authorization code flow with the provider, and returns
+// the ID token issued along with the nonce of the login.
+func login(t *testing.T, provider *mocks.MockOIDCProvider, client *common.OIDCClient) (string, string, error) {
+	ctx := context.Background()
+	state, nonce, verifier := "state", "nonce", "verifier-verifier-verifier-verifier-verifier"
+
+	authorizationURL, err := client.AuthorizationURL(ctx, state, nonce, verifier)
+	require.NoError(t, err)
+
+	code, returnedState, err := provider.Authorize(authorizationURL)
+	require.NoError(t, err)
+	assert.Equal(t, state, returnedState)
+
+	idToken, err := client.Exchange(ctx, code, verifier)
+	return idToken, nonce, err
+}
+
+func TestOIDCClient_AuthorizationURL(t *testing.T) {
+	provider := mocks.NewMockOIDCProvider()
+	defer provider.Close()
+	client := common.NewOIDCClient(provider.Config(), nil)
+
+	authorizationURL, err := client.AuthorizationURL(context.Background(), "state", "nonce", "verifier")
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+
+	assert.Equal(t, provider.Server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "state", query.Get("state"))
+	assert.Equal(t, "nonce", query.Get("nonce"))
+	assert.Equal(t, common.PKCEChallenge("verifier"), query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+}
+
+func TestOIDCClient_Login(t *testing.T) {
+	provider := mocks.NewMockOIDCProvider()
+	defer provider.Close()
+	client := common.NewOIDCClient(provider.Config(), nil)
+
+	idToken, nonce, err := login(t, provider, client)
+	require.NoError(t, err)
+
+	claims, err := client.VerifyIDToken(context.Background(), idToken, nonce)
+	require.NoError(t, err)
+	assert.Equal(t, "mock_subject", claims.Subject)
+	assert.Equal(t, "oidc@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "oidc", claims.PreferredUsername)
+
+	// Test case 2: The ID token is bound to the nonce of the login
+	_, err = client.VerifyIDToken(context.Background(), idToken, "other")
+	assert.ErrorIs(t, err, common.InvalidIDTokenException)
+}
+
+func TestOIDCClient_Exchange(t *testing.T) {
+	provider := mocks.NewMockOIDCProvider()
+	defer provider.Close()
+	ctx := context.Background()
+
+	// Test case 1: The code can only be redeemed with the verifier of the login
+	client := common.NewOIDCClient(provider.Config(), nil)
+	authorizationURL, err := client.AuthorizationURL(ctx, "state", "nonce", "verifier")
+	require.NoError(t, err)
+	code, _, err := provider.Authorize(authorizationURL)
+	require.NoError(t, err)
+
+	_, err = client.Exchange(ctx, code, "other")
+	assert.ErrorIs(t, err, common.OIDCExchangeException)
+
+	// Test case 2: Nor can it be redeemed twice
+	_, err = client.Exchange(ctx, code, "verifier")
+	assert.ErrorIs(t, err, common.OIDCExchangeException)
+
+	// Test case 3: The client must authenticate
+	config := provider.Config()
+	config.ClientSecret = "wrong"
+	_, _, err = login(t, provider, common.NewOIDCClient(config, nil))
+	assert.ErrorIs(t, err, common.OIDCExchangeException)
+}
+
+func TestOIDCClient_VerifyIDToken(t *testing.T) {
+	provider := mocks.NewMockOIDCProvider()
+	defer provider.Close()
+
+	tests := map[string]func(claims jwt.MapClaims){
+		"Issuer":          func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+		"Audience":        func(claims jwt.MapClaims) { claims["aud"] = "other_client" },
+		"AuthorizedParty": func(claims jwt.MapClaims) { claims["aud"] = []string{"mock_client", "other_client"} },
+		"Expired":         func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"NoExpiration":    func(claims jwt.MapClaims) { delete(claims, "exp") },
+		"NoSubject":       func(claims jwt.MapClaims) { delete(claims, "sub") },
+	}
+
+	for name, claims := range tests {
+		t.Run(name, func(t *testing.T) {
+			provider.Claims = claims
+			client := common.NewOIDCClient(provider.Config(), nil)
+
+			idToken, nonce, err := login(t, provider, client)
+			require.NoError(t, err)
+
+			_, err = client.VerifyIDToken(context.Background(), idToken, nonce)
+			assert.ErrorIs(t, err, common.InvalidIDTokenException)
+		})
+	}
+
+	t.Run("Signature", func(t *testing.T) {
+		provider.Claims = nil
+		client := common.NewOIDCClient(provider.Config(), nil)
+
+		// Tokens signed with the client secret are not accepted.
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iss":   provider.Server.URL,
+			"sub":   "mock_subject",
+			"aud":   provider.ClientID,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce",
+		})
+		idToken, err := token.SignedString([]byte(provider.ClientSecret))
+		require.NoError(t, err)
+
+		_, err = client.VerifyIDToken(context.Background(), idToken, "nonce")
+		assert.ErrorIs(t, err, common.InvalidIDTokenException)
+	})
+}
+
+func TestOIDCClient_Discover(t *testing.T) {
+	provider := mocks.NewMockOIDCProvider()
+	defer provider.Close()
+
+	// Test case 1: The discovery document is fetched from the issuer
+	discovery, err := common.NewOIDCClient(provider.Config(), nil).Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, provider.Server.URL+"/token", discovery.TokenEndpoint)
+
+	// Test case 2: The issuer of the document must be the one configured
+	config := provider.Config()
+	config.Issuer = provider.Server.URL + "/other"
+	_, err = common.NewOIDCClient(config, nil).Discover(context.Background())
+	assert.ErrorIs(t, err, common.OIDCDiscoveryException)
+}
@@ -0,0 +1,418 @@
+/*
+Package Name: common
+File Name: pagination.go
+Abstract: Keyset pagination for the routes that list items, with signed cursors.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ======== NAMESPACES ========
+
+// paginationT is used for creating a namespace
+type paginationT struct{}
+
+// the Pagination namespace
+var Pagination paginationT
+
+// ======== TYPES ========
+
+// SortField is a field a list can be sorted by.
+type SortField struct {
+	// Name is how clients refer to the field in the sort parameter.
+	Name string
+	// Column is the field in SQL. Its values must never be null.
+	Column string
+}
+
+// PageRequest is the page of a list a client asked for.
+type PageRequest struct {
+	// Limit is the maximum number of items of the page.
+	Limit int
+	// Sort is the field the list is sorted by, and Descending its direction.
+	// The id of the items breaks the ties.
+	Sort       SortField
+	Descending bool
+	// After and Before point to the item the page starts after or ends
+	// before. At most one of them is set.
+	After  *Cursor
+	Before *Cursor
+	// Total is whether the client asked for the number of items of the list.
+	Total bool
+}
+
+// Cursor points to an item of a list sorted in some order, so that pages keep
+// starting at the right item when items are added or removed before it.
+type Cursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	// Value is the value of the sort field of the item, and ID its id.
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+// OffsetRequest is the page of a list a client asked for by its offset. It is
+// meant for lists that cannot be paged with cursors, like search results
+// sorted by relevance.
+type OffsetRequest struct {
+	// Limit is the maximum number of items of the page, and Offset the number
+	// of items skipped before it.
+	Limit  int
+	Offset int
+}
+
+// Page is a page of a list.
+type Page[T any] struct {
+	Items []T
+	// Next and Previous are the cursors of the pages around this one, or
+	// empty if there are no items in that direction.
+	Next     string
+	Previous string
+	// Total is the number of items of the list, if it was asked for.
+	Total *int64
+}
+
+// ======== CONSTANTS ========
+
+// cursorMACSize is the length of the signature of cursors.
+const cursorMACSize = 16
+
+// ======== ERRORS ========
+var (
+	InvalidCursorException = errors.New("The cursor is not valid.")
+)
+
+// ======== PUBLIC METHODS ========
+
+// Pagination.ParsePage reads the page a client asked for from the query of a
+// request: limit, sort (the name of a field, starting with "-" for sorting in
+// descending order), after or before, and total. If any parameter is not valid,
+// it returns the errors in the same format as Validation.ValidateBody.
+func (paginationT) ParsePage(ctx *gin.Context, fields []SortField, defaultSort string) (*PageRequest, *gin.H) {
+	page := PageRequest{}
+	messages := []ValidationErrorMessage{}
+	invalid := func(field string, message string) {
+		messages = append(messages, ValidationErrorMessage{Field: field, Message: message})
+	}
+
+	// ======== LIMIT ========
+	maxLimit := Env.Int("PAGE_MAX_LIMIT", 100)
+	page.Limit = Env.Int("PAGE_DEFAULT_LIMIT", 20)
+	if limit := ctx.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxLimit {
+			invalid("limit", fmt.Sprintf("The limit must be a number between 1 and %d.", maxLimit))
+		}
+		page.Limit = value
+	}
+
+	// ======== SORT ========
+	sort := ctx.DefaultQuery("sort", defaultSort)
+	page.Descending = strings.HasPrefix(sort, "-")
+	name := strings.TrimPrefix(sort, "-")
+
+	names := make([]string, len(fields))
+	found := false
+	for i, field := range fields {
+		names[i] = field.Name
+		if field.Name == name {
+			page.Sort, found = field, true
+		}
+	}
+	if !found {
+		invalid("sort", fmt.Sprintf("The list cannot be sorted by '%s'. Try one of: %s.", name, strings.Join(names, ", ")))
+	}
+
+	// ======== CURSORS ========
+	after, before := ctx.Query("after"), ctx.Query("before")
+	if after != "" && before != "" {
+		invalid("before", "The after and before cursors cannot be sent together.")
+	}
+	for _, param := range []struct {
+		name   string
+		cursor **Cursor
+	}{{"after", &page.After}, {"before", &page.Before}} {
+		token := ctx.Query(param.name)
+		if token == "" {
+			continue
+		}
+		cursor, err := Pagination.DecodeCursor(token)
+		if err != nil {
+			invalid(param.name, err.Error())
+			continue
+		}
+		if found && (cursor.Sort != page.Sort.Name || cursor.Descending != page.Descending) {
+			invalid(param.name, "The cursor belongs to a list sorted in another order.")
+			continue
+		}
+		*param.cursor = cursor
+	}
+
+	// ======== TOTAL ========
+	if total := ctx.Query("total"); total != "" {
+		value, err := strconv.ParseBool(total)
+		if err != nil {
+			invalid("total", "The total must be true or false.")
+		}
+		page.Total = value
+	}
+
+	if len(messages) > 0 {
+		return nil, &gin.H{"errors": messages}
+	}
+	return &page, nil
+}
+
+// Pagination.ParseOffset reads the page a client asked for from the limit and
+// offset of the query of a request. Offsets are capped by PAGE_MAX_OFFSET, since
+// the database still has to go through every item skipped.
+func (paginationT) ParseOffset(ctx *gin.Context) (*OffsetRequest, *gin.H) {
+	request := OffsetRequest{Limit: Env.Int("PAGE_DEFAULT_LIMIT", 20)}
+	messages := []ValidationErrorMessage{}
+
+	maxLimit := Env.Int("PAGE_MAX_LIMIT", 100)
+	if limit := ctx.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxLimit {
+			messages = append(messages, ValidationErrorMessage{
+				Field:   "limit",
+				Message: fmt.Sprintf("The limit must be a number between 1 and %d.", maxLimit),
+			})
+		}
+		request.Limit = value
+	}
+
+	maxOffset := Env.Int("PAGE_MAX_OFFSET", 1000)
+	if offset := ctx.Query("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 || value > maxOffset {
+			messages = append(messages, ValidationErrorMessage{
+				Field:   "offset",
+				Message: fmt.Sprintf("The offset must be a number between 0 and %d.", maxOffset),
+			})
+		}
+		request.Offset = value
+	}
+
+	if len(messages) > 0 {
+		return nil, &gin.H{"errors": messages}
+	}
+	return &request, nil
+}
+
+// Pagination.EncodeCursor returns a cursor as an opaque token, signed so that
+// clients cannot change it.
+func (paginationT) EncodeCursor(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(append(signCursor(payload), payload...))
+}
+
+// Pagination.DecodeCursor returns the cursor of a token returned by EncodeCursor.
+func (paginationT) DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) < cursorMACSize {
+		return nil, InvalidCursorException
+	}
+
+	mac, payload := data[:cursorMACSize], data[cursorMACSize:]
+	if !hmac.Equal(mac, signCursor(payload)) {
+		return nil, InvalidCursorException
+	}
+
+	cursor := Cursor{}
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, InvalidCursorException
+	}
+	return &cursor, nil
+}
+
+// Keyset returns what a query needs for selecting the items of the page: the
+// condition of its WHERE clause, its ORDER BY clause, its LIMIT and the
+// arguments of the condition, whose placeholders start at $first.
+//
+// Instead of skipping the items of the pages before, which gets slower with
+// every page, the items are selected from the cursor on. One more item than
+// the limit is selected, so that NewPage can tell whether there are more.
+func (page PageRequest) Keyset(idColumn string, first int) (condition string, order string, limit int, args []interface{}) {
+	backward := page.Before != nil
+
+	// Pages before a cursor are selected in the opposite order, from the
+	// cursor backwards, and NewPage reverses them.
+	direction, operator := "ASC", ">"
+	if page.Descending != backward {
+		direction, operator = "DESC", "<"
+	}
+
+	condition = "TRUE"
+	cursor := page.After
+	if backward {
+		cursor = page.Before
+	}
+	if cursor != nil {
+		condition = fmt.Sprintf("(%s, %s) %s ($%d, $%d)", page.Sort.Column, idColumn, operator, first, first+1)
+		args = []interface{}{cursor.Value, cursor.ID}
+	}
+
+	order = fmt.Sprintf("%s %s, %s %s", page.Sort.Column, direction, idColumn, direction)
+	return condition, order, page.Limit + 1, args
+}
+
+// NewPage returns the page of the items selected with the keyset of a request.
+// The key returns the value of a sort field of an item and its id, which are
+// what the cursors of the page point to.
+func NewPage[T any](request PageRequest, items []T, key func(item T, field string) (string, int64)) Page[T] {
+	more := len(items) > request.Limit
+	if more {
+		items = items[:request.Limit]
+	}
+
+	backward := request.Before != nil
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := Page[T]{Items: items}
+	if len(items) == 0 {
+		return page
+	}
+
+	cursor := func(item T) string {
+		value, id := key(item, request.Sort.Name)
+		return Pagination.EncodeCursor(Cursor{
+			Sort:       request.Sort.Name,
+			Descending: request.Descending,
+			Value:      value,
+			ID:         id,
+		})
+	}
+
+	// There is at least the item of the cursor on the other side of it.
+	if (backward && more) || request.After != nil {
+		page.Previous = cursor(items[0])
+	}
+	if (!backward && more) || backward {
+		page.Next = cursor(items[len(items)-1])
+	}
+
+	return page
+}
+
+// SetHeaders sets the Link header of a response to the URLs of the pages around
+// the page, as in RFC 8288, and the X-Total-Count header to the number of items
+// of the list if it was counted.
+func (page Page[T]) SetHeaders(ctx *gin.Context) {
+	link := func(param string, cursor string) string {
+		query := ctx.Request.URL.Query()
+		query.Del("after")
+		query.Del("before")
+		query.Set(param, cursor)
+		return fmt.Sprintf("<%s?%s>", ctx.Request.URL.Path, query.Encode())
+	}
+
+	links := []string{}
+	if page.Next != "" {
+		links = append(links, link("after", page.Next)+`; rel="next"`)
+	}
+	if page.Previous != "" {
+		links = append(links, link("before", page.Previous)+`; rel="prev"`)
+	}
+	if len(links) > 0 {
+		ctx.Header("Link", strings.Join(links, ", "))
+	}
+
+	if page.Total != nil {
+		ctx.Header("X-Total-Count", strconv.FormatInt(*page.Total, 10))
+	}
+}
+
+// SetHeaders sets the Link header of a response to the URLs of the pages around
+// the page, as in RFC 8288. more is whether there are items after the page.
+func (request OffsetRequest) SetHeaders(ctx *gin.Context, more bool) {
+	link := func(offset int) string {
+		query := ctx.Request.URL.Query()
+		query.Set("offset", strconv.Itoa(offset))
+		return fmt.Sprintf("<%s?%s>", ctx.Request.URL.Path, query.Encode())
+	}
+
+	links := []string{}
+	if more {
+		links = append(links, link(request.Offset+request.Limit)+`; rel="next"`)
+	}
+	if request.Offset > 0 {
+		previous := request.Offset - request.Limit
+		if previous < 0 {
+			previous = 0
+		}
+		links = append(links, link(previous)+`; rel="prev"`)
+	}
+	if len(links) > 0 {
+		ctx.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+// ======== PRIVATE METHODS ========
+
+var (
+	// cursorFallbackKey signs cursors when no secret is configured. Cursors
+	// signed with it stop working when the API restarts.
+	cursorFallbackKey     []byte
+	cursorFallbackKeyOnce sync.Once
+)
+
+// signCursor returns the signature of the payload of a cursor. Cursors are
+// signed with CURSOR_SECRET, or SECRET_KEY if it is not set.
+func signCursor(payload []byte) []byte {
+	key := []byte(Env.String("CURSOR_SECRET", Env.String("SECRET_KEY", "")))
+	if len(key) == 0 {
+		cursorFallbackKeyOnce.Do(func() {
+			cursorFallbackKey = make([]byte, 32)
+			rand.Read(cursorFallbackKey)
+		})
+		key = cursorFallbackKey
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:cursorMACSize]
+}
@@ -0,0 +1,211 @@
+/*
+Package Name: common
+File Name: pagination_test.go
+Abstract: Tests for the keyset pagination.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSortFields are the fields of the lists of the tests.
+var testSortFields = []SortField{
+	{Name: "id", Column: "t.id"},
+	{Name: "name", Column: "t.name"},
+}
+
+// parseTestPage parses the page asked for by a request with the given query.
+func parseTestPage(query string) (*PageRequest, *gin.H) {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request, _ = http.NewRequest("GET", "/items?"+query, nil)
+	return Pagination.ParsePage(ctx, testSortFields, "id")
+}
+
+func TestPagination_Cursor(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "secret")
+	cursor := Cursor{Sort: "name", Descending: true, Value: "alice", ID: 42}
+
+	// Test case 1: Cursors can be decoded
+	token := Pagination.EncodeCursor(cursor)
+	decoded, err := Pagination.DecodeCursor(token)
+	require.NoError(t, err)
+	assert.Equal(t, cursor, *decoded)
+
+	// Test case 2: But not changed
+	tampered := []byte(token)
+	tampered[len(tampered)-2] ^= 1
+	_, err = Pagination.DecodeCursor(string(tampered))
+	assert.ErrorIs(t, err, InvalidCursorException)
+
+	// Test case 3: Nor used with another secret
+	t.Setenv("CURSOR_SECRET", "other")
+	_, err = Pagination.DecodeCursor(token)
+	assert.ErrorIs(t, err, InvalidCursorException)
+
+	_, err = Pagination.DecodeCursor("not a cursor")
+	assert.ErrorIs(t, err, InvalidCursorException)
+}
+
+func TestPagination_ParsePage(t *testing.T) {
+	// Test case 1: The defaults
+	page, errors := parseTestPage("")
+	require.Nil(t, errors)
+	assert.Equal(t, 20, page.Limit)
+	assert.Equal(t, "id", page.Sort.Name)
+	assert.False(t, page.Descending)
+	assert.Nil(t, page.After)
+	assert.False(t, page.Total)
+
+	// Test case 2: Every parameter
+	after := Pagination.EncodeCursor(Cursor{Sort: "name", Descending: true, Value: "bob", ID: 2})
+	page, errors = parseTestPage("limit=5&sort=-name&total=true&after=" + after)
+	require.Nil(t, errors)
+	assert.Equal(t, 5, page.Limit)
+	assert.Equal(t, "t.name", page.Sort.Column)
+	assert.True(t, page.Descending)
+	assert.Equal(t, "bob", page.After.Value)
+	assert.True(t, page.Total)
+
+	// Test case 3: Invalid parameters are reported in the format of the
+	// validation errors
+	_, errors = parseTestPage("limit=1000&sort=password&total=maybe")
+	require.NotNil(t, errors)
+	messages := (*errors)["errors"].([]ValidationErrorMessage)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "limit", messages[0].Field)
+	assert.Equal(t, "The list cannot be sorted by 'password'. Try one of: id, name.", messages[1].Message)
+	assert.Equal(t, "total", messages[2].Field)
+
+	// Test case 4: Cursors only work with the order they were issued for
+	_, errors = parseTestPage("sort=name&after=" + after)
+	require.NotNil(t, errors)
+	assert.Equal(t, "after", (*errors)["errors"].([]ValidationErrorMessage)[0].Field)
+
+	// Test case 5: And only one of them can be sent
+	_, errors = parseTestPage("sort=-name&after=" + after + "&before=" + after)
+	require.NotNil(t, errors)
+}
+
+func TestPagination_Keyset(t *testing.T) {
+	cursor := &Cursor{Sort: "name", Value: "bob", ID: 2}
+
+	// Test case 1: The first page
+	condition, order, limit, args := PageRequest{Limit: 10, Sort: testSortFields[1]}.Keyset("t.id", 1)
+	assert.Equal(t, "TRUE", condition)
+	assert.Equal(t, "t.name ASC, t.id ASC", order)
+	assert.Equal(t, 11, limit)
+	assert.Empty(t, args)
+
+	// Test case 2: The page after a cursor
+	condition, order, _, args = PageRequest{Limit: 10, Sort: testSortFields[1], After: cursor}.Keyset("t.id", 3)
+	assert.Equal(t, "(t.name, t.id) > ($3, $4)", condition)
+	assert.Equal(t, "t.name ASC, t.id ASC", order)
+	assert.Equal(t, []interface{}{"bob", int64(2)}, args)
+
+	// Test case 3: The page before a cursor is selected backwards
+	condition, order, _, _ = PageRequest{Limit: 10, Sort: testSortFields[1], Before: cursor}.Keyset("t.id", 1)
+	assert.Equal(t, "(t.name, t.id) < ($1, $2)", condition)
+	assert.Equal(t, "t.name DESC, t.id DESC", order)
+
+	// Test case 4: As are descending orders
+	condition, order, _, _ = PageRequest{Limit: 10, Sort: testSortFields[1], Descending: true, After: cursor}.Keyset("t.id", 1)
+	assert.Equal(t, "(t.name, t.id) < ($1, $2)", condition)
+	assert.Equal(t, "t.name DESC, t.id DESC", order)
+}
+
+func TestPagination_NewPage(t *testing.T) {
+	key := func(item int, field string) (string, int64) { return "", int64(item) }
+	request := PageRequest{Limit: 2, Sort: testSortFields[0]}
+
+	// Test case 1: The first page only has a next page
+	page := NewPage(request, []int{1, 2, 3}, key)
+	assert.Equal(t, []int{1, 2}, page.Items)
+	assert.Empty(t, page.Previous)
+	next, err := Pagination.DecodeCursor(page.Next)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), next.ID)
+
+	// Test case 2: The last page only has a previous page
+	request.After = next
+	page = NewPage(request, []int{3}, key)
+	assert.Equal(t, []int{3}, page.Items)
+	assert.Empty(t, page.Next)
+	assert.NotEmpty(t, page.Previous)
+
+	// Test case 3: Pages before a cursor are reversed back
+	request.After, request.Before = nil, &Cursor{ID: 3}
+	page = NewPage(request, []int{2, 1}, key)
+	assert.Equal(t, []int{1, 2}, page.Items)
+	assert.Empty(t, page.Previous)
+	assert.NotEmpty(t, page.Next)
+}
+
+func TestPagination_ParseOffset(t *testing.T) {
+	parse := func(query string) (*OffsetRequest, *gin.H) {
+		gin.SetMode(gin.TestMode)
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request, _ = http.NewRequest("GET", "/items?"+query, nil)
+		return Pagination.ParseOffset(ctx)
+	}
+
+	// Test case 1: The defaults
+	request, errors := parse("")
+	require.Nil(t, errors)
+	assert.Equal(t, OffsetRequest{Limit: 20, Offset: 0}, *request)
+
+	// Test case 2: A page in the middle links to the pages around it
+	request, errors = parse("q=ale&limit=10&offset=5")
+	require.Nil(t, errors)
+	assert.Equal(t, OffsetRequest{Limit: 10, Offset: 5}, *request)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest("GET", "/items?q=ale&limit=10&offset=5", nil)
+	request.SetHeaders(ctx, true)
+	assert.Equal(t,
+		`</items?limit=10&offset=15&q=ale>; rel="next", </items?limit=10&offset=0&q=ale>; rel="prev"`,
+		w.Header().Get("Link"),
+	)
+
+	// Test case 3: Invalid parameters are reported by field
+	_, errors = parse("limit=0&offset=-1")
+	require.NotNil(t, errors)
+	assert.Equal(t, []ValidationErrorMessage{
+		{Field: "limit", Message: "The limit must be a number between 1 and 100."},
+		{Field: "offset", Message: "The offset must be a number between 0 and 1000."},
+	}, (*errors)["errors"])
+}
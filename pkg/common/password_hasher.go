@@ -0,0 +1,266 @@
+/*
+Package Name: common
+File Name: password_hasher.go
+Abstract: The interface for password hashing algorithms, with argon2id, bcrypt and
+scrypt implementations and a hasher that dispatches between them.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ======== INTERFACES ========
+
+// PasswordHasher hashes passwords and checks them against their hashes.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of a password.
+	Hash(password string) (string, error)
+
+	// Compare returns whether a password matches an encoded hash.
+	Compare(password string, encodedHash string) (bool, error)
+
+	// NeedsRehash returns whether an encoded hash should be replaced
+	// by a new one the next time the password is available.
+	NeedsRehash(encodedHash string) bool
+}
+
+// ======== TYPES ========
+
+// bcryptHasher hashes passwords with bcrypt ($2a$, $2b$ and $2y$ hashes).
+type bcryptHasher struct{}
+
+// scryptHasher hashes passwords with scrypt, encoded in the PHC format used by
+// passlib: $scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<hash>
+type scryptHasher struct{}
+
+// multiHasher creates new hashes with the preferred algorithm, but can check
+// hashes of any of the supported ones.
+type multiHasher struct {
+	preferred string
+	hashers   map[string]PasswordHasher
+}
+
+// ======== CONSTANTS ========
+
+// The names of the supported algorithms.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmScrypt   = "scrypt"
+)
+
+// ======== ERRORS ========
+var (
+	UnsupportedAlgorithmException = errors.New("The password hashing algorithm is not supported.")
+)
+
+// ======== PUBLIC METHODS ========
+
+// NewPasswordHasher returns a hasher that creates new hashes with the preferred
+// algorithm and checks hashes of every supported algorithm, telling them apart
+// by their prefix. Hashes of any other algorithm need to be rehashed.
+func NewPasswordHasher(preferred string) (PasswordHasher, error) {
+	hasher := multiHasher{
+		preferred: preferred,
+		hashers: map[string]PasswordHasher{
+			AlgorithmArgon2id: Hasher,
+			AlgorithmBcrypt:   bcryptHasher{},
+			AlgorithmScrypt:   scryptHasher{},
+		},
+	}
+
+	if _, ok := hasher.hashers[preferred]; !ok {
+		return nil, UnsupportedAlgorithmException
+	}
+	return hasher, nil
+}
+
+// AlgorithmOf returns the algorithm an encoded hash was created with, or an
+// empty string if it is not supported.
+func AlgorithmOf(encodedHash string) string {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(encodedHash, "$2a$"),
+		strings.HasPrefix(encodedHash, "$2b$"),
+		strings.HasPrefix(encodedHash, "$2y$"):
+		return AlgorithmBcrypt
+	case strings.HasPrefix(encodedHash, "$scrypt$"):
+		return AlgorithmScrypt
+	}
+	return ""
+}
+
+// Hash returns the hash of the password using the preferred algorithm.
+func (hasher multiHasher) Hash(password string) (string, error) {
+	return hasher.hashers[hasher.preferred].Hash(password)
+}
+
+// Compare checks the password with the algorithm the hash was created with.
+func (hasher multiHasher) Compare(password string, encodedHash string) (bool, error) {
+	algorithm, ok := hasher.hashers[AlgorithmOf(encodedHash)]
+	if !ok {
+		return false, UnsupportedAlgorithmException
+	}
+	return algorithm.Compare(password, encodedHash)
+}
+
+// NeedsRehash returns whether the hash was not created with the preferred
+// algorithm or with weaker parameters than the current ones.
+func (hasher multiHasher) NeedsRehash(encodedHash string) bool {
+	if AlgorithmOf(encodedHash) != hasher.preferred {
+		return true
+	}
+	return hasher.hashers[hasher.preferred].NeedsRehash(encodedHash)
+}
+
+// Hash returns the bcrypt hash of the password, with the cost set in
+// BCRYPT_COST (12 by default).
+func (bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost())
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Compare returns whether the password matches the bcrypt hash.
+func (bcryptHasher) Compare(password string, encodedHash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NeedsRehash returns whether the hash has a lower cost than the current one.
+func (bcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost < bcryptCost()
+}
+
+// Hash returns the scrypt hash of the password, with the cost set in
+// SCRYPT_LN (log2 of N, 15 by default), SCRYPT_R (8) and SCRYPT_P (1).
+func (scryptHasher) Hash(password string) (string, error) {
+	ln, r, p := scryptParameters()
+
+	salt, err := generateRandomBytes(16)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := scrypt.Key([]byte(password), salt, 1<<ln, r, p, 32)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(
+		"$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		ln,
+		r,
+		p,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// Compare returns whether the password matches the scrypt hash.
+func (scryptHasher) Compare(password string, encodedHash string) (bool, error) {
+	ln, r, p, salt, hash, err := decodeScrypt(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	otherHash, err := scrypt.Key([]byte(password), salt, 1<<ln, r, p, len(hash))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(hash, otherHash) == 1, nil
+}
+
+// NeedsRehash returns whether the hash was created with weaker parameters
+// than the current ones.
+func (scryptHasher) NeedsRehash(encodedHash string) bool {
+	ln, r, p, _, _, err := decodeScrypt(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	currentLn, currentR, currentP := scryptParameters()
+	return ln < currentLn || r < currentR || p < currentP
+}
+
+// ======== PRIVATE METHODS ========
+
+// bcryptCost returns the cost of new bcrypt hashes.
+func bcryptCost() int {
+	return Env.Int("BCRYPT_COST", 12)
+}
+
+// scryptParameters returns the parameters of new scrypt hashes.
+func scryptParameters() (ln int, r int, p int) {
+	return Env.Int("SCRYPT_LN", 15), Env.Int("SCRYPT_R", 8), Env.Int("SCRYPT_P", 1)
+}
+
+// decodeScrypt extracts the parameters, salt and hash of an scrypt hash.
+func decodeScrypt(encodedHash string) (ln int, r int, p int, salt []byte, hash []byte, err error) {
+	vals := strings.Split(encodedHash, "$")
+	if len(vals) != 5 || vals[1] != "scrypt" {
+		return 0, 0, 0, nil, nil, InvalidHashException
+	}
+
+	if _, err = fmt.Sscanf(vals[2], "ln=%d,r=%d,p=%d", &ln, &r, &p); err != nil {
+		return 0, 0, 0, nil, nil, InvalidHashException
+	}
+	if ln < 1 || ln > 30 {
+		return 0, 0, 0, nil, nil, InvalidHashException
+	}
+
+	// passlib encodes the salt and hash with the standard alphabet without
+	// padding, which is what is accepted here.
+	if salt, err = base64.RawStdEncoding.DecodeString(vals[3]); err != nil {
+		return 0, 0, 0, nil, nil, InvalidHashException
+	}
+	if hash, err = base64.RawStdEncoding.DecodeString(vals[4]); err != nil || len(hash) == 0 {
+		return 0, 0, 0, nil, nil, InvalidHashException
+	}
+
+	return ln, r, p, salt, hash, nil
+}
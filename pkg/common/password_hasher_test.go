@@ -0,0 +1,114 @@
+/*
+Package Name: common
+File Name: password_hasher_test.go
+Abstract: Tests for the password hashing algorithms
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setCheapParameters makes every algorithm fast enough for testing.
+func setCheapParameters(t *testing.T) {
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+	t.Setenv("BCRYPT_COST", "4")
+	t.Setenv("SCRYPT_LN", "10")
+}
+
+func TestPasswordHasher_Algorithms(t *testing.T) {
+	setCheapParameters(t)
+
+	for _, algorithm := range []string{AlgorithmArgon2id, AlgorithmBcrypt, AlgorithmScrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher, err := NewPasswordHasher(algorithm)
+			require.NoError(t, err)
+
+			hashedPassword, err := hasher.Hash("mySecretPassword")
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, AlgorithmOf(hashedPassword))
+			assert.False(t, hasher.NeedsRehash(hashedPassword))
+
+			matches, err := hasher.Compare("mySecretPassword", hashedPassword)
+			assert.NoError(t, err)
+			assert.True(t, matches)
+
+			matches, err = hasher.Compare("wrongPassword", hashedPassword)
+			assert.NoError(t, err)
+			assert.False(t, matches)
+		})
+	}
+}
+
+func TestPasswordHasher_Dispatch(t *testing.T) {
+	setCheapParameters(t)
+
+	legacy, err := NewPasswordHasher(AlgorithmBcrypt)
+	require.NoError(t, err)
+	hasher, err := NewPasswordHasher(AlgorithmArgon2id)
+	require.NoError(t, err)
+
+	// Test case 1: Hashes of other algorithms can be checked, but need a rehash
+	legacyHash, err := legacy.Hash("mySecretPassword")
+	require.NoError(t, err)
+
+	matches, err := hasher.Compare("mySecretPassword", legacyHash)
+	assert.NoError(t, err)
+	assert.True(t, matches)
+	assert.True(t, hasher.NeedsRehash(legacyHash))
+
+	// Test case 2: Stronger parameters of the same algorithm also need a rehash
+	t.Setenv("BCRYPT_COST", "5")
+	assert.True(t, legacy.NeedsRehash(legacyHash))
+
+	// Test case 3: Unknown algorithms are rejected
+	_, err = hasher.Compare("mySecretPassword", "$1$salt$hash")
+	assert.ErrorIs(t, err, UnsupportedAlgorithmException)
+
+	_, err = NewPasswordHasher("md5")
+	assert.ErrorIs(t, err, UnsupportedAlgorithmException)
+}
+
+func TestPasswordHasher_DecodeScrypt(t *testing.T) {
+	ln, r, p, salt, hash, err := decodeScrypt("$scrypt$ln=16,r=8,p=1$Zm9v$MTIzNDU2")
+	require.NoError(t, err)
+	assert.Equal(t, 16, ln)
+	assert.Equal(t, 8, r)
+	assert.Equal(t, 1, p)
+	assert.Equal(t, []byte("foo"), salt)
+	assert.Equal(t, []byte("123456"), hash)
+
+	_, _, _, _, _, err = decodeScrypt("$scrypt$ln=16,r=8$Zm9v$MTIzNDU2")
+	assert.ErrorIs(t, err, InvalidHashException)
+}
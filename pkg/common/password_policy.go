@@ -0,0 +1,166 @@
+/*
+Package Name: common
+File Name: password_policy.go
+Abstract: The rules new passwords must follow, including not having appeared in a
+data breach.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// PasswordPolicy holds the rules new passwords must follow.
+type PasswordPolicy struct {
+	// MinLength and MaxLength bound the number of characters.
+	MinLength int
+	MaxLength int
+	// The classes of characters every password must contain.
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// MinStrength is the lowest score of EstimatePasswordStrength allowed.
+	MinStrength int
+	// History is how many of the last passwords of a user, including the
+	// current one, cannot be chosen again. Zero allows any.
+	History int
+	// Breached holds the SHA-1 hashes of passwords that have appeared in
+	// data breaches, as in the lists of Have I Been Pwned. It is nil if no
+	// list has been loaded.
+	Breached *BloomFilter
+}
+
+// ======== PUBLIC METHODS ========
+
+// DefaultPasswordPolicy returns the policy recommended by NIST SP 800-63B: at
+// least 8 characters, no composition rules and no guessable passwords.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:   8,
+		MaxLength:   128,
+		MinStrength: 2,
+		History:     5,
+	}
+}
+
+// Check returns the rules a password breaks, as messages for the user. The
+// password must not contain any of the identifiers of the user, such as their
+// username or email.
+func (policy PasswordPolicy) Check(password string, identifiers ...string) []string {
+	violations := []string{}
+
+	length := len([]rune(password))
+	if length < policy.MinLength {
+		violations = append(violations, fmt.Sprintf("The password must be at least %d characters long.", policy.MinLength))
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		violations = append(violations, fmt.Sprintf("The password must be at most %d characters long.", policy.MaxLength))
+	}
+
+	// ======== CHARACTER CLASSES ========
+	if policy.RequireLowercase && strings.IndexFunc(password, unicode.IsLower) < 0 {
+		violations = append(violations, "The password must contain a lowercase letter.")
+	}
+	if policy.RequireUppercase && strings.IndexFunc(password, unicode.IsUpper) < 0 {
+		violations = append(violations, "The password must contain an uppercase letter.")
+	}
+	if policy.RequireDigit && !containsClass(password, classDigit) {
+		violations = append(violations, "The password must contain a digit.")
+	}
+	if policy.RequireSymbol && !containsClass(password, classSymbol) {
+		violations = append(violations, "The password must contain a symbol.")
+	}
+
+	// ======== IDENTIFIERS ========
+	lower := strings.ToLower(password)
+	inputs := []string{}
+	for _, identifier := range identifiers {
+		identifier = strings.ToLower(identifier)
+		inputs = append(inputs, identifier)
+		// The local part of emails is often the name of the user.
+		if at := strings.LastIndex(identifier, "@"); at > 0 {
+			inputs = append(inputs, identifier[:at])
+		}
+	}
+	for _, input := range inputs {
+		if len([]rune(input)) >= 3 && strings.Contains(lower, input) {
+			violations = append(violations, "The password must not contain your username or email.")
+			break
+		}
+	}
+
+	// ======== GUESSABILITY ========
+	if policy.IsBreached(password) {
+		violations = append(violations, "The password has appeared in a data breach, so it must not be used.")
+	} else if EstimatePasswordStrength(password, inputs...) < policy.MinStrength {
+		violations = append(violations, "The password is too easy to guess. Try a longer one, or a few unrelated words.")
+	}
+
+	return violations
+}
+
+// Validate checks a password and returns the rules it breaks in the same format
+// as Validation.ValidateBody, or nil if it follows every rule.
+func (policy PasswordPolicy) Validate(field string, password string, identifiers ...string) *gin.H {
+	violations := policy.Check(password, identifiers...)
+	if len(violations) == 0 {
+		return nil
+	}
+	return Validation.FieldErrors(field, violations...)
+}
+
+// IsBreached returns whether a password is in the list of breached passwords. A
+// small fraction of the passwords that are not may be reported as breached too.
+func (policy PasswordPolicy) IsBreached(password string) bool {
+	if policy.Breached == nil {
+		return false
+	}
+	sum := sha1.Sum([]byte(password))
+	return policy.Breached.Test(sum[:])
+}
+
+// ======== PRIVATE METHODS ========
+
+// containsClass returns whether a password contains a character of a class.
+func containsClass(password string, class characterClass) bool {
+	for _, char := range password {
+		if classOf(char) == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+/*
+Package Name: common
+File Name: password_policy_test.go
+Abstract: Tests for the password policy and the strength estimator.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"crypto/sha1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimatePasswordStrength(t *testing.T) {
+	// Test case 1: Common passwords and their variations are guessed right away
+	assert.Equal(t, 0, EstimatePasswordStrength("password"))
+	assert.Equal(t, 0, EstimatePasswordStrength("password123"))
+	assert.Equal(t, 0, EstimatePasswordStrength("qwertyuiop"))
+	assert.Equal(t, 0, EstimatePasswordStrength("aaaaaaaaaa"))
+	assert.Equal(t, 0, EstimatePasswordStrength("abcdefgh"))
+
+	// Test case 2: The inputs of the user count as common words
+	assert.Less(t, EstimatePasswordStrength("alejandro2023", "alejandro"), EstimatePasswordStrength("alejandro2023"))
+
+	// Test case 3: Long and random passwords are strong
+	assert.Equal(t, 4, EstimatePasswordStrength("Tr0ub4dor&3"))
+	assert.Equal(t, 4, EstimatePasswordStrength("correct horse battery staple"))
+	assert.Equal(t, 4, EstimatePasswordStrength("x7#Kp!2vQz"))
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+
+	// Test case 1: Strong passwords follow the default policy
+	assert.Empty(t, policy.Check("correct horse battery staple", "user", "user@example.com"))
+
+	// Test case 2: Short and guessable passwords do not
+	assert.Contains(t, policy.Check("x7#Kp"), "The password must be at least 8 characters long.")
+	assert.Contains(t, policy.Check("password123"), "The password is too easy to guess. Try a longer one, or a few unrelated words.")
+
+	// Test case 3: Nor those containing the username or the email of the user
+	violations := policy.Check("xalejandrox7#Kp", "alejandro", "someone@example.com")
+	assert.Contains(t, violations, "The password must not contain your username or email.")
+	violations = policy.Check("x7#Kp!someone", "user", "someone@example.com")
+	assert.Contains(t, violations, "The password must not contain your username or email.")
+
+	// Test case 4: Character classes are only required when configured
+	policy.RequireUppercase = true
+	policy.RequireDigit = true
+	policy.RequireSymbol = true
+	violations = policy.Check("correct horse battery staple")
+	assert.Contains(t, violations, "The password must contain an uppercase letter.")
+	assert.Contains(t, violations, "The password must contain a digit.")
+	assert.NotContains(t, violations, "The password must contain a symbol.")
+
+	// Test case 5: As is the maximum length
+	policy = DefaultPasswordPolicy()
+	policy.MaxLength = 10
+	assert.Contains(t, policy.Check("correct horse battery staple"), "The password must be at most 10 characters long.")
+}
+
+func TestPasswordPolicy_Breached(t *testing.T) {
+	filter := NewBloomFilter(10, 0.0001)
+	sum := sha1.Sum([]byte("correct horse battery staple"))
+	filter.Add(sum[:])
+
+	policy := DefaultPasswordPolicy()
+	assert.False(t, policy.IsBreached("correct horse battery staple"))
+
+	// Passwords in the list are rejected even when they look strong
+	policy.Breached = filter
+	assert.True(t, policy.IsBreached("correct horse battery staple"))
+	assert.Equal(t,
+		[]string{"The password has appeared in a data breach, so it must not be used."},
+		policy.Check("correct horse battery staple"),
+	)
+	assert.Empty(t, policy.Check("x7#Kp!2vQz"))
+
+	// The violations are returned in the format of the validation errors
+	errors := policy.Validate("password", "correct horse battery staple")
+	require.NotNil(t, errors)
+	assert.Equal(t, []ValidationErrorMessage{{
+		Field:   "password",
+		Message: "The password has appeared in a data breach, so it must not be used.",
+	}}, (*errors)["errors"])
+	assert.Nil(t, policy.Validate("password", "x7#Kp!2vQz"))
+}
@@ -0,0 +1,277 @@
+/*
+Package Name: common
+File Name: password_strength.go
+Abstract: Estimates how hard a password is to guess by looking for the patterns
+people use when choosing them.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// ======== TYPES ========
+
+// characterClass is a class of characters, like lowercase letters or digits.
+type characterClass int
+
+// ======== CONSTANTS ========
+
+// The classes of characters.
+const (
+	classLowercase characterClass = iota
+	classUppercase
+	classDigit
+	classSymbol
+	classOther
+)
+
+// classPools are the number of characters of every class, which are the possible
+// values of a character of the class chosen at random.
+var classPools = map[characterClass]float64{
+	classLowercase: 26,
+	classUppercase: 26,
+	classDigit:     10,
+	classSymbol:    33,
+	classOther:     100,
+}
+
+// commonPasswordWords are the words most often found in passwords, from the most
+// to the least common. They are only a fallback for when no list of breached
+// passwords is loaded, which covers them and many more.
+var commonPasswordWords = []string{
+	"password", "qwerty", "letmein", "welcome", "admin", "login", "master",
+	"dragon", "monkey", "football", "baseball", "iloveyou", "sunshine",
+	"princess", "shadow", "superman", "trustno", "hello", "freedom",
+	"whatever", "secret", "passw0rd", "starwars", "computer", "michael",
+	"charlie", "summer", "winter", "love", "money", "test", "guest", "root",
+	"user", "changeme", "default", "access", "batman", "soccer", "hockey",
+	"killer", "pepper", "cheese", "flower", "orange", "banana", "internet",
+}
+
+// keyboardRows are the rows of a QWERTY keyboard, whose adjacent keys are
+// often typed in a row.
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// strengthThresholds are the bits of entropy (the base 2 logarithm of the
+// number of guesses needed) from which every score starts, as in zxcvbn:
+// 10^3, 10^6, 10^8 and 10^10 guesses.
+var strengthThresholds = []float64{9.97, 19.93, 26.58, 33.22}
+
+// ======== PUBLIC METHODS ========
+
+// EstimatePasswordStrength returns how hard a password is to guess, from 0 (too
+// guessable) to 4 (very unguessable), on the scale of zxcvbn.
+//
+// The password is split into the patterns guessers try first: common words and
+// the inputs of the user (e.g. their username), repeated characters ("aaaa"),
+// sequences ("abcd", "4321") and adjacent keys ("asdf"). Each pattern counts as
+// much as picking it among the ones like it, and every other character as much
+// as picking it among the classes of characters the password uses.
+func EstimatePasswordStrength(password string, userInputs ...string) int {
+	bits := passwordEntropy(password, userInputs)
+
+	score := 0
+	for _, threshold := range strengthThresholds {
+		if bits >= threshold {
+			score++
+		}
+	}
+	return score
+}
+
+// ======== PRIVATE METHODS ========
+
+// passwordEntropy returns the estimated bits of entropy of a password.
+func passwordEntropy(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	lower := []rune(strings.ToLower(password))
+	if len(lower) != len(runes) {
+		// Some characters change their length when lowercased.
+		lower = runes
+	}
+
+	// The inputs of the user are a dictionary of their own, so that they do not
+	// change the rank of the common words.
+	inputs := make([]string, 0, len(userInputs))
+	for _, input := range userInputs {
+		if len([]rune(input)) >= 3 {
+			inputs = append(inputs, strings.ToLower(input))
+		}
+	}
+
+	pool := characterPool(runes)
+	bits := 0.0
+	for i := 0; i < len(runes); {
+		length, rank := matchWord(lower, i, inputs)
+		if common, commonRank := matchWord(lower, i, commonPasswordWords); common > length {
+			length, rank = common, commonRank
+		}
+		if length > 0 {
+			// Guessers also try the words capitalized.
+			bits += math.Log2(float64(rank + 1))
+			if string(runes[i:i+length]) != string(lower[i:i+length]) {
+				bits++
+			}
+			i += length
+			continue
+		}
+		if length := repeatLength(runes, i); length >= 3 {
+			bits += math.Log2(classPools[classOf(runes[i])]) + math.Log2(float64(length))
+			i += length
+			continue
+		}
+		if length := sequenceLength(runes, i); length >= 3 {
+			// Sequences can go either way.
+			bits += math.Log2(classPools[classOf(runes[i])]) + math.Log2(float64(length)) + 1
+			i += length
+			continue
+		}
+		if length := keyboardLength(lower, i); length >= 4 {
+			bits += math.Log2(float64(len(strings.Join(keyboardRows, "")))) + math.Log2(float64(length)) + 1
+			i += length
+			continue
+		}
+
+		bits += math.Log2(pool)
+		i++
+	}
+
+	return bits
+}
+
+// matchWord returns the length of the longest word found at a position of the
+// password, and its position in the list.
+func matchWord(password []rune, start int, words []string) (int, int) {
+	length, rank := 0, 0
+	for i, word := range words {
+		runes := []rune(word)
+		if len(runes) > length && start+len(runes) <= len(password) &&
+			string(password[start:start+len(runes)]) == word {
+			length, rank = len(runes), i
+		}
+	}
+	return length, rank
+}
+
+// repeatLength returns how many times the character at a position is repeated.
+func repeatLength(password []rune, start int) int {
+	length := 1
+	for start+length < len(password) && password[start+length] == password[start] {
+		length++
+	}
+	return length
+}
+
+// sequenceLength returns the length of the sequence of letters or digits that
+// starts at a position, going up or down by one.
+func sequenceLength(password []rune, start int) int {
+	if start+1 >= len(password) || !isSequenceClass(password[start]) {
+		return 1
+	}
+	step := password[start+1] - password[start]
+	if step != 1 && step != -1 {
+		return 1
+	}
+
+	length := 1
+	for start+length < len(password) &&
+		password[start+length]-password[start+length-1] == step &&
+		classOf(password[start+length]) == classOf(password[start]) {
+		length++
+	}
+	return length
+}
+
+// keyboardLength returns the length of the run of adjacent keys that starts at a
+// position, in either direction of a row.
+func keyboardLength(password []rune, start int) int {
+	longest := 1
+	for _, row := range keyboardRows {
+		for _, step := range []int{1, -1} {
+			position := strings.IndexRune(row, password[start])
+			if position < 0 {
+				continue
+			}
+			length := 1
+			for start+length < len(password) {
+				position += step
+				if position < 0 || position >= len(row) || rune(row[position]) != password[start+length] {
+					break
+				}
+				length++
+			}
+			if length > longest {
+				longest = length
+			}
+		}
+	}
+	return longest
+}
+
+// characterPool returns the number of characters of the classes a password uses.
+func characterPool(password []rune) float64 {
+	seen := map[characterClass]bool{}
+	pool := 0.0
+	for _, char := range password {
+		class := classOf(char)
+		if !seen[class] {
+			seen[class] = true
+			pool += classPools[class]
+		}
+	}
+	if pool == 0 {
+		return 1
+	}
+	return pool
+}
+
+// classOf returns the class of a character.
+func classOf(char rune) characterClass {
+	switch {
+	case char >= 'a' && char <= 'z':
+		return classLowercase
+	case char >= 'A' && char <= 'Z':
+		return classUppercase
+	case char >= '0' && char <= '9':
+		return classDigit
+	case char < unicode.MaxASCII && unicode.IsPrint(char):
+		return classSymbol
+	default:
+		return classOther
+	}
+}
+
+// isSequenceClass returns whether a character can be part of a sequence.
+func isSequenceClass(char rune) bool {
+	class := classOf(char)
+	return class == classLowercase || class == classUppercase || class == classDigit
+}
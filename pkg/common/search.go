@@ -0,0 +1,143 @@
+/*
+Package Name: common
+File Name: search.go
+Abstract: Helpers for the routes that search items by text.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// ======== NAMESPACES ========
+
+// searchT is used for creating a namespace
+type searchT struct{}
+
+// the Search namespace
+var Search searchT
+
+// ======== CONSTANTS ========
+
+// The markers around the fragments of a text that match a search.
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// ======== PUBLIC METHODS ========
+
+// Search.Terms returns the words of a search: its runs of letters and digits,
+// in lower case and without duplicates.
+func (searchT) Terms(query string) []string {
+	terms := []string{}
+	seen := make(map[string]bool)
+	for _, term := range strings.FieldsFunc(strings.ToLower(query), func(char rune) bool {
+		return !unicode.IsLetter(char) && !unicode.IsDigit(char)
+	}) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Search.PrefixQuery returns a query for to_tsquery that matches the documents
+// with words starting with every term, like "ale:* & example:*". The terms
+// must come from Search.Terms, which leaves out the operators of tsquery.
+func (searchT) PrefixQuery(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	return strings.Join(prefixes, " & ")
+}
+
+// Search.ContainsPattern returns a LIKE pattern that matches the texts that
+// contain a value, escaping the characters LIKE would take as wildcards. It
+// must be used with ESCAPE '\'.
+func (searchT) ContainsPattern(value string) string {
+	return "%" + escapeLike(value) + "%"
+}
+
+// Search.Highlight wraps the fragments of a text that match any of the terms,
+// regardless of case, with HighlightStart and HighlightEnd. The rest of the
+// text is escaped, so the result can be shown as HTML.
+func (searchT) Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, char := range runes {
+		lower[i] = unicode.ToLower(char)
+	}
+
+	// Marks the characters that are part of a match.
+	matched := make([]bool, len(runes))
+	for _, term := range terms {
+		needle := []rune(term)
+		for i, char := range needle {
+			needle[i] = unicode.ToLower(char)
+		}
+		if len(needle) == 0 {
+			continue
+		}
+		for start := 0; start+len(needle) <= len(lower); start++ {
+			if string(lower[start:start+len(needle)]) == string(needle) {
+				for i := start; i < start+len(needle); i++ {
+					matched[i] = true
+				}
+			}
+		}
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(runes); {
+		end := i
+		for end < len(runes) && matched[end] == matched[i] {
+			end++
+		}
+		fragment := html.EscapeString(string(runes[i:end]))
+		if matched[i] {
+			fragment = HighlightStart + fragment + HighlightEnd
+		}
+		result.WriteString(fragment)
+		i = end
+	}
+	return result.String()
+}
+
+// ======== PRIVATE METHODS ========
+
+// escapeLike escapes the characters LIKE takes as wildcards, along with its
+// escape character.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,70 @@
+/*
+Package Name: common
+File Name: search_test.go
+Abstract: Tests for the search helpers.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch_Terms(t *testing.T) {
+	// Test case 1: Terms are the words of the query, without the operators of tsquery
+	terms := Search.Terms("Alex@Example.com & !ale | alex:*")
+	assert.Equal(t, []string{"alex", "example", "com", "ale"}, terms)
+	assert.Equal(t, "alex:* & example:* & com:* & ale:*", Search.PrefixQuery(terms))
+
+	// Test case 2: Queries made of symbols have no terms
+	assert.Empty(t, Search.Terms("%_'&"))
+}
+
+func TestSearch_ContainsPattern(t *testing.T) {
+	assert.Equal(t, `%ale%`, Search.ContainsPattern("ale"))
+	assert.Equal(t, `%100\%\_\\%`, Search.ContainsPattern(`100%_\`))
+}
+
+func TestSearch_Highlight(t *testing.T) {
+	// Test case 1: Matches are highlighted regardless of case, and overlapping ones are merged
+	assert.Equal(t,
+		"<mark>Alex</mark>andra@<mark>example</mark>.com",
+		Search.Highlight("Alexandra@example.com", []string{"ale", "lex", "example"}),
+	)
+
+	// Test case 2: The rest of the text is escaped
+	assert.Equal(t,
+		"&lt;b&gt;<mark>bob</mark>&lt;/b&gt;",
+		Search.Highlight("<b>bob</b>", []string{"bob"}),
+	)
+
+	// Test case 3: Texts without matches are left as they are
+	assert.Equal(t, "alex", Search.Highlight("alex", []string{"bob"}))
+}
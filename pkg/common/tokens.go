@@ -0,0 +1,70 @@
+/*
+Package Name: common
+File Name: tokens.go
+Abstract: Tokens provides helper functions for generating opaque, random
+tokens and hashing them before they are stored in the database.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// ======== NAMESPACES ========
+
+// tokensT is used for creating a namespace
+type tokensT struct{}
+
+// the Tokens namespace
+var Tokens tokensT
+
+// ======== PUBLIC METHODS ========
+
+// Tokens.Generate returns a URL-safe random token with 32 bytes of
+// entropy.
+//
+// Unlike passwords, these tokens are long and random, so they can be
+// stored using a fast hash (see Tokens.Hash) without making brute force
+// attacks feasible.
+func (tokensT) Generate() (string, error) {
+	bytes, err := generateRandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// Tokens.Hash returns the hex encoded SHA-256 digest of a token, which
+// is what should be persisted instead of the token itself.
+func (tokensT) Hash(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
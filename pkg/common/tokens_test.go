@@ -0,0 +1,57 @@
+/*
+Package Name: common
+File Name: tokens_test.go
+Abstract: Tests for the token helper functions.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens_GenerateAndHash(t *testing.T) {
+	// Test case 1: Generated tokens are random and URL-safe
+	token, err := Tokens.Generate()
+	require.NoError(t, err)
+	assert.Len(t, token, 43)
+	assert.NotContains(t, token, "+")
+	assert.NotContains(t, token, "/")
+
+	other, err := Tokens.Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	// Test case 2: Hashing is deterministic and never returns the token itself
+	assert.Equal(t, Tokens.Hash(token), Tokens.Hash(token))
+	assert.NotEqual(t, Tokens.Hash(token), Tokens.Hash(other))
+	assert.Len(t, Tokens.Hash(token), 64)
+}
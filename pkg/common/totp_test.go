@@ -0,0 +1,101 @@
+/*
+Package Name: common
+File Name: totp_test.go
+Abstract: Tests for the TOTP functions
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The SHA-1 secret of the test vectors of RFC 6238.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTP_Code(t *testing.T) {
+	// The test vectors of RFC 6238 use 8 digits, so the last 6 are compared.
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := TOTP.Code(rfcSecret, TOTP.Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected[2:], code, "time %d", unix)
+	}
+}
+
+func TestTOTP_Validate(t *testing.T) {
+	secret, err := TOTP.GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	step := TOTP.Step(now)
+	code, err := TOTP.Code(secret, step-1)
+	require.NoError(t, err)
+
+	// Test case 1: The code of the previous step is accepted with a skew of 1
+	matched, ok := TOTP.Validate(secret, code, now, 1, 0)
+	assert.True(t, ok)
+	assert.Equal(t, step-1, matched)
+
+	// Test case 2: But not without skew
+	_, ok = TOTP.Validate(secret, code, now, 0, 0)
+	assert.False(t, ok)
+
+	// Test case 3: A code cannot be used again once its step has been used
+	_, ok = TOTP.Validate(secret, code, now, 1, matched)
+	assert.False(t, ok)
+
+	// Test case 4: Malformed codes are rejected
+	_, ok = TOTP.Validate(secret, "12345", now, 1, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTP_URI(t *testing.T) {
+	uri, err := url.Parse(TOTP.URI("API", "user@example.com", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/API:user@example.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "API", uri.Query().Get("issuer"))
+}
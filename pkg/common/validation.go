@@ -4,7 +4,7 @@ File Name: database.go
 Abstract: This file contains functions for validating the body of a request.
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/22/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -80,6 +80,16 @@ func (validationT) ValidateBody(ctx *gin.Context, body interface{}) *gin.H {
 	return nil
 }
 
+// FieldErrors returns error messages about a field in the same format as
+// ValidateBody, for the checks that binding tags cannot express.
+func (validationT) FieldErrors(field string, messages ...string) *gin.H {
+	out := make([]ValidationErrorMessage, len(messages))
+	for i, message := range messages {
+		out[i] = ValidationErrorMessage{Field: field, Message: message}
+	}
+	return &gin.H{"errors": out}
+}
+
 // ======== PRIVATE METHODS ========
 
 // Helper function to get the form field name
@@ -0,0 +1,508 @@
+/*
+Package Name: common
+File Name: webauthn.go
+Abstract: The verification of the registration and authentication ceremonies of
+WebAuthn (passkeys), as described in the Web Authentication Level 2
+recommendation.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ======== NAMESPACES ========
+
+// webauthnT is used for creating a namespace
+type webauthnT struct{}
+
+// the WebAuthn namespace
+var WebAuthn webauthnT
+
+// ======== TYPES ========
+
+// WebAuthnRelyingParty identifies the API to authenticators. Credentials are
+// scoped to the ID, which is a domain, and can only be used from the origins.
+type WebAuthnRelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// WebAuthnCredential is a credential created by an authenticator.
+type WebAuthnCredential struct {
+	ID []byte
+	// PublicKey is the COSE encoding of the public key of the credential.
+	PublicKey []byte
+	SignCount uint32
+	// BackupEligible is whether the credential can be synced to other
+	// devices, and BackedUp whether it is.
+	BackupEligible bool
+	BackedUp       bool
+}
+
+// webauthnClientData is the client data the browser passes to authenticators.
+type webauthnClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// webauthnAuthenticatorData is the data authenticators sign.
+type webauthnAuthenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// The attested credential data, only present when registering.
+	credentialID []byte
+	publicKey    []byte
+}
+
+// ======== CONSTANTS ========
+
+// The types of the client data of every ceremony.
+const (
+	webauthnTypeCreate = "webauthn.create"
+	webauthnTypeGet    = "webauthn.get"
+)
+
+// The flags of the authenticator data.
+const (
+	webauthnFlagUserPresent    = 0x01
+	webauthnFlagUserVerified   = 0x04
+	webauthnFlagBackupEligible = 0x08
+	webauthnFlagBackedUp       = 0x10
+	webauthnFlagAttestedData   = 0x40
+	webauthnFlagExtensionData  = 0x80
+)
+
+// The COSE algorithms supported, which are the ones every authenticator
+// implements. They are also the ones the options ask for, in this order.
+const (
+	COSEAlgorithmES256 = -7
+	COSEAlgorithmEdDSA = -8
+	COSEAlgorithmRS256 = -257
+)
+
+// WebAuthnAlgorithms are the COSE algorithms of the credentials that can be
+// registered, by order of preference.
+var WebAuthnAlgorithms = []int{COSEAlgorithmES256, COSEAlgorithmEdDSA, COSEAlgorithmRS256}
+
+// The key types and parameters of COSE keys (RFC 9053).
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyRSAN      = -1
+	coseKeyRSAE      = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// ======== ERRORS ========
+var (
+	InvalidWebAuthnResponseException = errors.New("The response of the authenticator is not valid.")
+	UnsupportedAttestationException  = errors.New("The attestation format of the authenticator is not supported.")
+	ClonedAuthenticatorException     = errors.New("The signature counter of the authenticator went backwards, so it may have been cloned.")
+)
+
+// ======== PUBLIC METHODS ========
+
+// WebAuthn.GenerateChallenge returns a new random challenge for a ceremony,
+// with the same 32 bytes of entropy as the tokens.
+func (webauthnT) GenerateChallenge() ([]byte, error) {
+	return generateRandomBytes(32)
+}
+
+// WebAuthn.Challenge returns the challenge of the client data of a response,
+// so that the ceremony it belongs to can be found. The response still has to
+// be verified.
+func (webauthnT) Challenge(clientDataJSON []byte) ([]byte, error) {
+	clientData := webauthnClientData{}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, fmt.Errorf("%w (%v)", InvalidWebAuthnResponseException, err)
+	}
+
+	challenge, err := DecodeBase64URL(clientData.Challenge)
+	if err != nil || len(challenge) == 0 {
+		return nil, fmt.Errorf("%w (the challenge is not valid)", InvalidWebAuthnResponseException)
+	}
+	return challenge, nil
+}
+
+// WebAuthn.VerifyRegistration verifies the response of an authenticator to a
+// registration ceremony, and returns the credential it created. Only the none
+// and packed attestation formats are supported, and attestation certificates
+// are not checked against any trusted root, since passkeys are accepted from
+// any authenticator.
+func (webauthnT) VerifyRegistration(
+	rp WebAuthnRelyingParty,
+	challenge []byte,
+	clientDataJSON []byte,
+	attestationObject []byte,
+	requireUserVerification bool,
+) (*WebAuthnCredential, error) {
+	if err := verifyClientData(rp, webauthnTypeCreate, challenge, clientDataJSON); err != nil {
+		return nil, err
+	}
+
+	// ======== DECODE ATTESTATION ========
+	decoded, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("%w (the attestation object is not valid CBOR)", InvalidWebAuthnResponseException)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w (the attestation object is not a map)", InvalidWebAuthnResponseException)
+	}
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if statement == nil || rawAuthData == nil {
+		return nil, fmt.Errorf("%w (the attestation object is incomplete)", InvalidWebAuthnResponseException)
+	}
+
+	// ======== CHECK AUTHENTICATOR DATA ========
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyAuthenticatorData(rp, authData, requireUserVerification); err != nil {
+		return nil, err
+	}
+	if authData.flags&webauthnFlagAttestedData == 0 {
+		return nil, fmt.Errorf("%w (there is no attested credential)", InvalidWebAuthnResponseException)
+	}
+
+	algorithm, publicKey, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// ======== CHECK ATTESTATION ========
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	switch format {
+	case "none":
+		if len(statement) > 0 {
+			return nil, fmt.Errorf("%w (the none attestation has a statement)", InvalidWebAuthnResponseException)
+		}
+	case "packed":
+		if err := verifyPackedAttestation(statement, signed, algorithm, publicKey); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w (%s)", UnsupportedAttestationException, format)
+	}
+
+	return &WebAuthnCredential{
+		ID:             authData.credentialID,
+		PublicKey:      authData.publicKey,
+		SignCount:      authData.signCount,
+		BackupEligible: authData.flags&webauthnFlagBackupEligible != 0,
+		BackedUp:       authData.flags&webauthnFlagBackedUp != 0,
+	}, nil
+}
+
+// WebAuthn.VerifyAssertion verifies the response of an authenticator to an
+// authentication ceremony with a credential, and returns the new signature
+// counter of the credential.
+func (webauthnT) VerifyAssertion(
+	rp WebAuthnRelyingParty,
+	challenge []byte,
+	credential WebAuthnCredential,
+	clientDataJSON []byte,
+	authenticatorData []byte,
+	signature []byte,
+	requireUserVerification bool,
+) (uint32, error) {
+	if err := verifyClientData(rp, webauthnTypeGet, challenge, clientDataJSON); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyAuthenticatorData(rp, authData, requireUserVerification); err != nil {
+		return 0, err
+	}
+
+	// ======== CHECK SIGNATURE ========
+	algorithm, publicKey, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authenticatorData...), clientDataHash[:]...)
+	if !verifyCOSESignature(algorithm, publicKey, signed, signature) {
+		return 0, fmt.Errorf("%w (the signature does not match)", InvalidWebAuthnResponseException)
+	}
+
+	// ======== CHECK COUNTER ========
+	// Authenticators that keep a counter increase it with every signature,
+	// so a counter that does not increase means that the credential has been
+	// copied. Synced passkeys always report zero.
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ClonedAuthenticatorException
+	}
+
+	return authData.signCount, nil
+}
+
+// DecodeBase64URL decodes base64url data, with or without padding, which is
+// how WebAuthn encodes binary data in JSON.
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// ======== PRIVATE METHODS ========
+
+// verifyClientData checks that the client data belongs to a ceremony of the type
+// given with the challenge, and was collected by one of the origins.
+func verifyClientData(rp WebAuthnRelyingParty, ceremony string, challenge []byte, clientDataJSON []byte) error {
+	clientData := webauthnClientData{}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return fmt.Errorf("%w (%v)", InvalidWebAuthnResponseException, err)
+	}
+
+	if clientData.Type != ceremony {
+		return fmt.Errorf("%w (the type is not %s)", InvalidWebAuthnResponseException, ceremony)
+	}
+
+	received, err := DecodeBase64URL(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return fmt.Errorf("%w (the challenge does not match)", InvalidWebAuthnResponseException)
+	}
+
+	// Ceremonies started inside frames of other origins are not accepted.
+	if clientData.CrossOrigin {
+		return fmt.Errorf("%w (the ceremony is cross-origin)", InvalidWebAuthnResponseException)
+	}
+	for _, origin := range rp.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w (the origin %s is not allowed)", InvalidWebAuthnResponseException, clientData.Origin)
+}
+
+// parseAuthenticatorData parses the authenticator data, along with the attested
+// credential it contains when registering.
+func parseAuthenticatorData(data []byte) (*webauthnAuthenticatorData, error) {
+	invalid := fmt.Errorf("%w (the authenticator data is not valid)", InvalidWebAuthnResponseException)
+	if len(data) < 37 {
+		return nil, invalid
+	}
+
+	authData := webauthnAuthenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.flags&webauthnFlagAttestedData != 0 {
+		// The AAGUID of the authenticator is not used, since attestation
+		// is not checked against any trusted root.
+		if len(rest) < 18 {
+			return nil, invalid
+		}
+		length := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if length == 0 || length > 1023 || len(rest) < length {
+			return nil, invalid
+		}
+		authData.credentialID = rest[:length]
+		rest = rest[length:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, invalid
+		}
+		authData.publicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if authData.flags&webauthnFlagExtensionData != 0 {
+		extensions, after, err := decodeCBOR(rest)
+		if _, ok := extensions.(map[interface{}]interface{}); err != nil || !ok {
+			return nil, invalid
+		}
+		rest = after
+	}
+
+	if len(rest) > 0 {
+		return nil, invalid
+	}
+	return &authData, nil
+}
+
+// verifyAuthenticatorData checks that the authenticator data is scoped to the
+// relying party, and that the user was present and, if required, verified.
+func verifyAuthenticatorData(rp WebAuthnRelyingParty, authData *webauthnAuthenticatorData, requireUserVerification bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return fmt.Errorf("%w (the credential belongs to another relying party)", InvalidWebAuthnResponseException)
+	}
+	if authData.flags&webauthnFlagUserPresent == 0 {
+		return fmt.Errorf("%w (the user was not present)", InvalidWebAuthnResponseException)
+	}
+	if requireUserVerification && authData.flags&webauthnFlagUserVerified == 0 {
+		return fmt.Errorf("%w (the user was not verified)", InvalidWebAuthnResponseException)
+	}
+	return nil
+}
+
+// verifyPackedAttestation verifies a packed attestation statement, which is
+// signed either by an attestation certificate or by the credential itself.
+func verifyPackedAttestation(
+	statement map[interface{}]interface{},
+	signed []byte,
+	algorithm int64,
+	publicKey crypto.PublicKey,
+) error {
+	statementAlgorithm, _ := statement["alg"].(int64)
+	signature, _ := statement["sig"].([]byte)
+	if signature == nil {
+		return fmt.Errorf("%w (the packed attestation is not signed)", InvalidWebAuthnResponseException)
+	}
+
+	certificates, ok := statement["x5c"].([]interface{})
+	if !ok {
+		// Self attestation is signed with the credential being registered.
+		if statementAlgorithm != algorithm || !verifyCOSESignature(algorithm, publicKey, signed, signature) {
+			return fmt.Errorf("%w (the self attestation does not match)", InvalidWebAuthnResponseException)
+		}
+		return nil
+	}
+
+	if len(certificates) == 0 {
+		return fmt.Errorf("%w (the attestation certificate is missing)", InvalidWebAuthnResponseException)
+	}
+	raw, _ := certificates[0].([]byte)
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return fmt.Errorf("%w (the attestation certificate is not valid)", InvalidWebAuthnResponseException)
+	}
+	if !verifyCOSESignature(statementAlgorithm, certificate.PublicKey, signed, signature) {
+		return fmt.Errorf("%w (the attestation signature does not match)", InvalidWebAuthnResponseException)
+	}
+	return nil
+}
+
+// parseCOSEKey parses a COSE public key, and returns its algorithm along with
+// the key.
+func parseCOSEKey(data []byte) (int64, crypto.PublicKey, error) {
+	invalid := fmt.Errorf("%w (the public key is not valid)", InvalidWebAuthnResponseException)
+	decoded, rest, err := decodeCBOR(data)
+	key, ok := decoded.(map[interface{}]interface{})
+	if err != nil || len(rest) > 0 || !ok {
+		return 0, nil, invalid
+	}
+
+	keyType, _ := key[int64(coseKeyType)].(int64)
+	algorithm, _ := key[int64(coseKeyAlgorithm)].(int64)
+	curve, _ := key[int64(coseKeyCurve)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == COSEAlgorithmES256 && curve == coseCurveP256:
+		x, _ := key[int64(coseKeyX)].([]byte)
+		y, _ := key[int64(coseKeyY)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return 0, nil, invalid
+		}
+		publicKey := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return 0, nil, invalid
+		}
+		return algorithm, publicKey, nil
+
+	case keyType == coseKeyTypeOKP && algorithm == COSEAlgorithmEdDSA && curve == coseCurveEd25519:
+		x, _ := key[int64(coseKeyX)].([]byte)
+		if len(x) != ed25519.PublicKeySize {
+			return 0, nil, invalid
+		}
+		return algorithm, ed25519.PublicKey(x), nil
+
+	case keyType == coseKeyTypeRSA && algorithm == COSEAlgorithmRS256:
+		n, _ := key[int64(coseKeyRSAN)].([]byte)
+		e, _ := key[int64(coseKeyRSAE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return 0, nil, invalid
+		}
+		return algorithm, &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+
+	return 0, nil, UnsupportedKeyException
+}
+
+// verifyCOSESignature verifies a signature made with a COSE algorithm.
+func verifyCOSESignature(algorithm int64, publicKey crypto.PublicKey, signed []byte, signature []byte) bool {
+	hash := sha256.Sum256(signed)
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return algorithm == COSEAlgorithmES256 && ecdsa.VerifyASN1(key, hash[:], signature)
+	case ed25519.PublicKey:
+		return algorithm == COSEAlgorithmEdDSA && ed25519.Verify(key, signed, signature)
+	case *rsa.PublicKey:
+		return algorithm == COSEAlgorithmRS256 && rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+/*
+Package Name: common
+File Name: webauthn_test.go
+Abstract: Tests for the verification of WebAuthn ceremonies, run against a
+software authenticator.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package common_test
+
+import (
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRelyingParty = common.WebAuthnRelyingParty{
+	ID:      "example.com",
+	Name:    "Example",
+	Origins: []string{"https://example.com"},
+}
+
+// decode decodes the base64url fields of the responses of the authenticator.
+func decode(t *testing.T, value string) []byte {
+	data, err := common.DecodeBase64URL(value)
+	require.NoError(t, err)
+	return data
+}
+
+// register registers a credential of the authenticator.
+func register(t *testing.T, authenticator *mocks.MockAuthenticator) *common.WebAuthnCredential {
+	challenge := []byte("registration-challenge")
+	response := authenticator.Create(challenge, []byte("1"))
+
+	credential, err := common.WebAuthn.VerifyRegistration(
+		testRelyingParty,
+		challenge,
+		decode(t, response.Response.ClientDataJSON),
+		decode(t, response.Response.AttestationObject),
+		true,
+	)
+	require.NoError(t, err)
+	return credential
+}
+
+func TestWebAuthn_Registration(t *testing.T) {
+	authenticator := mocks.NewMockAuthenticator("https://example.com", "example.com")
+	challenge := []byte("registration-challenge")
+
+	verify := func(response mocks.MockCredentialResponse, challenge []byte, requireUserVerification bool) error {
+		_, err := common.WebAuthn.VerifyRegistration(
+			testRelyingParty,
+			challenge,
+			decode(t, response.Response.ClientDataJSON),
+			decode(t, response.Response.AttestationObject),
+			requireUserVerification,
+		)
+		return err
+	}
+
+	// Test case 1: A credential without attestation
+	response := authenticator.Create(challenge, []byte("1"))
+	credential, err := common.WebAuthn.VerifyRegistration(
+		testRelyingParty,
+		challenge,
+		decode(t, response.Response.ClientDataJSON),
+		decode(t, response.Response.AttestationObject),
+		true,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, decode(t, response.RawID), credential.ID)
+	assert.NotEmpty(t, credential.PublicKey)
+
+	// Test case 2: A credential with packed self attestation
+	authenticator.Format = "packed"
+	assert.NoError(t, verify(authenticator.Create(challenge, []byte("1")), challenge, true))
+	authenticator.Format = ""
+
+	// Test case 3: The challenge of another ceremony
+	assert.ErrorIs(t, verify(authenticator.Create(challenge, []byte("1")), []byte("other"), true), common.InvalidWebAuthnResponseException)
+
+	// Test case 4: A user that was not verified, which is fine unless required
+	authenticator.UserVerified = false
+	assert.NoError(t, verify(authenticator.Create(challenge, []byte("1")), challenge, false))
+	assert.ErrorIs(t, verify(authenticator.Create(challenge, []byte("1")), challenge, true), common.InvalidWebAuthnResponseException)
+	authenticator.UserVerified = true
+
+	// Test case 5: A phishing website cannot register credentials
+	phishing := mocks.NewMockAuthenticator("https://examp1e.com", "example.com")
+	assert.ErrorIs(t, verify(phishing.Create(challenge, []byte("1")), challenge, true), common.InvalidWebAuthnResponseException)
+
+	// Test case 6: Nor can credentials for other relying parties be registered
+	other := mocks.NewMockAuthenticator("https://example.com", "other.com")
+	assert.ErrorIs(t, verify(other.Create(challenge, []byte("1")), challenge, true), common.InvalidWebAuthnResponseException)
+
+	// Test case 7: An assertion is not an attestation
+	assertion := authenticator.Get(challenge, nil)
+	_, err = common.WebAuthn.VerifyRegistration(
+		testRelyingParty,
+		challenge,
+		decode(t, assertion.Response.ClientDataJSON),
+		decode(t, assertion.Response.AuthenticatorData),
+		true,
+	)
+	assert.ErrorIs(t, err, common.InvalidWebAuthnResponseException)
+}
+
+func TestWebAuthn_Assertion(t *testing.T) {
+	authenticator := mocks.NewMockAuthenticator("https://example.com", "example.com")
+	authenticator.CounterStep = 1
+	credential := register(t, authenticator)
+	challenge := []byte("authentication-challenge")
+
+	verify := func(response mocks.MockCredentialResponse, credential common.WebAuthnCredential) (uint32, error) {
+		return common.WebAuthn.VerifyAssertion(
+			testRelyingParty,
+			challenge,
+			credential,
+			decode(t, response.Response.ClientDataJSON),
+			decode(t, response.Response.AuthenticatorData),
+			decode(t, response.Response.Signature),
+			true,
+		)
+	}
+
+	// Test case 1: The challenge of the ceremony is found in the response
+	response := authenticator.Get(challenge, credential.ID)
+	found, err := common.WebAuthn.Challenge(decode(t, response.Response.ClientDataJSON))
+	require.NoError(t, err)
+	assert.Equal(t, challenge, found)
+
+	// Test case 2: A valid assertion increases the counter
+	counter, err := verify(response, *credential)
+	require.NoError(t, err)
+	assert.Greater(t, counter, credential.SignCount)
+	credential.SignCount = counter
+
+	// Test case 3: Replaying the assertion means the counter does not increase
+	_, err = verify(response, *credential)
+	assert.ErrorIs(t, err, common.ClonedAuthenticatorException)
+
+	// Test case 4: A signature made with another credential
+	other := mocks.NewMockAuthenticator("https://example.com", "example.com")
+	register(t, other)
+	_, err = verify(other.Get(challenge, nil), *credential)
+	assert.ErrorIs(t, err, common.InvalidWebAuthnResponseException)
+
+	// Test case 5: Tampering with the authenticator data breaks the signature
+	response = authenticator.Get(challenge, credential.ID)
+	tampered := decode(t, response.Response.AuthenticatorData)
+	tampered[36]++
+	_, err = common.WebAuthn.VerifyAssertion(
+		testRelyingParty,
+		challenge,
+		*credential,
+		decode(t, response.Response.ClientDataJSON),
+		tampered,
+		decode(t, response.Response.Signature),
+		true,
+	)
+	assert.ErrorIs(t, err, common.InvalidWebAuthnResponseException)
+
+	// Test case 6: Synced passkeys do not keep a counter
+	synced := mocks.NewMockAuthenticator("https://example.com", "example.com")
+	syncedCredential := register(t, synced)
+	for i := 0; i < 2; i++ {
+		counter, err := verify(synced.Get(challenge, nil), *syncedCredential)
+		require.NoError(t, err)
+		assert.Zero(t, counter)
+	}
+}
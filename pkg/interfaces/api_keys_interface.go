@@ -0,0 +1,79 @@
+/*
+Package Name: interfaces
+File Name: api_keys_interface.go
+Abstract: The interface of the service that manages API keys.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+// ======== TYPES ========
+
+// APIKey is a long-lived credential of a user for scripts and other
+// non-interactive clients. The key itself is never stored, only its prefix
+// (which identifies it) and its hash.
+type APIKey struct {
+	ID     int32  `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	// Scopes are the permissions granted to the key. A key without scopes
+	// is granted every permission of its owner.
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// ======== ERRORS ========
+var (
+	InvalidAPIKeyException  = errors.New("The API key provided is not valid, has expired or has been revoked.")
+	APIKeyNotFoundException = errors.New("The API key could not be found.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the APIKeysService.
+type APIKeysRepository interface {
+	// CreateAPIKey creates a new API key for the user and returns it along
+	// with the key itself, which cannot be retrieved again.
+	CreateAPIKey(userID int32, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error)
+
+	// GetAPIKeys returns the API keys of the user that have not been revoked.
+	GetAPIKeys(userID int32) ([]APIKey, error)
+
+	// RevokeAPIKey revokes an API key of the user.
+	RevokeAPIKey(userID int32, id int32) error
+
+	// CheckAPIKey validates an API key and returns the identity of its owner,
+	// limited to the scopes of the key.
+	CheckAPIKey(key string) (*Principal, error)
+}
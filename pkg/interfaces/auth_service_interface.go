@@ -5,7 +5,7 @@ Abstract: Interface for the AuthService used for avoiding import/dependency cycl
 and allowing to mock these services in tests.
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/22/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -31,14 +31,81 @@ SOFTWARE.
 */
 package interfaces
 
+import (
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== TYPES ========
+
+// TokenPair bundles the short-lived access token and the long-lived
+// refresh token that are handed out when a user logs in.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int64
+}
+
+// ======== CONSTANTS ========
+
+// The steps of a login a challenge token can be issued for.
+const (
+	ChallengePurposeMFA = "mfa"
+)
+
+// ======== ERRORS ========
+var (
+	InvalidRefreshTokenException = errors.New("The refresh token provided is not valid.")
+	ExpiredRefreshTokenException = errors.New("The refresh token provided has expired.")
+	ReusedRefreshTokenException  = errors.New("The refresh token provided has already been used, so every session derived from it has been revoked.")
+	RevokedTokenException        = errors.New("The access token provided has been revoked.")
+	InvalidChallengeException    = errors.New("The challenge token provided is not valid or has expired.")
+)
+
 // ======== INTERFACES ========
 
 // The interface for the AuthService.
 type AuthService interface {
 	// CheckToken checks whether a token is valid and returns the
-	// subject of the payload.
-	CheckToken(tokenString string) (*int32, error)
+	// principal it was issued for.
+	CheckToken(tokenString string) (*Principal, error)
+
+	// CreateToken return a token for a principal.
+	CreateToken(principal Principal) (*string, error)
+
+	// CreateImpersonationToken returns a token for a subject that names
+	// the actor acting as them, and expires once the ttl elapses.
+	CreateImpersonationToken(actorID int32, userID int32, sessionID string, ttl time.Duration) (*string, error)
+
+	// IssueTokens starts a session for a subject that authenticated with
+	// the given method on a device, and returns a new access token and a
+	// refresh token bound to it.
+	IssueTokens(id int32, method string, device Device) (*TokenPair, error)
+
+	// RefreshTokens exchanges a refresh token for a new token pair,
+	// rotating the refresh token in the process.
+	RefreshTokens(refreshToken string) (*TokenPair, error)
+
+	// RevokeToken revokes the token of a principal before it expires.
+	RevokeToken(principal Principal) error
+
+	// RevokeAllTokens revokes every token issued to a subject so far,
+	// and ends their sessions.
+	RevokeAllTokens(id int32) error
+
+	// CreateChallenge returns a short-lived token proving that a subject
+	// completed the first step of a login, which can only be used for
+	// completing the step given by the purpose.
+	CreateChallenge(id int32, purpose string) (string, error)
+
+	// CheckChallenge checks a challenge token and returns the subject it
+	// was issued for.
+	CheckChallenge(tokenString string, purpose string) (int32, error)
 
-	// CreateToken return a token for a subject.
-	CreateToken(id int32) (*string, error)
+	// PublicKeys returns the keys that can be used for verifying
+	// the tokens issued.
+	PublicKeys() common.JWKS
 }
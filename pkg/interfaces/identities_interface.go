@@ -0,0 +1,75 @@
+/*
+Package Name: interfaces
+File Name: identities_interface.go
+Abstract: The interface of the service that links the accounts of external
+identity providers to users.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+// ======== TYPES ========
+
+// OIDCAuthorization is a pending login with an OpenID Connect provider,
+// which is stored until the user comes back with an authorization code.
+type OIDCAuthorization struct {
+	// Nonce is the value the ID token must contain.
+	Nonce string
+	// Verifier is the PKCE code verifier of the login.
+	Verifier string
+}
+
+// ======== ERRORS ========
+var (
+	InvalidOIDCStateException = errors.New("The login with the identity provider is not valid or has expired.")
+	IdentityNotFoundException = errors.New("The identity is not linked to any user.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the IdentitiesService.
+type IdentitiesRepository interface {
+	// SaveAuthorization stores a pending login until the ttl elapses. The
+	// state identifies it and is only stored hashed.
+	SaveAuthorization(state string, authorization OIDCAuthorization, ttl time.Duration) error
+
+	// ConsumeAuthorization removes a pending login and returns it, so that
+	// every authorization code can only be redeemed once.
+	ConsumeAuthorization(state string) (*OIDCAuthorization, error)
+
+	// GetIdentityUser returns the user the subject of an issuer is linked to,
+	// and records that they have just logged in.
+	GetIdentityUser(issuer string, subject string) (int32, error)
+
+	// LinkIdentity links the subject of an issuer to a user.
+	LinkIdentity(userID int32, issuer string, subject string, email string) error
+}
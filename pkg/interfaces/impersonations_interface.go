@@ -0,0 +1,102 @@
+/*
+Package Name: interfaces
+File Name: impersonations_interface.go
+Abstract: The types and interface of the service that keeps the audit trail of
+impersonations.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+// ======== TYPES ========
+
+// Impersonation is a period during which an admin (the actor) acts as another
+// user, with a token issued to that user which names the actor.
+type Impersonation struct {
+	ID      int32  `json:"id"`
+	ActorID int32  `json:"actor_id"`
+	UserID  int32  `json:"user_id"`
+	Reason  string `json:"reason"`
+	// IPAddress is where the actor started the impersonation from.
+	IPAddress string     `json:"ip_address"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	// SessionID is the sid of the token issued for the impersonation.
+	SessionID string `json:"-"`
+}
+
+// ImpersonatedRequest is a request made while impersonating a user.
+type ImpersonatedRequest struct {
+	ID     int64  `json:"id"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Status is nil until the request has been handled.
+	Status    *int32    `json:"status"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ======== ERRORS ========
+var (
+	ImpersonationNotFoundException = errors.New("The impersonation could not be found.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the ImpersonationsService.
+type ImpersonationsRepository interface {
+	// StartImpersonation records that the actor is going to impersonate
+	// the user until the ttl elapses, and returns the impersonation along
+	// with the session id its token has to carry.
+	StartImpersonation(actorID int32, userID int32, reason string, ipAddress string, ttl time.Duration) (*Impersonation, error)
+
+	// GetImpersonations returns every impersonation, newest first.
+	GetImpersonations() ([]Impersonation, error)
+
+	// GetImpersonatedRequests returns the requests made during an
+	// impersonation, in the order they were made.
+	GetImpersonatedRequests(id int32) ([]ImpersonatedRequest, error)
+
+	// EndImpersonation ends an impersonation before it expires, and
+	// revokes its token.
+	EndImpersonation(id int32) error
+
+	// RecordRequest records a request made with the token of the
+	// impersonation with the given session id before it is handled, and
+	// returns the id of the record.
+	RecordRequest(sessionID string, request ImpersonatedRequest) (int64, error)
+
+	// CompleteRequest records the status a recorded request was answered with.
+	CompleteRequest(id int64, status int) error
+}
@@ -0,0 +1,86 @@
+/*
+Package Name: interfaces
+File Name: login_attempts_interface.go
+Abstract: The interface for tracking failed logins and locking out brute force attacks.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+// ======== TYPES ========
+
+// Lockout is the record of the failed logins of an account or IP address.
+type Lockout struct {
+	// Kind is either LockoutKindAccount or LockoutKindIP.
+	Kind          string     `json:"kind"`
+	Identifier    string     `json:"identifier"`
+	Failures      int32      `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// ======== CONSTANTS ========
+
+// What failed logins are tracked by.
+const (
+	LockoutKindAccount = "account"
+	LockoutKindIP      = "ip"
+)
+
+// ======== ERRORS ========
+var (
+	TooManyLoginAttemptsException = errors.New("Too many failed login attempts. Please try again later.")
+	LockoutNotFoundException      = errors.New("There is no lockout for the account or IP address provided.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the LoginAttemptsService.
+type LoginAttemptsRepository interface {
+	// Check returns how long the account and IP address have to wait
+	// before trying to log in again, or zero if they can try now.
+	Check(account string, ip string) (time.Duration, error)
+
+	// RecordFailure records a failed login of the account from the IP
+	// address, and returns how long they have to wait before trying again.
+	RecordFailure(account string, ip string) (time.Duration, error)
+
+	// Reset forgets the failed logins of the account, but not the ones of
+	// the IP address, which expire on their own.
+	Reset(account string) error
+
+	// GetLockouts returns the accounts and IP addresses with failed logins.
+	GetLockouts() ([]Lockout, error)
+
+	// Unlock forgets the failed logins of an account or IP address.
+	Unlock(kind string, identifier string) error
+}
@@ -0,0 +1,70 @@
+/*
+Package Name: interfaces
+File Name: magic_links_interface.go
+Abstract: The interface of the service that issues the links users can log in with
+instead of a password.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+// ======== CONSTANTS ========
+
+// MagicLinkNonceCookie is the cookie that binds a login link to the browser
+// that asked for it.
+const MagicLinkNonceCookie = "magic_link_nonce"
+
+// ======== ERRORS ========
+var (
+	InvalidMagicLinkException  = errors.New("The link provided is not valid, has expired or was requested from another browser.")
+	TooManyMagicLinksException = errors.New("Too many login links have been requested for this email. Please try again later.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the MagicLinksService.
+type MagicLinksRepository interface {
+	// Check returns how long has to pass before another link can be sent
+	// to the email, or zero if one can be sent now.
+	Check(email string) (time.Duration, error)
+
+	// CreateMagicLink returns the token of a new link for the email, which
+	// can be used once before the ttl elapses, and the nonce that has to be
+	// presented along with it. Links are created for emails without an
+	// account too, with a nil userID, so that they count towards the rate
+	// limit, but they cannot be used.
+	CreateMagicLink(email string, userID *int32, ttl time.Duration) (token string, nonce string, err error)
+
+	// ConsumeMagicLink marks a link as used and returns the user it was
+	// sent to, provided the nonce is the one it was created with.
+	ConsumeMagicLink(token string, nonce string) (int32, error)
+}
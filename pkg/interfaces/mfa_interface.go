@@ -0,0 +1,76 @@
+/*
+Package Name: interfaces
+File Name: mfa_interface.go
+Abstract: The interface for two-factor authentication with TOTP codes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import "errors"
+
+// ======== TYPES ========
+
+// MFAEnrollment is what a user needs for adding the API to their
+// authenticator app.
+type MFAEnrollment struct {
+	Secret string
+	// URI is the otpauth:// URI of the secret, usually shown as a QR code.
+	URI string
+}
+
+// ======== ERRORS ========
+var (
+	MFAAlreadyEnabledException = errors.New("Two-factor authentication is already enabled.")
+	MFANotEnabledException     = errors.New("Two-factor authentication is not enabled.")
+	MFANotEnrolledException    = errors.New("Two-factor authentication has not been set up yet.")
+	InvalidMFACodeException    = errors.New("The code provided is not valid.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the MFAService.
+type MFARepository interface {
+	// IsEnabled returns whether the user has two-factor authentication enabled.
+	IsEnabled(userID int32) (bool, error)
+
+	// BeginEnrollment generates a new secret for the user. Two-factor
+	// authentication is not enabled until the enrollment is confirmed.
+	BeginEnrollment(userID int32, account string) (*MFAEnrollment, error)
+
+	// ConfirmEnrollment enables two-factor authentication if the code is
+	// valid for the secret being enrolled, and returns the recovery codes
+	// of the user.
+	ConfirmEnrollment(userID int32, code string) ([]string, error)
+
+	// Verify checks a TOTP code or an unused recovery code of the user.
+	// Every code can only be used once.
+	Verify(userID int32, code string) error
+
+	// Disable disables two-factor authentication if the code is valid.
+	Disable(userID int32, code string) error
+}
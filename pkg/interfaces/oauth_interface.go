@@ -0,0 +1,147 @@
+/*
+Package Name: interfaces
+File Name: oauth_interface.go
+Abstract: The interface of the service that stores the clients, consents,
+authorization codes and refresh tokens of the OAuth 2.0 authorization
+server.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+// ======== TYPES ========
+
+// OAuthClient is an application registered for accessing the API on behalf of
+// users, or on its own behalf.
+type OAuthClient struct {
+	ID           string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	// Scopes are the permissions the client can ask for.
+	Scopes     []string `json:"scopes"`
+	GrantTypes []string `json:"grant_types"`
+	// Confidential clients authenticate with a secret. Public clients (e.g.
+	// single page or mobile apps) cannot keep one, and rely on PKCE alone.
+	Confidential bool `json:"confidential"`
+	// UserID is the user the client acts as in the client credentials grant.
+	UserID    *int32    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OAuthGrant is what an authorization code or a refresh token grants.
+type OAuthGrant struct {
+	ClientID string
+	UserID   int32
+	Scopes   []string
+	// RedirectURI and CodeChallenge are only set for authorization codes.
+	RedirectURI   string
+	CodeChallenge string
+}
+
+// OAuthConsent is the consent of a user for a client to access the API on
+// their behalf.
+type OAuthConsent struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
+
+// ======== CONSTANTS ========
+
+// The grant types supported by the authorization server.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// ======== ERRORS ========
+var (
+	OAuthClientNotFoundException  = errors.New("The client could not be found.")
+	InvalidOAuthClientException   = errors.New("The client could not be authenticated.")
+	InvalidOAuthGrantException    = errors.New("The authorization code or refresh token is not valid or has expired.")
+	OAuthConsentNotFoundException = errors.New("The application has not been granted access.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the OAuthService.
+type OAuthRepository interface {
+	// CreateClient registers a client and returns it along with its secret,
+	// which is empty for public clients and cannot be retrieved again.
+	CreateClient(client OAuthClient) (*OAuthClient, string, error)
+
+	// GetClients returns every client.
+	GetClients() ([]OAuthClient, error)
+
+	// GetClient returns a client.
+	GetClient(id string) (*OAuthClient, error)
+
+	// DeleteClient removes a client along with its consents and tokens.
+	DeleteClient(id string) error
+
+	// AuthenticateClient returns the client if the secret is its secret.
+	// Public clients are returned only if the secret is empty.
+	AuthenticateClient(id string, secret string) (*OAuthClient, error)
+
+	// GetConsent returns the scopes a user has consented to give a client,
+	// which are empty if the user has not given their consent.
+	GetConsent(userID int32, clientID string) ([]string, error)
+
+	// SaveConsent adds scopes to the consent of a user for a client.
+	SaveConsent(userID int32, clientID string, scopes []string) error
+
+	// GetConsents returns the consents a user has given.
+	GetConsents(userID int32) ([]OAuthConsent, error)
+
+	// RevokeConsent removes the consent of a user for a client, revoking the
+	// refresh tokens the client holds for the user.
+	RevokeConsent(userID int32, clientID string) error
+
+	// IssueAuthorizationCode returns a code that can be exchanged once for
+	// the grant before the ttl elapses.
+	IssueAuthorizationCode(grant OAuthGrant, ttl time.Duration) (string, error)
+
+	// ConsumeAuthorizationCode marks a code as used and returns its grant.
+	ConsumeAuthorizationCode(code string) (*OAuthGrant, error)
+
+	// IssueRefreshToken returns a refresh token for the grant.
+	IssueRefreshToken(grant OAuthGrant, ttl time.Duration) (string, error)
+
+	// RotateRefreshToken revokes a refresh token of the client and returns its
+	// grant along with a new refresh token for it.
+	RotateRefreshToken(token string, clientID string, ttl time.Duration) (*OAuthGrant, string, error)
+
+	// RevokeRefreshToken revokes a refresh token of the client.
+	RevokeRefreshToken(token string, clientID string) error
+}
@@ -0,0 +1,116 @@
+/*
+Package Name: interfaces
+File Name: passkeys_interface.go
+Abstract: The interface of the service that stores the passkeys of the users and the
+challenges of the WebAuthn ceremonies in progress.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== TYPES ========
+
+// Passkey is a WebAuthn credential of a user.
+type Passkey struct {
+	ID     int32  `json:"id"`
+	UserID int32  `json:"-"`
+	Name   string `json:"name"`
+	// CredentialID and PublicKey identify the credential to authenticators
+	// and verify its signatures.
+	CredentialID []byte `json:"-"`
+	PublicKey    []byte `json:"-"`
+	SignCount    uint32 `json:"-"`
+	// BackedUp is whether the passkey is synced to other devices.
+	BackedUp   bool       `json:"backed_up"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// ======== CONSTANTS ========
+
+// The purposes of the ceremonies. A challenge can only be used for the
+// ceremony it was issued for.
+const (
+	PasskeyCeremonyRegistration = "registration"
+	PasskeyCeremonyLogin        = "login"
+	PasskeyCeremonyMFA          = "mfa"
+)
+
+// ======== ERRORS ========
+var (
+	PasskeyNotFoundException          = errors.New("The passkey could not be found.")
+	PasskeyAlreadyRegisteredException = errors.New("The passkey has already been registered.")
+	InvalidPasskeyCeremonyException   = errors.New("The passkey challenge is not valid or has expired.")
+)
+
+// ======== PUBLIC METHODS ========
+
+// Credential returns the WebAuthn credential of the passkey.
+func (passkey Passkey) Credential() common.WebAuthnCredential {
+	return common.WebAuthnCredential{
+		ID:        passkey.CredentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+		BackedUp:  passkey.BackedUp,
+	}
+}
+
+// ======== INTERFACES ========
+
+// The interface for the PasskeysService.
+type PasskeysRepository interface {
+	// BeginCeremony returns the challenge of a new ceremony, which can be
+	// completed once before the ttl elapses. The user is nil for logins,
+	// since they are not known until a passkey is presented.
+	BeginCeremony(userID *int32, purpose string, ttl time.Duration) ([]byte, error)
+
+	// ConsumeCeremony ends the ceremony of a challenge and returns the user
+	// it was started for.
+	ConsumeCeremony(challenge []byte, purpose string) (*int32, error)
+
+	// CreatePasskey stores a credential registered by the user.
+	CreatePasskey(userID int32, name string, credential common.WebAuthnCredential) (*Passkey, error)
+
+	// GetPasskeys returns the passkeys of the user.
+	GetPasskeys(userID int32) ([]Passkey, error)
+
+	// GetPasskeyByCredentialID returns the passkey of a credential.
+	GetPasskeyByCredentialID(credentialID []byte) (*Passkey, error)
+
+	// RecordUse stores the signature counter of a passkey after it is used.
+	RecordUse(id int32, signCount uint32) error
+
+	// DeletePasskey deletes a passkey of the user.
+	DeletePasskey(userID int32, id int32) error
+}
@@ -0,0 +1,113 @@
+/*
+Package Name: interfaces
+File Name: principal.go
+Abstract: The principal represents whoever is authenticated in a request.
+It lives in this package so that both the services that create it and
+the middlewares that consume it can share it without import cycles.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import "time"
+
+// ======== TYPES ========
+
+// Principal is the authenticated identity of a request, built from the
+// claims of the token that was presented.
+type Principal struct {
+	// UserID is the id of the authenticated user.
+	UserID int32
+	// SessionID identifies the login the token was issued for, and is
+	// shared by every token refreshed from it.
+	SessionID string
+	// TokenID is the unique id of the token (its `jti` claim).
+	TokenID string
+	// Roles are the roles of the user when the token was issued.
+	Roles []string
+	// Scopes are the permissions granted to the token.
+	Scopes []string
+	// IssuedAt is when the token was issued.
+	IssuedAt time.Time
+	// ExpiresAt is when the token expires.
+	ExpiresAt time.Time
+	// AuthMethod is how the user authenticated (see the AuthMethod constants).
+	AuthMethod string
+	// EmailVerified is whether the user had verified their email when the
+	// token was issued.
+	EmailVerified bool
+	// ClientID is the OAuth client the token was issued to, and is empty
+	// for the tokens the API issues to its own users.
+	ClientID string
+	// ActorID is the admin that is acting as the user, and is zero unless
+	// the token was issued for impersonating them.
+	ActorID int32
+}
+
+// ======== CONSTANTS ========
+
+// The methods a user can authenticate with. The values follow the
+// registry of Authentication Method Reference values (RFC 8176) when
+// there is one.
+const (
+	AuthMethodPassword  = "pwd"
+	AuthMethodMFA       = "mfa"
+	AuthMethodAPIKey    = "api_key"
+	AuthMethodOIDC      = "oidc"
+	AuthMethodMagicLink = "magic_link"
+	AuthMethodPasskey   = "hwk"
+	// AuthMethodImpersonation is set on the tokens issued to admins for
+	// acting as a user, who did not authenticate at all.
+	AuthMethodImpersonation = "impersonation"
+)
+
+// ======== PUBLIC METHODS ========
+
+// HasRole returns whether the principal has a role.
+func (principal Principal) HasRole(role string) bool {
+	for _, value := range principal.Roles {
+		if value == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsImpersonated returns whether the principal is an admin acting as the user.
+func (principal Principal) IsImpersonated() bool {
+	return principal.ActorID != 0
+}
+
+// HasScope returns whether the principal has been granted a scope.
+func (principal Principal) HasScope(scope string) bool {
+	for _, value := range principal.Scopes {
+		if value == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+/*
+Package Name: interfaces
+File Name: sessions_interface.go
+Abstract: The interface of the service that stores the sessions of the users,
+which are started by every login.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== TYPES ========
+
+// Session is a login of a user on a device. Browser clients can be identified by
+// the cookie of their session, while every other client uses tokens bound to it.
+type Session struct {
+	ID         int32     `json:"id"`
+	UserID     int32     `json:"-"`
+	Kind       string    `json:"kind"`
+	DeviceName string    `json:"device_name"`
+	AuthMethod string    `json:"auth_method"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is whether the session is the one of the request.
+	Current bool `json:"current"`
+	// FamilyID is the family of the refresh tokens of token sessions.
+	FamilyID string `json:"-"`
+	// CSRFTokenHash is the hash of the token that requests with unsafe
+	// methods have to send along the cookie of cookie sessions.
+	CSRFTokenHash string `json:"-"`
+}
+
+// Device describes where a login comes from.
+type Device struct {
+	// Name is given by the client, or guessed from the user agent.
+	Name      string
+	UserAgent string
+	IPAddress string
+}
+
+// ======== CONSTANTS ========
+
+// The kinds of sessions.
+const (
+	SessionKindCookie = "cookie"
+	SessionKindToken  = "token"
+)
+
+const (
+	// SessionCookie is the HttpOnly cookie that identifies the session.
+	SessionCookie = "session"
+	// CSRFCookie is the cookie the CSRF token of the session is stored in,
+	// which scripts of the client can read.
+	CSRFCookie = "csrf_token"
+	// CSRFHeader is the header the CSRF token has to be sent in.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// ======== ERRORS ========
+var (
+	InvalidSessionException   = errors.New("The session is not valid or has expired.")
+	SessionNotFoundException  = errors.New("The session could not be found.")
+	InvalidCSRFTokenException = errors.New("The CSRF token is missing or does not match the session.")
+)
+
+// ======== PUBLIC METHODS ========
+
+// CheckCSRFToken returns whether a token is the CSRF token of the session.
+func (session Session) CheckCSRFToken(token string) bool {
+	return token != "" &&
+		subtle.ConstantTimeCompare([]byte(session.CSRFTokenHash), []byte(common.Tokens.Hash(token))) == 1
+}
+
+// ======== INTERFACES ========
+
+// The interface for the SessionsService.
+type SessionsRepository interface {
+	// CreateSession starts a cookie session for a user that authenticated
+	// with the given method, and returns it along with its token and CSRF
+	// token.
+	CreateSession(userID int32, method string, device Device) (*Session, string, string, error)
+
+	// CheckSession checks whether the token of a cookie session is valid, and
+	// returns the principal it was started for along with the session.
+	CheckSession(token string) (*Principal, *Session, error)
+
+	// GetSessions returns the active sessions of a user, of every kind.
+	GetSessions(userID int32) ([]Session, error)
+
+	// RevokeSession ends a session of a user, revoking the tokens bound to it.
+	RevokeSession(userID int32, id int32) error
+
+	// RevokeOtherSessions ends every session of a user except the one a
+	// principal was authenticated with, and returns how many were ended.
+	RevokeOtherSessions(userID int32, current Principal) (int, error)
+}
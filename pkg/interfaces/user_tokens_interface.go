@@ -0,0 +1,69 @@
+/*
+Package Name: interfaces
+File Name: user_tokens_interface.go
+Abstract: The interface for the single-use tokens sent to users by email.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package interfaces
+
+import (
+	"errors"
+	"time"
+)
+
+// ======== CONSTANTS ========
+
+// The purposes a user token can be issued for. A token can only be consumed
+// for the purpose it was issued for.
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// ======== ERRORS ========
+var (
+	InvalidUserTokenException = errors.New("The token provided is not valid or has expired.")
+)
+
+// ======== INTERFACES ========
+
+// The interface for the UserTokensService.
+type UserTokensRepository interface {
+	// IssueUserToken returns a new token for the user that can be consumed
+	// once for the purpose given before the ttl elapses. Any token issued
+	// before for the same user and purpose stops being valid.
+	IssueUserToken(userID int32, purpose string, ttl time.Duration) (string, error)
+
+	// CheckUserToken returns the user a token was issued to without consuming
+	// it, for checking a request before acting on it.
+	CheckUserToken(token string, purpose string) (int32, error)
+
+	// ConsumeUserToken marks a token as used and returns the user it was
+	// issued to.
+	ConsumeUserToken(token string, purpose string) (int32, error)
+}
@@ -0,0 +1,55 @@
+/*
+Package Name: lib
+File Name: hasher.go
+Abstract: Provides the password hasher used across the API.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package lib
+
+import (
+	"os"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== METHODS ========
+
+// GetPasswordHasher returns the password hasher. New passwords are hashed with
+// the algorithm set in PASSWORD_HASH_ALGORITHM (argon2id, bcrypt or scrypt;
+// argon2id by default), but hashes of any of them can be checked.
+func GetPasswordHasher(logger Logger) common.PasswordHasher {
+	algorithm := common.Env.String("PASSWORD_HASH_ALGORITHM", common.AlgorithmArgon2id)
+
+	hasher, err := common.NewPasswordHasher(algorithm)
+	if err != nil {
+		logger.Fatal("Unable to set up the password hasher:", algorithm, err)
+		os.Exit(1)
+	}
+
+	return hasher
+}
@@ -6,7 +6,7 @@ for the lib files.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -42,5 +42,10 @@ var Module = fx.Options(
 		GetLogger,
 		GetDatabase,
 		GetRouter,
+		GetScheduler,
+		GetMailer,
+		GetPasswordHasher,
+		GetPasswordPolicy,
+		GetOIDCClient,
 	),
 )
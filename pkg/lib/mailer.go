@@ -0,0 +1,158 @@
+/*
+Package Name: lib
+File Name: mailer.go
+Abstract: The mailer used for sending emails to users, with log, file and SMTP transports.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package lib
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ======== TYPES ========
+
+// Mail is an email sent to a single recipient.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. The transport is chosen with the MAILER_TRANSPORT
+// environment variable, so that emails can be inspected during development
+// without setting up a mail server.
+type Mailer interface {
+	Send(mail Mail) error
+}
+
+// logMailer writes the emails to the log.
+type logMailer struct {
+	logger Logger
+}
+
+// fileMailer writes every email to a file in a directory.
+type fileMailer struct {
+	dir string
+}
+
+// smtpMailer sends the emails through an SMTP server.
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// ======== METHODS ========
+
+// GetMailer returns the mailer for the transport set in MAILER_TRANSPORT:
+//
+//   - log (default): emails are written to the log.
+//   - file: emails are written to MAILER_DIR (defaults to ./mail).
+//   - smtp: emails are sent through SMTP_HOST:SMTP_PORT as MAILER_FROM,
+//     authenticating with SMTP_USERNAME and SMTP_PASSWORD if set.
+func GetMailer(logger Logger) Mailer {
+	switch transport := os.Getenv("MAILER_TRANSPORT"); transport {
+	case "", "log":
+		return logMailer{logger: logger}
+	case "file":
+		dir := os.Getenv("MAILER_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return fileMailer{dir: dir}
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		mailer := smtpMailer{
+			addr: net.JoinHostPort(host, os.Getenv("SMTP_PORT")),
+			from: os.Getenv("MAILER_FROM"),
+		}
+		if username := os.Getenv("SMTP_USERNAME"); username != "" {
+			mailer.auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		return mailer
+	default:
+		logger.Fatal("Unknown mailer transport:", transport)
+		os.Exit(1)
+		return nil
+	}
+}
+
+// Send writes the email to the log.
+func (mailer logMailer) Send(mail Mail) error {
+	mailer.logger.Info(fmt.Sprintf("Sending email to %s: %s\n%s", mail.To, mail.Subject, mail.Body))
+	return nil
+}
+
+// Send writes the email to a new file in the directory of the mailer.
+func (mailer fileMailer) Send(mail Mail) error {
+	if err := os.MkdirAll(mailer.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(mail.To))
+	return os.WriteFile(filepath.Join(mailer.dir, name), formatMail("", mail), 0o644)
+}
+
+// Send sends the email through the SMTP server.
+func (mailer smtpMailer) Send(mail Mail) error {
+	return smtp.SendMail(mailer.addr, mailer.auth, mailer.from, []string{mail.To}, formatMail(mailer.from, mail))
+}
+
+// ======== PRIVATE METHODS ========
+
+// formatMail formats an email as a plain text message.
+func formatMail(from string, mail Mail) []byte {
+	builder := strings.Builder{}
+	if from != "" {
+		builder.WriteString("From: " + from + "\r\n")
+	}
+	builder.WriteString("To: " + mail.To + "\r\n")
+	builder.WriteString("Subject: " + mail.Subject + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(mail.Body)
+	return []byte(builder.String())
+}
+
+// sanitizeFileName replaces the characters that are not safe in file names.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '@' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,62 @@
+/*
+Package Name: lib
+File Name: oidc.go
+Abstract: Provides the client of the OpenID Connect provider users can log in
+with, if one is configured.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package lib
+
+import (
+	"strings"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== METHODS ========
+
+// GetOIDCClient returns the client of the OpenID Connect provider set in
+// OIDC_ISSUER, or nil if there is none and users can only log in with
+// their password. The client is registered with the provider using
+// OIDC_CLIENT_ID, OIDC_CLIENT_SECRET and OIDC_REDIRECT_URL, and requests
+// the scopes in OIDC_SCOPES ("openid email profile" by default).
+func GetOIDCClient(logger Logger) *common.OIDCClient {
+	issuer := common.Env.String("OIDC_ISSUER", "")
+	if issuer == "" {
+		return nil
+	}
+
+	logger.Info("Users can log in with the identity provider", issuer)
+	return common.NewOIDCClient(common.OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     common.Env.String("OIDC_CLIENT_ID", ""),
+		ClientSecret: common.Env.String("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  common.Env.String("OIDC_REDIRECT_URL", ""),
+		Scopes:       strings.Fields(common.Env.String("OIDC_SCOPES", "openid email profile")),
+	}, nil)
+}
@@ -0,0 +1,74 @@
+/*
+Package Name: lib
+File Name: password_policy.go
+Abstract: Provides the policy new passwords must follow.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package lib
+
+import (
+	"os"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== METHODS ========
+
+// GetPasswordPolicy returns the password policy. Every rule of the default
+// policy can be changed through the environment, and a list of breached
+// passwords built with cmd/breached-passwords can be loaded from the file set
+// in PASSWORD_BREACHED_FILTER.
+func GetPasswordPolicy(logger Logger) common.PasswordPolicy {
+	policy := common.DefaultPasswordPolicy()
+	policy.MinLength = common.Env.Int("PASSWORD_MIN_LENGTH", policy.MinLength)
+	policy.MaxLength = common.Env.Int("PASSWORD_MAX_LENGTH", policy.MaxLength)
+	policy.RequireLowercase = common.Env.Bool("PASSWORD_REQUIRE_LOWERCASE", policy.RequireLowercase)
+	policy.RequireUppercase = common.Env.Bool("PASSWORD_REQUIRE_UPPERCASE", policy.RequireUppercase)
+	policy.RequireDigit = common.Env.Bool("PASSWORD_REQUIRE_DIGIT", policy.RequireDigit)
+	policy.RequireSymbol = common.Env.Bool("PASSWORD_REQUIRE_SYMBOL", policy.RequireSymbol)
+	policy.MinStrength = common.Env.Int("PASSWORD_MIN_STRENGTH", policy.MinStrength)
+	policy.History = common.Env.Int("PASSWORD_HISTORY", policy.History)
+
+	if path := common.Env.String("PASSWORD_BREACHED_FILTER", ""); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			logger.Fatal("Unable to open the list of breached passwords:", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		policy.Breached, err = common.ReadBloomFilter(file)
+		if err != nil {
+			logger.Fatal("Unable to load the list of breached passwords:", err)
+			os.Exit(1)
+		}
+		logger.Info("Loaded the list of breached passwords from", path)
+	}
+
+	return policy
+}
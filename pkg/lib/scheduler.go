@@ -0,0 +1,139 @@
+/*
+Package Name: lib
+File Name: scheduler.go
+Abstract: The scheduler used for running background maintenance tasks
+(e.g. purging expired rows) periodically while the API is running.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package lib
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// ======== TYPES ========
+
+// Scheduler runs tasks periodically during the lifetime of the application.
+// Tasks start when the application starts and are stopped when it stops.
+type Scheduler struct {
+	logger Logger
+	mutex  sync.Mutex
+	tasks  []scheduledTask
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// scheduledTask is a task registered in the scheduler.
+type scheduledTask struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+// ======== METHODS ========
+
+// GetScheduler returns a scheduler bound to the lifecycle of the application.
+func GetScheduler(lifecycle fx.Lifecycle, logger Logger) *Scheduler {
+	scheduler := &Scheduler{logger: logger}
+
+	lifecycle.Append(
+		fx.Hook{
+			OnStart: func(context.Context) error {
+				scheduler.start()
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				scheduler.stop()
+				return nil
+			},
+		},
+	)
+
+	return scheduler
+}
+
+// Every registers a task that runs once when the application starts and then
+// every interval. Errors returned by the task are logged and the task keeps
+// being scheduled.
+func (scheduler *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	task := scheduledTask{name: name, interval: interval, run: run}
+	scheduler.tasks = append(scheduler.tasks, task)
+
+	// If the scheduler is already running, start the task right away.
+	if scheduler.ctx != nil {
+		go scheduler.loop(scheduler.ctx, task)
+	}
+}
+
+// ======== PRIVATE METHODS ========
+
+// start starts every registered task.
+func (scheduler *Scheduler) start() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scheduler.ctx, scheduler.cancel = context.WithCancel(context.Background())
+	for _, task := range scheduler.tasks {
+		go scheduler.loop(scheduler.ctx, task)
+	}
+}
+
+// stop stops every running task.
+func (scheduler *Scheduler) stop() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	if scheduler.cancel != nil {
+		scheduler.cancel()
+	}
+}
+
+// loop runs a task until the context is cancelled.
+func (scheduler *Scheduler) loop(ctx context.Context, task scheduledTask) {
+	ticker := time.NewTicker(task.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := task.run(ctx); err != nil {
+			scheduler.logger.Error("Scheduled task", task.name, "failed:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Package Name: roles
+File Name: roles_controller.go
+Abstract: The controller for listing roles and assigning them to users.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package roles
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/gin-gonic/gin"
+)
+
+// ======== TYPES ========
+
+// RolesController data type
+type RolesController struct {
+	logger  lib.Logger
+	service RolesRepository
+}
+
+type AssignRoleBody struct {
+	Role string `json:"role" form:"role" binding:"required"`
+}
+
+// ======== METHODS ========
+
+// GetRolesController creates a new roles controller.
+func GetRolesController(logger lib.Logger, service RolesRepository) RolesController {
+	return RolesController{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// GetAll returns every role with its permissions.
+func (controller RolesController) GetAll(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting all roles.")
+
+	roles, err := controller.service.GetRoles()
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+// GetUserRoles returns the roles of a user.
+func (controller RolesController) GetUserRoles(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting roles of user with id", ctx.Param("id"))
+
+	id, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+
+	roles, err := controller.service.GetUserRoles(id)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+// Assign assigns a role to a user.
+func (controller RolesController) Assign(ctx *gin.Context) {
+	controller.logger.Info("[POST] Assigning role to user with id", ctx.Param("id"))
+
+	id, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := AssignRoleBody{}
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	if err := controller.service.AssignRole(id, body.Role); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Role assigned successfully.",
+	})
+}
+
+// Unassign removes a role from a user.
+func (controller RolesController) Unassign(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Removing role", ctx.Param("role"), "from user with id", ctx.Param("id"))
+
+	id, ok := getUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := controller.service.UnassignRole(id, ctx.Param("role")); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Role removed successfully.",
+	})
+}
+
+// ======== PRIVATE METHODS ========
+
+// getUserID converts the id parameter of the route to an int32, aborting the
+// request if it is not valid.
+func getUserID(ctx *gin.Context) (int32, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return 0, false
+	}
+	return int32(id), true
+}
@@ -0,0 +1,53 @@
+/*
+Package Name: roles
+File Name: roles_repository.go
+Abstract: Interface for the RolesService used for allowing to mock it in tests.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package roles
+
+// ======== INTERFACES ========
+
+// The interface for the RolesService.
+type RolesRepository interface {
+	// GetRoles returns every role with its permissions.
+	GetRoles() ([]Role, error)
+
+	// GetUserRoles returns the roles assigned to a user.
+	GetUserRoles(userID int32) ([]Role, error)
+
+	// GetUserPermissions returns the names of the roles of a user and the
+	// permissions they grant.
+	GetUserPermissions(userID int32) (roles []string, permissions []string, err error)
+
+	// AssignRole assigns a role to a user.
+	AssignRole(userID int32, role string) error
+
+	// UnassignRole removes a role from a user.
+	UnassignRole(userID int32, role string) error
+}
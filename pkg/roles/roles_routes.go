@@ -0,0 +1,76 @@
+/*
+Package Name: roles
+File Name: roles_routes.go
+Abstract: The routes for listing roles and assigning them to users.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package roles
+
+import (
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+)
+
+// ======== TYPES ========
+
+// RolesRoutes struct
+type RolesRoutes struct {
+	logger          lib.Logger
+	router          *lib.Router
+	rolesController RolesController
+	authMiddleware  middlewares.AuthMiddleware
+}
+
+// ======== PUBLIC METHODS ========
+
+// Returns a RolesRoutes struct.
+func SetRolesRoutes(
+	logger lib.Logger,
+	router *lib.Router,
+	rolesController RolesController,
+	authMiddleware middlewares.AuthMiddleware,
+) RolesRoutes {
+	return RolesRoutes{
+		logger:          logger,
+		router:          router,
+		rolesController: rolesController,
+		authMiddleware:  authMiddleware,
+	}
+}
+
+// Setup the roles routes
+func (route RolesRoutes) Setup() {
+	route.logger.Info("Setting up [ROLES] routes.")
+	api := route.router.Group("/").Use(route.authMiddleware.Handler())
+	{
+		api.GET("/roles", route.authMiddleware.Require("roles:read"), route.rolesController.GetAll)
+		api.GET("/users/:id/roles", route.authMiddleware.Require("roles:read"), route.rolesController.GetUserRoles)
+		api.POST("/users/:id/roles", route.authMiddleware.Require("roles:write"), route.rolesController.Assign)
+		api.DELETE("/users/:id/roles/:role", route.authMiddleware.Require("roles:write"), route.rolesController.Unassign)
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Package Name: roles
+File Name: roles_service.go
+Abstract: The service for retrieving roles and assigning them to users.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package roles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ======== TYPES ========
+
+// RolesService service layer
+type RolesService struct {
+	logger lib.Logger
+	db     *lib.Database
+}
+
+// ======== CONSTANTS ========
+
+// rolesQuery selects every role along with the names of its permissions. The
+// placeholder is replaced by the joins and conditions that filter the roles.
+const rolesQuery = `
+	SELECT r.id, r.name, r.description,
+		COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+	FROM auth.role r
+	LEFT JOIN auth.role_permission rp ON rp.role_id = r.id
+	LEFT JOIN auth.permission p ON p.id = rp.permission_id
+	%s
+	GROUP BY r.id
+	ORDER BY r.name;`
+
+// ======== PUBLIC METHODS ========
+
+// GetRolesService returns the roles service.
+func GetRolesService(logger lib.Logger, db *lib.Database) RolesRepository {
+	return RolesService{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// GetRoles returns every role with its permissions.
+func (service RolesService) GetRoles() ([]Role, error) {
+	service.logger.Info("Retrieving all roles.")
+	return service.getRolesByQuery(fmt.Sprintf(rolesQuery, ""))
+}
+
+// GetUserRoles returns the roles assigned to a user.
+func (service RolesService) GetUserRoles(userID int32) ([]Role, error) {
+	service.logger.Info("Retrieving roles of user with id", userID)
+	return service.getRolesByQuery(
+		fmt.Sprintf(rolesQuery, "JOIN auth.user_role ur ON ur.role_id = r.id WHERE ur.user_id = $1"),
+		userID,
+	)
+}
+
+// GetUserPermissions returns the names of the roles of a user and the
+// permissions they grant, sorted and without duplicates.
+func (service RolesService) GetUserPermissions(userID int32) (roles []string, permissions []string, err error) {
+	userRoles, err := service.GetUserRoles(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, role := range userRoles {
+		roles = append(roles, role.Name)
+		for _, permission := range role.Permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	sort.Strings(permissions)
+
+	return roles, permissions, nil
+}
+
+// AssignRole assigns a role to a user. Assigning a role the user already has
+// does nothing.
+func (service RolesService) AssignRole(userID int32, role string) error {
+	roleID, err := service.getRoleID(role)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.db.Exec(
+		context.Background(),
+		`INSERT INTO auth.user_role (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`,
+		userID,
+		roleID,
+	)
+	if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "23503" {
+		// A foreign key violation means the user does not exist.
+		return fmt.Errorf("The user with the id '%d' could not be found.", userID)
+	}
+	return err
+}
+
+// UnassignRole removes a role from a user.
+func (service RolesService) UnassignRole(userID int32, role string) error {
+	roleID, err := service.getRoleID(role)
+	if err != nil {
+		return err
+	}
+
+	tag, err := service.db.Exec(
+		context.Background(),
+		`DELETE FROM auth.user_role WHERE user_id = $1 AND role_id = $2;`,
+		userID,
+		roleID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("The user with the id '%d' does not have the role '%s'.", userID, role)
+	}
+
+	return nil
+}
+
+// ======== PRIVATE METHODS ========
+
+// getRoleID returns the id of a role from its name.
+func (service RolesService) getRoleID(role string) (int32, error) {
+	var id int32
+	err := service.db.QueryRow(
+		context.Background(),
+		`SELECT id FROM auth.role WHERE name = $1;`,
+		role,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("The role '%s' does not exist.", role)
+	}
+	return id, err
+}
+
+// getRolesByQuery returns the roles selected by a query.
+func (service RolesService) getRolesByQuery(query string, args ...interface{}) ([]Role, error) {
+	rows, err := service.db.Query(context.Background(), query, args...)
+	if err != nil {
+		service.logger.Error("Error while executing query. Err:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.Permissions); err != nil {
+			service.logger.Error("Error while iterating dataset. Err:", err)
+			return nil, err
+		}
+		results = append(results, role)
+	}
+
+	return results, rows.Err()
+}
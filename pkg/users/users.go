@@ -5,7 +5,7 @@ Abstract: Wrapper for exposing to fx all the components of the 'users' context.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -40,4 +40,5 @@ var Context = fx.Options(
 	fx.Provide(GetUsersController),
 	fx.Provide(GetUsersService),
 	fx.Provide(SetUsersRoutes),
+	fx.Invoke(ScheduleUnverifiedUsersCleanup),
 )
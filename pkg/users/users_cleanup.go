@@ -0,0 +1,67 @@
+/*
+Package Name: users
+File Name: users_cleanup.go
+Abstract: Removes the accounts whose email has not been verified after a while.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package users
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+)
+
+// ======== PUBLIC METHODS ========
+
+// ScheduleUnverifiedUsersCleanup periodically deletes the accounts that have not
+// verified their email within UNVERIFIED_USERS_MAX_AGE of signing up. Nothing is
+// deleted unless the variable is set.
+func ScheduleUnverifiedUsersCleanup(logger lib.Logger, scheduler *lib.Scheduler, service UsersRepository) {
+	maxAge := common.Env.Duration("UNVERIFIED_USERS_MAX_AGE", 0)
+	if maxAge <= 0 {
+		return
+	}
+
+	scheduler.Every(
+		"delete unverified users",
+		common.Env.Duration("UNVERIFIED_USERS_CLEANUP_INTERVAL", time.Hour),
+		func(ctx context.Context) error {
+			deleted, err := service.DeleteUnverifiedUsers(time.Now().Add(-maxAge))
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logger.Info("Deleted", deleted, "users that did not verify their email.")
+			}
+			return nil
+		},
+	)
+}
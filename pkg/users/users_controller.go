@@ -5,7 +5,7 @@ Abstract: The user controller for performing operations after a route is called.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -33,9 +33,14 @@ package users
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
 	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
 	"github.com/gin-gonic/gin"
 )
@@ -45,31 +50,78 @@ import (
 // UsersController data type
 type UsersController struct {
 	// service domains.UserService
-	logger  lib.Logger
-	service UsersRepository
+	logger   lib.Logger
+	service  UsersRepository
+	auth     interfaces.AuthService
+	hasher   common.PasswordHasher
+	policy   common.PasswordPolicy
+	sessions interfaces.SessionsRepository
+	attempts interfaces.LoginAttemptsRepository
 }
 
+// UpdateUserBody is the new data of a user.
+type UpdateUserBody struct {
+	Username string `json:"username" form:"username" binding:"required,alpha"`
+	Email    string `json:"email" form:"email" binding:"required,email"`
+}
+
+// UpdateMeBody is the data of the authenticated user to change. The fields that
+// are not sent are left as they are.
+type UpdateMeBody struct {
+	Username *string `json:"username" form:"username" binding:"omitempty,alpha"`
+	Email    *string `json:"email" form:"email" binding:"omitempty,email"`
+}
+
+// ChangePasswordBody is the current password of the authenticated user along
+// with the new one.
+type ChangePasswordBody struct {
+	CurrentPassword string `json:"current_password" form:"current_password" binding:"required"`
+	Password        string `json:"password" form:"password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" form:"confirm_password" binding:"required,eqfield=Password"`
+}
+
+// ======== CONSTANTS ========
+
+// searchMaxLength is the maximum length of the query of a search.
+const searchMaxLength = 100
+
+// ======== ERRORS ========
+var (
+	IncorrectPasswordException = errors.New("The password provided is incorrect.")
+)
+
 // ======== METHODS ========
 
 // Creates a new user controller and exposes its routes
 // to the router.
-func GetUsersController(logger lib.Logger, service UsersRepository) UsersController {
+func GetUsersController(
+	logger lib.Logger,
+	service UsersRepository,
+	auth interfaces.AuthService,
+	hasher common.PasswordHasher,
+	policy common.PasswordPolicy,
+	sessions interfaces.SessionsRepository,
+	attempts interfaces.LoginAttemptsRepository,
+) UsersController {
 	return UsersController{
-		logger:  logger,
-		service: service,
+		logger:   logger,
+		service:  service,
+		auth:     auth,
+		hasher:   hasher,
+		policy:   policy,
+		sessions: sessions,
+		attempts: attempts,
 	}
 }
 
 func (controller UsersController) Get(ctx *gin.Context) {
-	// Get the id from the context
-	idParam := ctx.Param("id")
-	controller.logger.Info("[GET] Getting user with id", idParam)
+	controller.logger.Info("[GET] Getting user with id", ctx.Param("id"))
 
-	// ======== TYPE CONVERSION ========
-	// Convert the id from string to int
-	id, err := strconv.Atoi(idParam)
-	if err != nil {
-		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+	// ======== CHECK PERMISSIONS ========
+	// Users can always see their own profile, but they need the users:read
+	// permission for seeing anyone else's.
+	id, ok := parseUserID(ctx, "users:read")
+	if !ok {
 		return
 	}
 
@@ -89,24 +141,313 @@ func (controller UsersController) Get(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, publicUser)
 }
 
+// GetAll returns a page of the users that match the filter of the request. The
+// URLs of the pages around it are in the Link header.
 func (controller UsersController) GetAll(ctx *gin.Context) {
 	controller.logger.Info("[GET] Getting all users.")
 
-	// ======== RETRIEVE USER ========
-	internalUsers, err := controller.service.GetUsers()
+	// ======== VALIDATE PARAMETERS ========
+	request, errors := common.Pagination.ParsePage(ctx, UserSortFields, "id")
+	if errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+	filter, errors := common.Filters.ParseQuery(ctx, UserFilterFields)
+	if errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== RETRIEVE USERS ========
+	internalUsers, err := controller.service.GetUsers(*request, filter)
 	if err != nil {
-		ctx.AbortWithError(http.StatusBadRequest, err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
+	page := common.NewPage(*request, internalUsers, InternalUser.CursorKey)
+	if request.Total {
+		total, err := controller.service.CountUsers(filter)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		page.Total = &total
+	}
+
 	// The controller.service.GetUser(int) function returns a models.InternalUser
 	// struct, which contains the password. To avoid exposing this data to the
 	// end user, we must convert the internal user to a public user as follows:
-	publicUsers := make([]PublicUser, len(internalUsers))
-	for user := range internalUsers {
-		publicUsers[user] = internalUsers[user].ToPublic()
+	publicUsers := make([]PublicUser, len(page.Items))
+	for user := range page.Items {
+		publicUsers[user] = page.Items[user].ToPublic()
 	}
 
-	// We can now return the user
+	// We can now return the users
+	page.SetHeaders(ctx)
 	ctx.JSON(http.StatusOK, publicUsers)
 }
+
+// Search returns the users that match the q parameter, from the most relevant,
+// with the fragments that match it highlighted. The results are paged with the
+// limit and offset parameters, and the URLs of the pages around them are in the
+// Link header.
+func (controller UsersController) Search(ctx *gin.Context) {
+	controller.logger.Info("[GET] Searching users.")
+
+	// ======== VALIDATE PARAMETERS ========
+	query := strings.TrimSpace(ctx.Query("q"))
+	terms := common.Search.Terms(query)
+	if query == "" || len(terms) == 0 {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, common.Validation.FieldErrors(
+			"q", "The search must contain at least a letter or a number.",
+		))
+		return
+	}
+	if len([]rune(query)) > searchMaxLength {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, common.Validation.FieldErrors(
+			"q", fmt.Sprintf("The search cannot be longer than %d characters.", searchMaxLength),
+		))
+		return
+	}
+	request, errors := common.Pagination.ParseOffset(ctx)
+	if errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== SEARCH USERS ========
+	// One more match than the limit is searched for, to know whether there
+	// is a next page.
+	matches, err := controller.service.SearchUsers(query, request.Limit+1, request.Offset)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	more := len(matches) > request.Limit
+	if more {
+		matches = matches[:request.Limit]
+	}
+
+	results := make([]UserSearchResult, len(matches))
+	for i, match := range matches {
+		results[i] = match.ToSearchResult(terms)
+	}
+
+	request.SetHeaders(ctx, more)
+	ctx.JSON(http.StatusOK, results)
+}
+
+// Update changes the username and email of a user.
+func (controller UsersController) Update(ctx *gin.Context) {
+	controller.logger.Info("[PUT] Updating user with id", ctx.Param("id"))
+
+	// ======== CHECK PERMISSIONS ========
+	// Users can always update their own profile, but they need the
+	// users:write permission for updating anyone else's.
+	id, ok := parseUserID(ctx, "users:write")
+	if !ok {
+		return
+	}
+
+	// ======== VALIDATE PARAMETERS ========
+	body := UpdateUserBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== UPDATE USER ========
+	internalUser, err := controller.service.UpdateUser(int32(id), body.Username, body.Email)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, internalUser.ToPublic())
+}
+
+// Delete deletes a user.
+func (controller UsersController) Delete(ctx *gin.Context) {
+	controller.logger.Info("[DELETE] Deleting user with id", ctx.Param("id"))
+
+	// ======== CHECK PERMISSIONS ========
+	// Users can always delete their own account, but they need the
+	// users:write permission for deleting anyone else's.
+	id, ok := parseUserID(ctx, "users:write")
+	if !ok {
+		return
+	}
+
+	// ======== REVOKE TOKENS ========
+	// The tokens of the user are revoked first, since they would otherwise
+	// keep working after the user is deleted until they expire.
+	if err := controller.auth.RevokeAllTokens(int32(id)); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// ======== DELETE USER ========
+	if err := controller.service.DeleteUser(int32(id)); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "User deleted successfully.",
+	})
+}
+
+// Me returns the authenticated user, so that clients do not have to find out
+// their id from their token.
+func (controller UsersController) Me(ctx *gin.Context) {
+	controller.logger.Info("[GET] Getting the authenticated user.")
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	internalUser, err := controller.service.GetUserById(int(principal.UserID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, internalUser.ToPublic())
+}
+
+// UpdateMe changes the username or the email of the authenticated user.
+func (controller UsersController) UpdateMe(ctx *gin.Context) {
+	controller.logger.Info("[PATCH] Updating the authenticated user.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := UpdateMeBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== RETRIEVE USER ========
+	principal := middlewares.MustGetPrincipal(ctx)
+	internalUser, err := controller.service.GetUserById(int(principal.UserID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// ======== UPDATE USER ========
+	username, email := internalUser.Username, internalUser.Email
+	if body.Username != nil {
+		username = *body.Username
+	}
+	if body.Email != nil {
+		email = *body.Email
+	}
+
+	internalUser, err = controller.service.UpdateUser(principal.UserID, username, email)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, internalUser.ToPublic())
+}
+
+// ChangePassword sets a new password for the authenticated user, who has to
+// provide the current one, and logs them out of every other device.
+func (controller UsersController) ChangePassword(ctx *gin.Context) {
+	controller.logger.Info("[POST] Changing the password of the authenticated user.")
+
+	// ======== VALIDATE PARAMETERS ========
+	body := ChangePasswordBody{}
+
+	// Validate the body and, if successful, assign the
+	// contents to the DTO.
+	if errors := common.Validation.ValidateBody(ctx, &body); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== CHECK CURRENT PASSWORD ========
+	principal := middlewares.MustGetPrincipal(ctx)
+	internalUser, err := controller.service.GetUserById(int(principal.UserID))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// Wrong passwords count as failed logins, so that whoever holds a stolen
+	// token cannot guess the password of the user here instead of logging in.
+	if middlewares.AbortIfThrottled(ctx, controller.attempts, internalUser.Email) {
+		return
+	}
+
+	matches, err := controller.hasher.Compare(body.CurrentPassword, internalUser.Password)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if !matches {
+		middlewares.RecordFailedLogin(ctx, controller.logger, controller.attempts, internalUser.Email)
+		ctx.AbortWithError(http.StatusBadRequest, IncorrectPasswordException)
+		return
+	}
+
+	// ======== CHECK PASSWORD POLICY ========
+	if errors := controller.policy.Validate("password", body.Password, internalUser.Username, internalUser.Email); errors != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, errors)
+		return
+	}
+
+	// ======== UPDATE PASSWORD ========
+	// The new password is hashed with the current parameters of the hasher.
+	err = controller.service.UpdatePassword(principal.UserID, body.Password)
+	if errors.Is(err, PasswordReusedException) {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, common.Validation.FieldErrors("password", err.Error()))
+		return
+	} else if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Whoever knew the old password could still be logged in elsewhere, so
+	// every other session of the user is revoked.
+	revoked, err := controller.sessions.RevokeOtherSessions(principal.UserID, *principal)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":          "Password changed successfully.",
+		"revoked_sessions": revoked,
+	})
+}
+
+// ======== PRIVATE METHODS ========
+
+// parseUserID returns the id of the user a request is about. Users can always
+// act on themselves, but acting on anyone else requires a permission. If the id
+// is not valid or the permission is missing, the request is aborted.
+func parseUserID(ctx *gin.Context, permission string) (int, bool) {
+	// ======== TYPE CONVERSION ========
+	// Convert the id from string to int
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, errors.New("The id must be an int."))
+		return 0, false
+	}
+
+	principal := middlewares.MustGetPrincipal(ctx)
+	if int(principal.UserID) != id && !principal.HasScope(permission) {
+		ctx.AbortWithError(http.StatusForbidden, middlewares.ForbiddenException).SetMeta(gin.H{
+			"required_permissions": []string{permission},
+		})
+		return 0, false
+	}
+
+	return id, true
+}
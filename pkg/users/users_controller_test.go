@@ -0,0 +1,475 @@
+package users_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alexmodrono/gin-restapi-template/internal/middlewares"
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+	"github.com/alexmodrono/gin-restapi-template/pkg/users"
+	"github.com/alexmodrono/gin-restapi-template/test/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUsersRouter returns a router with the users routes, whose principal is the
+// user 1.
+func newUsersRouter(authService *mocks.MockAuthService, usersService *mocks.MockUsersService, sessions *mocks.MockSessionsService) *gin.Engine {
+	return newUsersRouterWithAttempts(authService, usersService, sessions, &mocks.MockLoginAttemptsService{})
+}
+
+// newUsersRouterWithAttempts returns a router with the users routes that counts
+// the failed logins with the given service.
+func newUsersRouterWithAttempts(
+	authService *mocks.MockAuthService,
+	usersService *mocks.MockUsersService,
+	sessions *mocks.MockSessionsService,
+	attempts *mocks.MockLoginAttemptsService,
+) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	errors_middleware := middlewares.GetErrorsMiddleware(mocks.NewMockLogger(), router)
+	errors_middleware.Setup()
+
+	logger := &mocks.MockLogger{}
+	authMiddleware := middlewares.GetAuthMiddleware(logger, authService, &mocks.MockAPIKeysService{}, sessions, &mocks.MockImpersonationsService{})
+	usersController := users.GetUsersController(logger, usersService, authService, common.Hasher, common.DefaultPasswordPolicy(), sessions, attempts)
+	users.SetUsersRoutes(logger, router, usersController, authMiddleware).Setup()
+
+	return router
+}
+
+// request performs an authenticated request and returns its status and body.
+func request(router *gin.Engine, method string, path string, body interface{}) (int, map[string]interface{}) {
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer mock_jwt_token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+// requestWithAPIKey performs a request authenticated with the mock API key and
+// returns its status.
+func requestWithAPIKey(router *gin.Engine, method string, path string, body interface{}) int {
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", mocks.MockAPIKey)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestUsersController_UpdateAndDelete(t *testing.T) {
+	authService := &mocks.MockAuthService{EmailVerified: true}
+	usersService := &mocks.MockUsersService{}
+	router := newUsersRouter(authService, usersService, &mocks.MockSessionsService{})
+	request := func(method string, path string, body interface{}) (int, map[string]interface{}) {
+		return request(router, method, path, body)
+	}
+
+	t.Run("UpdateYourself", func(t *testing.T) {
+		code, response := request("PUT", "/users/1", users.UpdateUserBody{Username: "alejandro", Email: "alex@example.com"})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "alejandro", response["username"])
+		assert.Equal(t, "alex@example.com", response["email"])
+		assert.Nil(t, response["password"])
+	})
+
+	t.Run("UpdateInvalidBody", func(t *testing.T) {
+		code, _ := request("PUT", "/users/1", users.UpdateUserBody{Username: "alejandro", Email: "not an email"})
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("UpdateTakenEmail", func(t *testing.T) {
+		code, response := request("PUT", "/users/1", users.UpdateUserBody{Username: "user", Email: "user2@example.com"})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "User with email user2@example.com already exists.", response["error"])
+	})
+
+	t.Run("UpdateSomeoneElse", func(t *testing.T) {
+		code, response := request("PUT", "/users/2", users.UpdateUserBody{Username: "other", Email: "other@example.com"})
+
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Equal(t, []interface{}{"users:write"}, response["required_permissions"])
+	})
+
+	t.Run("DeleteSomeoneElse", func(t *testing.T) {
+		code, _ := request("DELETE", "/users/2", nil)
+
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Empty(t, usersService.DeletedUsers)
+		assert.Empty(t, authService.RevokedUsers)
+	})
+
+	t.Run("DeleteWithPermission", func(t *testing.T) {
+		authService.Scopes = []string{"users:write"}
+		defer func() { authService.Scopes = nil }()
+
+		code, _ := request("DELETE", "/users/2", nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []int32{2}, usersService.DeletedUsers)
+		// The tokens already issued to the user stop working.
+		assert.Equal(t, []int32{2}, authService.RevokedUsers)
+	})
+
+	t.Run("DeleteYourself", func(t *testing.T) {
+		code, _ := request("DELETE", "/users/1", nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []int32{2, 1}, usersService.DeletedUsers)
+		assert.Equal(t, []int32{2, 1}, authService.RevokedUsers)
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		code, _ := request("DELETE", "/users/abc", nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	// Users need no permission for changing their own account, so neither API
+	// keys nor OAuth clients can do it.
+	t.Run("WithAPIKey", func(t *testing.T) {
+		deleted := len(usersService.DeletedUsers)
+
+		code := requestWithAPIKey(router, "PUT", "/users/1", users.UpdateUserBody{Username: "attacker", Email: "attacker@example.com"})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code = requestWithAPIKey(router, "DELETE", "/users/1", nil)
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Len(t, usersService.DeletedUsers, deleted)
+	})
+
+	t.Run("WithOAuthClient", func(t *testing.T) {
+		authService.ClientID = "third_party"
+		defer func() { authService.ClientID = "" }()
+		deleted := len(usersService.DeletedUsers)
+
+		code, _ := request("PUT", "/users/1", users.UpdateUserBody{Username: "attacker", Email: "attacker@example.com"})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = request("DELETE", "/users/1", nil)
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Len(t, usersService.DeletedUsers, deleted)
+	})
+}
+
+func TestUsersController_Me(t *testing.T) {
+	// The email of the user is not verified, which does not keep them from
+	// managing their account.
+	authService := &mocks.MockAuthService{}
+	usersService := &mocks.MockUsersService{PreviousPasswords: []string{"correct horse battery staple"}}
+	sessions := &mocks.MockSessionsService{Sessions: map[int32]interfaces.Session{
+		1: {ID: 1, UserID: 1, Kind: interfaces.SessionKindToken, FamilyID: "mock_session"},
+		2: {ID: 2, UserID: 1, Kind: interfaces.SessionKindToken, FamilyID: "other_session"},
+		3: {ID: 3, UserID: 2, Kind: interfaces.SessionKindToken, FamilyID: "another_user_session"},
+	}}
+	router := newUsersRouter(authService, usersService, sessions)
+	request := func(method string, path string, body interface{}) (int, map[string]interface{}) {
+		return request(router, method, path, body)
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		code, response := request("GET", "/users/me", nil)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, float64(1), response["id"])
+		assert.Equal(t, "user", response["username"])
+		assert.Nil(t, response["password"])
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		// Only the fields sent are changed.
+		code, response := request("PATCH", "/users/me", map[string]string{"username": "alejandro"})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "alejandro", response["username"])
+		assert.Equal(t, "user@example.com", response["email"])
+	})
+
+	t.Run("UpdateWhileImpersonating", func(t *testing.T) {
+		authService.ActorID = 2
+		defer func() { authService.ActorID = 0 }()
+
+		code, _ := request("PATCH", "/users/me", map[string]string{"username": "alejandro"})
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	// Changing the email would be enough for taking over the account, so
+	// neither API keys nor OAuth clients can change the account.
+	t.Run("UpdateWithAPIKey", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, requestWithAPIKey(router, "GET", "/users/me", nil))
+
+		code := requestWithAPIKey(router, "PATCH", "/users/me", map[string]string{"email": "attacker@example.com"})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code = requestWithAPIKey(router, "POST", "/users/me/password", users.ChangePasswordBody{
+			CurrentPassword: "password123",
+			Password:        "purple monkey dishwasher",
+			ConfirmPassword: "purple monkey dishwasher",
+		})
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Empty(t, usersService.UpdatedPasswords)
+	})
+
+	t.Run("UpdateWithOAuthClient", func(t *testing.T) {
+		authService.ClientID = "third_party"
+		defer func() { authService.ClientID = "" }()
+
+		code, _ := request("PATCH", "/users/me", map[string]string{"email": "attacker@example.com"})
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, _ = request("POST", "/users/me/password", users.ChangePasswordBody{
+			CurrentPassword: "password123",
+			Password:        "purple monkey dishwasher",
+			ConfirmPassword: "purple monkey dishwasher",
+		})
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Empty(t, usersService.UpdatedPasswords)
+	})
+
+	t.Run("ChangePasswordWithWrongPassword", func(t *testing.T) {
+		code, response := request("POST", "/users/me/password", users.ChangePasswordBody{
+			CurrentPassword: "wrong_password",
+			Password:        "purple monkey dishwasher",
+			ConfirmPassword: "purple monkey dishwasher",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, users.IncorrectPasswordException.Error(), response["error"])
+		assert.Empty(t, usersService.UpdatedPasswords)
+	})
+
+	t.Run("ChangePasswordToWeakPassword", func(t *testing.T) {
+		code, _ := request("POST", "/users/me/password", users.ChangePasswordBody{
+			CurrentPassword: "password123",
+			Password:        "password1234",
+			ConfirmPassword: "password1234",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Empty(t, usersService.UpdatedPasswords)
+	})
+
+	t.Run("ChangePasswordToReusedPassword", func(t *testing.T) {
+		code, response := request("POST", "/users/me/password", users.ChangePasswordBody{
+			CurrentPassword: "password123",
+			Password:        "correct horse battery staple",
+			ConfirmPassword: "correct horse battery staple",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, response["errors"], map[string]interface{}{
+			"field":   "password",
+			"message": users.PasswordReusedException.Error(),
+		})
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		code, response := request("POST", "/users/me/password", users.ChangePasswordBody{
+			CurrentPassword: "password123",
+			Password:        "purple monkey dishwasher",
+			ConfirmPassword: "purple monkey dishwasher",
+		})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, float64(1), response["revoked_sessions"])
+		assert.Equal(t, "purple monkey dishwasher", usersService.UpdatedPasswords[1])
+
+		// Only the other sessions of the user are revoked.
+		assert.Contains(t, sessions.Sessions, int32(1))
+		assert.NotContains(t, sessions.Sessions, int32(2))
+		assert.Contains(t, sessions.Sessions, int32(3))
+	})
+}
+
+func TestUsersController_GetAll(t *testing.T) {
+	authService := &mocks.MockAuthService{EmailVerified: true, Scopes: []string{"users:read"}}
+	usersService := &mocks.MockUsersService{UserCount: 5}
+	router := newUsersRouter(authService, usersService, &mocks.MockSessionsService{})
+
+	// list performs a request for a page of users and returns their ids and
+	// the links of the pages around it.
+	list := func(url string) (int, []float64, map[string]string, http.Header) {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response []map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		ids := []float64{}
+		for _, user := range response {
+			ids = append(ids, user["id"].(float64))
+		}
+
+		links := map[string]string{}
+		for _, match := range regexp.MustCompile(`<([^>]*)>; rel="(\w+)"`).FindAllStringSubmatch(w.Header().Get("Link"), -1) {
+			links[match[2]] = match[1]
+		}
+		return w.Code, ids, links, w.Header()
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		// Test case 1: The first page links to the next one
+		code, ids, links, header := list("/users/?limit=2&total=true")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []float64{1, 2}, ids)
+		assert.Equal(t, "5", header.Get("X-Total-Count"))
+		assert.NotContains(t, links, "prev")
+		require.Contains(t, links, "next")
+
+		// Test case 2: Which links to both
+		code, ids, links, _ = list(links["next"])
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []float64{3, 4}, ids)
+		require.Contains(t, links, "prev")
+		require.Contains(t, links, "next")
+		previous := links["prev"]
+
+		// Test case 3: Up to the last one
+		_, ids, links, _ = list(links["next"])
+		assert.Equal(t, []float64{5}, ids)
+		assert.NotContains(t, links, "next")
+
+		// Test case 4: And back
+		_, ids, links, _ = list(previous)
+		assert.Equal(t, []float64{1, 2}, ids)
+		assert.NotContains(t, links, "prev")
+	})
+
+	t.Run("Sorted", func(t *testing.T) {
+		code, ids, links, _ := list("/users/?limit=3&sort=-created_at")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []float64{5, 4, 3}, ids)
+
+		_, ids, _, _ = list(links["next"])
+		assert.Equal(t, []float64{2, 1}, ids)
+	})
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+		code, _, _, _ := list("/users/?sort=password")
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, _, _, _ = list("/users/?after=forged")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Filtered", func(t *testing.T) {
+		// Test case 1: The filter is passed to the service, and kept in the links
+		code, _, links, _ := list("/users/?limit=2&filter=" + url.QueryEscape("created_at>2023-01-01;username==ale*"))
+		assert.Equal(t, http.StatusOK, code)
+		require.NotNil(t, usersService.Filter)
+		condition, args := usersService.Filter.SQL(1)
+		assert.Equal(t, `(u.created_at > $1 AND u.username LIKE $2 ESCAPE '\')`, condition)
+		assert.Len(t, args, 2)
+		assert.Contains(t, links["next"], "filter=")
+
+		// Test case 2: Invalid filters are reported by field
+		code, response := request(router, "GET", "/users/?filter="+url.QueryEscape("password==x;id==one"), nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+		errors := response["errors"].([]interface{})
+		require.Len(t, errors, 2)
+		assert.Equal(t, "filter.password", errors[0].(map[string]interface{})["field"])
+		assert.Equal(t, "filter.id", errors[1].(map[string]interface{})["field"])
+	})
+}
+
+func TestUsersController_Search(t *testing.T) {
+	authService := &mocks.MockAuthService{EmailVerified: true, Scopes: []string{"users:read"}}
+	usersService := &mocks.MockUsersService{UserCount: 3}
+	router := newUsersRouter(authService, usersService, &mocks.MockSessionsService{})
+
+	// search performs a search and returns the results and the Link header.
+	search := func(query string) (int, []map[string]interface{}, string) {
+		req, _ := http.NewRequest("GET", "/users/search?"+query, nil)
+		req.Header.Set("Authorization", "Bearer mock_jwt_token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response []map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response, w.Header().Get("Link")
+	}
+
+	t.Run("Ranked", func(t *testing.T) {
+		// Test case 1: The results are ranked and highlighted
+		code, results, link := search("q=user&limit=2")
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, results, 2)
+		assert.Equal(t, float64(1), results[0]["id"])
+		assert.Equal(t, "user", results[0]["username"])
+		assert.Equal(t, map[string]interface{}{
+			"username": "<mark>user</mark>",
+			"email":    "<mark>user</mark>@example.com",
+		}, results[0]["highlights"])
+		assert.NotContains(t, results[0], "password")
+		assert.Contains(t, link, `offset=2`)
+		assert.Contains(t, link, `rel="next"`)
+
+		// Test case 2: The last page links back
+		code, results, link = search("q=user&limit=2&offset=2")
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, results, 1)
+		assert.NotContains(t, link, `rel="next"`)
+		assert.Contains(t, link, `rel="prev"`)
+	})
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+		for _, query := range []string{"", "q=%25%25", "q=" + strings.Repeat("a", 101), "q=user&offset=-1"} {
+			code, response := request(router, "GET", "/users/search?"+query, nil)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+			assert.Contains(t, response, "errors", query)
+		}
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		authService.Scopes = nil
+		defer func() { authService.Scopes = []string{"users:read"} }()
+
+		code, _, _ := search("q=user")
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+}
+
+func TestUsersController_ChangePassword_Throttled(t *testing.T) {
+	usersService := &mocks.MockUsersService{}
+	attempts := &mocks.MockLoginAttemptsService{}
+	router := newUsersRouterWithAttempts(&mocks.MockAuthService{}, usersService, &mocks.MockSessionsService{}, attempts)
+
+	changePassword := func(current string) int {
+		code, _ := request(router, "POST", "/users/me/password", users.ChangePasswordBody{
+			CurrentPassword: current,
+			Password:        "purple monkey dishwasher",
+			ConfirmPassword: "purple monkey dishwasher",
+		})
+		return code
+	}
+
+	// Wrong passwords count as failed logins of the account
+	for i := 0; i < mocks.MockLoginAttemptsThreshold; i++ {
+		assert.Equal(t, http.StatusBadRequest, changePassword("wrong_password"))
+	}
+	assert.Equal(t, mocks.MockLoginAttemptsThreshold, attempts.Failures["user@example.com"])
+
+	// So the password cannot be guessed indefinitely, even the right one is
+	// rejected until the account can log in again
+	assert.Equal(t, http.StatusTooManyRequests, changePassword("password123"))
+	assert.Empty(t, usersService.UpdatedPasswords)
+}
@@ -5,7 +5,7 @@ Abstract: A representation of a user in the database.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -32,7 +32,10 @@ SOFTWARE.
 package users
 
 import (
+	"strconv"
 	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
 )
 
 // ======== TYPES ========
@@ -45,15 +48,53 @@ type InternalUser struct {
 	Email     string
 	Password  string
 	CreatedAt time.Time
+	// EmailVerifiedAt is when the user verified their email, or nil if
+	// they have not done it yet.
+	EmailVerifiedAt *time.Time
 }
 
 // PublicUser is basically a user that will be returned by the api. As its own
 // name says, it should be used for returning user data publicly.
 type PublicUser struct {
-	ID        int32     `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            int32     `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UserMatch is a user found by a search, along with how relevant it is.
+type UserMatch struct {
+	User InternalUser
+	Rank float64
+}
+
+// UserSearchResult is a user found by a search as returned by the api. The
+// highlights are the username and email with the fragments that match the
+// search wrapped in <mark> tags.
+type UserSearchResult struct {
+	PublicUser
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// ======== CONSTANTS ========
+
+// UserSortFields are the fields lists of users can be sorted by.
+var UserSortFields = []common.SortField{
+	{Name: "id", Column: "u.id"},
+	{Name: "username", Column: "u.username"},
+	{Name: "email", Column: "u.email"},
+	{Name: "created_at", Column: "u.created_at"},
+}
+
+// UserFilterFields are the fields lists of users can be filtered by.
+var UserFilterFields = []common.FilterField{
+	{Name: "id", Column: "u.id", Type: common.FilterInteger},
+	{Name: "username", Column: "u.username", Type: common.FilterString},
+	{Name: "email", Column: "u.email", Type: common.FilterString},
+	{Name: "email_verified", Column: "(u.email_verified_at IS NOT NULL)", Type: common.FilterBoolean},
+	{Name: "created_at", Column: "u.created_at", Type: common.FilterDate},
 }
 
 // ======== PUBLIC METHODS ========
@@ -61,20 +102,59 @@ type PublicUser struct {
 // Converts an internal user to a public user.
 func (self InternalUser) ToPublic() PublicUser {
 	return PublicUser{
-		ID:        self.ID,
-		Username:  self.Username,
-		Email:     self.Email,
-		CreatedAt: self.CreatedAt,
+		ID:            self.ID,
+		Username:      self.Username,
+		Email:         self.Email,
+		EmailVerified: self.EmailVerifiedAt != nil,
+		CreatedAt:     self.CreatedAt,
+	}
+}
+
+// ToSearchResult converts a match of a search into a search result, with the
+// fragments that match the terms of the search highlighted.
+func (self UserMatch) ToSearchResult(terms []string) UserSearchResult {
+	return UserSearchResult{
+		PublicUser: self.User.ToPublic(),
+		Rank:       self.Rank,
+		Highlights: map[string]string{
+			"username": common.Search.Highlight(self.User.Username, terms),
+			"email":    common.Search.Highlight(self.User.Email, terms),
+		},
+	}
+}
+
+// CursorKey returns the value of a sort field of the user along with its id,
+// which is what the cursors of the pages of users point to.
+func (self InternalUser) CursorKey(field string) (string, int64) {
+	switch field {
+	case "username":
+		return self.Username, int64(self.ID)
+	case "email":
+		return self.Email, int64(self.ID)
+	case "created_at":
+		// The column is a date.
+		return self.CreatedAt.Format("2006-01-02"), int64(self.ID)
+	default:
+		return strconv.Itoa(int(self.ID)), int64(self.ID)
 	}
 }
 
 // Creates a new instance of an internal user from data.
 func InternalUserFromData(values []interface{}) InternalUser {
-	return InternalUser{
+	user := InternalUser{
 		ID:        values[0].(int32),
 		Username:  values[1].(string),
 		Email:     values[2].(string),
 		Password:  values[3].(string),
 		CreatedAt: values[4].(time.Time),
 	}
+
+	// The column is null until the user verifies their email.
+	if len(values) > 5 {
+		if verifiedAt, ok := values[5].(time.Time); ok {
+			user.EmailVerifiedAt = &verifiedAt
+		}
+	}
+
+	return user
 }
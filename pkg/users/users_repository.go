@@ -5,7 +5,7 @@ Abstract: Interface for the UsersService used for avoiding import/dependency cyc
 and allowing to mock these services in tests.
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/26/2023
-Last Updated: 07/26/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -31,6 +31,21 @@ SOFTWARE.
 */
 package users
 
+import (
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+)
+
+// ======== ERRORS ========
+
+var (
+	PasswordReusedException = errors.New("This password has been used recently. Please choose a different one.")
+	UserNotFoundException   = errors.New("The user could not be found.")
+	UsernameTakenException  = errors.New("The username is already taken.")
+)
+
 // ======== INTERFACES ========
 
 // The interface for the AuthService.
@@ -39,7 +54,25 @@ type UsersRepository interface {
 
 	GetUserByEmail(email string) (*InternalUser, error)
 
-	GetUsers() (users []InternalUser, err error)
+	GetUsers(page common.PageRequest, filter *common.Filter) (users []InternalUser, err error)
+
+	CountUsers(filter *common.Filter) (int64, error)
+
+	SearchUsers(query string, limit int, offset int) ([]UserMatch, error)
 
 	CreateUser(email string, username string, password string) (*int32, error)
+
+	CheckPasswordHistory(id int32, password string) error
+
+	UpdatePassword(id int32, password string) error
+
+	RehashPassword(id int32, password string) error
+
+	UpdateUser(id int32, username string, email string) (*InternalUser, error)
+
+	DeleteUser(id int32) error
+
+	MarkEmailVerified(id int32) error
+
+	DeleteUnverifiedUsers(createdBefore time.Time) (int64, error)
 }
@@ -5,7 +5,7 @@ Abstract: The file containing all the user routes.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -66,9 +66,42 @@ func SetUsersRoutes(
 // Setup the user routes
 func (route UsersRoutes) Setup() {
 	route.logger.Info("Setting up [USERS] routes.")
-	api := route.router.Group("/users").Use(route.authMiddleware.Handler())
+	api := route.router.Group("/users").Use(route.authMiddleware.Handler(middlewares.RequireVerifiedEmail()))
 	{
-		api.GET("/", route.usersController.GetAll)
+		api.GET("/", route.authMiddleware.Require("users:read"), route.usersController.GetAll)
+		api.GET("/search", route.authMiddleware.Require("users:read"), route.usersController.Search)
 		api.GET("/:id", route.usersController.Get)
 	}
+
+	// The authenticated user can always see and fix their own account, even
+	// before verifying their email, since it may have a typo. As with the
+	// other routes that manage the account, it cannot be changed with an API
+	// key or the token of an OAuth client, since changing the email would
+	// be enough for taking over the account, nor while impersonating its user.
+	me := route.router.Group("/users/me")
+	changeMe := route.authMiddleware.Handler(
+		middlewares.RejectAPIKeys(),
+		middlewares.RejectOAuthClients(),
+		middlewares.RejectImpersonation(),
+	)
+	{
+		me.GET("", route.authMiddleware.Handler(), route.usersController.Me)
+		me.PATCH("", changeMe, route.usersController.UpdateMe)
+		me.POST("/password", changeMe, route.usersController.ChangePassword)
+	}
+
+	// Users can change or delete their own account without any permission,
+	// so, as with the other routes that manage the account, it cannot be done
+	// with an API key or the token of an OAuth client, nor while impersonating
+	// its user.
+	account := route.router.Group("/users").Use(route.authMiddleware.Handler(
+		middlewares.RequireVerifiedEmail(),
+		middlewares.RejectAPIKeys(),
+		middlewares.RejectOAuthClients(),
+		middlewares.RejectImpersonation(),
+	))
+	{
+		account.PUT("/:id", route.usersController.Update)
+		account.DELETE("/:id", route.usersController.Delete)
+	}
 }
@@ -5,7 +5,7 @@ Abstract: The user service for performing operations upon users in the database.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/08/2023
-Last Updated: 07/24/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -39,24 +39,40 @@ import (
 
 	"github.com/alexmodrono/gin-restapi-template/pkg/common"
 	"github.com/alexmodrono/gin-restapi-template/pkg/lib"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // ======== TYPES ========
 
+// queryer runs queries, either on the pool or in a transaction.
+type queryer interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
 // UsersService service layer
 type UsersService struct {
 	logger lib.Logger
 	db     *lib.Database
+	hasher common.PasswordHasher
+	policy common.PasswordPolicy
 }
 
 // ======== PUBLIC METHODS ========
 
 // GetUsersService returns the user service.
-func GetUsersService(logger lib.Logger, db *lib.Database) UsersRepository {
+func GetUsersService(
+	logger lib.Logger,
+	db *lib.Database,
+	hasher common.PasswordHasher,
+	policy common.PasswordPolicy,
+) UsersRepository {
 	return UsersService{
 		logger: logger,
 		db:     db,
+		hasher: hasher,
+		policy: policy,
 	}
 }
 
@@ -78,21 +94,34 @@ func (service UsersService) GetUserByEmail(email string) (*InternalUser, error)
 	return service.getUserByQuery("email", email)
 }
 
-// GetUsers returns all the users
-func (service UsersService) GetUsers() (users []InternalUser, err error) {
-	rows, err := service.db.Query(context.Background(), "SELECT * FROM auth.user;")
-	service.logger.Info("Retrieving all users.")
+// GetUsers returns a page of the users that match a filter. The users are
+// selected from the cursor of the page on, so that every page is as fast as the
+// first one.
+func (service UsersService) GetUsers(page common.PageRequest, filter *common.Filter) (users []InternalUser, err error) {
+	service.logger.Info("Retrieving a page of users sorted by", page.Sort.Name)
+
+	filterCondition, args := filter.SQL(1)
+	condition, order, limit, keysetArgs := page.Keyset("u.id", len(args)+1)
+	rows, err := service.db.Query(
+		context.Background(),
+		fmt.Sprintf(
+			`SELECT u.* FROM auth.user u WHERE %s AND %s ORDER BY %s LIMIT %d;`,
+			filterCondition, condition, order, limit,
+		),
+		append(args, keysetArgs...)...,
+	)
 	if err != nil {
-		service.logger.Fatal("Error while executing query. Err:", err)
+		service.logger.Error("Error while executing query. Err:", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	var results []InternalUser
+	results := []InternalUser{}
 
 	for rows.Next() {
 		values, err := rows.Values()
 		if err != nil {
-			service.logger.Fatal("Error while iterating dataset. Err:", err)
+			service.logger.Error("Error while iterating dataset. Err:", err)
 			return nil, err
 		}
 
@@ -101,14 +130,75 @@ func (service UsersService) GetUsers() (users []InternalUser, err error) {
 		results = append(results, InternalUserFromData(values))
 	}
 
-	return results, nil
+	return results, rows.Err()
+}
+
+// CountUsers returns the number of users that match a filter.
+func (service UsersService) CountUsers(filter *common.Filter) (int64, error) {
+	condition, args := filter.SQL(1)
+
+	var count int64
+	err := service.db.QueryRow(
+		context.Background(),
+		fmt.Sprintf(`SELECT count(*) FROM auth.user u WHERE %s;`, condition),
+		args...,
+	).Scan(&count)
+	return count, err
+}
+
+// SearchUsers returns the users whose username or email contain the query,
+// resemble it, or have words starting with its terms, from the most relevant.
+// Whole words weigh more than similar text, and usernames more than emails.
+func (service UsersService) SearchUsers(query string, limit int, offset int) ([]UserMatch, error) {
+	service.logger.Info("Searching users matching", query)
+
+	rows, err := service.db.Query(
+		context.Background(),
+		`WITH search AS (
+			SELECT $1::text AS text, to_tsquery('simple', $2) AS query
+		)
+		SELECT (
+			ts_rank(auth.user_search_document(u.username, u.email), s.query)
+			+ greatest(word_similarity(s.text, u.username), word_similarity(s.text, u.email) * 0.8)
+		)::float8 AS rank, u.*
+		FROM auth.user u, search s
+		WHERE auth.user_search_document(u.username, u.email) @@ s.query
+			OR u.username ILIKE $3 ESCAPE '\'
+			OR u.email ILIKE $3 ESCAPE '\'
+			OR s.text <% u.username
+			OR s.text <% u.email
+		ORDER BY rank DESC, u.id
+		LIMIT $4 OFFSET $5;`,
+		query,
+		common.Search.PrefixQuery(common.Search.Terms(query)),
+		common.Search.ContainsPattern(query),
+		limit,
+		offset,
+	)
+	if err != nil {
+		service.logger.Error("Error while executing query. Err:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []UserMatch{}
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			service.logger.Error("Error while iterating dataset. Err:", err)
+			return nil, err
+		}
+		results = append(results, UserMatch{User: InternalUserFromData(values[1:]), Rank: values[0].(float64)})
+	}
+
+	return results, rows.Err()
 }
 
 // CreateUser inserts a new user in the database
 func (service UsersService) CreateUser(email string, username string, password string) (*int32, error) {
 
 	// ======== HASHING THE PASSWORD ========
-	hashedPassword, err := common.Hasher.Hash(password)
+	hashedPassword, err := service.hasher.Hash(password)
 	if err != nil {
 		service.logger.Fatal("An error ocurred while hashing the password:", err)
 		return nil, err
@@ -125,35 +215,323 @@ func (service UsersService) CreateUser(email string, username string, password s
 		time.Now(),
 	).Scan(&id)
 	if err != nil {
-		return handleError(err, username, email)
+		return nil, handleError(err, email)
 	}
 
 	// Return the first user in the result set.
 	return &id, nil
 }
 
-// TODO: add update and delete operations for users.
+// CheckPasswordHistory returns PasswordReusedException if a password is the
+// current password of a user or one of the previous ones kept in their history.
+// UpdatePassword checks it again, so this is only for rejecting passwords before
+// doing anything that cannot be undone.
+func (service UsersService) CheckPasswordHistory(id int32, password string) error {
+	_, err := service.checkPasswordHistory(context.Background(), service.db, id, password, false)
+	return err
+}
+
+// UpdatePassword hashes the new password of a user and stores it. The password
+// cannot be the current one nor any of the previous ones kept in the history of
+// the user, as set by the password policy.
+func (service UsersService) UpdatePassword(id int32, password string) error {
+	service.logger.Info("Updating the password of user with id", id)
+
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	// ======== CHECKING THE HISTORY ========
+	// The row of the user is locked so that concurrent changes cannot skip the
+	// history of each other.
+	current, err := service.checkPasswordHistory(ctx, tx, id, password, true)
+	if err != nil {
+		return err
+	}
+	kept := service.historyKept()
+
+	// ======== HASHING THE PASSWORD ========
+	hashedPassword, err := service.hasher.Hash(password)
+	if err != nil {
+		service.logger.Error("An error ocurred while hashing the password:", err)
+		return err
+	}
+
+	// ======== QUERIES ========
+	_, err = tx.Exec(ctx, `UPDATE auth.user SET password = $2 WHERE id = $1;`, id, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	// The current password becomes the newest of the history, which only keeps
+	// the ones that are checked along with the current password.
+	if kept > 0 {
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO auth.password_history (user_id, password) VALUES ($1, $2);`,
+			id,
+			current,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(
+		ctx,
+		`DELETE FROM auth.password_history WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM auth.password_history
+			WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+		);`,
+		id,
+		kept,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RehashPassword stores the password of a user hashed with the current
+// parameters of the hasher. Unlike UpdatePassword, the password is the same, so
+// it is not checked against nor added to the history.
+func (service UsersService) RehashPassword(id int32, password string) error {
+	service.logger.Info("Rehashing the password of user with id", id)
+
+	// ======== HASHING THE PASSWORD ========
+	hashedPassword, err := service.hasher.Hash(password)
+	if err != nil {
+		service.logger.Error("An error ocurred while hashing the password:", err)
+		return err
+	}
+
+	// ======== QUERIES ========
+	tag, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.user SET password = $2 WHERE id = $1;`,
+		id,
+		hashedPassword,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("The user with the id '%d' could not be found.", id)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that the user has verified their email. Verifying an
+// email that was already verified keeps the original date.
+func (service UsersService) MarkEmailVerified(id int32) error {
+	service.logger.Info("Marking the email of user with id", id, "as verified")
+
+	tag, err := service.db.Exec(
+		context.Background(),
+		`UPDATE auth.user SET email_verified_at = coalesce(email_verified_at, now()) WHERE id = $1;`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("The user with the id '%d' could not be found.", id)
+	}
+
+	return nil
+}
+
+// DeleteUnverifiedUsers deletes the users that signed up before the date given
+// and have not verified their email yet, and returns how many were deleted.
+func (service UsersService) DeleteUnverifiedUsers(createdBefore time.Time) (int64, error) {
+	tag, err := service.db.Exec(
+		context.Background(),
+		`DELETE FROM auth.user WHERE email_verified_at IS NULL AND created_at < $1;`,
+		createdBefore,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// UpdateUser changes the username and email of a user and returns the user
+// updated. A new email is not verified until the user verifies it, and the
+// links sent to the old one stop working.
+func (service UsersService) UpdateUser(id int32, username string, email string) (*InternalUser, error) {
+	service.logger.Info("Updating user with id", id)
+
+	ctx := context.Background()
+	tx, err := service.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback(ctx)
+
+	// The old email is locked along with the row so that it can be compared
+	// with the new one.
+	var previousEmail string
+	err = tx.QueryRow(ctx, `SELECT email FROM auth.user WHERE id = $1 FOR UPDATE;`, id).Scan(&previousEmail)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("The user with the id '%d' could not be found.", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := InternalUser{}
+	err = tx.QueryRow(
+		ctx,
+		`UPDATE auth.user SET username = $2, email = $3,
+			email_verified_at = CASE WHEN email = $3 THEN email_verified_at END
+		WHERE id = $1
+		RETURNING id, username, email, password, created_at, email_verified_at;`,
+		id,
+		username,
+		email,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.EmailVerifiedAt)
+	if err != nil {
+		return nil, handleError(err, email)
+	}
+
+	// ======== INVALIDATE LINKS ========
+	// Verification, password reset and login links were sent to the old
+	// email, so they must not act on the account of the new one.
+	if email != previousEmail {
+		_, err = tx.Exec(
+			ctx,
+			`UPDATE auth.user_token SET used_at = now() WHERE user_id = $1 AND used_at IS NULL;`,
+			id,
+		)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(
+			ctx,
+			`UPDATE auth.magic_link SET used_at = now() WHERE user_id = $1 AND used_at IS NULL;`,
+			id,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// DeleteUser deletes a user along with everything that belongs to them, like
+// their sessions, roles and API keys. The access tokens already issued to the
+// user are not part of the database, so they must be revoked through the auth
+// service before deleting the user.
+func (service UsersService) DeleteUser(id int32) error {
+	service.logger.Info("Deleting user with id", id)
+
+	tag, err := service.db.Exec(context.Background(), `DELETE FROM auth.user WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("The user with the id '%d' could not be found.", id)
+	}
+
+	return nil
+}
 
 // ======== PRIVATE METHODS ========
 
-// Converts an error to a more user-friendly error.
-func handleError(err error, username string, email string) (*int32, error) {
+// historyKept returns the number of previous passwords kept in the history of
+// every user. The current password counts as the newest of the history.
+func (service UsersService) historyKept() int {
+	if service.policy.History > 1 {
+		return service.policy.History - 1
+	}
+	return 0
+}
+
+// checkPasswordHistory returns the current password hash of a user, or
+// PasswordReusedException if the password is the current one or one of the
+// history. If lock is set, the row of the user is locked for the transaction.
+func (service UsersService) checkPasswordHistory(
+	ctx context.Context,
+	db queryer,
+	id int32,
+	password string,
+	lock bool,
+) (string, error) {
+	query := `SELECT password FROM auth.user WHERE id = $1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+
+	var current string
+	err := db.QueryRow(ctx, query+`;`, id).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("The user with the id '%d' could not be found.", id)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	previous := []string{}
+	if kept := service.historyKept(); kept > 0 {
+		rows, err := db.Query(
+			ctx,
+			`SELECT password FROM auth.password_history
+			WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2;`,
+			id,
+			kept,
+		)
+		if err != nil {
+			return "", err
+		}
+		previous, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if service.policy.History > 0 {
+		for _, hash := range append([]string{current}, previous...) {
+			// Hashes that cannot be checked, like the unusable passwords of users
+			// that signed up with a social login, are never reused.
+			if matches, err := service.hasher.Compare(password, hash); err == nil && matches {
+				return "", PasswordReusedException
+			}
+		}
+	}
+
+	return current, nil
+}
+
+// Converts an error of an insert or update to a more user-friendly error.
+func handleError(err error, email string) error {
 	// Check if the error is a PostgreSQL error (*pgconn.PgError)
 	// and handle unique constraint violations based on the constraint name.
 	if pgerr, ok := err.(*pgconn.PgError); ok {
 		if pgerr.ConstraintName == "user_username_unique" {
 			// The username already exists, return a specific error message.
-			return nil, fmt.Errorf("Username %s is already taken.", username)
+			return UsernameTakenException
 		} else if pgerr.ConstraintName == "user_email_unique" {
 			// The email already exists, return a specific error message.
-			return nil, fmt.Errorf("User with email %s already exists.", email)
+			return fmt.Errorf("User with email %s already exists.", email)
 		} else {
 			// Handle other PostgreSQL errors.
-			return nil, fmt.Errorf("Unexpected error while performing operation on user %s: %v\n", email, pgerr)
+			return fmt.Errorf("Unexpected error while performing operation on user %s: %v\n", email, pgerr)
 		}
 	}
 	// Handle other types of errors (non-PostgreSQL errors).
-	return nil, fmt.Errorf("Unexpected error while performing operation on user %s: %v\n", email, err)
+	return fmt.Errorf("Unexpected error while performing operation on user %s: %v\n", email, err)
 }
 
 // getUserByQuery returns a user from the database based on a specific query.
@@ -205,23 +583,5 @@ func (service UsersService) getUserByQuery(queryType string, args ...interface{}
 		return &user, nil
 	}
 
-	var message string
-	if val, ok := args[0].(int); ok {
-		message = fmt.Sprintf(
-			"The user with the %s '%d' could not be found.",
-			queryType,
-			val,
-		)
-	} else if val, ok := args[0].(string); ok {
-		message = fmt.Sprintf(
-			"The user with the %s '%s' could not be found.",
-			queryType,
-			val,
-		)
-	} else {
-		// Handle the case when args[0] is neither int nor string.
-		message = "Invalid value for the user query."
-	}
-
-	return nil, errors.New(message)
+	return nil, UserNotFoundException
 }
@@ -0,0 +1,39 @@
+/*
+File Name: create_api_keys_table.sql
+Abstract: This file contains the table that stores the API keys of the
+users. Only the prefix of each key, which identifies it, and the SHA-256
+hash of the whole key are stored.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.api_key
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    name          varchar(100)  not null,
+    prefix        varchar(16)   not null,
+    key_hash      varchar(64)   not null,
+    -- An empty array grants every permission of the user.
+    scopes        text[]        not null default '{}',
+    created_at    timestamptz   not null default now(),
+    expires_at    timestamptz,
+    last_used_at  timestamptz,
+    revoked_at    timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT api_key_prefix_unique UNIQUE (prefix)
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS api_key_user_idx
+    ON auth.api_key (user_id);
+
+ALTER TABLE auth.api_key
+    owner to api;
@@ -0,0 +1,52 @@
+/*
+File Name: create_identities_tables.sql
+Abstract: This file contains the tables used for logging in with external
+OpenID Connect providers: the identities of the providers linked to each
+user, and the logins that have been started but not finished yet.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.user_identity
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    -- The issuer and subject claims of the ID tokens of the user.
+    issuer        varchar(255)  not null,
+    subject       varchar(255)  not null,
+    email         varchar(100),
+    created_at    timestamptz   not null default now(),
+    last_login_at timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT user_identity_subject_unique UNIQUE (issuer, subject)
+);
+
+-- Only the hash of the state is stored. The nonce and the code verifier are
+-- useless without the authorization code, which is never stored.
+CREATE TABLE IF NOT EXISTS auth.oidc_authorization
+(
+    -- ======== KEYS ========
+    state_hash    varchar(64)   not null
+            primary key,
+    nonce         varchar(64)   not null,
+    code_verifier varchar(128)  not null,
+    created_at    timestamptz   not null default now(),
+    expires_at    timestamptz   not null
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS user_identity_user_idx
+    ON auth.user_identity (user_id);
+
+ALTER TABLE auth.user_identity
+    owner to api;
+
+ALTER TABLE auth.oidc_authorization
+    owner to api;
@@ -0,0 +1,69 @@
+/*
+File Name: create_impersonations_tables.sql
+Abstract: This file contains the tables that keep the audit trail of the
+impersonations started by admins: who impersonated whom, why and until
+when, along with every request made while impersonating the user. The ids
+of the users are not foreign keys, so that the trail outlives them.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.impersonation
+(
+    -- ======== KEYS ========
+    id              SERIAL        not null
+            primary key,
+    -- The sid of the token issued for the impersonation.
+    session_id      varchar(64)   not null,
+    actor_id        integer       not null,
+    user_id         integer       not null,
+    reason          varchar(500)  not null,
+    ip_address      varchar(45)   not null default '',
+    created_at      timestamptz   not null default now(),
+    expires_at      timestamptz   not null,
+    ended_at        timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT impersonation_session_id_unique UNIQUE (session_id)
+);
+
+CREATE TABLE IF NOT EXISTS auth.impersonation_request
+(
+    -- ======== KEYS ========
+    id                BIGSERIAL     not null
+            primary key,
+    impersonation_id  integer       not null
+            references auth.impersonation (id) on delete cascade,
+    method            varchar(10)   not null,
+    path              text          not null,
+    -- Set once the request has been handled.
+    status            smallint,
+    ip_address        varchar(45)   not null default '',
+    user_agent        text          not null default '',
+    created_at        timestamptz   not null default now()
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS impersonation_request_impersonation_idx
+    ON auth.impersonation_request (impersonation_id);
+
+ALTER TABLE auth.impersonation
+    owner to api;
+
+ALTER TABLE auth.impersonation_request
+    owner to api;
+
+-- ======== DATA ========
+INSERT INTO auth.permission (name, description)
+VALUES ('users:impersonate', 'Act as any user with fewer permissions, and end impersonations.'),
+       ('impersonations:read', 'List the impersonations and the requests made during them.')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO auth.role_permission (role_id, permission_id)
+SELECT r.id, p.id
+FROM auth.role r, auth.permission p
+WHERE r.name = 'admin' AND p.name IN ('users:impersonate', 'impersonations:read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,40 @@
+/*
+File Name: create_login_attempts_table.sql
+Abstract: This file contains the table that keeps track of the failed
+login attempts of every account and IP address, which is used for
+slowing down and locking out brute force attacks.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.login_attempt
+(
+    -- ======== KEYS ========
+    -- Either 'account' (the identifier is the email) or 'ip'.
+    kind            varchar(16)   not null,
+    identifier      varchar(255)  not null,
+    failures        integer       not null default 0,
+    last_failure_at timestamptz   not null default now(),
+    locked_until    timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    primary key (kind, identifier)
+);
+
+ALTER TABLE auth.login_attempt
+    owner to api;
+
+-- ======== DATA ========
+INSERT INTO auth.permission (name, description)
+VALUES ('lockouts:read', 'List the accounts and IP addresses that are locked out.'),
+       ('lockouts:write', 'Lift the lockout of an account or IP address.')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO auth.role_permission (role_id, permission_id)
+SELECT r.id, p.id
+FROM auth.role r, auth.permission p
+WHERE r.name = 'admin' AND p.name LIKE 'lockouts:%'
+ON CONFLICT DO NOTHING;
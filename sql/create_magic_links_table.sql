@@ -0,0 +1,38 @@
+/*
+File Name: create_magic_links_table.sql
+Abstract: This file contains the table that stores the links sent to users
+for logging in without a password. Only the SHA-256 hashes of the token of
+each link and of the nonce of the browser that asked for it are stored.
+Links are also recorded for emails without an account, so that they are
+rate limited the same way.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.magic_link
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    email         varchar(255)  not null,
+    user_id       integer
+            references auth.user (id) on delete cascade,
+    token_hash    varchar(64)   not null,
+    nonce_hash    varchar(64)   not null,
+    created_at    timestamptz   not null default now(),
+    expires_at    timestamptz   not null,
+    used_at       timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT magic_link_token_hash_unique UNIQUE (token_hash)
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS magic_link_email_created_at_idx
+    ON auth.magic_link (email, created_at);
+
+ALTER TABLE auth.magic_link
+    owner to api;
@@ -0,0 +1,45 @@
+/*
+File Name: create_mfa_tables.sql
+Abstract: This file contains the tables used for two-factor authentication:
+the TOTP secret of each user and their one-time recovery codes, of which
+only the SHA-256 hash is stored.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.user_mfa
+(
+    -- ======== KEYS ========
+    user_id        integer       not null
+            primary key
+            references auth.user (id) on delete cascade,
+    secret         varchar(64)   not null,
+    created_at     timestamptz   not null default now(),
+    -- Null until the user confirms the enrollment with a code.
+    enabled_at     timestamptz,
+    -- The time step of the last code used, so that codes cannot be replayed.
+    last_used_step bigint        not null default 0
+);
+
+ALTER TABLE auth.user_mfa
+    owner to api;
+
+CREATE TABLE IF NOT EXISTS auth.mfa_recovery_code
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    code_hash     varchar(64)   not null,
+    used_at       timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT mfa_recovery_code_unique UNIQUE (user_id, code_hash)
+);
+
+ALTER TABLE auth.mfa_recovery_code
+    owner to api;
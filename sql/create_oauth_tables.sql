@@ -0,0 +1,106 @@
+/*
+File Name: create_oauth_tables.sql
+Abstract: This file contains the tables of the OAuth 2.0 authorization
+server: the registered clients, the consents users give them, and the
+authorization codes and refresh tokens issued to them. Only the hashes of
+the secrets, codes and tokens are stored.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.oauth_client
+(
+    -- ======== KEYS ========
+    id            varchar(64)   not null
+            primary key,
+    name          varchar(100)  not null,
+    -- Null for public clients, which cannot keep a secret.
+    secret_hash   varchar(64),
+    redirect_uris text[]        not null default '{}',
+    scopes        text[]        not null default '{}',
+    grant_types   text[]        not null default '{}',
+    -- The user the client acts as in the client credentials grant.
+    user_id       integer
+            references auth.user (id) on delete cascade,
+    created_at    timestamptz   not null default now()
+);
+
+CREATE TABLE IF NOT EXISTS auth.oauth_consent
+(
+    -- ======== KEYS ========
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    client_id     varchar(64)   not null
+            references auth.oauth_client (id) on delete cascade,
+    scopes        text[]        not null default '{}',
+    granted_at    timestamptz   not null default now(),
+
+    primary key (user_id, client_id)
+);
+
+CREATE TABLE IF NOT EXISTS auth.oauth_authorization_code
+(
+    -- ======== KEYS ========
+    code_hash      varchar(64)  not null
+            primary key,
+    client_id      varchar(64)  not null
+            references auth.oauth_client (id) on delete cascade,
+    user_id        integer      not null
+            references auth.user (id) on delete cascade,
+    scopes         text[]       not null default '{}',
+    redirect_uri   text         not null,
+    code_challenge varchar(128) not null,
+    created_at     timestamptz  not null default now(),
+    expires_at     timestamptz  not null,
+    used_at        timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS auth.oauth_refresh_token
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    token_hash    varchar(64)   not null,
+    client_id     varchar(64)   not null
+            references auth.oauth_client (id) on delete cascade,
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    scopes        text[]        not null default '{}',
+    created_at    timestamptz   not null default now(),
+    expires_at    timestamptz   not null,
+    revoked_at    timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT oauth_refresh_token_hash_unique UNIQUE (token_hash)
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS oauth_refresh_token_user_client_idx
+    ON auth.oauth_refresh_token (user_id, client_id);
+
+ALTER TABLE auth.oauth_client
+    owner to api;
+
+ALTER TABLE auth.oauth_consent
+    owner to api;
+
+ALTER TABLE auth.oauth_authorization_code
+    owner to api;
+
+ALTER TABLE auth.oauth_refresh_token
+    owner to api;
+
+-- ======== DATA ========
+INSERT INTO auth.permission (name, description)
+VALUES ('oauth:read', 'List the registered OAuth clients.'),
+       ('oauth:write', 'Register and remove OAuth clients.')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO auth.role_permission (role_id, permission_id)
+SELECT r.id, p.id
+FROM auth.role r, auth.permission p
+WHERE r.name = 'admin' AND p.name LIKE 'oauth:%'
+ON CONFLICT DO NOTHING;
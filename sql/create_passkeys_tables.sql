@@ -0,0 +1,55 @@
+/*
+File Name: create_passkeys_tables.sql
+Abstract: This file contains the tables that store the passkeys (WebAuthn
+credentials) of the users, along with their signature counters, and the
+challenges of the ceremonies in progress, which can only be used once.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.passkey
+(
+    -- ======== KEYS ========
+    id              SERIAL        not null
+            primary key,
+    user_id         integer       not null
+            references auth.user (id) on delete cascade,
+    name            varchar(100)  not null,
+    credential_id   bytea         not null,
+    -- The COSE encoding of the public key of the credential.
+    public_key      bytea         not null,
+    sign_count      bigint        not null default 0,
+    backup_eligible boolean       not null default false,
+    backed_up       boolean       not null default false,
+    created_at      timestamptz   not null default now(),
+    last_used_at    timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT passkey_credential_id_unique UNIQUE (credential_id)
+);
+
+CREATE TABLE IF NOT EXISTS auth.passkey_ceremony
+(
+    -- ======== KEYS ========
+    challenge_hash  varchar(64)   not null
+            primary key,
+    -- The user is only known when registering a passkey or using one as a
+    -- second factor, not when logging in with one.
+    user_id         integer
+            references auth.user (id) on delete cascade,
+    purpose         varchar(32)   not null,
+    expires_at      timestamptz   not null
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS passkey_user_idx
+    ON auth.passkey (user_id);
+
+ALTER TABLE auth.passkey
+    owner to api;
+
+ALTER TABLE auth.passkey_ceremony
+    owner to api;
@@ -0,0 +1,29 @@
+/*
+File Name: create_password_history_table.sql
+Abstract: This file contains the table that stores the previous password
+hashes of every user, so that recent passwords cannot be chosen again. Only
+as many hashes as the password policy checks are kept.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.password_history
+(
+    -- ======== KEYS ========
+    id         SERIAL       not null
+            primary key,
+    user_id    integer      not null
+            references auth.user (id) on delete cascade,
+    password   varchar(100) not null,
+    created_at timestamptz  not null default now()
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS password_history_user_idx
+    ON auth.password_history (user_id, created_at DESC);
+
+ALTER TABLE auth.password_history
+    owner to api;
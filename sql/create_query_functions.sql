@@ -7,13 +7,15 @@ the database driver.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/10/2023
-Last Updated: 07/12/2023
+Last Updated: 10/16/2026
 */
 
 -- ======== QUERY FUNCTIONS ========
 -- ===== FILTER QUERIES =====
 -- This fuction returns username, email, and created_at values
 -- for the given input user id
+-- The function is dropped first because its return type cannot be replaced.
+DROP FUNCTION IF EXISTS auth.get_user_by_id(int);
 CREATE OR REPLACE FUNCTION auth.get_user_by_id(for_id int)
     RETURNS TABLE
             (
@@ -21,14 +23,15 @@ CREATE OR REPLACE FUNCTION auth.get_user_by_id(for_id int)
                 username    varchar,
                 email       varchar,
                 password    varchar,
-                created_at  date
+                created_at  date,
+                email_verified_at timestamptz
             )
     language plpgsql
 AS
 $$
 BEGIN
     RETURN QUERY
-        SELECT u.id, u.username, u.email, u.password, u.created_at
+        SELECT u.id, u.username, u.email, u.password, u.created_at, u.email_verified_at
         FROM auth.user u
         WHERE u.id = for_id;
 END
@@ -36,6 +39,8 @@ $$;
 
 -- This fuction returns username, email, and created_at values
 -- for the given input user email
+-- The function is dropped first because its return type cannot be replaced.
+DROP FUNCTION IF EXISTS auth.get_user_by_email(varchar);
 CREATE OR REPLACE FUNCTION auth.get_user_by_email(for_email varchar)
     RETURNS TABLE
             (
@@ -43,14 +48,15 @@ CREATE OR REPLACE FUNCTION auth.get_user_by_email(for_email varchar)
                 username    varchar,
                 email       varchar,
                 password    varchar,
-                created_at  date
+                created_at  date,
+                email_verified_at timestamptz
             )
     language plpgsql
 AS
 $$
 BEGIN
     RETURN QUERY
-        SELECT u.id, u.username, u.email, u.password, u.created_at
+        SELECT u.id, u.username, u.email, u.password, u.created_at, u.email_verified_at
         FROM auth.user u
         WHERE u.email = for_email;
 END
@@ -58,6 +64,8 @@ $$;
 
 -- This fuction returns username, email, and created_at values
 -- for the given input username
+-- The function is dropped first because its return type cannot be replaced.
+DROP FUNCTION IF EXISTS auth.get_user_by_username(varchar);
 CREATE OR REPLACE FUNCTION auth.get_user_by_username(for_username varchar)
     RETURNS TABLE
             (
@@ -65,14 +73,15 @@ CREATE OR REPLACE FUNCTION auth.get_user_by_username(for_username varchar)
                 username    varchar,
                 email       varchar,
                 password    varchar,
-                created_at  date
+                created_at  date,
+                email_verified_at timestamptz
             )
     language plpgsql
 AS
 $$
 BEGIN
     RETURN QUERY
-        SELECT u.id, u.username, u.email, u.password, u.created_at
+        SELECT u.id, u.username, u.email, u.password, u.created_at, u.email_verified_at
         FROM auth.user u
         WHERE u.username = for_username;
 END
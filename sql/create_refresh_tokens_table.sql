@@ -0,0 +1,41 @@
+/*
+File Name: create_refresh_tokens_table.sql
+Abstract: This file contains the table that stores the refresh tokens
+handed out to users. Only the SHA-256 hash of each token is stored, and
+tokens are grouped in families so that all the tokens derived from the
+same login can be revoked at once.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.refresh_token
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    family_id     varchar(64)   not null,
+    auth_method   varchar(32)   not null,
+    token_hash    varchar(64)   not null,
+    created_at    timestamptz   not null default now(),
+    expires_at    timestamptz   not null,
+    used_at       timestamptz,
+    revoked_at    timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT refresh_token_hash_unique UNIQUE (token_hash)
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS refresh_token_family_idx
+    ON auth.refresh_token (family_id);
+
+CREATE INDEX IF NOT EXISTS refresh_token_user_idx
+    ON auth.refresh_token (user_id);
+
+ALTER TABLE auth.refresh_token
+    owner to api;
@@ -0,0 +1,59 @@
+/*
+File Name: create_revoked_tokens_table.sql
+Abstract: This file contains the tables used for revoking access tokens
+before they expire. Single tokens are revoked by their `jti` claim, ending a
+session revokes the tokens bound to it, and logging out of every device
+revokes all the tokens of a user issued before a given moment. Rows can be
+purged once the tokens they refer to expire.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.revoked_token
+(
+    -- ======== KEYS ========
+    jti           varchar(64)   not null
+            primary key,
+    expires_at    timestamptz   not null,
+    revoked_at    timestamptz   not null default now()
+);
+
+CREATE TABLE IF NOT EXISTS auth.revoked_user_tokens
+(
+    -- ======== KEYS ========
+    -- The user is not a foreign key, since the revocation must outlive
+    -- the user for the tokens of deleted users to stay revoked.
+    user_id       integer       not null
+            primary key,
+    issued_before timestamptz   not null,
+    expires_at    timestamptz   not null
+);
+
+CREATE TABLE IF NOT EXISTS auth.revoked_session
+(
+    -- ======== KEYS ========
+    session_id    varchar(64)   not null
+            primary key,
+    expires_at    timestamptz   not null
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS revoked_token_expires_idx
+    ON auth.revoked_token (expires_at);
+
+ALTER TABLE auth.revoked_token
+    owner to api;
+
+ALTER TABLE auth.revoked_user_tokens
+    owner to api;
+
+ALTER TABLE auth.revoked_session
+    owner to api;
+
+-- ======== MIGRATIONS ========
+-- Deleting a user used to delete the revocation of their tokens too.
+ALTER TABLE auth.revoked_user_tokens
+    DROP CONSTRAINT IF EXISTS revoked_user_tokens_user_id_fkey;
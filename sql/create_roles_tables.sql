@@ -0,0 +1,90 @@
+/*
+File Name: create_roles_tables.sql
+Abstract: This file contains the tables used for role-based access
+control. Permissions are granted to roles, and roles are assigned to
+users. The permissions of a user are embedded in the tokens issued to
+them, so changes take effect the next time their tokens are refreshed.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.role
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    name          varchar(50)   not null,
+    description   varchar(255)  not null default '',
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT role_name_unique UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS auth.permission
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    name          varchar(100)  not null,
+    description   varchar(255)  not null default '',
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT permission_name_unique UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS auth.role_permission
+(
+    -- ======== KEYS ========
+    role_id       integer       not null
+            references auth.role (id) on delete cascade,
+    permission_id integer       not null
+            references auth.permission (id) on delete cascade,
+
+    primary key (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS auth.user_role
+(
+    -- ======== KEYS ========
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    role_id       integer       not null
+            references auth.role (id) on delete cascade,
+    assigned_at   timestamptz   not null default now(),
+
+    primary key (user_id, role_id)
+);
+
+ALTER TABLE auth.role
+    owner to api;
+
+ALTER TABLE auth.permission
+    owner to api;
+
+ALTER TABLE auth.role_permission
+    owner to api;
+
+ALTER TABLE auth.user_role
+    owner to api;
+
+-- ======== DATA ========
+INSERT INTO auth.permission (name, description)
+VALUES ('users:read', 'List and read any user.'),
+       ('users:write', 'Update and delete any user.'),
+       ('roles:read', 'List the roles and the roles of any user.'),
+       ('roles:write', 'Assign roles to and remove roles from any user.')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO auth.role (name, description)
+VALUES ('admin', 'Has every permission.')
+ON CONFLICT (name) DO NOTHING;
+
+-- The admin role is granted every permission that exists.
+INSERT INTO auth.role_permission (role_id, permission_id)
+SELECT r.id, p.id
+FROM auth.role r, auth.permission p
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,47 @@
+/*
+File Name: create_sessions_table.sql
+Abstract: This file contains the table that stores the sessions started by
+every login, which users can list and end. Browser clients are identified
+by the cookie of their session, while the refresh tokens of every other
+client belong to the family of their session. Only the SHA-256 hashes of
+the session and CSRF tokens are stored.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.session
+(
+    -- ======== KEYS ========
+    id              SERIAL        not null
+            primary key,
+    user_id         integer       not null
+            references auth.user (id) on delete cascade,
+    -- Only set for cookie sessions.
+    token_hash      varchar(64),
+    csrf_token_hash varchar(64),
+    -- Only set for token sessions.
+    family_id       varchar(64),
+    auth_method     varchar(16)   not null,
+    device_name     varchar(100)  not null default '',
+    user_agent      text          not null default '',
+    ip_address      varchar(45)   not null default '',
+    created_at      timestamptz   not null default now(),
+    last_seen_at    timestamptz   not null default now(),
+    expires_at      timestamptz   not null,
+    revoked_at      timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT session_token_hash_unique UNIQUE (token_hash),
+    CONSTRAINT session_family_id_unique UNIQUE (family_id),
+    CONSTRAINT session_kind_check CHECK ((token_hash IS NULL) <> (family_id IS NULL))
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS session_user_idx
+    ON auth.session (user_id);
+
+ALTER TABLE auth.session
+    owner to api;
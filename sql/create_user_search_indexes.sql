@@ -0,0 +1,46 @@
+/*
+File Name: create_user_search_indexes.sql
+Abstract: This file contains the extensions, functions and indexes that
+back the search of users by username or email: trigram indexes for partial
+and fuzzy matches, and a full-text index for matching whole words.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== EXTENSIONS ========
+-- pg_trgm is a trusted extension since PostgreSQL 13, so the owner of the
+-- database can create it.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- ======== FUNCTIONS ========
+-- This function returns the text search document of a user. The parts of
+-- the email are split into words, so that "alex@example.com" matches
+-- "example". It is immutable so that it can be indexed, and queries must
+-- call it exactly like the index does to use it.
+CREATE OR REPLACE FUNCTION auth.user_search_document(username text, email text)
+    RETURNS tsvector
+    language sql
+    immutable
+    parallel safe
+AS
+$$
+    SELECT setweight(to_tsvector('simple', username), 'A') ||
+           setweight(to_tsvector('simple', translate(email, '@.+-_', '     ')), 'B');
+$$;
+
+ALTER FUNCTION auth.user_search_document(text, text)
+    owner to api;
+
+-- ======== INDEXES ========
+-- The trigram indexes serve both the similarity operators and ILIKE
+-- with wildcards on both sides.
+CREATE INDEX IF NOT EXISTS user_username_trgm_idx
+    ON auth.user USING gin (username gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS user_email_trgm_idx
+    ON auth.user USING gin (email gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS user_search_document_idx
+    ON auth.user USING gin (auth.user_search_document(username, email));
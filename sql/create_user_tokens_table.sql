@@ -0,0 +1,35 @@
+/*
+File Name: create_user_tokens_table.sql
+Abstract: This file contains the table that stores the single-use tokens
+sent to users by email (e.g. for resetting their password). Only the
+SHA-256 hash of each token is stored, along with what it can be used for.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+*/
+
+-- ======== TABLES ========
+CREATE TABLE IF NOT EXISTS auth.user_token
+(
+    -- ======== KEYS ========
+    id            SERIAL        not null
+            primary key,
+    user_id       integer       not null
+            references auth.user (id) on delete cascade,
+    purpose       varchar(32)   not null,
+    token_hash    varchar(64)   not null,
+    created_at    timestamptz   not null default now(),
+    expires_at    timestamptz   not null,
+    used_at       timestamptz,
+
+    -- ======== CONSTRAINTS ========
+    CONSTRAINT user_token_hash_unique UNIQUE (token_hash)
+);
+
+-- ======== INDEXES ========
+CREATE INDEX IF NOT EXISTS user_token_user_purpose_idx
+    ON auth.user_token (user_id, purpose);
+
+ALTER TABLE auth.user_token
+    owner to api;
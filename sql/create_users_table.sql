@@ -7,7 +7,7 @@ the database driver.
 
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/10/2023
-Last Updated: 07/12/2023
+Last Updated: 10/16/2026
 */
 
 -- ======== SCHEMAS ========
@@ -23,6 +23,7 @@ CREATE TABLE auth.user
     email         varchar(100)  not null,
     password      varchar(100)  not null,
     created_at    date          not null,
+    email_verified_at timestamptz,
 
     -- ======== CONSTRAINTS ========
     CONSTRAINT user_email_unique UNIQUE (email),
@@ -31,3 +32,9 @@ CREATE TABLE auth.user
 
 ALTER TABLE auth.user
     owner to api;
+
+-- ======== MIGRATIONS ========
+-- Adds the columns introduced after the table was first created to
+-- existing databases.
+ALTER TABLE auth.user
+    ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
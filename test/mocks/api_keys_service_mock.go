@@ -0,0 +1,96 @@
+/*
+Package Name: mocks
+File Name: api_keys_service_mock.go
+Abstract: Mock of the API keys service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// MockAPIKey is the key accepted by the MockAPIKeysService.
+const MockAPIKey = "sk_000000000000_mock_api_key"
+
+// Mock APIKeysService for testing purposes
+type MockAPIKeysService struct {
+	// Keys are the API keys created, indexed by their id.
+	Keys map[int32]interfaces.APIKey
+	// Scopes are the permissions granted to the principal returned by CheckAPIKey.
+	Scopes []string
+}
+
+func (s *MockAPIKeysService) CreateAPIKey(userID int32, name string, scopes []string, expiresAt *time.Time) (*interfaces.APIKey, string, error) {
+	// Mock the CreateAPIKey method to always return MockAPIKey.
+	if s.Keys == nil {
+		s.Keys = map[int32]interfaces.APIKey{}
+	}
+	apiKey := interfaces.APIKey{
+		ID:        int32(len(s.Keys) + 1),
+		Name:      name,
+		Prefix:    "000000000000",
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	s.Keys[apiKey.ID] = apiKey
+	return &apiKey, MockAPIKey, nil
+}
+
+func (s *MockAPIKeysService) GetAPIKeys(userID int32) ([]interfaces.APIKey, error) {
+	apiKeys := []interfaces.APIKey{}
+	for _, apiKey := range s.Keys {
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, nil
+}
+
+func (s *MockAPIKeysService) RevokeAPIKey(userID int32, id int32) error {
+	if _, ok := s.Keys[id]; !ok {
+		return interfaces.APIKeyNotFoundException
+	}
+	delete(s.Keys, id)
+	return nil
+}
+
+func (s *MockAPIKeysService) CheckAPIKey(key string) (*interfaces.Principal, error) {
+	// Mock the CheckAPIKey method so that only MockAPIKey is valid.
+	if key != MockAPIKey {
+		return nil, interfaces.InvalidAPIKeyException
+	}
+	return &interfaces.Principal{
+		UserID:        1,
+		SessionID:     "api_key:1",
+		Scopes:        s.Scopes,
+		AuthMethod:    interfaces.AuthMethodAPIKey,
+		EmailVerified: true,
+	}, nil
+}
@@ -4,7 +4,7 @@ File Name: auth_service_mock.go
 Abstract: Interface for mocking the auth service in tests.
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/26/2023
-Last Updated: 07/26/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -30,20 +30,112 @@ SOFTWARE.
 */
 package mocks
 
+import (
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
 // Mock AuthService for testing purposes
-type MockAuthService struct{}
+type MockAuthService struct {
+	// RevokedTokens records the ids of the tokens revoked through RevokeToken.
+	RevokedTokens []string
+	// RevokedUsers records the users whose tokens were revoked through RevokeAllTokens.
+	RevokedUsers []int32
+	// Scopes are the permissions granted to the principal returned by CheckToken.
+	Scopes []string
+	// EmailVerified is whether the principal returned by CheckToken has verified their email.
+	EmailVerified bool
+	// Devices records the devices tokens were issued to through IssueTokens.
+	Devices []interfaces.Device
+	// ActorID is the admin impersonating the principal returned by CheckToken.
+	ActorID int32
+	// ClientID is the OAuth client the token checked by CheckToken was issued to.
+	ClientID string
+}
 
-func (s *MockAuthService) CreateToken(userID int32) (*string, error) {
+func (s *MockAuthService) CreateToken(principal interfaces.Principal) (*string, error) {
 	// Mock the CreateToken method to return a test JWT token for testing.
 	// You can replace this with any logic to generate a mock JWT token for testing.
 	token := "mock_jwt_token"
 	return &token, nil
 }
 
-func (s *MockAuthService) CheckToken(tokenString string) (*int32, error) {
-	// Mock the CheckToken method to return the subject of the payload of a JWT
-	// for testing.
-	// You can replace this with any logic to generate a random ID for testing.
-	sub := int32(1)
-	return &sub, nil
+func (s *MockAuthService) CheckToken(tokenString string) (*interfaces.Principal, error) {
+	// Mock the CheckToken method to return the principal of a JWT for testing.
+	// You can replace this with any logic to generate a random principal for testing.
+	return &interfaces.Principal{
+		UserID:        1,
+		SessionID:     "mock_session",
+		TokenID:       "mock_jti",
+		Scopes:        s.Scopes,
+		AuthMethod:    interfaces.AuthMethodPassword,
+		EmailVerified: s.EmailVerified,
+		ActorID:       s.ActorID,
+		ClientID:      s.ClientID,
+	}, nil
+}
+
+func (s *MockAuthService) CreateImpersonationToken(actorID int32, userID int32, sessionID string, ttl time.Duration) (*string, error) {
+	// Mock the CreateImpersonationToken method to return a known token for testing.
+	token := "mock_impersonation_token"
+	return &token, nil
+}
+
+func (s *MockAuthService) IssueTokens(userID int32, method string, device interfaces.Device) (*interfaces.TokenPair, error) {
+	// Mock the IssueTokens method to return a known pair of tokens for testing.
+	s.Devices = append(s.Devices, device)
+	return &interfaces.TokenPair{
+		AccessToken:  "mock_jwt_token",
+		RefreshToken: "mock_refresh_token",
+		ExpiresIn:    900,
+	}, nil
+}
+
+func (s *MockAuthService) RefreshTokens(refreshToken string) (*interfaces.TokenPair, error) {
+	// Mock the RefreshTokens method so that only the refresh token returned by
+	// IssueTokens can be exchanged, and only once.
+	switch refreshToken {
+	case "mock_refresh_token":
+		return &interfaces.TokenPair{
+			AccessToken:  "mock_jwt_token",
+			RefreshToken: "mock_rotated_refresh_token",
+			ExpiresIn:    900,
+		}, nil
+	case "mock_used_refresh_token":
+		return nil, interfaces.ReusedRefreshTokenException
+	}
+	return nil, interfaces.InvalidRefreshTokenException
+}
+
+func (s *MockAuthService) RevokeToken(principal interfaces.Principal) error {
+	// Mock the RevokeToken method by recording the revoked token.
+	s.RevokedTokens = append(s.RevokedTokens, principal.TokenID)
+	return nil
+}
+
+func (s *MockAuthService) RevokeAllTokens(userID int32) error {
+	// Mock the RevokeAllTokens method by recording the user.
+	s.RevokedUsers = append(s.RevokedUsers, userID)
+	return nil
+}
+
+func (s *MockAuthService) PublicKeys() common.JWKS {
+	// Mock the PublicKeys method with an empty key set.
+	return common.JWKS{Keys: []common.JWK{}}
+}
+
+func (s *MockAuthService) CreateChallenge(id int32, purpose string) (string, error) {
+	// Mock the CreateChallenge method to return a known challenge token for testing.
+	return "mock_" + purpose + "_challenge_token", nil
+}
+
+func (s *MockAuthService) CheckChallenge(tokenString string, purpose string) (int32, error) {
+	// Mock the CheckChallenge method so that only the token returned by
+	// CreateChallenge for the same purpose is valid.
+	if tokenString == "mock_"+purpose+"_challenge_token" {
+		return 1, nil
+	}
+	return 0, interfaces.InvalidChallengeException
 }
@@ -0,0 +1,79 @@
+/*
+Package Name: mocks
+File Name: identities_service_mock.go
+Abstract: Mock of the identities service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// Mock IdentitiesService for testing purposes
+type MockIdentitiesService struct {
+	// Authorizations are the pending logins, indexed by their state.
+	Authorizations map[string]interfaces.OIDCAuthorization
+	// Identities are the users linked to each subject.
+	Identities map[string]int32
+}
+
+func (s *MockIdentitiesService) SaveAuthorization(state string, authorization interfaces.OIDCAuthorization, ttl time.Duration) error {
+	if s.Authorizations == nil {
+		s.Authorizations = map[string]interfaces.OIDCAuthorization{}
+	}
+	s.Authorizations[state] = authorization
+	return nil
+}
+
+func (s *MockIdentitiesService) ConsumeAuthorization(state string) (*interfaces.OIDCAuthorization, error) {
+	authorization, ok := s.Authorizations[state]
+	if !ok {
+		return nil, interfaces.InvalidOIDCStateException
+	}
+	delete(s.Authorizations, state)
+	return &authorization, nil
+}
+
+func (s *MockIdentitiesService) GetIdentityUser(issuer string, subject string) (int32, error) {
+	userID, ok := s.Identities[subject]
+	if !ok {
+		return 0, interfaces.IdentityNotFoundException
+	}
+	return userID, nil
+}
+
+func (s *MockIdentitiesService) LinkIdentity(userID int32, issuer string, subject string, email string) error {
+	if s.Identities == nil {
+		s.Identities = map[string]int32{}
+	}
+	s.Identities[subject] = userID
+	return nil
+}
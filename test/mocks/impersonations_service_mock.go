@@ -0,0 +1,117 @@
+/*
+Package Name: mocks
+File Name: impersonations_service_mock.go
+Abstract: Mock implementation of the ImpersonationsService for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// Mock ImpersonationsService for testing purposes
+type MockImpersonationsService struct {
+	Impersonations []interfaces.Impersonation
+	// Requests records the requests recorded through RecordRequest, with the
+	// status they were completed with.
+	Requests []interfaces.ImpersonatedRequest
+	// Unavailable makes RecordRequest fail, as if the database was down.
+	Unavailable bool
+}
+
+func (s *MockImpersonationsService) StartImpersonation(
+	actorID int32,
+	userID int32,
+	reason string,
+	ipAddress string,
+	ttl time.Duration,
+) (*interfaces.Impersonation, error) {
+	// Mock the StartImpersonation method so that the session of the first
+	// impersonation is the one of the principal returned by MockAuthService.
+	impersonation := interfaces.Impersonation{
+		ID:        int32(len(s.Impersonations) + 1),
+		ActorID:   actorID,
+		UserID:    userID,
+		Reason:    reason,
+		IPAddress: ipAddress,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(ttl),
+		SessionID: "mock_session",
+	}
+	s.Impersonations = append(s.Impersonations, impersonation)
+	return &impersonation, nil
+}
+
+func (s *MockImpersonationsService) GetImpersonations() ([]interfaces.Impersonation, error) {
+	impersonations := []interfaces.Impersonation{}
+	for i := len(s.Impersonations) - 1; i >= 0; i-- {
+		impersonations = append(impersonations, s.Impersonations[i])
+	}
+	return impersonations, nil
+}
+
+func (s *MockImpersonationsService) GetImpersonatedRequests(id int32) ([]interfaces.ImpersonatedRequest, error) {
+	// Every recorded request belongs to the first impersonation.
+	if id < 1 || int(id) > len(s.Impersonations) {
+		return nil, interfaces.ImpersonationNotFoundException
+	}
+	if id != 1 {
+		return []interfaces.ImpersonatedRequest{}, nil
+	}
+	return append([]interfaces.ImpersonatedRequest{}, s.Requests...), nil
+}
+
+func (s *MockImpersonationsService) EndImpersonation(id int32) error {
+	for i := range s.Impersonations {
+		if s.Impersonations[i].ID == id && s.Impersonations[i].EndedAt == nil {
+			now := time.Now()
+			s.Impersonations[i].EndedAt = &now
+			return nil
+		}
+	}
+	return interfaces.ImpersonationNotFoundException
+}
+
+func (s *MockImpersonationsService) RecordRequest(sessionID string, request interfaces.ImpersonatedRequest) (int64, error) {
+	if s.Unavailable {
+		return 0, errors.New("The database is not available.")
+	}
+	request.ID = int64(len(s.Requests) + 1)
+	s.Requests = append(s.Requests, request)
+	return request.ID, nil
+}
+
+func (s *MockImpersonationsService) CompleteRequest(id int64, status int) error {
+	value := int32(status)
+	s.Requests[id-1].Status = &value
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+Package Name: mocks
+File Name: login_attempts_service_mock.go
+Abstract: Mock of the login attempts service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// MockLoginAttemptsThreshold is the number of failures after which the
+// MockLoginAttemptsService locks an account out.
+const MockLoginAttemptsThreshold = 3
+
+// Mock LoginAttemptsService for testing purposes
+type MockLoginAttemptsService struct {
+	// Failures records the failed logins of every account.
+	Failures map[string]int
+	// IPFailures records the failed logins from every IP address.
+	IPFailures map[string]int
+}
+
+func (s *MockLoginAttemptsService) Check(account string, ip string) (time.Duration, error) {
+	// Mock the Check method so that accounts are locked out for a minute
+	// after MockLoginAttemptsThreshold failures.
+	if s.Failures[account] >= MockLoginAttemptsThreshold {
+		return time.Minute, nil
+	}
+	return 0, nil
+}
+
+func (s *MockLoginAttemptsService) RecordFailure(account string, ip string) (time.Duration, error) {
+	if s.Failures == nil {
+		s.Failures = map[string]int{}
+	}
+	s.Failures[account]++
+	if s.IPFailures == nil {
+		s.IPFailures = map[string]int{}
+	}
+	s.IPFailures[ip]++
+	return s.Check(account, ip)
+}
+
+func (s *MockLoginAttemptsService) Reset(account string) error {
+	delete(s.Failures, account)
+	return nil
+}
+
+func (s *MockLoginAttemptsService) GetLockouts() ([]interfaces.Lockout, error) {
+	lockouts := []interfaces.Lockout{}
+	for account, failures := range s.Failures {
+		lockouts = append(lockouts, interfaces.Lockout{
+			Kind:       interfaces.LockoutKindAccount,
+			Identifier: account,
+			Failures:   int32(failures),
+		})
+	}
+	return lockouts, nil
+}
+
+func (s *MockLoginAttemptsService) Unlock(kind string, identifier string) error {
+	if _, ok := s.Failures[identifier]; !ok {
+		return interfaces.LockoutNotFoundException
+	}
+	delete(s.Failures, identifier)
+	return nil
+}
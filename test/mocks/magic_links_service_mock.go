@@ -0,0 +1,95 @@
+/*
+Package Name: mocks
+File Name: magic_links_service_mock.go
+Abstract: Mock of the magic links service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// Mock MagicLinksService for testing purposes
+type MockMagicLinksService struct {
+	// Links maps the tokens of the links created to the links themselves.
+	Links map[string]MockMagicLink
+	// MaxRequests is how many links can be created for an email before
+	// Check asks to wait. Zero means there is no limit.
+	MaxRequests int
+}
+
+// MockMagicLink is a link created by the MockMagicLinksService.
+type MockMagicLink struct {
+	Email  string
+	UserID *int32
+	Nonce  string
+	Used   bool
+}
+
+func (s *MockMagicLinksService) Check(email string) (time.Duration, error) {
+	// Mock the Check method so that only MaxRequests links can be created
+	// for every email.
+	count := 0
+	for _, link := range s.Links {
+		if link.Email == email {
+			count++
+		}
+	}
+	if s.MaxRequests > 0 && count >= s.MaxRequests {
+		return time.Minute, nil
+	}
+	return 0, nil
+}
+
+func (s *MockMagicLinksService) CreateMagicLink(email string, userID *int32, ttl time.Duration) (string, string, error) {
+	// Mock the CreateMagicLink method to return predictable tokens and nonces for testing.
+	if s.Links == nil {
+		s.Links = map[string]MockMagicLink{}
+	}
+	id := len(s.Links) + 1
+	token := fmt.Sprintf("mock_magic_link_token_%d", id)
+	nonce := fmt.Sprintf("mock_magic_link_nonce_%d", id)
+	s.Links[token] = MockMagicLink{Email: email, UserID: userID, Nonce: nonce}
+	return token, nonce, nil
+}
+
+func (s *MockMagicLinksService) ConsumeMagicLink(token string, nonce string) (int32, error) {
+	// Mock the ConsumeMagicLink method so that links can only be used once
+	// and along with their nonce.
+	link, ok := s.Links[token]
+	if !ok || link.Used || link.UserID == nil || link.Nonce != nonce {
+		return 0, interfaces.InvalidMagicLinkException
+	}
+	link.Used = true
+	s.Links[token] = link
+	return *link.UserID, nil
+}
@@ -0,0 +1,101 @@
+/*
+Package Name: mocks
+File Name: mfa_service_mock.go
+Abstract: Mock of the MFA service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import "github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+
+// The codes accepted by the MockMFAService.
+const (
+	MockTOTPCode     = "123456"
+	MockRecoveryCode = "abcde-fghij"
+)
+
+// Mock MFAService for testing purposes
+type MockMFAService struct {
+	// Enrolled records the users that began the enrollment.
+	Enrolled map[int32]bool
+	// Enabled records the users that have two-factor authentication enabled.
+	Enabled map[int32]bool
+}
+
+func (s *MockMFAService) IsEnabled(userID int32) (bool, error) {
+	return s.Enabled[userID], nil
+}
+
+func (s *MockMFAService) BeginEnrollment(userID int32, account string) (*interfaces.MFAEnrollment, error) {
+	// Mock the BeginEnrollment method to return a known secret for testing.
+	if s.Enabled[userID] {
+		return nil, interfaces.MFAAlreadyEnabledException
+	}
+	if s.Enrolled == nil {
+		s.Enrolled = map[int32]bool{}
+	}
+	s.Enrolled[userID] = true
+	return &interfaces.MFAEnrollment{
+		Secret: "JBSWY3DPEHPK3PXP",
+		URI:    "otpauth://totp/API:" + account + "?secret=JBSWY3DPEHPK3PXP",
+	}, nil
+}
+
+func (s *MockMFAService) ConfirmEnrollment(userID int32, code string) ([]string, error) {
+	// Mock the ConfirmEnrollment method so that only MockTOTPCode is valid.
+	if !s.Enrolled[userID] {
+		return nil, interfaces.MFANotEnrolledException
+	}
+	if code != MockTOTPCode {
+		return nil, interfaces.InvalidMFACodeException
+	}
+	if s.Enabled == nil {
+		s.Enabled = map[int32]bool{}
+	}
+	s.Enabled[userID] = true
+	return []string{MockRecoveryCode}, nil
+}
+
+func (s *MockMFAService) Verify(userID int32, code string) error {
+	// Mock the Verify method so that MockTOTPCode and MockRecoveryCode are valid.
+	if !s.Enabled[userID] {
+		return interfaces.MFANotEnabledException
+	}
+	if code != MockTOTPCode && code != MockRecoveryCode {
+		return interfaces.InvalidMFACodeException
+	}
+	return nil
+}
+
+func (s *MockMFAService) Disable(userID int32, code string) error {
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	delete(s.Enabled, userID)
+	return nil
+}
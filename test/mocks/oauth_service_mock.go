@@ -0,0 +1,186 @@
+/*
+Package Name: mocks
+File Name: oauth_service_mock.go
+Abstract: Mock of the OAuth service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// MockOAuthClientSecret is the secret of the confidential clients created by
+// the MockOAuthService.
+const MockOAuthClientSecret = "mock_client_secret"
+
+// Mock OAuthService for testing purposes
+type MockOAuthService struct {
+	// Clients are the registered clients, indexed by their id.
+	Clients map[string]interfaces.OAuthClient
+	// Consents are the scopes consented to, indexed by client id. The mock
+	// only keeps the consents of a single user.
+	Consents map[string][]string
+	// Codes are the authorization codes issued, indexed by the code.
+	Codes map[string]interfaces.OAuthGrant
+	// RefreshTokens are the refresh tokens issued, indexed by the token.
+	RefreshTokens map[string]interfaces.OAuthGrant
+}
+
+func (s *MockOAuthService) CreateClient(client interfaces.OAuthClient) (*interfaces.OAuthClient, string, error) {
+	// Mock the CreateClient method to give clients sequential ids.
+	if s.Clients == nil {
+		s.Clients = map[string]interfaces.OAuthClient{}
+	}
+	client.ID = fmt.Sprintf("client_%d", len(s.Clients)+1)
+	client.CreatedAt = time.Now()
+	s.Clients[client.ID] = client
+
+	if client.Confidential {
+		return &client, MockOAuthClientSecret, nil
+	}
+	return &client, "", nil
+}
+
+func (s *MockOAuthService) GetClients() ([]interfaces.OAuthClient, error) {
+	clients := []interfaces.OAuthClient{}
+	for _, client := range s.Clients {
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+func (s *MockOAuthService) GetClient(id string) (*interfaces.OAuthClient, error) {
+	client, ok := s.Clients[id]
+	if !ok {
+		return nil, interfaces.OAuthClientNotFoundException
+	}
+	return &client, nil
+}
+
+func (s *MockOAuthService) DeleteClient(id string) error {
+	if _, ok := s.Clients[id]; !ok {
+		return interfaces.OAuthClientNotFoundException
+	}
+	delete(s.Clients, id)
+	return nil
+}
+
+func (s *MockOAuthService) AuthenticateClient(id string, secret string) (*interfaces.OAuthClient, error) {
+	// Mock the AuthenticateClient method so that confidential clients must
+	// use MockOAuthClientSecret and public clients no secret at all.
+	client, ok := s.Clients[id]
+	if !ok || (client.Confidential && secret != MockOAuthClientSecret) || (!client.Confidential && secret != "") {
+		return nil, interfaces.InvalidOAuthClientException
+	}
+	return &client, nil
+}
+
+func (s *MockOAuthService) GetConsent(userID int32, clientID string) ([]string, error) {
+	return s.Consents[clientID], nil
+}
+
+func (s *MockOAuthService) SaveConsent(userID int32, clientID string, scopes []string) error {
+	if s.Consents == nil {
+		s.Consents = map[string][]string{}
+	}
+	s.Consents[clientID] = append(s.Consents[clientID], scopes...)
+	return nil
+}
+
+func (s *MockOAuthService) GetConsents(userID int32) ([]interfaces.OAuthConsent, error) {
+	consents := []interfaces.OAuthConsent{}
+	for clientID, scopes := range s.Consents {
+		consents = append(consents, interfaces.OAuthConsent{
+			ClientID:   clientID,
+			ClientName: s.Clients[clientID].Name,
+			Scopes:     scopes,
+			GrantedAt:  time.Now(),
+		})
+	}
+	return consents, nil
+}
+
+func (s *MockOAuthService) RevokeConsent(userID int32, clientID string) error {
+	if _, ok := s.Consents[clientID]; !ok {
+		return interfaces.OAuthConsentNotFoundException
+	}
+	delete(s.Consents, clientID)
+	return nil
+}
+
+func (s *MockOAuthService) IssueAuthorizationCode(grant interfaces.OAuthGrant, ttl time.Duration) (string, error) {
+	if s.Codes == nil {
+		s.Codes = map[string]interfaces.OAuthGrant{}
+	}
+	code := fmt.Sprintf("code_%d", len(s.Codes)+1)
+	s.Codes[code] = grant
+	return code, nil
+}
+
+func (s *MockOAuthService) ConsumeAuthorizationCode(code string) (*interfaces.OAuthGrant, error) {
+	grant, ok := s.Codes[code]
+	if !ok {
+		return nil, interfaces.InvalidOAuthGrantException
+	}
+	delete(s.Codes, code)
+	return &grant, nil
+}
+
+func (s *MockOAuthService) IssueRefreshToken(grant interfaces.OAuthGrant, ttl time.Duration) (string, error) {
+	if s.RefreshTokens == nil {
+		s.RefreshTokens = map[string]interfaces.OAuthGrant{}
+	}
+	token := fmt.Sprintf("refresh_%d_%d", len(s.RefreshTokens)+1, time.Now().UnixNano())
+	s.RefreshTokens[token] = grant
+	return token, nil
+}
+
+func (s *MockOAuthService) RotateRefreshToken(token string, clientID string, ttl time.Duration) (*interfaces.OAuthGrant, string, error) {
+	grant, ok := s.RefreshTokens[token]
+	if !ok || grant.ClientID != clientID {
+		return nil, "", interfaces.InvalidOAuthGrantException
+	}
+	delete(s.RefreshTokens, token)
+
+	newToken, err := s.IssueRefreshToken(grant, ttl)
+	if err != nil {
+		return nil, "", err
+	}
+	return &grant, newToken, nil
+}
+
+func (s *MockOAuthService) RevokeRefreshToken(token string, clientID string) error {
+	if grant, ok := s.RefreshTokens[token]; ok && grant.ClientID == clientID {
+		delete(s.RefreshTokens, token)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+Package Name: mocks
+File Name: oidc_provider_mock.go
+Abstract: A mock OpenID Connect provider served with httptest for testing
+the login with external identity providers.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MockOIDCUser is the user that logs in with the MockOIDCProvider.
+type MockOIDCUser struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+// MockOIDCProvider is an OpenID Connect provider that implements discovery,
+// the token endpoint (with PKCE) and the keys endpoint. The user logs in
+// through Authorize instead of the authorization endpoint.
+type MockOIDCProvider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// User is the user that logs in through Authorize.
+	User MockOIDCUser
+	// Claims are applied to the ID tokens issued, and can be used for
+	// issuing invalid tokens.
+	Claims func(claims jwt.MapClaims)
+
+	key   *rsa.PrivateKey
+	mutex sync.Mutex
+	codes map[string]mockOIDCCode
+}
+
+// mockOIDCCode is an authorization code issued by the MockOIDCProvider.
+type mockOIDCCode struct {
+	nonce     string
+	challenge string
+	user      MockOIDCUser
+}
+
+// NewMockOIDCProvider starts a MockOIDCProvider, which has to be closed.
+func NewMockOIDCProvider() *MockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	provider := &MockOIDCProvider{
+		ClientID:     "mock_client",
+		ClientSecret: "mock_secret",
+		RedirectURL:  "https://app.example.com/callback",
+		User: MockOIDCUser{
+			Subject:           "mock_subject",
+			Email:             "oidc@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "oidc",
+		},
+		key:   key,
+		codes: map[string]mockOIDCCode{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("/jwks", provider.jwks)
+	mux.HandleFunc("/token", provider.token)
+	provider.Server = httptest.NewServer(mux)
+
+	return provider
+}
+
+// Close shuts the provider down.
+func (provider *MockOIDCProvider) Close() {
+	provider.Server.Close()
+}
+
+// Config returns the configuration of the client registered with the provider.
+func (provider *MockOIDCProvider) Config() common.OIDCConfig {
+	return common.OIDCConfig{
+		Issuer:       provider.Server.URL,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  provider.RedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// Authorize logs the user in as if they had been sent to the authorization
+// URL given, and returns the code and state they are sent back with.
+func (provider *MockOIDCProvider) Authorize(authorizationURL string) (code string, state string, err error) {
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	query := parsed.Query()
+	if query.Get("client_id") != provider.ClientID ||
+		query.Get("redirect_uri") != provider.RedirectURL ||
+		query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" {
+		return "", "", errors.New("invalid authorization request")
+	}
+
+	code, err = common.Tokens.Generate()
+	if err != nil {
+		return "", "", err
+	}
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	provider.codes[code] = mockOIDCCode{
+		nonce:     query.Get("nonce"),
+		challenge: query.Get("code_challenge"),
+		user:      provider.User,
+	}
+
+	return code, query.Get("state"), nil
+}
+
+// discovery serves the discovery document of the provider.
+func (provider *MockOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, common.OIDCDiscovery{
+		Issuer:                provider.Server.URL,
+		AuthorizationEndpoint: provider.Server.URL + "/authorize",
+		TokenEndpoint:         provider.Server.URL + "/token",
+		JwksURI:               provider.Server.URL + "/jwks",
+	})
+}
+
+// jwks serves the public key of the provider.
+func (provider *MockOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	jwk, err := common.NewJWK("mock_key", "RS256", &provider.key.PublicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, common.JWKS{Keys: []common.JWK{*jwk}})
+}
+
+// token exchanges an authorization code for an ID token.
+func (provider *MockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, _ := r.BasicAuth()
+	if clientID != provider.ClientID || clientSecret != provider.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	provider.mutex.Lock()
+	code, ok := provider.codes[r.PostFormValue("code")]
+	delete(provider.codes, r.PostFormValue("code"))
+	provider.mutex.Unlock()
+
+	if !ok ||
+		r.PostFormValue("grant_type") != "authorization_code" ||
+		r.PostFormValue("redirect_uri") != provider.RedirectURL ||
+		common.PKCEChallenge(r.PostFormValue("code_verifier")) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                provider.Server.URL,
+		"sub":                code.user.Subject,
+		"aud":                provider.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              code.nonce,
+		"email":              code.user.Email,
+		"email_verified":     code.user.EmailVerified,
+		"preferred_username": code.user.PreferredUsername,
+	}
+	if provider.Claims != nil {
+		provider.Claims(claims)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock_key"
+	idToken, err := token.SignedString(provider.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock_access_token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
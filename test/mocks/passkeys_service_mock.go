@@ -0,0 +1,138 @@
+/*
+Package Name: mocks
+File Name: passkeys_service_mock.go
+Abstract: Mock implementation of the PasskeysService for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// Mock PasskeysService for testing purposes
+type MockPasskeysService struct {
+	Passkeys []interfaces.Passkey
+	// Ceremonies maps the challenges of the ceremonies started to them.
+	Ceremonies map[string]MockPasskeyCeremony
+}
+
+// MockPasskeyCeremony is a ceremony started by the MockPasskeysService.
+type MockPasskeyCeremony struct {
+	UserID  *int32
+	Purpose string
+}
+
+func (s *MockPasskeysService) BeginCeremony(userID *int32, purpose string, ttl time.Duration) ([]byte, error) {
+	// Mock the BeginCeremony method to return predictable challenges for testing.
+	if s.Ceremonies == nil {
+		s.Ceremonies = map[string]MockPasskeyCeremony{}
+	}
+	challenge := []byte(fmt.Sprintf("mock_passkey_challenge_%d", len(s.Ceremonies)+1))
+	s.Ceremonies[string(challenge)] = MockPasskeyCeremony{UserID: userID, Purpose: purpose}
+	return challenge, nil
+}
+
+func (s *MockPasskeysService) ConsumeCeremony(challenge []byte, purpose string) (*int32, error) {
+	// Mock the ConsumeCeremony method so that ceremonies can only be
+	// completed once and for their purpose.
+	ceremony, ok := s.Ceremonies[string(challenge)]
+	if !ok || ceremony.Purpose != purpose {
+		return nil, interfaces.InvalidPasskeyCeremonyException
+	}
+	delete(s.Ceremonies, string(challenge))
+	return ceremony.UserID, nil
+}
+
+func (s *MockPasskeysService) CreatePasskey(userID int32, name string, credential common.WebAuthnCredential) (*interfaces.Passkey, error) {
+	// Mock the CreatePasskey method so that credentials can only be
+	// registered once.
+	for _, passkey := range s.Passkeys {
+		if bytes.Equal(passkey.CredentialID, credential.ID) {
+			return nil, interfaces.PasskeyAlreadyRegisteredException
+		}
+	}
+	passkey := interfaces.Passkey{
+		ID:           int32(len(s.Passkeys) + 1),
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		BackedUp:     credential.BackedUp,
+		CreatedAt:    time.Now(),
+	}
+	s.Passkeys = append(s.Passkeys, passkey)
+	return &passkey, nil
+}
+
+func (s *MockPasskeysService) GetPasskeys(userID int32) ([]interfaces.Passkey, error) {
+	passkeys := []interfaces.Passkey{}
+	for _, passkey := range s.Passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+func (s *MockPasskeysService) GetPasskeyByCredentialID(credentialID []byte) (*interfaces.Passkey, error) {
+	for _, passkey := range s.Passkeys {
+		if bytes.Equal(passkey.CredentialID, credentialID) {
+			return &passkey, nil
+		}
+	}
+	return nil, interfaces.PasskeyNotFoundException
+}
+
+func (s *MockPasskeysService) RecordUse(id int32, signCount uint32) error {
+	for i := range s.Passkeys {
+		if s.Passkeys[i].ID == id {
+			now := time.Now()
+			s.Passkeys[i].SignCount = signCount
+			s.Passkeys[i].LastUsedAt = &now
+			return nil
+		}
+	}
+	return interfaces.PasskeyNotFoundException
+}
+
+func (s *MockPasskeysService) DeletePasskey(userID int32, id int32) error {
+	for i, passkey := range s.Passkeys {
+		if passkey.ID == id && passkey.UserID == userID {
+			s.Passkeys = append(s.Passkeys[:i], s.Passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return interfaces.PasskeyNotFoundException
+}
@@ -0,0 +1,67 @@
+/*
+Package Name: mocks
+File Name: roles_service_mock.go
+Abstract: Mock of the roles service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"fmt"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/roles"
+)
+
+// Mock RolesService for testing purposes
+type MockRolesService struct {
+	// Permissions are the permissions granted to every user by the "user" role.
+	Permissions []string
+}
+
+func (s *MockRolesService) GetRoles() ([]roles.Role, error) {
+	return []roles.Role{{ID: 1, Name: "user", Permissions: s.Permissions}}, nil
+}
+
+func (s *MockRolesService) GetUserRoles(userID int32) ([]roles.Role, error) {
+	return s.GetRoles()
+}
+
+func (s *MockRolesService) GetUserPermissions(userID int32) ([]string, []string, error) {
+	return []string{"user"}, s.Permissions, nil
+}
+
+func (s *MockRolesService) AssignRole(userID int32, role string) error {
+	if role != "user" {
+		return fmt.Errorf("The role '%s' does not exist.", role)
+	}
+	return nil
+}
+
+func (s *MockRolesService) UnassignRole(userID int32, role string) error {
+	return s.AssignRole(userID, role)
+}
@@ -0,0 +1,130 @@
+/*
+Package Name: mocks
+File Name: sessions_service_mock.go
+Abstract: Mock of the sessions service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/common"
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+const (
+	// MockSessionToken is the token of the sessions created by the MockSessionsService.
+	MockSessionToken = "mock_session_token"
+	// MockCSRFToken is the CSRF token of the sessions created by the MockSessionsService.
+	MockCSRFToken = "mock_csrf_token"
+)
+
+// Mock SessionsService for testing purposes
+type MockSessionsService struct {
+	// Sessions are the sessions that have not been revoked, indexed by their id.
+	Sessions map[int32]interfaces.Session
+	// Scopes are the permissions granted to the principal returned by CheckSession.
+	Scopes []string
+}
+
+func (s *MockSessionsService) CreateSession(userID int32, method string, device interfaces.Device) (*interfaces.Session, string, string, error) {
+	// Mock the CreateSession method so that every session has the same tokens.
+	if s.Sessions == nil {
+		s.Sessions = map[int32]interfaces.Session{}
+	}
+	session := interfaces.Session{
+		ID:            int32(len(s.Sessions) + 1),
+		UserID:        userID,
+		Kind:          interfaces.SessionKindCookie,
+		DeviceName:    device.Name,
+		AuthMethod:    method,
+		UserAgent:     device.UserAgent,
+		IPAddress:     device.IPAddress,
+		CreatedAt:     time.Now(),
+		LastSeenAt:    time.Now(),
+		ExpiresAt:     time.Now().Add(time.Hour),
+		CSRFTokenHash: common.Tokens.Hash(MockCSRFToken),
+	}
+	s.Sessions[session.ID] = session
+	return &session, MockSessionToken, MockCSRFToken, nil
+}
+
+func (s *MockSessionsService) CheckSession(token string) (*interfaces.Principal, *interfaces.Session, error) {
+	// Mock the CheckSession method so that only MockSessionToken is valid
+	// while the first session has not been revoked.
+	session, ok := s.Sessions[1]
+	if token != MockSessionToken || !ok {
+		return nil, nil, interfaces.InvalidSessionException
+	}
+	return &interfaces.Principal{
+		UserID:        session.UserID,
+		SessionID:     "session:1",
+		Scopes:        s.Scopes,
+		AuthMethod:    session.AuthMethod,
+		EmailVerified: true,
+	}, &session, nil
+}
+
+func (s *MockSessionsService) GetSessions(userID int32) ([]interfaces.Session, error) {
+	// Mock the GetSessions method by returning the sessions of the user by id.
+	sessions := []interfaces.Session{}
+	for _, session := range s.Sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+	return sessions, nil
+}
+
+func (s *MockSessionsService) RevokeSession(userID int32, id int32) error {
+	// Mock the RevokeSession method so that users can only revoke their own sessions.
+	if session, ok := s.Sessions[id]; !ok || session.UserID != userID {
+		return interfaces.SessionNotFoundException
+	}
+	delete(s.Sessions, id)
+	return nil
+}
+
+func (s *MockSessionsService) RevokeOtherSessions(userID int32, current interfaces.Principal) (int, error) {
+	// Mock the RevokeOtherSessions method by revoking the sessions of the user
+	// but the one of the principal.
+	revoked := 0
+	for id, session := range s.Sessions {
+		currentSession := fmt.Sprintf("session:%d", id) == current.SessionID ||
+			(session.FamilyID != "" && session.FamilyID == current.SessionID)
+		if session.UserID == userID && !currentSession {
+			delete(s.Sessions, id)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
@@ -0,0 +1,89 @@
+/*
+Package Name: mocks
+File Name: user_tokens_service_mock.go
+Abstract: Mock of the user tokens service for testing purposes.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexmodrono/gin-restapi-template/pkg/interfaces"
+)
+
+// Mock UserTokensService for testing purposes
+type MockUserTokensService struct {
+	// Tokens maps the tokens issued to the user and purpose they were issued for.
+	Tokens map[string]MockUserToken
+	// BeforeConsume is called before a token is consumed, for simulating
+	// another request that uses it at the same time.
+	BeforeConsume func(token string)
+}
+
+// MockUserToken is a token issued by the MockUserTokensService.
+type MockUserToken struct {
+	UserID  int32
+	Purpose string
+	Used    bool
+}
+
+func (s *MockUserTokensService) IssueUserToken(userID int32, purpose string, ttl time.Duration) (string, error) {
+	// Mock the IssueUserToken method to return a predictable token for testing.
+	if s.Tokens == nil {
+		s.Tokens = map[string]MockUserToken{}
+	}
+	token := fmt.Sprintf("mock_%s_token_%d", purpose, len(s.Tokens)+1)
+	s.Tokens[token] = MockUserToken{UserID: userID, Purpose: purpose}
+	return token, nil
+}
+
+func (s *MockUserTokensService) CheckUserToken(token string, purpose string) (int32, error) {
+	// Mock the CheckUserToken method to return the user of the tokens that
+	// have not been used yet.
+	issued, ok := s.Tokens[token]
+	if !ok || issued.Used || issued.Purpose != purpose {
+		return 0, interfaces.InvalidUserTokenException
+	}
+	return issued.UserID, nil
+}
+
+func (s *MockUserTokensService) ConsumeUserToken(token string, purpose string) (int32, error) {
+	// Mock the ConsumeUserToken method so that tokens can only be used once.
+	if s.BeforeConsume != nil {
+		s.BeforeConsume(token)
+	}
+	issued, ok := s.Tokens[token]
+	if !ok || issued.Used || issued.Purpose != purpose {
+		return 0, interfaces.InvalidUserTokenException
+	}
+	issued.Used = true
+	s.Tokens[token] = issued
+	return issued.UserID, nil
+}
@@ -4,7 +4,7 @@ File Name: users_service_mock.go
 Abstract: Interface for mocking the users service in tests.
 Author: Alejandro Modroño <alex@sureservice.es>
 Created: 07/26/2023
-Last Updated: 07/26/2023
+Last Updated: 10/16/2026
 
 # MIT License
 
@@ -31,7 +31,9 @@ SOFTWARE.
 package mocks
 
 import (
-	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/alexmodrono/gin-restapi-template/pkg/common"
@@ -39,7 +41,38 @@ import (
 )
 
 // Mock UsersService for testing purposes
-type MockUsersService struct{}
+type MockUsersService struct {
+	// UpdatedPasswords records the new passwords set through UpdatePassword by user id.
+	UpdatedPasswords map[int32]string
+	// PreviousPasswords are the recent passwords of the users, which cannot be
+	// chosen again.
+	PreviousPasswords []string
+	// RehashedPasswords records the passwords rehashed through RehashPassword by user id.
+	RehashedPasswords map[int32]string
+	// VerifiedUsers records the users whose email was marked as verified.
+	VerifiedUsers []int32
+	// PasswordHash is the stored hash of the password of the test user. If it
+	// is empty, "password123" is hashed with the current parameters.
+	PasswordHash string
+	// EmailVerified is whether the test user has verified their email.
+	EmailVerified bool
+	// UserCount is the number of test users listed by GetUsers, 2 by default.
+	UserCount int
+	// Filter records the filter of the last call to GetUsers, which the mock
+	// does not apply.
+	Filter *common.Filter
+	// DeletedUsers records the users deleted through DeleteUser.
+	DeletedUsers []int32
+	// CreatedUsernames records the usernames passed to CreateUser.
+	CreatedUsernames []string
+	// TakenUsernames are the usernames that CreateUser rejects as taken.
+	TakenUsernames []string
+	// CreateUserError is returned by CreateUser if it is set.
+	CreateUserError error
+	// LookupError is returned by GetUserByEmail instead of
+	// users.UserNotFoundException if it is set.
+	LookupError error
+}
 
 func (s *MockUsersService) GetUserById(id int) (*users.InternalUser, error) {
 	// Mock the GetUserByEmail method to return a test user with a known password
@@ -53,47 +86,220 @@ func (s *MockUsersService) GetUserById(id int) (*users.InternalUser, error) {
 			Password: password,
 		}, nil
 	}
-	return nil, errors.New("user not found")
+	if id == 2 {
+		return &users.InternalUser{
+			ID:       2,
+			Username: "user2",
+			Email:    "user2@example.com",
+		}, nil
+	}
+	return nil, users.UserNotFoundException
 }
 
 func (s *MockUsersService) GetUserByEmail(email string) (*users.InternalUser, error) {
 	// Mock the GetUserByEmail method to return a test user with a known password
 	// for testing the login functionality.
 	if email == "user@example.com" {
-		password, _ := common.Hasher.Hash("password123")
-		return &users.InternalUser{
+		password := s.PasswordHash
+		if password == "" {
+			password, _ = common.Hasher.Hash("password123")
+		}
+		user := &users.InternalUser{
 			ID:       1,
 			Username: "user",
 			Email:    "user@example.com",
 			Password: password,
-		}, nil
+		}
+		if s.EmailVerified {
+			verifiedAt := time.Now()
+			user.EmailVerifiedAt = &verifiedAt
+		}
+		return user, nil
 	}
-	return nil, errors.New("user not found")
+	if s.LookupError != nil {
+		return nil, s.LookupError
+	}
+	return nil, users.UserNotFoundException
 }
 
-func (s *MockUsersService) GetUsers() ([]users.InternalUser, error) {
-	users := []users.InternalUser{
-		{
-			ID:        1,
-			Username:  "user",
-			Email:     "user@example.com",
-			Password:  "$argon2id$v=18$m=65536,t=3,p=2$Zm9v$MTIzNDU2",
-			CreatedAt: time.Now(),
-		},
-		{
-			ID:        2,
-			Username:  "user2",
-			Email:     "user2@example.com",
-			Password:  "$argon2id$v=18$m=65536,t=3,p=2$Zm9v$MTIzNDU2",
-			CreatedAt: time.Now(),
-		},
+func (s *MockUsersService) GetUsers(page common.PageRequest, filter *common.Filter) ([]users.InternalUser, error) {
+	// Mock the GetUsers method by selecting the page from the test users as
+	// the keyset of the page would.
+	s.Filter = filter
+	all := s.testUsers()
+
+	// compare compares the sort keys of two users.
+	compare := func(a users.InternalUser, value string, id int64) int {
+		aValue, aID := a.CursorKey(page.Sort.Name)
+		if page.Sort.Name != "id" && aValue != value {
+			return strings.Compare(aValue, value)
+		}
+		if aID < id {
+			return -1
+		} else if aID > id {
+			return 1
+		}
+		return 0
+	}
+
+	backward := page.Before != nil
+	ascending := page.Descending == backward
+	sort.Slice(all, func(i, j int) bool {
+		value, id := all[j].CursorKey(page.Sort.Name)
+		return (compare(all[i], value, id) < 0) == ascending
+	})
+
+	cursor := page.After
+	if backward {
+		cursor = page.Before
 	}
-	return users, nil
+	// One more user than the limit is selected, as the keyset does.
+	selected := []users.InternalUser{}
+	for _, user := range all {
+		if cursor != nil {
+			if order := compare(user, cursor.Value, cursor.ID); order == 0 || (order > 0) != ascending {
+				continue
+			}
+		}
+		if len(selected) <= page.Limit {
+			selected = append(selected, user)
+		}
+	}
+	return selected, nil
+}
+
+func (s *MockUsersService) CountUsers(filter *common.Filter) (int64, error) {
+	// Mock the CountUsers method by counting the test users.
+	return int64(len(s.testUsers())), nil
+}
+
+func (s *MockUsersService) SearchUsers(query string, limit int, offset int) ([]users.UserMatch, error) {
+	// Mock the SearchUsers method by matching the test users whose username or
+	// email contain the query, ranking the shorter ones first.
+	query = strings.ToLower(query)
+	matches := []users.UserMatch{}
+	for _, user := range s.testUsers() {
+		if strings.Contains(user.Username, query) || strings.Contains(user.Email, query) {
+			matches = append(matches, users.UserMatch{User: user, Rank: 1 / float64(len(user.Username))})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Rank > matches[j].Rank })
+
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	matches = matches[offset:]
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
 }
 
 func (s *MockUsersService) CreateUser(email, username, password string) (*int32, error) {
 	// Mock the CreateUser method to return a test user ID for the signup functionality.
 	// You can replace this with any logic to generate a mock user ID for testing.
+	s.CreatedUsernames = append(s.CreatedUsernames, username)
+	if s.CreateUserError != nil {
+		return nil, s.CreateUserError
+	}
+	for _, taken := range s.TakenUsernames {
+		if username == taken {
+			return nil, users.UsernameTakenException
+		}
+	}
 	userID := int32(1)
 	return &userID, nil
 }
+
+func (s *MockUsersService) CheckPasswordHistory(id int32, password string) error {
+	// Mock the CheckPasswordHistory method by rejecting the previous passwords.
+	for _, previous := range s.PreviousPasswords {
+		if password == previous {
+			return users.PasswordReusedException
+		}
+	}
+	return nil
+}
+
+func (s *MockUsersService) UpdatePassword(id int32, password string) error {
+	// Mock the UpdatePassword method to record the new password of the user.
+	if err := s.CheckPasswordHistory(id, password); err != nil {
+		return err
+	}
+	if s.UpdatedPasswords == nil {
+		s.UpdatedPasswords = map[int32]string{}
+	}
+	s.UpdatedPasswords[id] = password
+	return nil
+}
+
+func (s *MockUsersService) RehashPassword(id int32, password string) error {
+	// Mock the RehashPassword method to record the rehashed password of the user.
+	if s.RehashedPasswords == nil {
+		s.RehashedPasswords = map[int32]string{}
+	}
+	s.RehashedPasswords[id] = password
+	return nil
+}
+
+func (s *MockUsersService) UpdateUser(id int32, username string, email string) (*users.InternalUser, error) {
+	// Mock the UpdateUser method to return the test users updated. The username
+	// and email of the second test user are taken.
+	user, err := s.GetUserById(int(id))
+	if err != nil {
+		return nil, err
+	}
+	if id != 2 && username == "user2" {
+		return nil, users.UsernameTakenException
+	}
+	if id != 2 && email == "user2@example.com" {
+		return nil, fmt.Errorf("User with email %s already exists.", email)
+	}
+	user.Username = username
+	user.Email = email
+	return user, nil
+}
+
+func (s *MockUsersService) DeleteUser(id int32) error {
+	// Mock the DeleteUser method to record the users deleted.
+	if _, err := s.GetUserById(int(id)); err != nil {
+		return err
+	}
+	s.DeletedUsers = append(s.DeletedUsers, id)
+	return nil
+}
+
+func (s *MockUsersService) MarkEmailVerified(id int32) error {
+	// Mock the MarkEmailVerified method to record the users that verified their email.
+	s.VerifiedUsers = append(s.VerifiedUsers, id)
+	return nil
+}
+
+func (s *MockUsersService) DeleteUnverifiedUsers(createdBefore time.Time) (int64, error) {
+	// Mock the DeleteUnverifiedUsers method as if there were no unverified users.
+	return 0, nil
+}
+
+// testUsers returns the users listed by GetUsers, named user, user2, user3...
+func (s *MockUsersService) testUsers() []users.InternalUser {
+	count := s.UserCount
+	if count == 0 {
+		count = 2
+	}
+
+	all := make([]users.InternalUser, count)
+	for i := range all {
+		username := "user"
+		if i > 0 {
+			username = fmt.Sprintf("user%d", i+1)
+		}
+		all[i] = users.InternalUser{
+			ID:        int32(i + 1),
+			Username:  username,
+			Email:     username + "@example.com",
+			Password:  "$argon2id$v=18$m=65536,t=3,p=2$Zm9v$MTIzNDU2",
+			CreatedAt: time.Date(2023, 1, 1+i, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return all
+}
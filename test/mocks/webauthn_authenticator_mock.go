@@ -0,0 +1,277 @@
+/*
+Package Name: mocks
+File Name: webauthn_authenticator_mock.go
+Abstract: A software WebAuthn authenticator for testing the registration and login
+with passkeys without a browser.
+
+Author: Alejandro Modroño <alex@sureservice.es>
+Created: 10/16/2026
+Last Updated: 10/16/2026
+
+# MIT License
+
+# Copyright 2023 Alejandro Modroño Vara
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package mocks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// MockAuthenticator is a software authenticator that creates ES256 credentials
+// and signs with them, as a platform authenticator would. Its fields can be
+// changed for producing responses that must be rejected.
+type MockAuthenticator struct {
+	// Origin is the origin the client data is collected from, and RPID the
+	// relying party the credentials are scoped to.
+	Origin string
+	RPID   string
+	// UserVerified is whether the user is verified (e.g. with a PIN or a
+	// fingerprint), besides being present.
+	UserVerified bool
+	// Format is the attestation format, which is either "none" (the
+	// default) or "packed" for self attestation.
+	Format string
+	// CounterStep is how much the signature counter increases with every
+	// signature. Synced passkeys leave it at zero.
+	CounterStep uint32
+
+	credentials map[string]*mockCredential
+}
+
+// MockCredentialResponse is the JSON serialization of the response of an
+// authenticator, as returned by PublicKeyCredential.toJSON() in browsers.
+type MockCredentialResponse struct {
+	ID       string                    `json:"id"`
+	RawID    string                    `json:"rawId"`
+	Type     string                    `json:"type"`
+	Response MockAuthenticatorResponse `json:"response"`
+}
+
+// MockAuthenticatorResponse holds the data of both attestations and assertions.
+type MockAuthenticatorResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AttestationObject string `json:"attestationObject,omitempty"`
+	AuthenticatorData string `json:"authenticatorData,omitempty"`
+	Signature         string `json:"signature,omitempty"`
+	UserHandle        string `json:"userHandle,omitempty"`
+}
+
+// mockCredential is a credential created by the MockAuthenticator.
+type mockCredential struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	counter    uint32
+}
+
+// cborPair is an entry of a CBOR map, which is encoded in order.
+type cborPair struct {
+	key   interface{}
+	value interface{}
+}
+
+// NewMockAuthenticator returns an authenticator that verifies its users.
+func NewMockAuthenticator(origin string, rpID string) *MockAuthenticator {
+	return &MockAuthenticator{
+		Origin:       origin,
+		RPID:         rpID,
+		UserVerified: true,
+		credentials:  map[string]*mockCredential{},
+	}
+}
+
+// Create creates a credential for the user in response to the challenge of a
+// registration ceremony.
+func (a *MockAuthenticator) Create(challenge []byte, userHandle []byte) MockCredentialResponse {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	credential := &mockCredential{id: id, key: key, userHandle: userHandle, counter: a.CounterStep}
+	a.credentials[string(id)] = credential
+
+	clientDataJSON := a.clientData("webauthn.create", challenge)
+
+	// The attested credential data: an empty AAGUID, the length of the id,
+	// the id and the COSE key.
+	attested := make([]byte, 18)
+	binary.BigEndian.PutUint16(attested[16:], uint16(len(id)))
+	attested = append(attested, id...)
+	attested = append(attested, encodeCBOR([]cborPair{
+		{int64(1), int64(2)},
+		{int64(3), int64(-7)},
+		{int64(-1), int64(1)},
+		{int64(-2), padded(key.X)},
+		{int64(-3), padded(key.Y)},
+	})...)
+	authData := append(a.authenticatorData(0x40, credential.counter), attested...)
+
+	format := a.Format
+	statement := []cborPair{}
+	if format == "packed" {
+		statement = []cborPair{
+			{"alg", int64(-7)},
+			{"sig", sign(key, authData, clientDataJSON)},
+		}
+	} else {
+		format = "none"
+	}
+
+	attestationObject := encodeCBOR([]cborPair{
+		{"fmt", format},
+		{"attStmt", statement},
+		{"authData", authData},
+	})
+
+	return MockCredentialResponse{
+		ID:    encode(id),
+		RawID: encode(id),
+		Type:  "public-key",
+		Response: MockAuthenticatorResponse{
+			ClientDataJSON:    encode(clientDataJSON),
+			AttestationObject: encode(attestationObject),
+		},
+	}
+}
+
+// Get signs the challenge of an authentication ceremony with a credential. If
+// the id is nil, the first credential created is used, as when the user picks
+// a passkey.
+func (a *MockAuthenticator) Get(challenge []byte, id []byte) MockCredentialResponse {
+	credential := a.credentials[string(id)]
+	if credential == nil {
+		for _, value := range a.credentials {
+			credential = value
+			break
+		}
+	}
+
+	credential.counter += a.CounterStep
+	clientDataJSON := a.clientData("webauthn.get", challenge)
+	authData := a.authenticatorData(0, credential.counter)
+
+	return MockCredentialResponse{
+		ID:    encode(credential.id),
+		RawID: encode(credential.id),
+		Type:  "public-key",
+		Response: MockAuthenticatorResponse{
+			ClientDataJSON:    encode(clientDataJSON),
+			AuthenticatorData: encode(authData),
+			Signature:         encode(sign(credential.key, authData, clientDataJSON)),
+			UserHandle:        encode(credential.userHandle),
+		},
+	}
+}
+
+// clientData returns the client data a browser would collect for a ceremony.
+func (a *MockAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	clientData, _ := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   encode(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return clientData
+}
+
+// authenticatorData returns the authenticator data with the flags given, besides
+// the user presence and verification flags.
+func (a *MockAuthenticator) authenticatorData(flags byte, counter uint32) []byte {
+	flags |= 0x01
+	if a.UserVerified {
+		flags |= 0x04
+	}
+
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	data := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], counter)
+	return data
+}
+
+// sign signs the authenticator data and the hash of the client data.
+func sign(key *ecdsa.PrivateKey, authData []byte, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	hash := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		panic(err)
+	}
+	return signature
+}
+
+// padded returns a coordinate of a P-256 key with its 32 bytes.
+func padded(value *big.Int) []byte {
+	return value.FillBytes(make([]byte, 32))
+}
+
+// encode encodes binary data with base64url, as WebAuthn does in JSON.
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// encodeCBOR encodes the few CBOR data items authenticators use.
+func encodeCBOR(value interface{}) []byte {
+	switch value := value.(type) {
+	case int64:
+		if value < 0 {
+			return cborHeader(1, uint64(-1-value))
+		}
+		return cborHeader(0, uint64(value))
+	case []byte:
+		return append(cborHeader(2, uint64(len(value))), value...)
+	case string:
+		return append(cborHeader(3, uint64(len(value))), value...)
+	case []cborPair:
+		data := cborHeader(5, uint64(len(value)))
+		for _, pair := range value {
+			data = append(data, encodeCBOR(pair.key)...)
+			data = append(data, encodeCBOR(pair.value)...)
+		}
+		return data
+	}
+	panic(fmt.Sprintf("cannot encode %T", value))
+}
+
+// cborHeader encodes the major type and argument of a data item.
+func cborHeader(major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{major<<5 | byte(argument)}
+	case argument <= 0xff:
+		return []byte{major<<5 | 24, byte(argument)}
+	case argument <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(argument))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(argument))
+	}
+}